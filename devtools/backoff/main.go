@@ -41,6 +41,8 @@ func main() {
 		"ExponentialFull":  config.ExponentialBackoffFullJitter.backoff(*attempts),
 		"ExponentialEqual": config.ExponentialBackoffEqualJitter.backoff(*attempts),
 		"Fibonacci":        config.FibonacciBackoff.backoff(*attempts),
+		"Decorrelated":     config.DecorrelatedJitterBackoff.backoff(*attempts),
+		"Composed":         config.ComposedBackoff.backoff(*attempts),
 	}
 
 	out, err := os.Create(*output)
@@ -60,6 +62,8 @@ func main() {
 		"ExponentialFull",
 		"ExponentialEqual",
 		"Fibonacci",
+		"Decorrelated",
+		"Composed",
 	})
 	if err != nil {
 		panic(err)
@@ -74,6 +78,8 @@ func main() {
 			durations["ExponentialFull"][i],
 			durations["ExponentialEqual"][i],
 			durations["Fibonacci"][i],
+			durations["Decorrelated"][i],
+			durations["Composed"][i],
 		})
 	}
 }
@@ -87,6 +93,8 @@ type config struct {
 	ExponentialBackoffFullJitter  *exponentialBackoffFullJitter  `yaml:"exponentialBackoffFullJitter"`
 	ExponentialBackoffEqualJitter *exponentialBackoffEqualJitter `yaml:"exponentialBackoffEqualJitter"`
 	FibonacciBackoff              *fibonacciBackoff              `yaml:"fibonacciBackoff"`
+	DecorrelatedJitterBackoff     *decorrelatedJitterBackoff     `yaml:"decorrelatedJitterBackoff"`
+	ComposedBackoff               *composedBackoff               `yaml:"composedBackoff"`
 }
 
 type fixedBackoff struct {
@@ -227,3 +235,35 @@ func (b *fibonacciBackoff) backoff(max int) []string {
 	}
 	return ds
 }
+
+type decorrelatedJitterBackoff struct {
+	Offset      int64
+	Fluctuation int64
+}
+
+func (b *decorrelatedJitterBackoff) backoff(max int) []string {
+	o := time.Duration(b.Offset) * time.Microsecond
+	f := time.Duration(b.Fluctuation) * time.Microsecond
+	backoff := zbackoff.NewDecorrelatedJitterBackoff(o, f)
+	ds := make([]string, max+1)
+	for i := range max + 1 {
+		ds[i] = strconv.FormatInt(int64(backoff.Attempt(i)), 10)
+	}
+	return ds
+}
+
+type composedBackoff struct {
+	Spec string
+}
+
+func (b *composedBackoff) backoff(max int) []string {
+	backoff, err := zbackoff.Parse(b.Spec)
+	if err != nil {
+		panic(err)
+	}
+	ds := make([]string, max+1)
+	for i := range max + 1 {
+		ds[i] = strconv.FormatInt(int64(backoff.Attempt(i)), 10)
+	}
+	return ds
+}
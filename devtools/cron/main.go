@@ -3,14 +3,18 @@ package main
 import (
 	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/aileron-projects/go/ztime/zcron"
 )
 
 var (
-	cron = flag.String("cron", "* * * * * *", "cron expression")
+	cron      = flag.String("cron", "* * * * * *", "cron expression")
+	printOnly = flag.Bool("print", false, "print the schedule without running jobs")
+	n         = flag.Int("n", 10, "number of scheduled times to print")
 )
 
 func main() {
@@ -20,6 +24,24 @@ func main() {
 		os.Exit(1)
 	}
 
+	if *printOnly {
+		ct, err := zcron.Parse(*cron)
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+		fmt.Println("Expression :", ct.String())
+		fmt.Println("Description:", ct.Description())
+		now := time.Now().In(ct.Location())
+		if prev := ct.PrevBefore(now); !prev.IsZero() {
+			fmt.Println("Previous   :", prev.Format(time.RFC3339))
+		}
+		for t := range ct.Schedules(now, time.Time{}, *n) {
+			fmt.Println("Next       :", t.Format(time.RFC3339))
+		}
+		return
+	}
+
 	count := 0
 	c, err := zcron.NewCron(&zcron.Config{
 		Crontab: *cron,
@@ -6,6 +6,7 @@ import (
 	"math/rand/v2"
 	"net/http"
 	"os"
+	"strconv"
 	"sync"
 	"time"
 
@@ -66,6 +67,13 @@ func main() {
 		fmt.Println("    |", "queueSize=", c.QueueSize)
 		fmt.Println("    |", "interval=", c.Interval, " millisecond")
 		waitMode = true
+	case "gcraLimiter":
+		c := config.GLimiter
+		limiter = zrate.NewGCRALimiterWidth(c.Limit, c.Burst, time.Duration(c.Width)*time.Millisecond)
+		fmt.Println("Use gcraLimiter:")
+		fmt.Println("    |", "limit=", c.Limit)
+		fmt.Println("    |", "burst=", c.Burst)
+		fmt.Println("    |", "width=", c.Width, " millisecond")
 	default:
 		panic("limiter not defined.")
 	}
@@ -114,15 +122,33 @@ func (h *testHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	h.nReq += 1
 	h.mu.Unlock()
 
+	// Cost of the request can be specified by the query like "?cost=10".
+	cost, err := strconv.Atoi(r.URL.Query().Get("cost"))
+	if err != nil {
+		cost = 1
+	}
+	limiter := h.limiter.(zrate.WeightedLimiter)
+
 	var token zrate.Token
 	if h.waitMode {
-		token = h.limiter.WaitNow(r.Context())
+		token = limiter.WaitN(r.Context(), cost)
 		defer token.Release()
 	} else {
-		token = h.limiter.AllowNow()
+		token = limiter.AllowN(cost)
 		defer token.Release()
 	}
 
+	if q, ok := zrate.QuotaOf(token); ok {
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(q.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(q.Remaining))
+		if !q.Reset.IsZero() {
+			w.Header().Set("RateLimit-Reset", strconv.FormatInt(int64(time.Until(q.Reset).Seconds()+0.999), 10))
+		}
+		if q.RetryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.FormatInt(int64(q.RetryAfter.Seconds()+0.999), 10))
+		}
+	}
+
 	h.mu.Lock()
 	if token.OK() {
 		h.nOK += 1
@@ -148,6 +174,7 @@ type config struct {
 	SLimiter          *SlidingWindowLimiter `yaml:"slidingWindowLimiter"`
 	TLimiter          *TokenBucketLimiter   `yaml:"tokenBucketLimiter"`
 	LLimiter          *LeakyBucketLimiter   `yaml:"leakyBucketLimiter"`
+	GLimiter          *GCRALimiter          `yaml:"gcraLimiter"`
 }
 
 type ConcurrentLimiter struct {
@@ -174,3 +201,9 @@ type LeakyBucketLimiter struct {
 	QueueSize int `yaml:"queueSize"`
 	Interval  int `yaml:"interval"`
 }
+
+type GCRALimiter struct {
+	Limit int `yaml:"limit"`
+	Burst int `yaml:"burst"`
+	Width int `yaml:"width"`
+}
@@ -0,0 +1,86 @@
+package znet
+
+import (
+	"strconv"
+	"time"
+)
+
+// ConnState represents the state of a client connection to a server.
+// It is used by the optional ConnState hook of servers in
+// znet/ztcp and znet/zudp. See also [net/http.ConnState].
+type ConnState int
+
+const (
+	// StateNew represents a new connection that is
+	// accepted but not yet passed to the handler.
+	StateNew ConnState = iota
+	// StateActive represents a connection that is served by the handler.
+	// Connections transition from StateIdle to StateActive
+	// when the handler calls [IdleSetter.SetIdle] with false.
+	StateActive
+	// StateIdle represents a connection that is marked as idle
+	// by the handler with [IdleSetter.SetIdle]. Idle connections
+	// are closed by the graceful shutdown of servers.
+	StateIdle
+	// StateHijacked represents a hijacked connection.
+	// This is a terminal state.
+	// Hijacked connections are no longer managed by the server.
+	StateHijacked
+	// StateClosed represents a closed connection.
+	// This is a terminal state.
+	StateClosed
+)
+
+func (c ConnState) String() string {
+	switch c {
+	case StateNew:
+		return "new"
+	case StateActive:
+		return "active"
+	case StateIdle:
+		return "idle"
+	case StateHijacked:
+		return "hijacked"
+	case StateClosed:
+		return "closed"
+	}
+	return "ConnState(" + strconv.Itoa(int(c)) + ")"
+}
+
+// IdleSetter marks connections idle.
+// Connections given to the handlers of servers in
+// znet/ztcp and znet/zudp implement this interface.
+// Handlers can mark a connection idle while waiting for the next
+// request so that the connection is closed without waiting
+// the handler to return when the server is gracefully shutting down.
+type IdleSetter interface {
+	SetIdle(idle bool)
+}
+
+// ConnMetrics reports metrics of a connection.
+// Connections given to the handlers and ConnState hooks of
+// servers in znet/ztcp and znet/zudp implement this interface.
+type ConnMetrics interface {
+	// BytesRead returns the number of bytes read from the connection.
+	BytesRead() int64
+	// BytesWritten returns the number of bytes written to the connection.
+	BytesWritten() int64
+	// AcceptedAt returns the time the connection was accepted.
+	AcceptedAt() time.Time
+}
+
+// ConnStats is the statistics of connections of a server.
+type ConnStats struct {
+	// Accepted is the total number of accepted connections.
+	Accepted uint64
+	// Rejected is the total number of connections rejected
+	// before passed to the handler.
+	Rejected uint64
+	// Hijacked is the total number of hijacked connections.
+	Hijacked uint64
+	// Active is the number of connections currently open
+	// including idle connections.
+	Active int64
+	// Idle is the number of connections currently idle.
+	Idle int64
+}
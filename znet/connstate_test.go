@@ -0,0 +1,27 @@
+package znet
+
+import (
+	"testing"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestConnState(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		state ConnState
+		want  string
+	}{
+		"new":      {StateNew, "new"},
+		"active":   {StateActive, "active"},
+		"idle":     {StateIdle, "idle"},
+		"hijacked": {StateHijacked, "hijacked"},
+		"closed":   {StateClosed, "closed"},
+		"unknown":  {ConnState(99), "ConnState(99)"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ztesting.AssertEqual(t, "string not match", tc.want, tc.state.String())
+		})
+	}
+}
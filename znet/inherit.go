@@ -0,0 +1,184 @@
+package znet
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// EnvInheritFDs is the environment variable name used to pass
+	// listeners to a new process by [Supervisor.Upgrade].
+	// Its value is comma separated "<NAME>=<FD>" pairs where NAME is
+	// the key of a [Service] and FD is the file descriptor number
+	// inherited from the parent process.
+	EnvInheritFDs = "ZNET_INHERIT_FDS"
+
+	// Environment variables used by systemd socket activation.
+	// See https://www.freedesktop.org/software/systemd/man/latest/sd_listen_fds.html
+	envListenPID     = "LISTEN_PID"
+	envListenFDs     = "LISTEN_FDS"
+	envListenFDNames = "LISTEN_FDNAMES"
+	// listenFDsStart is the first file descriptor number
+	// passed by systemd socket activation.
+	listenFDsStart = 3
+)
+
+// ErrNoInheritedFile is returned when the file
+// specified by an address is not inherited.
+var ErrNoInheritedFile = errors.New("znet: inherited file not found")
+
+// inherited holds files inherited from the parent process.
+var inherited = &inheritedStore{}
+
+// inheritedStore stores files inherited from the parent process.
+// Each file can be taken only once.
+type inheritedStore struct {
+	mu    sync.Mutex
+	files []*inheritedFile
+}
+
+type inheritedFile struct {
+	name string
+	fd   int
+}
+
+// load loads inherited files from environment variables.
+// The environment variables are unset after loaded
+// so that the files are not passed to child processes.
+func (s *inheritedStore) load() {
+	if v, ok := os.LookupEnv(EnvInheritFDs); ok {
+		_ = os.Unsetenv(EnvInheritFDs)
+		for pair := range strings.SplitSeq(v, ",") {
+			name, fd, found := strings.Cut(pair, "=")
+			n, err := strconv.Atoi(fd)
+			if !found || err != nil || n < 0 {
+				continue
+			}
+			s.add(name, n)
+		}
+	}
+
+	pid, fds, names := os.Getenv(envListenPID), os.Getenv(envListenFDs), os.Getenv(envListenFDNames)
+	if fds == "" {
+		return
+	}
+	_ = os.Unsetenv(envListenPID)
+	_ = os.Unsetenv(envListenFDs)
+	_ = os.Unsetenv(envListenFDNames)
+	if pid != strconv.Itoa(os.Getpid()) {
+		return // Not for this process.
+	}
+	n, err := strconv.Atoi(fds)
+	if err != nil || n <= 0 {
+		return
+	}
+	nameList := strings.Split(names, ":")
+	for i := range n {
+		name := "unknown" // Same as sd_listen_fds_with_names.
+		if i < len(nameList) && nameList[i] != "" {
+			name = nameList[i]
+		}
+		s.add(name, listenFDsStart+i)
+	}
+}
+
+func (s *inheritedStore) add(name string, fd int) {
+	for _, f := range s.files {
+		if f.fd == fd {
+			return // Already registered.
+		}
+	}
+	s.files = append(s.files, &inheritedFile{name: name, fd: fd})
+}
+
+// take removes and returns the first file that matches.
+func (s *inheritedStore) take(match func(*inheritedFile) bool) *os.File {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.load()
+	for i, f := range s.files {
+		if match(f) {
+			s.files = append(s.files[:i], s.files[i+1:]...)
+			return os.NewFile(uintptr(f.fd), f.name)
+		}
+	}
+	return nil
+}
+
+// InheritedFile returns a file inherited from the parent process
+// identified by the name. Files are inherited through systemd socket
+// activation (LISTEN_FDS, LISTEN_PID and LISTEN_FDNAMES) or
+// [EnvInheritFDs] set by [Supervisor.Upgrade].
+// For systemd socket activation, the name is the FileDescriptorName
+// of the socket unit. Files without name are named "unknown".
+// When multiple files have the same name, they are returned in order.
+// Each file is returned only once and the caller owns the returned file.
+// It returns nil if no file found.
+func InheritedFile(name string) *os.File {
+	return inherited.take(func(f *inheritedFile) bool { return f.name == name })
+}
+
+// IsFileAddr returns true if the addr is an address of
+// inherited file, which is in the form of "fd://<FD>"
+// or "systemd://<NAME>". See [ListenFile].
+func IsFileAddr(addr string) bool {
+	return strings.HasPrefix(addr, "fd://") || strings.HasPrefix(addr, "systemd://")
+}
+
+// fileFromAddr returns the file specified by the addr.
+func fileFromAddr(addr string) (*os.File, error) {
+	if name, ok := strings.CutPrefix(addr, "systemd://"); ok {
+		if f := InheritedFile(name); f != nil {
+			return f, nil
+		}
+		return nil, errors.Join(ErrNoInheritedFile, errors.New("addr="+addr))
+	}
+	if fd, ok := strings.CutPrefix(addr, "fd://"); ok {
+		n, err := strconv.Atoi(fd)
+		if err != nil || n < 0 {
+			return nil, errors.Join(ErrNoInheritedFile, errors.New("addr="+addr))
+		}
+		if f := inherited.take(func(f *inheritedFile) bool { return f.fd == n }); f != nil {
+			return f, nil
+		}
+		// Do not adopt unknown descriptors. They may be used by others.
+		return nil, errors.Join(ErrNoInheritedFile, errors.New("addr="+addr))
+	}
+	return nil, errors.Join(ErrNoInheritedFile, errors.New("addr="+addr))
+}
+
+// ListenFile returns a listener created from the inherited file.
+// The addr must be one of the following forms.
+//
+//   - fd://<FD> : FD is the file descriptor number. e.g. "fd://3"
+//   - systemd://<NAME> : NAME is the name given by [InheritedFile]. e.g. "systemd://http"
+//
+// Only the files inherited in the way described in [InheritedFile]
+// can be used. [ErrNoInheritedFile] is returned for other file descriptors.
+// The file is closed after the listener is created.
+// This is not supported on Windows.
+func ListenFile(addr string) (net.Listener, error) {
+	f, err := fileFromAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return net.FileListener(f)
+}
+
+// ListenFilePacket returns a packet conn created from the inherited file.
+// See [ListenFile] for the form of the addr.
+// The file is closed after the packet conn is created.
+// This is not supported on Windows.
+func ListenFilePacket(addr string) (net.PacketConn, error) {
+	f, err := fileFromAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return net.FilePacketConn(f)
+}
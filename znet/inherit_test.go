@@ -0,0 +1,86 @@
+package znet
+
+import (
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestIsFileAddr(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		addr string
+		want bool
+	}{
+		"fd":            {"fd://3", true},
+		"systemd":       {"systemd://http", true},
+		"tcp":           {"tcp://127.0.0.1:80", false},
+		"no network":    {"127.0.0.1:80", false},
+		"file prefix":   {"file://foo", false},
+		"fd no slashes": {"fd:3", false},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ztesting.AssertEqual(t, "result not match", tc.want, IsFileAddr(tc.addr))
+		})
+	}
+}
+
+func TestInheritedStore_load(t *testing.T) {
+	t.Run("systemd and inherit", func(t *testing.T) {
+		t.Setenv(EnvInheritFDs, "x=10,invalid,y=-1,a=3")
+		t.Setenv(envListenPID, strconv.Itoa(os.Getpid()))
+		t.Setenv(envListenFDs, "3")
+		t.Setenv(envListenFDNames, "a::b")
+		s := &inheritedStore{}
+		s.load()
+		want := []*inheritedFile{{"x", 10}, {"a", 3}, {"unknown", 4}, {"b", 5}}
+		ztesting.AssertEqual(t, "files not match", len(want), len(s.files))
+		for i := range want {
+			ztesting.AssertEqual(t, "name not match", want[i].name, s.files[i].name)
+			ztesting.AssertEqual(t, "fd not match", want[i].fd, s.files[i].fd)
+		}
+		for _, env := range []string{EnvInheritFDs, envListenPID, envListenFDs, envListenFDNames} {
+			_, found := os.LookupEnv(env)
+			ztesting.AssertEqual(t, "env must be unset", false, found)
+		}
+	})
+	t.Run("pid not match", func(t *testing.T) {
+		t.Setenv(envListenPID, "0")
+		t.Setenv(envListenFDs, "3")
+		s := &inheritedStore{}
+		s.load()
+		ztesting.AssertEqual(t, "files must be empty", 0, len(s.files))
+		_, found := os.LookupEnv(envListenFDs)
+		ztesting.AssertEqual(t, "env must be unset", false, found)
+	})
+	t.Run("invalid fds", func(t *testing.T) {
+		t.Setenv(envListenPID, strconv.Itoa(os.Getpid()))
+		t.Setenv(envListenFDs, "abc")
+		s := &inheritedStore{}
+		s.load()
+		ztesting.AssertEqual(t, "files must be empty", 0, len(s.files))
+	})
+}
+
+func TestListenFile_error(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		addr string
+	}{
+		"systemd not found": {"systemd://not-exist"},
+		"invalid fd":        {"fd://abc"},
+		"negative fd":       {"fd://-1"},
+		"not file addr":     {"tcp://127.0.0.1:0"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ListenFile(tc.addr)
+			ztesting.AssertEqualErr(t, "error not match", ErrNoInheritedFile, err)
+			_, err = ListenFilePacket(tc.addr)
+			ztesting.AssertEqualErr(t, "error not match", ErrNoInheritedFile, err)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+//go:build unix
+
+package znet
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+// dupFD returns a duplicated file descriptor of the socket.
+// The returned descriptor is owned by the caller and
+// not closed by the finalizer of [os.File].
+func dupFD(t *testing.T, v interface{ File() (*os.File, error) }) string {
+	t.Helper()
+	f, err := v.File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strconv.Itoa(fd)
+}
+
+func TestListenFile(t *testing.T) {
+	orig, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer orig.Close()
+	t.Setenv(EnvInheritFDs, "web="+dupFD(t, orig.(*net.TCPListener)))
+
+	ln, err := ListenFile("systemd://web")
+	ztesting.AssertEqualErr(t, "error not match", nil, err)
+	defer ln.Close()
+	ztesting.AssertEqual(t, "address not match", orig.Addr().String(), ln.Addr().String())
+	_, err = ListenFile("systemd://web")
+	ztesting.AssertEqualErr(t, "file must be taken only once", ErrNoInheritedFile, err)
+
+	fd := dupFD(t, orig.(*net.TCPListener))
+	_, err = ListenFile("fd://" + fd)
+	ztesting.AssertEqualErr(t, "not inherited fd must not be used", ErrNoInheritedFile, err)
+	n, _ := strconv.Atoi(fd)
+	ztesting.AssertEqualErr(t, "not inherited fd must not be closed", nil, syscall.Close(n))
+}
+
+func TestListenFilePacket(t *testing.T) {
+	orig, _ := net.ListenPacket("udp", "127.0.0.1:0")
+	defer orig.Close()
+	fd := dupFD(t, orig.(*net.UDPConn))
+	t.Setenv(EnvInheritFDs, "dns="+fd)
+
+	pc, err := ListenFilePacket("fd://" + fd) // Registered fd is also taken by number.
+	ztesting.AssertEqualErr(t, "error not match", nil, err)
+	defer pc.Close()
+	ztesting.AssertEqual(t, "address not match", orig.LocalAddr().String(), pc.LocalAddr().String())
+	_, err = ListenFilePacket("systemd://dns")
+	ztesting.AssertEqualErr(t, "file must be taken only once", ErrNoInheritedFile, err)
+}
+
+func TestSupervisor_inherit(t *testing.T) {
+	orig, _ := net.Listen("tcp", "127.0.0.1:0")
+	defer orig.Close()
+	t.Setenv(EnvInheritFDs, "http="+dupFD(t, orig.(*net.TCPListener))+",invalid")
+
+	svc, _ := testHTTPService("http", "tcp://invalid address")
+	s := &Supervisor{Services: []*Service{svc}}
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() { errCh <- s.Run(ctx) }()
+	waitState(t, s, SupervisorReady)
+
+	res, err := http.Get("http://" + orig.Addr().String())
+	ztesting.AssertEqualErr(t, "error not match", nil, err)
+	b, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	ztesting.AssertEqual(t, "body not match", "http", string(b))
+
+	cancel()
+	ztesting.AssertEqualErr(t, "error not match", nil, <-errCh)
+}
+
+func TestSupervisor_fileAddr(t *testing.T) {
+	orig, _ := net.ListenPacket("udp", "127.0.0.1:0")
+	defer orig.Close()
+	t.Setenv(EnvInheritFDs, "dns="+dupFD(t, orig.(*net.UDPConn)))
+
+	svc := &Service{Addr: "systemd://dns", ServePacket: func(pc net.PacketConn) error {
+		buf := make([]byte, 64)
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		_, _ = pc.WriteTo(buf[:n], addr)
+		return nil
+	}}
+	svc.Shutdown = func(context.Context) error { return nil }
+	s := &Supervisor{Services: []*Service{svc}}
+	errCh := make(chan error)
+	go func() { errCh <- s.Run(context.Background()) }()
+	waitState(t, s, SupervisorReady)
+
+	conn, _ := net.Dial("udp", orig.LocalAddr().String())
+	defer conn.Close()
+	_, _ = conn.Write([]byte("ping"))
+	buf := make([]byte, 64)
+	n, _ := conn.Read(buf)
+	ztesting.AssertEqual(t, "body not match", "ping", string(buf[:n]))
+	ztesting.AssertEqualErr(t, "error not match", nil, <-errCh)
+}
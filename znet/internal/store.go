@@ -32,6 +32,24 @@ func (m *CloserStore[T]) CloseAll() error {
 	return errors.Join(errs...)
 }
 
+// CloseIf call Close() method of the stored closers
+// that satisfy the cond and delete them from the store after closed.
+func (m *CloserStore[T]) CloseIf(cond func(T) bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var errs []error
+	for closer := range m.closers {
+		if !cond(closer) {
+			continue
+		}
+		delete(m.closers, closer)
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Store stores the given closer to the store.
 // It replaces the existing closer if the same
 // closer is already exist.
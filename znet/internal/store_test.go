@@ -66,4 +66,16 @@ func TestCloserStore(t *testing.T) {
 		ztesting.AssertEqual(t, "foo is not closed", 1, foo.closed)
 		ztesting.AssertEqual(t, "bar is not closed", 1, bar.closed)
 	})
+	t.Run("close if", func(t *testing.T) {
+		s := internal.CloserStore[*testCloser]{}
+		foo := &testCloser{name: "foo", closeErr: io.EOF} // Return dummy error
+		bar := &testCloser{name: "bar"}
+		s.Store(foo)
+		s.Store(bar)
+		err := s.CloseIf(func(c *testCloser) bool { return c.name == "foo" })
+		ztesting.AssertEqualErr(t, "close error not match", io.EOF, err)
+		ztesting.AssertEqual(t, "length not match", 1, s.Length())
+		ztesting.AssertEqual(t, "foo is not closed", 1, foo.closed)
+		ztesting.AssertEqual(t, "bar is closed", 0, bar.closed)
+	})
 }
@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"syscall"
+
+	"github.com/aileron-projects/go/zsyscall"
+)
+
+// TransparentDialer returns a copy of the dialer d that binds
+// sockets to the non-local address laddr.
+// The laddr must be a [*net.TCPAddr] or [*net.UDPAddr].
+// IP_TRANSPARENT, or IPV6_TRANSPARENT for IPv6 addresses,
+// and SO_REUSEADDR are set to the sockets before binding.
+// Control functions of d, if any, are called before
+// setting the options.
+// If d is nil, a zero [net.Dialer] is used as the base.
+// IP_TRANSPARENT requires CAP_NET_ADMIN capability and
+// is supported only on Linux.
+func TransparentDialer(d *net.Dialer, laddr net.Addr) *net.Dialer {
+	nd := &net.Dialer{}
+	if d != nil {
+		*nd = *d
+	}
+	nd.LocalAddr = laddr
+	opt := &zsyscall.SockOption{SO: &zsyscall.SockSOOption{ReuseAddr: true}}
+	opts := zsyscall.SockOptSO
+	if addrOf(laddr).Unmap().Is4() {
+		opt.IP = &zsyscall.SockIPOption{Transparent: true}
+		opts |= zsyscall.SockOptIP
+	} else {
+		opt.IPV6 = &zsyscall.SockIPV6Option{Transparent: true}
+		opts |= zsyscall.SockOptIPV6
+	}
+	ctrl := opt.ControlFunc(opts)
+	base, baseCtx := nd.Control, nd.ControlContext
+	nd.Control = nil
+	nd.ControlContext = func(ctx context.Context, network, address string, c syscall.RawConn) error {
+		var err error
+		switch {
+		case baseCtx != nil:
+			err = baseCtx(ctx, network, address, c)
+		case base != nil:
+			err = base(network, address, c)
+		}
+		if err != nil {
+			return err
+		}
+		return ctrl(network, address, c)
+	}
+	return nd
+}
+
+// addrOf returns the IP address of the addr.
+// It returns zero address if the addr is not an IP address.
+func addrOf(addr net.Addr) netip.Addr {
+	switch a := addr.(type) {
+	case *net.TCPAddr:
+		return a.AddrPort().Addr()
+	case *net.UDPAddr:
+		return a.AddrPort().Addr()
+	}
+	return netip.Addr{}
+}
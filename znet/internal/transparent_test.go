@@ -0,0 +1,48 @@
+package internal_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/znet/internal"
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestTransparentDialer(t *testing.T) {
+	t.Parallel()
+	t.Run("nil dialer", func(t *testing.T) {
+		laddr := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 80}
+		d := internal.TransparentDialer(nil, laddr)
+		ztesting.AssertEqual(t, "local address not match", net.Addr(laddr), d.LocalAddr)
+		ztesting.AssertEqual(t, "control must be nil", true, d.Control == nil)
+		ztesting.AssertEqual(t, "control context must not be nil", true, d.ControlContext != nil)
+	})
+	t.Run("copy dialer", func(t *testing.T) {
+		base := &net.Dialer{Timeout: time.Second}
+		laddr := &net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 53}
+		d := internal.TransparentDialer(base, laddr)
+		ztesting.AssertEqual(t, "timeout not match", time.Second, d.Timeout)
+		ztesting.AssertEqual(t, "local address not match", net.Addr(laddr), d.LocalAddr)
+		ztesting.AssertEqual(t, "base dialer must not be modified", nil, base.LocalAddr)
+	})
+	t.Run("control error", func(t *testing.T) {
+		testErr := errors.New("test")
+		base := &net.Dialer{Control: func(string, string, syscall.RawConn) error { return testErr }}
+		d := internal.TransparentDialer(base, &net.TCPAddr{IP: net.ParseIP("127.0.0.1")})
+		err := d.ControlContext(context.Background(), "tcp", "127.0.0.1:80", nil)
+		ztesting.AssertEqualErr(t, "error not match", testErr, err)
+	})
+	t.Run("control context error", func(t *testing.T) {
+		testErr := errors.New("test")
+		base := &net.Dialer{
+			ControlContext: func(context.Context, string, string, syscall.RawConn) error { return testErr },
+		}
+		d := internal.TransparentDialer(base, &net.TCPAddr{IP: net.ParseIP("127.0.0.1")})
+		err := d.ControlContext(context.Background(), "tcp", "127.0.0.1:80", nil)
+		ztesting.AssertEqualErr(t, "error not match", testErr, err)
+	})
+}
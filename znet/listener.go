@@ -94,6 +94,7 @@ func NewLimitListener(ln net.Listener, limit int) *LimitListener {
 
 // LimitListener limits the number of simultaneous connection.
 // Use [NewLimitListener] to create a new instance of LimitListener.
+// Use [SourceLimitListener] to limit connections for each client.
 //
 // Note that the linux command "netstat" or "ss" like below
 // does not show the correct number of connections currently accepted.
@@ -139,6 +140,13 @@ func (l *limitListenerConn) Close() error {
 	return err
 }
 
+// NetConn returns the underlying connection.
+// It is used to obtain the connection such as [crypto/tls.Conn]
+// wrapped by the limit listeners.
+func (l *limitListenerConn) NetConn() net.Conn {
+	return l.Conn
+}
+
 // NewTLSListener creates a new net listener that returns
 // a *tls.Conn connection.
 // IP addresses provided by the nonTLS arguments are considered
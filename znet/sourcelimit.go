@@ -0,0 +1,379 @@
+package znet
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/aileron-projects/go/ztime/zrate"
+)
+
+var (
+	// ErrSourceConnLimit is the rejection reason reported by
+	// [SourceLimitListener] when the source reached the
+	// maximum number of concurrent connections.
+	ErrSourceConnLimit = errors.New("znet: source connection limit exceeded")
+	// ErrSourceRateLimit is the rejection reason reported by
+	// [SourceLimitListener] when the source exceeded the accept rate.
+	ErrSourceRateLimit = errors.New("znet: source accept rate exceeded")
+	// ErrSourceQueueTimeout is the rejection reason reported by
+	// [SourceLimitListener] when a queued connection timed out.
+	ErrSourceQueueTimeout = errors.New("znet: source queue timeout")
+)
+
+// SourceLimitListener limits connections by their source addresses.
+// Unlike [LimitListener], which limits the total number of connections,
+// SourceLimitListener limits the number of concurrent connections and
+// the accept rate for each source so that a noisy client cannot
+// starve others. Sources are grouped by the network prefix of the
+// remote address, for example /24 for IPv4 and /64 for IPv6.
+// Connections from non-IP addresses such as unix sockets are not limited.
+//
+// Connections that exceeded the limit are closed, after writing the
+// RejectMessage if any, or queued until other connections from the same
+// source are closed when Queue is true. Accept is not blocked by the
+// rejected or queued connections.
+//
+// Example:
+//
+//	ln, _ := net.Listen("tcp", ":8080")
+//	ln = &znet.SourceLimitListener{
+//		Listener:    ln,
+//		MaxConns:    10,
+//		IPv4Prefix:  24,
+//		IPv6Prefix:  64,
+//		RateLimiter: func() zrate.Limiter { return zrate.NewTokenBucketLimiter(10, 5) },
+//		OnReject:    func(c net.Conn, err error) { log.Println(c.RemoteAddr(), err) },
+//	}
+type SourceLimitListener struct {
+	net.Listener
+	// MaxConns is the maximum number of concurrent
+	// connections for each source.
+	// If zero or negative, the number of connections is not limited.
+	MaxConns int
+	// IPv4Prefix is the prefix length used to group IPv4 sources.
+	// If zero or out of range, 32 is used which means that
+	// each IP address is a source.
+	IPv4Prefix int
+	// IPv6Prefix is the prefix length used to group IPv6 sources.
+	// If zero or out of range, 128 is used which means that
+	// each IP address is a source.
+	IPv6Prefix int
+	// RateLimiter, if non-nil, returns a new rate limiter
+	// that limits the accept rate of a source.
+	// It is called once for each source.
+	// Connections are rejected when [zrate.Limiter.AllowNow]
+	// returned a token that is not OK. They are never queued.
+	RateLimiter func() zrate.Limiter
+	// Queue, if true, queues connections that exceeded MaxConns
+	// instead of rejecting them. Queued connections are returned
+	// from Accept when other connections from the same source are closed.
+	Queue bool
+	// MaxQueue is the maximum number of queued connections
+	// for each source. Connections are rejected when the queue is full.
+	// If zero or negative, the queue size is not limited.
+	MaxQueue int
+	// QueueTimeout is the maximum duration of connections in the queue.
+	// Connections are rejected after timeout.
+	// If zero or negative, queued connections do not time out.
+	QueueTimeout time.Duration
+	// RejectMessage, if non-empty, is written to rejected
+	// connections before they are closed.
+	RejectMessage []byte
+	// OnReject, if non-nil, is called for every rejected connection
+	// before it is closed. The err is one of [ErrSourceConnLimit],
+	// [ErrSourceRateLimit] or [ErrSourceQueueTimeout].
+	// OnReject is called in a separate goroutine so it must be
+	// safe for concurrent use. OnReject must not close the conn.
+	OnReject func(conn net.Conn, err error)
+	// SourceExpiry is the duration to keep the state of sources
+	// that do not have active connections. It should be longer than
+	// the period of the rate limiter.
+	// If zero or negative, 5 minutes is used.
+	SourceExpiry time.Duration
+
+	once      sync.Once
+	accepted  chan acceptResult
+	ready     chan net.Conn
+	done      chan struct{} // Closed when the acceptor exited.
+	closed    chan struct{} // Closed when the listener is closed.
+	closeOnce sync.Once
+	err       error // Error that stopped the acceptor.
+
+	// mu protects sources and lastSweep.
+	mu        sync.Mutex
+	sources   map[netip.Prefix]*sourceState
+	lastSweep time.Time
+}
+
+type acceptResult struct {
+	conn net.Conn
+	err  error
+}
+
+// sourceState is the state of a source.
+type sourceState struct {
+	active   int
+	limiter  zrate.Limiter
+	queue    []*queuedConn
+	lastSeen time.Time
+}
+
+type queuedConn struct {
+	net.Conn
+	timer *time.Timer // Nil if no timeout.
+}
+
+func (c *queuedConn) stop() {
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+}
+
+// Wrap returns a new SourceLimitListener that wraps the ln
+// with the same configuration as l.
+// The returned listener has its own source states, so the limits
+// are not shared with l nor other listeners returned by Wrap.
+// The Listener field of l is not used and can be nil.
+// Wrap is used by [github.com/aileron-projects/go/znet/ztcp.Server]
+// to apply the limits to each listener.
+func (l *SourceLimitListener) Wrap(ln net.Listener) *SourceLimitListener {
+	return &SourceLimitListener{
+		Listener:      ln,
+		MaxConns:      l.MaxConns,
+		IPv4Prefix:    l.IPv4Prefix,
+		IPv6Prefix:    l.IPv6Prefix,
+		RateLimiter:   l.RateLimiter,
+		Queue:         l.Queue,
+		MaxQueue:      l.MaxQueue,
+		QueueTimeout:  l.QueueTimeout,
+		RejectMessage: l.RejectMessage,
+		OnReject:      l.OnReject,
+		SourceExpiry:  l.SourceExpiry,
+	}
+}
+
+func (l *SourceLimitListener) init() {
+	l.accepted = make(chan acceptResult)
+	l.ready = make(chan net.Conn)
+	l.done = make(chan struct{})
+	l.closed = make(chan struct{})
+	l.sources = map[netip.Prefix]*sourceState{}
+	go l.acceptLoop()
+}
+
+// acceptLoop accepts connections from the inner listener.
+func (l *SourceLimitListener) acceptLoop() {
+	defer close(l.done)
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			if to, ok := err.(interface{ Timeout() bool }); ok && to.Timeout() {
+				select {
+				case l.accepted <- acceptResult{err: err}:
+					continue // Temporary error.
+				case <-l.closed:
+					return
+				}
+			}
+			l.err = err
+			return
+		}
+		select {
+		case l.accepted <- acceptResult{conn: conn}:
+		case <-l.closed:
+			_ = conn.Close()
+			return
+		}
+	}
+}
+
+func (l *SourceLimitListener) Accept() (net.Conn, error) {
+	l.once.Do(l.init)
+	for {
+		select {
+		case conn := <-l.ready:
+			return conn, nil
+		case r := <-l.accepted:
+			if r.err != nil {
+				return nil, r.err
+			}
+			if conn := l.admit(r.conn); conn != nil {
+				return conn, nil
+			}
+		case <-l.done:
+			return nil, l.err
+		}
+	}
+}
+
+// Close closes the listener and queued connections.
+func (l *SourceLimitListener) Close() error {
+	l.once.Do(l.init)
+	l.closeOnce.Do(func() {
+		close(l.closed)
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		for _, st := range l.sources {
+			for _, qc := range st.queue {
+				qc.stop()
+				_ = qc.Close()
+			}
+			st.queue = nil
+		}
+	})
+	return l.Listener.Close()
+}
+
+// sourceOf returns the source prefix of the conn.
+// It returns false when the remote address is not an IP address.
+func (l *SourceLimitListener) sourceOf(conn net.Conn) (netip.Prefix, bool) {
+	ap, err := netip.ParseAddrPort(conn.RemoteAddr().String())
+	if err != nil {
+		return netip.Prefix{}, false
+	}
+	addr := ap.Addr().Unmap().WithZone("")
+	bits := l.IPv6Prefix
+	if addr.Is4() {
+		bits = l.IPv4Prefix
+	}
+	if bits <= 0 || bits > addr.BitLen() {
+		bits = addr.BitLen()
+	}
+	p, _ := addr.Prefix(bits) // Never fail with valid bits.
+	return p, true
+}
+
+// admit checks the limits of the conn.
+// It returns nil when the conn is rejected or queued.
+func (l *SourceLimitListener) admit(conn net.Conn) net.Conn {
+	src, ok := l.sourceOf(conn)
+	if !ok {
+		return conn
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	l.sweep(now)
+	st := l.sources[src]
+	if st == nil {
+		st = &sourceState{}
+		if l.RateLimiter != nil {
+			st.limiter = l.RateLimiter()
+		}
+		l.sources[src] = st
+	}
+	st.lastSeen = now
+
+	if st.limiter != nil {
+		token := st.limiter.AllowNow()
+		token.Release() // Only the rate is limited.
+		if !token.OK() {
+			l.mu.Unlock()
+			go l.reject(conn, ErrSourceRateLimit)
+			return nil
+		}
+	}
+	if l.MaxConns <= 0 || st.active < l.MaxConns {
+		st.active++
+		l.mu.Unlock()
+		return l.wrap(src, conn)
+	}
+	if !l.Queue || (l.MaxQueue > 0 && len(st.queue) >= l.MaxQueue) {
+		l.mu.Unlock()
+		go l.reject(conn, ErrSourceConnLimit)
+		return nil
+	}
+	qc := &queuedConn{Conn: conn}
+	if l.QueueTimeout > 0 {
+		qc.timer = time.AfterFunc(l.QueueTimeout, func() { l.dequeue(src, qc) })
+	}
+	st.queue = append(st.queue, qc)
+	l.mu.Unlock()
+	return nil
+}
+
+// dequeue removes the timed out conn from the queue and rejects it.
+func (l *SourceLimitListener) dequeue(src netip.Prefix, qc *queuedConn) {
+	l.mu.Lock()
+	st := l.sources[src]
+	found := false
+	if st != nil {
+		for i, c := range st.queue {
+			if c == qc {
+				st.queue = append(st.queue[:i], st.queue[i+1:]...)
+				found = true
+				break
+			}
+		}
+	}
+	l.mu.Unlock()
+	if found {
+		l.reject(qc.Conn, ErrSourceQueueTimeout)
+	}
+}
+
+// release is called when a connection from the src is closed.
+// A queued connection, if any, takes over the released slot.
+func (l *SourceLimitListener) release(src netip.Prefix) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	st := l.sources[src]
+	st.lastSeen = time.Now()
+	if len(st.queue) == 0 {
+		st.active--
+		return
+	}
+	qc := st.queue[0]
+	st.queue = st.queue[1:]
+	qc.stop()
+	conn := l.wrap(src, qc.Conn)
+	go func() {
+		select {
+		case l.ready <- conn:
+		case <-l.closed:
+			_ = conn.Close()
+		}
+	}()
+}
+
+// sweep deletes expired sources.
+// It must be called with l.mu held.
+func (l *SourceLimitListener) sweep(now time.Time) {
+	expiry := l.SourceExpiry
+	if expiry <= 0 {
+		expiry = 5 * time.Minute
+	}
+	if now.Sub(l.lastSweep) < expiry {
+		return
+	}
+	l.lastSweep = now
+	for src, st := range l.sources {
+		if st.active == 0 && len(st.queue) == 0 && now.Sub(st.lastSeen) >= expiry {
+			delete(l.sources, src)
+		}
+	}
+}
+
+func (l *SourceLimitListener) wrap(src netip.Prefix, conn net.Conn) net.Conn {
+	return &limitListenerConn{
+		Conn:    conn,
+		release: func() { l.release(src) },
+	}
+}
+
+// reject closes the conn after writing the reject message.
+// It is called in a separate goroutine from Accept so that
+// slow clients do not block accepting other connections.
+func (l *SourceLimitListener) reject(conn net.Conn, err error) {
+	if l.OnReject != nil {
+		l.OnReject(conn, err)
+	}
+	if len(l.RejectMessage) > 0 {
+		_ = conn.SetWriteDeadline(time.Now().Add(time.Second))
+		_, _ = conn.Write(l.RejectMessage)
+	}
+	_ = conn.Close()
+}
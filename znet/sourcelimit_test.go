@@ -0,0 +1,294 @@
+package znet_test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/znet"
+	"github.com/aileron-projects/go/ztesting"
+	"github.com/aileron-projects/go/ztime/zrate"
+)
+
+// chanListener returns connections sent to the channel.
+type chanListener struct {
+	net.Listener
+	ch   chan net.Conn
+	once sync.Once
+	done chan struct{}
+}
+
+func newChanListener() *chanListener {
+	return &chanListener{ch: make(chan net.Conn), done: make(chan struct{})}
+}
+
+func (l *chanListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.ch:
+		return c, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *chanListener) Close() error {
+	l.once.Do(func() { close(l.done) })
+	return nil
+}
+
+// pipeConn is the server side of [net.Pipe] with remote address.
+type pipeConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c *pipeConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// dial sends a new connection from the ip to the listener.
+// It returns the client side connection.
+func (l *chanListener) dial(ip string) net.Conn {
+	server, client := net.Pipe()
+	l.ch <- &pipeConn{Conn: server, remote: &net.TCPAddr{IP: net.ParseIP(ip), Port: 12345}}
+	return client
+}
+
+// acceptAsync accepts connections in a new goroutine.
+func acceptAsync(ln net.Listener) chan net.Conn {
+	ch := make(chan net.Conn, 10)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				close(ch)
+				return
+			}
+			ch <- c
+		}
+	}()
+	return ch
+}
+
+func recvConn(t *testing.T, ch chan net.Conn) net.Conn {
+	t.Helper()
+	select {
+	case c := <-ch:
+		return c
+	case <-time.After(time.Second):
+		t.Fatal("connection not accepted")
+	}
+	return nil
+}
+
+func noConn(t *testing.T, ch chan net.Conn) {
+	t.Helper()
+	select {
+	case c := <-ch:
+		t.Fatalf("unexpected connection accepted from %s", c.RemoteAddr())
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSourceLimitListener_reject(t *testing.T) {
+	t.Parallel()
+	inner := newChanListener()
+	var mu sync.Mutex
+	var rejected []error
+	ln := &znet.SourceLimitListener{
+		Listener:      inner,
+		MaxConns:      1,
+		IPv4Prefix:    24,
+		RejectMessage: []byte("busy"),
+		OnReject: func(_ net.Conn, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			rejected = append(rejected, err)
+		},
+	}
+	defer ln.Close()
+	accepted := acceptAsync(ln)
+
+	inner.dial("192.168.0.1")
+	c1 := recvConn(t, accepted)
+
+	client := inner.dial("192.168.0.2") // Same /24 source.
+	b, _ := io.ReadAll(client)
+	ztesting.AssertEqual(t, "reject message not match", "busy", string(b))
+	noConn(t, accepted)
+
+	inner.dial("192.168.1.1") // Other source.
+	recvConn(t, accepted)
+
+	c1.Close()
+	c1.Close() // Released only once.
+	inner.dial("192.168.0.3")
+	recvConn(t, accepted)
+	go func() { _, _ = io.ReadAll(inner.dial("192.168.0.4")) }()
+	noConn(t, accepted)
+
+	mu.Lock()
+	defer mu.Unlock()
+	ztesting.AssertEqual(t, "number of rejection not match", 2, len(rejected))
+	ztesting.AssertEqualErr(t, "error not match", znet.ErrSourceConnLimit, rejected[0])
+}
+
+func TestSourceLimitListener_queue(t *testing.T) {
+	t.Parallel()
+	inner := newChanListener()
+	var mu sync.Mutex
+	var rejected []error
+	ln := &znet.SourceLimitListener{
+		Listener:     inner,
+		MaxConns:     1,
+		Queue:        true,
+		MaxQueue:     1,
+		QueueTimeout: 100 * time.Millisecond,
+		OnReject: func(_ net.Conn, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			rejected = append(rejected, err)
+		},
+	}
+	defer ln.Close()
+	accepted := acceptAsync(ln)
+
+	inner.dial("10.0.0.1")
+	c1 := recvConn(t, accepted)
+	inner.dial("10.0.0.1") // Queued.
+	noConn(t, accepted)
+	go func() { _, _ = io.ReadAll(inner.dial("10.0.0.1")) }() // Queue full.
+	inner.dial("10.0.0.2")                                    // Other source is not blocked.
+	recvConn(t, accepted)
+
+	c1.Close()
+	c2 := recvConn(t, accepted) // Queued connection is accepted.
+
+	inner.dial("10.0.0.1") // Queued and timed out.
+	time.Sleep(200 * time.Millisecond)
+	c2.Close()
+	noConn(t, accepted)
+
+	mu.Lock()
+	defer mu.Unlock()
+	ztesting.AssertEqual(t, "number of rejection not match", 2, len(rejected))
+	ztesting.AssertEqualErr(t, "error not match", znet.ErrSourceConnLimit, rejected[0])
+	ztesting.AssertEqualErr(t, "error not match", znet.ErrSourceQueueTimeout, rejected[1])
+}
+
+func TestSourceLimitListener_rate(t *testing.T) {
+	t.Parallel()
+	inner := newChanListener()
+	var mu sync.Mutex
+	var rejected []error
+	ln := &znet.SourceLimitListener{
+		Listener:    inner,
+		IPv6Prefix:  64,
+		RateLimiter: func() zrate.Limiter { return zrate.NewFixedWindowLimiterWidth(2, time.Hour) },
+		OnReject: func(_ net.Conn, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			rejected = append(rejected, err)
+		},
+	}
+	defer ln.Close()
+	accepted := acceptAsync(ln)
+
+	inner.dial("2001:db8::1")
+	recvConn(t, accepted)
+	inner.dial("2001:db8::2")
+	recvConn(t, accepted)
+	inner.dial("2001:db8::3")
+	noConn(t, accepted)
+	inner.dial("2001:db8:0:1::1") // Other /64 source.
+	recvConn(t, accepted)
+
+	mu.Lock()
+	defer mu.Unlock()
+	ztesting.AssertEqual(t, "number of rejection not match", 1, len(rejected))
+	ztesting.AssertEqualErr(t, "error not match", znet.ErrSourceRateLimit, rejected[0])
+}
+
+func TestSourceLimitListener_close(t *testing.T) {
+	t.Parallel()
+	inner := newChanListener()
+	ln := &znet.SourceLimitListener{Listener: inner, MaxConns: 1, Queue: true}
+	accepted := acceptAsync(ln)
+
+	inner.dial("10.0.0.1")
+	recvConn(t, accepted)
+	queued := inner.dial("10.0.0.1")
+	noConn(t, accepted)
+
+	ln.Close()
+	_, err := queued.Read(make([]byte, 1))
+	ztesting.AssertEqualErr(t, "queued connection must be closed", io.EOF, err)
+	_, ok := <-accepted
+	ztesting.AssertEqual(t, "accept must return error", false, ok)
+	_, err = ln.Accept()
+	ztesting.AssertEqualErr(t, "error not match", net.ErrClosed, err)
+}
+
+func TestSourceLimitListener_nonIP(t *testing.T) {
+	t.Parallel()
+	inner := &testListener{remote: &net.UnixAddr{Name: "test.sock", Net: "unix"}}
+	ln := &znet.SourceLimitListener{Listener: inner, MaxConns: 1}
+	for range 3 {
+		_, err := ln.Accept()
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+	}
+}
+
+func TestSourceLimitListener_acceptError(t *testing.T) {
+	t.Parallel()
+	testErr := errors.New("accept error")
+	ln := &znet.SourceLimitListener{Listener: &testListener{err: testErr}}
+	_, err := ln.Accept()
+	ztesting.AssertEqualErr(t, "error not match", testErr, err)
+}
+
+func TestSourceLimitListener_slowClient(t *testing.T) {
+	t.Parallel()
+	inner := newChanListener()
+	ln := &znet.SourceLimitListener{
+		Listener:      inner,
+		MaxConns:      1,
+		RejectMessage: []byte("busy"),
+	}
+	defer ln.Close()
+	accepted := acceptAsync(ln)
+
+	inner.dial("10.0.0.1")
+	recvConn(t, accepted)
+	slow := inner.dial("10.0.0.1") // Rejected but never reads the message.
+	defer slow.Close()
+
+	start := time.Now()
+	inner.dial("10.0.0.2")
+	recvConn(t, accepted)
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("accept blocked by the rejected connection for %s", d)
+	}
+}
+
+func TestSourceLimitListener_Wrap(t *testing.T) {
+	t.Parallel()
+	config := &znet.SourceLimitListener{MaxConns: 1, IPv4Prefix: 24}
+	inner1, inner2 := newChanListener(), newChanListener()
+	ln1, ln2 := config.Wrap(inner1), config.Wrap(inner2)
+	defer ln1.Close()
+	defer ln2.Close()
+	ztesting.AssertEqual(t, "max conns not match", 1, ln2.MaxConns)
+	ztesting.AssertEqual(t, "prefix not match", 24, ln2.IPv4Prefix)
+	accepted1, accepted2 := acceptAsync(ln1), acceptAsync(ln2)
+
+	inner1.dial("10.0.0.1")
+	recvConn(t, accepted1)
+	inner2.dial("10.0.0.1") // Limits are not shared.
+	recvConn(t, accepted2)
+	go func() { _, _ = io.ReadAll(inner1.dial("10.0.0.2")) }()
+	noConn(t, accepted1)
+}
@@ -0,0 +1,461 @@
+package znet
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aileron-projects/go/znet/internal"
+	"github.com/aileron-projects/go/zsync"
+)
+
+var (
+	// ErrSupervisorRunning is returned when [Supervisor.Run]
+	// is called while the supervisor is already running.
+	ErrSupervisorRunning = errors.New("znet: supervisor already running")
+	// ErrSupervisorNotReady is returned when [Supervisor.Upgrade]
+	// is called while the supervisor is not ready.
+	ErrSupervisorNotReady = errors.New("znet: supervisor not ready")
+	// ErrInvalidService is returned when a [Service] is
+	// not properly configured.
+	ErrInvalidService = errors.New("znet: invalid service")
+)
+
+// SupervisorState is the running state of a [Supervisor].
+type SupervisorState int32
+
+const (
+	// SupervisorStopped is the state that the supervisor is not running.
+	SupervisorStopped SupervisorState = iota
+	// SupervisorStarting is the state that listeners are being bound.
+	SupervisorStarting
+	// SupervisorReady is the state that all listeners are
+	// bound and services are accepting connections.
+	SupervisorReady
+	// SupervisorDraining is the state that the supervisor is
+	// waiting the drain delay or shutting down services.
+	SupervisorDraining
+)
+
+func (s SupervisorState) String() string {
+	switch s {
+	case SupervisorStopped:
+		return "stopped"
+	case SupervisorStarting:
+		return "starting"
+	case SupervisorReady:
+		return "ready"
+	case SupervisorDraining:
+		return "draining"
+	}
+	return "unknown"
+}
+
+// Service is a server run by a [Supervisor].
+// Typically [net/http.Server], ztcp.Server or zudp.Server
+// are used as a service.
+type Service struct {
+	// Name is the name of the service.
+	// Name is used as the key to identify the listener
+	// inherited from the parent process.
+	// If empty, Addr is used as the key.
+	Name string
+	// Addr is the address to listen to.
+	// It must be in the form accepted by [ParseNetAddr].
+	// "tcp" is assumed when no network prefix found.
+	// For "udp", "udp4", "udp6" and "unixgram",
+	// a [net.PacketConn] is created and ServePacket is used.
+	// For other networks, a [net.Listener] is created
+	// and Serve is used.
+	// Inherited files can be used with "fd://<FD>" or
+	// "systemd://<NAME>". See [ListenFile]. In that case,
+	// ServePacket is used only when Serve is nil.
+	Addr string
+	// Serve serves the listener.
+	// It must not be nil for stream oriented networks.
+	// Typically [net/http.Server.Serve] should be set.
+	Serve func(net.Listener) error
+	// ServePacket serves the packet conn.
+	// It must not be nil for datagram oriented networks.
+	// Typically zudp.Server.Serve should be set.
+	ServePacket func(net.PacketConn) error
+	// Shutdown gracefully shutdowns the service.
+	// Shutdown must not be nil.
+	// Typically [net/http.Server.Shutdown] should be set.
+	Shutdown func(context.Context) error
+	// Close, if non-nil, immediately closes the service.
+	// It is called when shutdown timeout occurred.
+	// Typically [net/http.Server.Close] should be set.
+	Close func() error
+}
+
+func (s *Service) key() string {
+	return cmp.Or(s.Name, s.Addr)
+}
+
+// listen creates a listener or packet conn for the service.
+// If the f is non-nil, it is used instead of creating a new one.
+func (s *Service) listen(ctx context.Context, f *os.File) (*serviceConn, error) {
+	network, address := ParseNetAddr(s.Addr)
+	packet := false
+	switch {
+	case IsFileAddr(s.Addr):
+		packet = s.Serve == nil
+	case network == "":
+		network = NetTCP
+	case network == NetUDP, network == NetUDP4, network == NetUDP6, network == NetUnixgram:
+		packet = true
+	}
+	if s.Shutdown == nil || (packet && s.ServePacket == nil) || (!packet && s.Serve == nil) {
+		return nil, errors.Join(ErrInvalidService, errors.New("service="+s.key()))
+	}
+
+	var err error
+	sc := &serviceConn{}
+	if f != nil {
+		defer f.Close()
+		if packet {
+			sc.pc, err = net.FilePacketConn(f)
+		} else {
+			sc.ln, err = net.FileListener(f)
+		}
+		return sc, err
+	}
+	if IsFileAddr(s.Addr) {
+		if packet {
+			sc.pc, err = ListenFilePacket(s.Addr)
+		} else {
+			sc.ln, err = ListenFile(s.Addr)
+		}
+		return sc, err
+	}
+	lc := &net.ListenConfig{}
+	if packet {
+		sc.pc, err = lc.ListenPacket(ctx, network, address)
+	} else {
+		sc.ln, err = lc.Listen(ctx, network, address)
+	}
+	return sc, err
+}
+
+// serviceConn holds either a listener or a packet conn.
+type serviceConn struct {
+	ln net.Listener
+	pc net.PacketConn
+}
+
+func (c *serviceConn) serve(s *Service) error {
+	if c.ln != nil {
+		return s.Serve(c.ln)
+	}
+	return s.ServePacket(c.pc)
+}
+
+func (c *serviceConn) close() error {
+	if c.ln != nil {
+		return c.ln.Close()
+	}
+	return c.pc.Close()
+}
+
+// file returns a duplicated file of the underlying socket.
+func (c *serviceConn) file() (*os.File, error) {
+	var v any = c.pc
+	if c.ln != nil {
+		v = c.ln
+	}
+	if ul, ok := v.(*net.UnixListener); ok {
+		ul.SetUnlinkOnClose(false) // The new process continues to use the socket file.
+	}
+	filer, ok := v.(interface{ File() (*os.File, error) })
+	if !ok {
+		return nil, errors.New("znet: socket does not support File()")
+	}
+	return filer.File()
+}
+
+// Supervisor runs multiple services and manages their lifecycle.
+// Supervisor reports its liveness and readiness.
+// It becomes ready after all listeners are bound and becomes
+// not ready while draining. When shutting down, the Supervisor
+// waits DrainDelay before calling Shutdown of services so that
+// load balancers can deregister the instance.
+//
+// Supervisor supports zero-downtime binary upgrades.
+// [Supervisor.Upgrade] or one of UpgradeSignals starts a new process
+// with the same executable and arguments passing the listening sockets
+// as inherited file descriptors. The new process takes over listeners
+// by running a Supervisor with the same services while the current
+// process drains. Note that handing over listeners is not supported
+// on Windows. For unix domain sockets, the socket file must not be
+// removed by services on shutdown.
+//
+// Example:
+//
+//	svr := &http.Server{Handler: handler}
+//	s := &znet.Supervisor{
+//		Services: []*znet.Service{
+//			{Name: "http", Addr: ":8080", Serve: svr.Serve, Shutdown: svr.Shutdown, Close: svr.Close},
+//		},
+//		DrainDelay:     5 * time.Second,
+//		UpgradeSignals: []os.Signal{syscall.SIGHUP, syscall.SIGUSR2},
+//	}
+//
+//	sigCtx, cancel := signal.NotifyContext(context.Background(),
+//		os.Interrupt, syscall.SIGTERM)
+//	defer cancel()
+//
+//	if err := s.Run(sigCtx); err != nil {
+//		panic(err)
+//	}
+type Supervisor struct {
+	// Services is the list of services to run.
+	Services []*Service
+	// DrainDelay is the duration to wait before shutting
+	// down services. The supervisor is not ready while waiting.
+	// If zero or negative, services are shutdown immediately.
+	DrainDelay time.Duration
+	// ShutdownTimeout is the timeout duration applied
+	// for the Shutdown function of each service.
+	// For ShutdownTimeout<=0, 30 seconds is used.
+	ShutdownTimeout time.Duration
+	// UpgradeSignals is the list of signals that
+	// trigger [Supervisor.Upgrade].
+	// Typically SIGHUP and SIGUSR2 are used.
+	UpgradeSignals []os.Signal
+	// OnUpgrade, if non-nil, is called with the result
+	// of upgrades triggered by UpgradeSignals.
+	OnUpgrade func(err error)
+
+	state atomic.Int32
+
+	// mu protects conns and drain.
+	mu    sync.Mutex
+	conns []*serviceConn
+	drain chan struct{}
+
+	// startProcess starts a new process with given files
+	// and environment. If nil, [startProcess] is used.
+	// startProcess is used for testing.
+	startProcess func(files []*os.File, env string) error
+}
+
+// State returns the current state of the supervisor.
+func (s *Supervisor) State() SupervisorState {
+	return SupervisorState(s.state.Load())
+}
+
+// Live reports if the supervisor is running.
+func (s *Supervisor) Live() bool {
+	return s.State() != SupervisorStopped
+}
+
+// Ready reports if the supervisor is ready to accept connections.
+func (s *Supervisor) Ready() bool {
+	return s.State() == SupervisorReady
+}
+
+// LivenessHandler returns a handler for liveness probes.
+// It responds 200 OK when [Supervisor.Live] returns true
+// and 503 Service Unavailable otherwise.
+func (s *Supervisor) LivenessHandler() http.Handler {
+	return healthHandler(s, s.Live)
+}
+
+// ReadinessHandler returns a handler for readiness probes.
+// It responds 200 OK when [Supervisor.Ready] returns true
+// and 503 Service Unavailable otherwise.
+func (s *Supervisor) ReadinessHandler() http.Handler {
+	return healthHandler(s, s.Ready)
+}
+
+func healthHandler(s *Supervisor, ok func() bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if !ok() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_, _ = w.Write([]byte(s.State().String()))
+	})
+}
+
+// Run binds listeners and runs all services.
+// Listeners inherited from the parent process are used instead of
+// creating new ones. They are looked up by the Name, or the Addr if
+// the Name is empty, of services using [InheritedFile].
+// Services are gracefully shutdown after the DrainDelay when
+// the sigCtx is done or after [Supervisor.Upgrade] succeeded.
+// If one of the services exited with non-nil error,
+// other services are shutdown immediately.
+// It returns the first non-nil error returned from services.
+func (s *Supervisor) Run(sigCtx context.Context) error {
+	if !s.state.CompareAndSwap(int32(SupervisorStopped), int32(SupervisorStarting)) {
+		return ErrSupervisorRunning
+	}
+	defer s.state.Store(int32(SupervisorStopped))
+
+	conns, err := s.listen(sigCtx)
+	if err != nil {
+		return err
+	}
+	drain := make(chan struct{})
+	s.mu.Lock()
+	s.conns, s.drain = conns, drain
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.conns, s.drain = nil, nil
+		s.mu.Unlock()
+	}()
+
+	group := &zsync.RunGroup{}
+	for i, svc := range s.Services {
+		runner := &internal.ServerRunner{
+			Serve:           func() error { return conns[i].serve(svc) },
+			Shutdown:        svc.Shutdown,
+			Close:           svc.Close,
+			ShutdownTimeout: s.ShutdownTimeout,
+		}
+		group.Register(runner)
+	}
+
+	var sigCh chan os.Signal
+	if len(s.UpgradeSignals) > 0 {
+		sigCh = make(chan os.Signal, 1)
+		signal.Notify(sigCh, s.UpgradeSignals...)
+		defer signal.Stop(sigCh)
+	}
+
+	shutdownCtx, shutdown := context.WithCancel(context.Background())
+	defer shutdown()
+	done := make(chan error, 1)
+	go func() { done <- group.RunAndFailFast(shutdownCtx) }()
+	s.state.Store(int32(SupervisorReady))
+
+WAIT:
+	for {
+		select {
+		case err := <-done:
+			return err
+		case <-sigCh:
+			err := s.Upgrade()
+			if s.OnUpgrade != nil {
+				s.OnUpgrade(err)
+			}
+		case <-drain:
+			break WAIT
+		case <-sigCtx.Done():
+			break WAIT
+		}
+	}
+
+	s.state.Store(int32(SupervisorDraining))
+	if s.DrainDelay > 0 {
+		timer := time.NewTimer(s.DrainDelay)
+		select {
+		case err := <-done:
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+	shutdown()
+	return <-done
+}
+
+// listen binds listeners for all services.
+// All listeners are closed when an error occurred.
+func (s *Supervisor) listen(ctx context.Context) ([]*serviceConn, error) {
+	conns := make([]*serviceConn, 0, len(s.Services))
+	for _, svc := range s.Services {
+		c, err := svc.listen(ctx, InheritedFile(svc.key()))
+		if err != nil {
+			for _, c := range conns {
+				_ = c.close()
+			}
+			return nil, err
+		}
+		conns = append(conns, c)
+	}
+	return conns, nil
+}
+
+// Upgrade starts a new process with the same executable and
+// arguments of the current process passing all listeners.
+// The environment variable [EnvInheritFDs] is set to the new process.
+// After the new process started, the supervisor starts draining.
+// Upgrade can be called only when the supervisor is ready.
+// Upgrade does not wait the new process to become ready.
+func (s *Supervisor) Upgrade() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.State() != SupervisorReady || s.drain == nil {
+		return ErrSupervisorNotReady
+	}
+	select {
+	case <-s.drain:
+		return ErrSupervisorNotReady // Already upgraded.
+	default:
+	}
+
+	files := make([]*os.File, 0, len(s.conns))
+	defer func() {
+		for _, f := range files {
+			_ = f.Close() // Files are duplicated in the new process.
+		}
+	}()
+	pairs := make([]string, 0, len(s.conns))
+	for i, c := range s.conns {
+		f, err := c.file()
+		if err != nil {
+			return err
+		}
+		// Inherited files are numbered from 3 next to stdin, stdout and stderr.
+		pairs = append(pairs, s.Services[i].key()+"="+strconv.Itoa(3+len(files)))
+		files = append(files, f)
+	}
+
+	start := startProcess
+	if s.startProcess != nil {
+		start = s.startProcess
+	}
+	if err := start(files, EnvInheritFDs+"="+strings.Join(pairs, ",")); err != nil {
+		return err
+	}
+	close(s.drain)
+	return nil
+}
+
+// startProcess starts the current executable with the same
+// arguments. The files are inherited by the new process.
+func startProcess(files []*os.File, env string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe, os.Args[1:]...)
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, EnvInheritFDs+"=") {
+			cmd.Env = append(cmd.Env, e)
+		}
+	}
+	cmd.Env = append(cmd.Env, env)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = files
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go func() { _ = cmd.Wait() }() // Reap the process if it exited before this process.
+	return nil
+}
@@ -0,0 +1,216 @@
+package znet
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+// waitState waits the supervisor to become the state.
+func waitState(t *testing.T, s *Supervisor, state SupervisorState) {
+	t.Helper()
+	for range 500 {
+		if s.State() == state {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("state not match. want:%s got:%s", state, s.State())
+}
+
+// testHTTPService returns a new service with http server.
+func testHTTPService(name, addr string) (*Service, *http.Server) {
+	svr := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(name))
+		}),
+	}
+	return &Service{Name: name, Addr: addr, Serve: svr.Serve, Shutdown: svr.Shutdown, Close: svr.Close}, svr
+}
+
+func TestSupervisorState(t *testing.T) {
+	t.Parallel()
+	ztesting.AssertEqual(t, "string not match", "stopped", SupervisorStopped.String())
+	ztesting.AssertEqual(t, "string not match", "starting", SupervisorStarting.String())
+	ztesting.AssertEqual(t, "string not match", "ready", SupervisorReady.String())
+	ztesting.AssertEqual(t, "string not match", "draining", SupervisorDraining.String())
+	ztesting.AssertEqual(t, "string not match", "unknown", SupervisorState(99).String())
+}
+
+func TestSupervisor_Run(t *testing.T) {
+	t.Parallel()
+	svc, _ := testHTTPService("http", "127.0.0.1:0")
+	udpSvc := &Service{
+		Addr: "udp://127.0.0.1:0",
+		ServePacket: func(pc net.PacketConn) error {
+			buf := make([]byte, 64)
+			for {
+				n, addr, err := pc.ReadFrom(buf)
+				if err != nil {
+					return err
+				}
+				_, _ = pc.WriteTo(buf[:n], addr)
+			}
+		},
+	}
+	s := &Supervisor{Services: []*Service{svc, udpSvc}, DrainDelay: 100 * time.Millisecond}
+	udpSvc.Shutdown = func(context.Context) error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.conns[1].close()
+	}
+
+	health := func(h http.Handler) (int, string) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Code, w.Body.String()
+	}
+	code, body := health(s.LivenessHandler())
+	ztesting.AssertEqual(t, "status code not match", http.StatusServiceUnavailable, code)
+	ztesting.AssertEqual(t, "body not match", "stopped", body)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() { errCh <- s.Run(ctx) }()
+	waitState(t, s, SupervisorReady)
+
+	code, _ = health(s.LivenessHandler())
+	ztesting.AssertEqual(t, "status code not match", http.StatusOK, code)
+	code, body = health(s.ReadinessHandler())
+	ztesting.AssertEqual(t, "status code not match", http.StatusOK, code)
+	ztesting.AssertEqual(t, "body not match", "ready", body)
+	ztesting.AssertEqual(t, "error not match", ErrSupervisorRunning, s.Run(ctx))
+
+	s.mu.Lock()
+	httpAddr := s.conns[0].ln.Addr().String()
+	udpAddr := s.conns[1].pc.LocalAddr().String()
+	s.mu.Unlock()
+
+	res, err := http.Get("http://" + httpAddr)
+	ztesting.AssertEqualErr(t, "error not match", nil, err)
+	b, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	ztesting.AssertEqual(t, "body not match", "http", string(b))
+
+	conn, _ := net.Dial("udp", udpAddr)
+	_, _ = conn.Write([]byte("ping"))
+	buf := make([]byte, 64)
+	n, _ := conn.Read(buf)
+	conn.Close()
+	ztesting.AssertEqual(t, "body not match", "ping", string(buf[:n]))
+
+	cancel()
+	waitState(t, s, SupervisorDraining)
+	code, body = health(s.ReadinessHandler())
+	ztesting.AssertEqual(t, "status code not match", http.StatusServiceUnavailable, code)
+	ztesting.AssertEqual(t, "body not match", "draining", body)
+	code, _ = health(s.LivenessHandler())
+	ztesting.AssertEqual(t, "status code not match", http.StatusOK, code)
+
+	// Listeners are still available while draining.
+	res, err = http.Get("http://" + httpAddr)
+	ztesting.AssertEqualErr(t, "error not match", nil, err)
+	res.Body.Close()
+
+	ztesting.AssertEqualErr(t, "error not match", nil, <-errCh)
+	ztesting.AssertEqual(t, "state not match", SupervisorStopped, s.State())
+}
+
+func TestSupervisor_RunError(t *testing.T) {
+	t.Parallel()
+	t.Run("invalid service", func(t *testing.T) {
+		s := &Supervisor{Services: []*Service{{Addr: "udp://127.0.0.1:0", Serve: func(net.Listener) error { return nil }}}}
+		err := s.Run(context.Background())
+		ztesting.AssertEqualErr(t, "error not match", ErrInvalidService, err)
+	})
+	t.Run("listen error", func(t *testing.T) {
+		ln, _ := net.Listen("tcp", "127.0.0.1:0")
+		defer ln.Close()
+		svc1, _ := testHTTPService("svc1", "127.0.0.1:0")
+		svc2, _ := testHTTPService("svc2", ln.Addr().String())
+		s := &Supervisor{Services: []*Service{svc1, svc2}}
+		err := s.Run(context.Background())
+		ztesting.AssertEqual(t, "error must be returned", true, err != nil)
+		ztesting.AssertEqual(t, "state not match", SupervisorStopped, s.State())
+	})
+	t.Run("serve error", func(t *testing.T) {
+		testErr := errors.New("serve error")
+		svc1, _ := testHTTPService("svc1", "127.0.0.1:0")
+		svc2 := &Service{
+			Addr:     "127.0.0.1:0",
+			Serve:    func(ln net.Listener) error { ln.Close(); return testErr },
+			Shutdown: func(context.Context) error { return nil },
+		}
+		s := &Supervisor{Services: []*Service{svc1, svc2}, DrainDelay: time.Minute}
+		err := s.Run(context.Background())
+		ztesting.AssertEqualErr(t, "error not match", testErr, err)
+	})
+}
+
+func TestSupervisor_Upgrade(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("file listeners are not supported on windows")
+	}
+	svc, _ := testHTTPService("http", "127.0.0.1:0")
+	var env string
+	var ln net.Listener
+	s := &Supervisor{
+		Services: []*Service{svc},
+		startProcess: func(files []*os.File, e string) error {
+			env = e
+			var err error
+			ln, err = net.FileListener(files[0])
+			return err
+		},
+	}
+	ztesting.AssertEqualErr(t, "error not match", ErrSupervisorNotReady, s.Upgrade())
+
+	errCh := make(chan error)
+	go func() { errCh <- s.Run(context.Background()) }()
+	waitState(t, s, SupervisorReady)
+
+	ztesting.AssertEqualErr(t, "error not match", nil, s.Upgrade())
+	ztesting.AssertEqualErr(t, "error not match", nil, <-errCh)
+	ztesting.AssertEqual(t, "env not match", EnvInheritFDs+"=http=3", env)
+
+	// Handed over listener is still available.
+	newSvc, newSvr := testHTTPService("new", "")
+	defer newSvr.Close()
+	go func() { _ = newSvc.Serve(ln) }()
+	res, err := http.Get("http://" + ln.Addr().String())
+	ztesting.AssertEqualErr(t, "error not match", nil, err)
+	b, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	ztesting.AssertEqual(t, "body not match", "new", string(b))
+}
+
+func TestSupervisor_UpgradeError(t *testing.T) {
+	t.Parallel()
+	testErr := errors.New("start error")
+	svc, _ := testHTTPService("http", "127.0.0.1:0")
+	var upgradeErr error
+	s := &Supervisor{
+		Services:     []*Service{svc},
+		startProcess: func([]*os.File, string) error { return testErr },
+		OnUpgrade:    func(err error) { upgradeErr = err },
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() { errCh <- s.Run(ctx) }()
+	waitState(t, s, SupervisorReady)
+	ztesting.AssertEqualErr(t, "error not match", testErr, s.Upgrade())
+	ztesting.AssertEqual(t, "state not match", SupervisorReady, s.State())
+	cancel()
+	ztesting.AssertEqualErr(t, "error not match", nil, <-errCh)
+	ztesting.AssertEqualErr(t, "OnUpgrade must not be called", nil, upgradeErr)
+}
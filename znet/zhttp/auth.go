@@ -0,0 +1,235 @@
+package zhttp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	CauseUnauthorized = "znet/zhttp: authentication failed"
+)
+
+var (
+	// ErrInvalidCredentials is the error returned when
+	// the credentials file has invalid format.
+	ErrInvalidCredentials = errors.New("znet/zhttp: invalid credentials format")
+)
+
+// PasswordComparer compares hashed passwords and passwords.
+// Compare must return nil when the hashed value of the pw
+// matched to the hashedPW and non-nil error otherwise.
+// Password hashers in zcrypto such as
+// [github.com/aileron-projects/go/zcrypto/zbcrypt.BCrypt] and
+// [github.com/aileron-projects/go/zcrypto/zargon2.Argon2id]
+// implement this interface.
+type PasswordComparer interface {
+	Compare(hashedPW, pw []byte) error
+}
+
+// BasicCredentials holds credentials for basic authentication.
+// Users can have passwords hashed by different algorithms.
+// Passwords of users that have a scheme in the Schemes are compared
+// by the Hashers of the scheme and others are compared by the Hasher.
+// See https://datatracker.ietf.org/doc/rfc7617/
+type BasicCredentials struct {
+	// Hasher compares hashed passwords and given passwords
+	// of the users that do not have a scheme.
+	// Hasher must not be nil if there are such users.
+	Hasher PasswordComparer
+	// Hashers is the map of scheme name and comparer.
+	// It is used for the users that have a scheme in the Schemes.
+	Hashers map[string]PasswordComparer
+	// Users is the map of username and hashed password.
+	Users map[string][]byte
+	// Schemes is the map of username and scheme name.
+	// Users that are not in Schemes use the Hasher.
+	Schemes map[string]string
+}
+
+// Verify returns if the username and the password is valid or not.
+// It returns false when the username is not found or
+// the comparer of the user's scheme is not found.
+// For unknown users, a password of another user is compared
+// so that the response time does not reveal if the user exists.
+func (c *BasicCredentials) Verify(username, password string) bool {
+	hashedPW, ok := c.Users[username]
+	if !ok {
+		for name, pw := range c.Users {
+			if h := c.comparer(name); h != nil {
+				_ = h.Compare(pw, []byte(password))
+			}
+			break
+		}
+		return false
+	}
+	h := c.comparer(username)
+	if h == nil {
+		return false
+	}
+	return h.Compare(hashedPW, []byte(password)) == nil
+}
+
+// comparer returns the password comparer for the user.
+func (c *BasicCredentials) comparer(username string) PasswordComparer {
+	if scheme, ok := c.Schemes[username]; ok {
+		return c.Hashers[scheme]
+	}
+	return c.Hasher
+}
+
+// LoadBasicCredentials loads credentials from the file.
+// See [ParseBasicCredentials] for the file format.
+func LoadBasicCredentials(path string, hasher PasswordComparer) (*BasicCredentials, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBasicCredentials(b, hasher)
+}
+
+// ParseBasicCredentials parses credentials.
+// Each line of b must have the format of "<username>:<hashed-password>"
+// or "<username>:{<scheme>}<hashed-password>".
+// Hashed passwords that start with "$" such as bcrypt hashes
+// are used as-is. Others are hex decoded. Passwords hashed by
+// zargon2, zscrypt and zpbkdf2 should be hex encoded.
+// Passwords without scheme are compared by the hasher.
+// Passwords with scheme are compared by the [BasicCredentials.Hashers]
+// of the scheme, which must be set by callers before use.
+// This allows a single file to contain passwords hashed by
+// different algorithms.
+// Empty lines and lines starting with "#" are ignored.
+//
+// Example:
+//
+//	# bcrypt, compared by the hasher.
+//	alice:$2a$10$QvZtX4TS8Zh7wMH.x2HxUe/6.jDddimt6NHBkk.XoLH66C4R0hhrW
+//	# argon2id, compared by the Hashers["argon2id"].
+//	bob:{argon2id}3132333435363738393028b9bb8a406e9dd05f754e60d9f5565f66a6d80a9930f67617ed4e97b710d824
+func ParseBasicCredentials(b []byte, hasher PasswordComparer) (*BasicCredentials, error) {
+	c := &BasicCredentials{
+		Hasher:  hasher,
+		Hashers: map[string]PasswordComparer{},
+		Users:   map[string][]byte{},
+		Schemes: map[string]string{},
+	}
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		username, hashedPW, found := strings.Cut(line, ":")
+		if !found || username == "" || hashedPW == "" {
+			return nil, errors.Join(ErrInvalidCredentials, errors.New("line "+strconv.Itoa(n)+": missing username or password"))
+		}
+		if hashedPW[0] == '{' {
+			scheme, rest, found := strings.Cut(hashedPW[1:], "}")
+			if !found || scheme == "" || rest == "" {
+				return nil, errors.Join(ErrInvalidCredentials, errors.New("line "+strconv.Itoa(n)+": invalid scheme"))
+			}
+			c.Schemes[username] = scheme
+			hashedPW = rest
+		} else {
+			delete(c.Schemes, username)
+		}
+		if hashedPW[0] == '$' {
+			c.Users[username] = []byte(hashedPW)
+			continue
+		}
+		pw, err := hex.DecodeString(hashedPW)
+		if err != nil {
+			return nil, errors.Join(ErrInvalidCredentials, errors.New("line "+strconv.Itoa(n)+": "+err.Error()))
+		}
+		c.Users[username] = pw
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// BasicAuth is the server middleware for basic authentication.
+// It authenticates requests with the "Authorization" header.
+// Authenticated username can be obtained with [UsernameFromContext].
+// BasicAuth implements [ServerMiddleware].
+// See https://datatracker.ietf.org/doc/rfc7617/
+type BasicAuth struct {
+	// Credentials is the credentials of users.
+	// Credentials must not be nil.
+	Credentials *BasicCredentials
+	// Realm is the realm sent with the "WWW-Authenticate"
+	// header when authentication failed.
+	Realm string
+	// ErrorHandler is the optional error handler.
+	// If nil, a default error handler is used which
+	// responds the status code of the error.
+	ErrorHandler ErrorHandler[*HTTPError]
+}
+
+// ServerMiddleware is the implementation of [ServerMiddleware.ServerMiddleware].
+func (m *BasicAuth) ServerMiddleware(next http.Handler) http.Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := parseBasicAuth(r.Header.Get("Authorization"))
+		if !ok || !m.Credentials.Verify(username, password) {
+			w.Header().Set("WWW-Authenticate", "Basic realm="+strconv.Quote(m.Realm))
+			handleAuthError(w, r, m.ErrorHandler, &HTTPError{Code: http.StatusUnauthorized, Cause: CauseUnauthorized})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), usernameContextKey, username)))
+	})
+}
+
+type authContextKey int
+
+const (
+	usernameContextKey authContextKey = iota
+	claimsContextKey
+)
+
+// UsernameFromContext returns the authenticated username
+// stored in the context by [BasicAuth] or [HMACVerifier].
+// For HMACVerifier, the key id is returned as the username.
+// It returns an empty string if not found.
+func UsernameFromContext(ctx context.Context) string {
+	username, _ := ctx.Value(usernameContextKey).(string)
+	return username
+}
+
+// handleAuthError handles the err with the eh if non-nil.
+// Otherwise, it uses a default error handler.
+func handleAuthError(w http.ResponseWriter, r *http.Request, eh ErrorHandler[*HTTPError], err *HTTPError) {
+	if eh != nil {
+		eh(w, r, err)
+		return
+	}
+	defaultErrorHandler(w, r, err)
+}
+
+// parseBasicAuth parses the value of "Authorization" or
+// "Proxy-Authorization" header with the basic scheme.
+// For example, "Basic dXNlcjpwYXNz" is parsed into "user" and "pass".
+// See also [net/http.Request.BasicAuth].
+func parseBasicAuth(auth string) (username, password string, ok bool) {
+	const prefix = "Basic "
+	if len(auth) < len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return "", "", false
+	}
+	c, err := base64.StdEncoding.DecodeString(auth[len(prefix):])
+	if err != nil {
+		return "", "", false
+	}
+	username, password, ok = strings.Cut(string(c), ":")
+	if !ok {
+		return "", "", false
+	}
+	return username, password, true
+}
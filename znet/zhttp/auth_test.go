@@ -0,0 +1,196 @@
+package zhttp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aileron-projects/go/zcrypto/zbcrypt"
+	"github.com/aileron-projects/go/zcrypto/zscrypt"
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestParseBasicAuth(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		auth               string
+		username, password string
+		ok                 bool
+	}{
+		"valid":          {"Basic dXNlcjpwYXNz", "user", "pass", true},
+		"lower case":     {"basic dXNlcjpwYXNz", "user", "pass", true},
+		"empty password": {"Basic dXNlcjo=", "user", "", true},
+		"no colon":       {"Basic dXNlcg==", "", "", false},
+		"invalid base64": {"Basic ###", "", "", false},
+		"other scheme":   {"Bearer dXNlcjpwYXNz", "", "", false},
+		"empty":          {"", "", "", false},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			username, password, ok := parseBasicAuth(tc.auth)
+			ztesting.AssertEqual(t, "username not match", tc.username, username)
+			ztesting.AssertEqual(t, "password not match", tc.password, password)
+			ztesting.AssertEqual(t, "ok not match", tc.ok, ok)
+		})
+	}
+}
+
+func TestBasicCredentials(t *testing.T) {
+	t.Parallel()
+	hasher, _ := zbcrypt.New(4)
+	hashed, _ := hasher.Sum([]byte("pass"))
+	c := &BasicCredentials{Hasher: hasher, Users: map[string][]byte{"user": hashed}}
+	ztesting.AssertEqual(t, "verify result not match", true, c.Verify("user", "pass"))
+	ztesting.AssertEqual(t, "verify result not match", false, c.Verify("user", "wrong"))
+	ztesting.AssertEqual(t, "verify result not match", false, c.Verify("unknown", "pass"))
+}
+
+type countComparer struct {
+	count int
+}
+
+func (c *countComparer) Compare(hashedPW, pw []byte) error {
+	c.count++
+	if string(hashedPW) != string(pw) {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+func TestBasicCredentials_schemes(t *testing.T) {
+	t.Parallel()
+	bcrypt, _ := zbcrypt.New(4)
+	bcryptHashed, _ := bcrypt.Sum([]byte("alice-pass"))
+	scrypt, _ := zscrypt.New(16, 1024, 8, 1, 32)
+	scryptHashed, _ := scrypt.Sum([]byte("bob-pass"))
+	c := &BasicCredentials{
+		Hasher:  bcrypt,
+		Hashers: map[string]PasswordComparer{"scrypt": scrypt},
+		Users:   map[string][]byte{"alice": bcryptHashed, "bob": scryptHashed, "carol": []byte("pass")},
+		Schemes: map[string]string{"bob": "scrypt", "carol": "unknown"},
+	}
+	ztesting.AssertEqual(t, "verify result not match", true, c.Verify("alice", "alice-pass"))
+	ztesting.AssertEqual(t, "verify result not match", false, c.Verify("alice", "bob-pass"))
+	ztesting.AssertEqual(t, "verify result not match", true, c.Verify("bob", "bob-pass"))
+	ztesting.AssertEqual(t, "verify result not match", false, c.Verify("bob", "alice-pass"))
+	ztesting.AssertEqual(t, "verify result not match", false, c.Verify("carol", "pass"))
+}
+
+func TestBasicCredentials_unknownUser(t *testing.T) {
+	t.Parallel()
+	hasher := &countComparer{}
+	c := &BasicCredentials{Hasher: hasher, Users: map[string][]byte{"user": []byte("pass")}}
+	ztesting.AssertEqual(t, "verify result not match", false, c.Verify("unknown", "pass"))
+	ztesting.AssertEqual(t, "password not compared for unknown user", 1, hasher.count)
+	ztesting.AssertEqual(t, "verify result not match", true, c.Verify("user", "pass"))
+	ztesting.AssertEqual(t, "compare count not match", 2, hasher.count)
+}
+
+func TestParseBasicCredentials(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		input string
+		users map[string]string
+		err   error
+	}{
+		"empty":           {"", map[string]string{}, nil},
+		"comment":         {"# comment\n\n", map[string]string{}, nil},
+		"bcrypt":          {"alice:$2a$10$foo", map[string]string{"alice": "$2a$10$foo"}, nil},
+		"hex":             {"bob:666f6f", map[string]string{"bob": "foo"}, nil},
+		"multiple":        {"alice:$2a$10$foo\n bob:666f6f \n", map[string]string{"alice": "$2a$10$foo", "bob": "foo"}, nil},
+		"scheme bcrypt":   {"alice:{bcrypt}$2a$10$foo", map[string]string{"alice": "$2a$10$foo"}, nil},
+		"scheme hex":      {"bob:{scrypt}666f6f", map[string]string{"bob": "foo"}, nil},
+		"empty scheme":    {"bob:{}666f6f", nil, ErrInvalidCredentials},
+		"unclosed scheme": {"bob:{scrypt666f6f", nil, ErrInvalidCredentials},
+		"scheme only":     {"bob:{scrypt}", nil, ErrInvalidCredentials},
+		"no separator":    {"alice", nil, ErrInvalidCredentials},
+		"empty username":  {":666f6f", nil, ErrInvalidCredentials},
+		"empty password":  {"alice:", nil, ErrInvalidCredentials},
+		"invalid hex":     {"alice:xyz", nil, ErrInvalidCredentials},
+		"invalid in last": {"alice:666f6f\nbob", nil, ErrInvalidCredentials},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c, err := ParseBasicCredentials([]byte(tc.input), nil)
+			ztesting.AssertEqualErr(t, "error not match", tc.err, err)
+			if tc.err != nil {
+				return
+			}
+			ztesting.AssertEqual(t, "number of users not match", len(tc.users), len(c.Users))
+			for k, v := range tc.users {
+				ztesting.AssertEqual(t, "password not match", v, string(c.Users[k]))
+			}
+		})
+	}
+}
+
+func TestLoadBasicCredentials(t *testing.T) {
+	t.Parallel()
+	hasher, _ := zscrypt.New(16, 1024, 8, 1, 32)
+	path := filepath.Join(t.TempDir(), "credentials")
+	content := "alice:219ed9951a5010e9aafc3f613307e5bddeaeb389534daf08aaafe9dce6a7e725b89ab9e1bea701820a02cc7fad2af7a5\n"
+	_ = os.WriteFile(path, []byte(content), 0o600)
+
+	c, err := LoadBasicCredentials(path, hasher)
+	ztesting.AssertEqualErr(t, "error not match", nil, err)
+	ztesting.AssertEqual(t, "verify result not match", true, c.Verify("alice", "password"))
+	ztesting.AssertEqual(t, "verify result not match", false, c.Verify("alice", "wrong"))
+
+	_, err = LoadBasicCredentials(filepath.Join(t.TempDir(), "not-exist"), hasher)
+	ztesting.AssertEqualErr(t, "error not match", os.ErrNotExist, err)
+}
+
+func TestBasicAuth(t *testing.T) {
+	t.Parallel()
+	c, _ := ParseBasicCredentials([]byte("alice:$2a$10$QvZtX4TS8Zh7wMH.x2HxUe/6.jDddimt6NHBkk.XoLH66C4R0hhrW"), &zbcrypt.BCrypt{})
+	m := &BasicAuth{Credentials: c, Realm: "test"}
+	var username string
+	h := m.ServerMiddleware(HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username = UsernameFromContext(r.Context())
+	}))
+
+	testCases := map[string]struct {
+		username, password string
+		code               int
+	}{
+		"valid":          {"alice", "password", http.StatusOK},
+		"wrong password": {"alice", "wrong", http.StatusUnauthorized},
+		"unknown user":   {"bob", "password", http.StatusUnauthorized},
+		"no header":      {"", "", http.StatusUnauthorized},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			username = ""
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+			if tc.username != "" {
+				r.SetBasicAuth(tc.username, tc.password)
+			}
+			h.ServeHTTP(w, r)
+			ztesting.AssertEqual(t, "status code not match", tc.code, w.Code)
+			if tc.code == http.StatusOK {
+				ztesting.AssertEqual(t, "username not match", tc.username, username)
+			} else {
+				ztesting.AssertEqual(t, "header not match", `Basic realm="test"`, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestUsernameFromContext(t *testing.T) {
+	t.Parallel()
+	ztesting.AssertEqual(t, "username not match", "", UsernameFromContext(context.Background()))
+	ctx := context.WithValue(context.Background(), usernameContextKey, "alice")
+	ztesting.AssertEqual(t, "username not match", "alice", UsernameFromContext(ctx))
+}
+
+func TestParseBasicCredentials_schemes(t *testing.T) {
+	t.Parallel()
+	input := "alice:$2a$10$foo\nbob:{scrypt}666f6f\nbob:666f6f\ncarol:{argon2id}$foo"
+	c, err := ParseBasicCredentials([]byte(input), nil)
+	ztesting.AssertEqualErr(t, "error not match", nil, err)
+	ztesting.AssertEqual(t, "schemes not match", map[string]string{"carol": "argon2id"}, c.Schemes)
+}
@@ -0,0 +1,330 @@
+package zhttp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/aileron-projects/go/znet"
+	"github.com/aileron-projects/go/zstrings"
+	"github.com/aileron-projects/go/ztime/zrate"
+)
+
+const (
+	CauseProxyAuth       = "znet/zhttp: proxy authentication required"
+	CauseRateLimit       = "znet/zhttp: too many requests from the client"
+	CauseInvalidTarget   = "znet/zhttp: invalid proxy target"
+	CauseDestinationDeny = "znet/zhttp: destination is not allowed"
+	CauseConnect         = "znet/zhttp: connecting to the tunnel destination failed"
+	CauseTunnel          = "znet/zhttp: tunneling failed"
+)
+
+const (
+	defaultDialTimeout   = 30 * time.Second
+	defaultDialKeepAlive = 30 * time.Second
+	connectEstablished   = "HTTP/1.1 200 Connection Established\r\n\r\n"
+)
+
+var (
+	// ErrDestinationDenied is the error returned when
+	// the destination is not allowed by [ForwardProxy].
+	ErrDestinationDenied = errors.New("znet/zhttp: destination denied")
+)
+
+// PerClientLimiter returns a function that can be used as [ForwardProxy.ClientLimiter].
+// A limiter is created with newLimiter for each client IP address
+// obtained from the [net/http.Request.RemoteAddr].
+// Note that created limiters are never removed. Use it only when
+// the number of clients is limited.
+func PerClientLimiter(newLimiter func() zrate.Limiter) func(r *http.Request) zrate.Limiter {
+	var limiters sync.Map
+	return func(r *http.Request) zrate.Limiter {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		if lim, ok := limiters.Load(host); ok {
+			return lim.(zrate.Limiter)
+		}
+		lim, _ := limiters.LoadOrStore(host, newLimiter())
+		return lim.(zrate.Limiter)
+	}
+}
+
+// ForwardProxy is the HTTP forward proxy.
+// Requests with absolute-form URL such as "GET http://example.com/ HTTP/1.1"
+// are proxied using [Proxy] and CONNECT requests such as
+// "CONNECT example.com:443 HTTP/1.1" are tunneled.
+// Upgrade requests are handled in the same way as [Proxy].
+// Requests are checked in the order of proxy authentication,
+// client rate limiting and destination validation.
+// See the references.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/rfc9110/ (9.3.6. CONNECT)
+//   - https://datatracker.ietf.org/doc/rfc9112/ (3.2.2. absolute-form)
+type ForwardProxy struct {
+	// Transport is the transport used to proxy
+	// requests other than CONNECT.
+	// If nil, a clone of the [net/http.DefaultTransport]
+	// that dials with the Dialer is used.
+	// Note that the AllowedIPs is applied only to the
+	// IP address literals in the request URL when
+	// a non-nil transport is given.
+	Transport http.RoundTripper
+	// Dialer is the dialer used to connect to the destination
+	// of CONNECT requests and the destinations of the default transport.
+	// If nil, a dialer with 30 seconds timeout is used.
+	// When AllowedIPs is non-nil, the destination IP address is checked
+	// before calling the Dialer.ControlContext or Dialer.Control.
+	Dialer *net.Dialer
+
+	// Rewrite, if non-nil, modifies proxy request of
+	// non-CONNECT requests. See [Proxy.Rewrite].
+	// Request URL is used as-is when Rewrite is nil.
+	Rewrite func(in, out *http.Request)
+
+	// Credentials is the optional credentials for
+	// proxy authentication using "Proxy-Authorization" header.
+	// Only the basic authentication scheme is supported.
+	// If nil, proxy authentication is disabled.
+	// See https://datatracker.ietf.org/doc/rfc7617/
+	Credentials *BasicCredentials
+	// Realm is the realm sent with the "Proxy-Authenticate" header
+	// when proxy authentication failed.
+	Realm string
+
+	// ClientLimiter returns a rate limiter for the client of the request.
+	// Requests are rejected with 429 Too Many Requests if the limiter
+	// does not allow the request.
+	// For CONNECT requests, the token is held until the tunnel is closed.
+	// ClientLimiter must return non-nil limiter.
+	// See also [PerClientLimiter].
+	ClientLimiter func(r *http.Request) zrate.Limiter
+
+	// AllowedHosts is the list of hostname patterns that
+	// are allowed as destinations.
+	// Patterns are matched to the lowercased hostname without port
+	// and trailing dot using [github.com/aileron-projects/go/zstrings.Match].
+	// If empty, all hostnames are allowed except for DeniedHosts.
+	AllowedHosts []string
+	// DeniedHosts is the list of hostname patterns that are
+	// not allowed as destinations.
+	// DeniedHosts is always prior to the AllowedHosts.
+	// Patterns are matched to the lowercased hostname without port
+	// and trailing dot using [github.com/aileron-projects/go/zstrings.Match].
+	DeniedHosts []string
+	// AllowedIPs is the IP whitelist for the destinations.
+	// It is checked against the resolved IP addresses when dialing.
+	// If nil, all IP addresses are allowed.
+	AllowedIPs *znet.WhiteList
+
+	// ErrorHandler is the optional error handler.
+	// If nil, a default error handler is used.
+	// See [Proxy.ErrorHandler].
+	ErrorHandler ErrorHandler[*HTTPError]
+
+	once  sync.Once
+	dial  func(ctx context.Context, network, address string) (net.Conn, error)
+	proxy *Proxy
+}
+
+func (p *ForwardProxy) init() {
+	d := &net.Dialer{Timeout: defaultDialTimeout, KeepAlive: defaultDialKeepAlive}
+	if p.Dialer != nil {
+		d = p.Dialer
+	}
+	if p.AllowedIPs != nil {
+		dd := *d // Shallow copy not to modify the given dialer.
+		control, controlContext := d.Control, d.ControlContext
+		dd.Control = nil
+		dd.ControlContext = func(ctx context.Context, network, address string, c syscall.RawConn) error {
+			host, _, _ := net.SplitHostPort(address)
+			if !p.AllowedIPs.Allowed(host) {
+				return ErrDestinationDenied
+			}
+			if controlContext != nil {
+				return controlContext(ctx, network, address, c)
+			}
+			if control != nil {
+				return control(network, address, c)
+			}
+			return nil
+		}
+		d = &dd
+	}
+	p.dial = d.DialContext
+
+	transport := p.Transport
+	if transport == nil {
+		t := http.DefaultTransport.(*http.Transport).Clone()
+		t.Proxy = nil
+		t.DialContext = p.dial
+		transport = t
+	}
+	rewrite := p.Rewrite
+	if rewrite == nil {
+		rewrite = func(_, _ *http.Request) {}
+	}
+	p.proxy = &Proxy{
+		Rewrite:   rewrite,
+		Transport: transport,
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err *HTTPError) {
+			if errors.Is(err.Err, ErrDestinationDenied) {
+				err = &HTTPError{Err: err.Err, Code: http.StatusForbidden, Cause: CauseDestinationDeny}
+			}
+			p.handleError(w, r, err)
+		},
+	}
+}
+
+func (p *ForwardProxy) handleError(w http.ResponseWriter, r *http.Request, err *HTTPError) {
+	if eh := p.ErrorHandler; eh != nil {
+		eh(w, r, err)
+		return
+	}
+	defaultErrorHandler(w, r, err)
+}
+
+func (p *ForwardProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.once.Do(p.init)
+
+	if p.Credentials != nil {
+		username, password, ok := parseBasicAuth(r.Header.Get("Proxy-Authorization"))
+		if !ok || !p.Credentials.Verify(username, password) {
+			w.Header().Set("Proxy-Authenticate", "Basic realm="+strconv.Quote(p.Realm))
+			p.handleError(w, r, &HTTPError{Code: http.StatusProxyAuthRequired, Cause: CauseProxyAuth})
+			return
+		}
+	}
+
+	if p.ClientLimiter != nil {
+		token := p.ClientLimiter(r).AllowNow()
+		defer token.Release()
+		if !token.OK() {
+			p.handleError(w, r, &HTTPError{Err: token.Err(), Code: http.StatusTooManyRequests, Cause: CauseRateLimit})
+			return
+		}
+	}
+
+	var host string
+	if r.Method == http.MethodConnect {
+		h, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			p.handleError(w, r, &HTTPError{Err: err, Code: http.StatusBadRequest, Cause: CauseInvalidTarget})
+			return
+		}
+		host = h
+	} else {
+		if r.URL == nil || !r.URL.IsAbs() || r.URL.Host == "" {
+			p.handleError(w, r, &HTTPError{Code: http.StatusBadRequest, Cause: CauseInvalidTarget, Detail: "request target must be absolute-form"})
+			return
+		}
+		host = r.URL.Hostname()
+	}
+
+	if !p.hostAllowed(host) {
+		p.handleError(w, r, &HTTPError{Code: http.StatusForbidden, Cause: CauseDestinationDeny, Detail: "host " + strconv.Quote(host) + " is not allowed"})
+		return
+	}
+
+	if r.Method != http.MethodConnect {
+		p.proxy.ServeHTTP(w, r)
+		return
+	}
+	if err := p.tunnel(w, r); err != nil {
+		p.handleError(w, r, err)
+	}
+}
+
+// hostAllowed returns if the destination host is allowed or not.
+// The host must not contain port number.
+// The host is lowercased and a trailing dot is removed before matching.
+func (p *ForwardProxy) hostAllowed(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, pattern := range p.DeniedHosts {
+		if ok, _ := zstrings.Match(pattern, host); ok {
+			return false
+		}
+	}
+	if p.AllowedIPs != nil {
+		if ip := net.ParseIP(host); ip != nil && !p.AllowedIPs.Allowed(ip.String()) {
+			return false // IP literal. Check in advance.
+		}
+	}
+	if len(p.AllowedHosts) == 0 {
+		return true
+	}
+	for _, pattern := range p.AllowedHosts {
+		if ok, _ := zstrings.Match(pattern, host); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// tunnel handles CONNECT requests.
+// Connections are hijacked for HTTP/1 and the request body and the
+// response writer are used as a bidirectional stream for HTTP/2 or later.
+func (p *ForwardProxy) tunnel(w http.ResponseWriter, r *http.Request) *HTTPError {
+	backConn, err := p.dial(r.Context(), "tcp", r.Host)
+	if err != nil {
+		if errors.Is(err, ErrDestinationDenied) {
+			return &HTTPError{Err: err, Code: http.StatusForbidden, Cause: CauseDestinationDeny}
+		}
+		return &HTTPError{Err: err, Code: http.StatusBadGateway, Cause: CauseConnect}
+	}
+	defer backConn.Close() // Ensure close.
+
+	var front io.ReadCloser
+	var back io.Writer
+	if r.ProtoMajor == 1 {
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			return &HTTPError{
+				Err:    err,
+				Code:   http.StatusInternalServerError,
+				Cause:  CauseHijack,
+				Detail: "hijacking ResponseWriter failed (" + fmt.Sprintf("%T", w) + ")",
+			}
+		}
+		defer conn.Close() // Ensure close.
+		if _, err := brw.WriteString(connectEstablished); err != nil {
+			return &HTTPError{Err: err, Code: -1, Cause: CauseCopyResponse}
+		}
+		if err := brw.Flush(); err != nil {
+			return &HTTPError{Err: err, Code: -1, Cause: CauseFlushBody}
+		}
+		// Read from the brw.Reader that may have buffered data.
+		front, back = struct {
+			io.Reader
+			io.Closer
+		}{brw.Reader, conn}, conn
+	} else {
+		w.WriteHeader(http.StatusOK)
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			return &HTTPError{Err: err, Code: -1, Cause: CauseFlushBody}
+		}
+		front, back = r.Body, withImmediateFlushWriter(w)
+	}
+
+	errChan := make(chan error, 2)
+	go copyBuf(backConn, front, errChan)
+	go copyBuf(back, backConn, errChan)
+	err = <-errChan
+	_ = backConn.Close() // Unblock the other side.
+	_ = front.Close()
+	<-errChan
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		return &HTTPError{Err: err, Code: -1, Cause: CauseTunnel}
+	}
+	return nil
+}
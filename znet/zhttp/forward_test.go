@@ -0,0 +1,196 @@
+package zhttp
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/aileron-projects/go/zcrypto/zbcrypt"
+	"github.com/aileron-projects/go/znet"
+	"github.com/aileron-projects/go/ztesting"
+	"github.com/aileron-projects/go/ztime/zrate"
+)
+
+func TestPerClientLimiter(t *testing.T) {
+	t.Parallel()
+	f := PerClientLimiter(func() zrate.Limiter { return zrate.NewConcurrentLimiter(1) })
+	r1 := &http.Request{RemoteAddr: "127.0.0.1:12345"}
+	r2 := &http.Request{RemoteAddr: "127.0.0.1:23456"}
+	r3 := &http.Request{RemoteAddr: "127.0.0.2:12345"}
+	token := f(r1).AllowNow()
+	ztesting.AssertEqual(t, "token not match", true, token.OK())
+	ztesting.AssertEqual(t, "same client must be limited", false, f(r2).AllowNow().OK())
+	ztesting.AssertEqual(t, "other client must not be limited", true, f(r3).AllowNow().OK())
+	token.Release()
+	ztesting.AssertEqual(t, "token not match", true, f(r2).AllowNow().OK())
+}
+
+// testProxyClient returns a http client that uses the proxy.
+// The status code of the CONNECT response is stored in the connectCode.
+func testProxyClient(proxyURL string, connectCode *int) *http.Client {
+	u, _ := url.Parse(proxyURL)
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyURL(u),
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			OnProxyConnectResponse: func(_ context.Context, _ *url.URL, _ *http.Request, res *http.Response) error {
+				*connectCode = res.StatusCode
+				return nil
+			},
+		},
+	}
+}
+
+func TestForwardProxy(t *testing.T) {
+	t.Parallel()
+	backend := httptest.NewServer(HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello " + r.Header.Get("Proxy-Authorization")))
+	}))
+	defer backend.Close()
+	tlsBackend := httptest.NewTLSServer(HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello tls"))
+	}))
+	defer tlsBackend.Close()
+
+	hasher, _ := zbcrypt.New(4)
+	hashed, _ := hasher.Sum([]byte("pass"))
+	denyAll := znet.NewWhiteList()
+
+	testCases := map[string]struct {
+		proxy  *ForwardProxy
+		user   *url.Userinfo
+		target string
+		code   int
+		body   string
+	}{
+		"absolute-form":        {&ForwardProxy{}, nil, backend.URL, http.StatusOK, "hello "},
+		"connect":              {&ForwardProxy{}, nil, tlsBackend.URL, http.StatusOK, "hello tls"},
+		"auth success":         {&ForwardProxy{Credentials: &BasicCredentials{Hasher: hasher, Users: map[string][]byte{"user": hashed}}}, url.UserPassword("user", "pass"), backend.URL, http.StatusOK, "hello "},
+		"auth failure":         {&ForwardProxy{Credentials: &BasicCredentials{Hasher: hasher, Users: map[string][]byte{"user": hashed}}}, url.UserPassword("user", "wrong"), backend.URL, http.StatusProxyAuthRequired, ""},
+		"auth missing":         {&ForwardProxy{Credentials: &BasicCredentials{Hasher: hasher, Users: map[string][]byte{"user": hashed}}}, nil, backend.URL, http.StatusProxyAuthRequired, ""},
+		"rate limited":         {&ForwardProxy{ClientLimiter: func(r *http.Request) zrate.Limiter { return zrate.NoopLimiter(false) }}, nil, backend.URL, http.StatusTooManyRequests, ""},
+		"host allowed":         {&ForwardProxy{AllowedHosts: []string{"127.0.0.*"}}, nil, backend.URL, http.StatusOK, "hello "},
+		"host not allowed":     {&ForwardProxy{AllowedHosts: []string{"example.com"}}, nil, backend.URL, http.StatusForbidden, ""},
+		"host denied":          {&ForwardProxy{DeniedHosts: []string{"127.*"}}, nil, backend.URL, http.StatusForbidden, ""},
+		"host denied upper":    {&ForwardProxy{DeniedHosts: []string{"local*"}}, nil, strings.Replace(backend.URL, "127.0.0.1", "LOCALHOST", 1), http.StatusForbidden, ""},
+		"host denied dot":      {&ForwardProxy{DeniedHosts: []string{"*host"}}, nil, strings.Replace(backend.URL, "127.0.0.1", "localhost.", 1), http.StatusForbidden, ""},
+		"connect denied upper": {&ForwardProxy{DeniedHosts: []string{"local*"}}, nil, strings.Replace(tlsBackend.URL, "127.0.0.1", "LOCALHOST", 1), http.StatusForbidden, ""},
+		"connect denied dot":   {&ForwardProxy{DeniedHosts: []string{"*host"}}, nil, strings.Replace(tlsBackend.URL, "127.0.0.1", "localhost.", 1), http.StatusForbidden, ""},
+		"ip denied":            {&ForwardProxy{AllowedIPs: denyAll}, nil, backend.URL, http.StatusForbidden, ""},
+		"ip denied on connect": {&ForwardProxy{AllowedIPs: denyAll}, nil, tlsBackend.URL, http.StatusForbidden, ""},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			svr := httptest.NewServer(tc.proxy)
+			defer svr.Close()
+			proxyURL, _ := url.Parse(svr.URL)
+			proxyURL.User = tc.user
+
+			var connectCode int
+			client := testProxyClient(proxyURL.String(), &connectCode)
+			res, err := client.Get(tc.target)
+			if strings.HasPrefix(tc.target, "https://") && tc.code != http.StatusOK {
+				// CONNECT failures are returned as errors from the client.
+				ztesting.AssertEqual(t, "error must be returned", true, err != nil)
+				ztesting.AssertEqual(t, "status code not match", tc.code, connectCode)
+				return
+			}
+			ztesting.AssertEqualErr(t, "error not match", nil, err)
+			defer res.Body.Close()
+			body, _ := io.ReadAll(res.Body)
+			ztesting.AssertEqual(t, "status code not match", tc.code, res.StatusCode)
+			if tc.code == http.StatusOK {
+				ztesting.AssertEqual(t, "body not match", tc.body, string(body))
+			}
+		})
+	}
+}
+
+func TestForwardProxy_dialerControl(t *testing.T) {
+	t.Parallel()
+	backend := httptest.NewTLSServer(HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello tls"))
+	}))
+	defer backend.Close()
+	allowed := znet.NewWhiteList()
+	_ = allowed.Allow("127.0.0.1/32")
+
+	testCases := map[string]struct {
+		dialer func(called chan<- string) *net.Dialer
+	}{
+		"control context": {func(called chan<- string) *net.Dialer {
+			return &net.Dialer{ControlContext: func(_ context.Context, _, address string, _ syscall.RawConn) error {
+				called <- address
+				return nil
+			}}
+		}},
+		"control": {func(called chan<- string) *net.Dialer {
+			return &net.Dialer{Control: func(_, address string, _ syscall.RawConn) error {
+				called <- address
+				return nil
+			}}
+		}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			called := make(chan string, 1)
+			svr := httptest.NewServer(&ForwardProxy{Dialer: tc.dialer(called), AllowedIPs: allowed})
+			defer svr.Close()
+			var connectCode int
+			res, err := testProxyClient(svr.URL, &connectCode).Get(backend.URL)
+			ztesting.AssertEqualErr(t, "error not match", nil, err)
+			defer res.Body.Close()
+			ztesting.AssertEqual(t, "status code not match", http.StatusOK, connectCode)
+			select {
+			case addr := <-called:
+				ztesting.AssertEqual(t, "dialed address not match", backend.Listener.Addr().String(), addr)
+			default:
+				t.Error("dialer control must be called")
+			}
+		})
+	}
+}
+
+func TestForwardProxy_invalidTarget(t *testing.T) {
+	t.Parallel()
+	p := &ForwardProxy{}
+	t.Run("origin-form", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/foo", nil)
+		p.ServeHTTP(w, r)
+		ztesting.AssertEqual(t, "status code not match", http.StatusBadRequest, w.Code)
+	})
+	t.Run("connect without port", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodConnect, "/", nil)
+		r.Host = "example.com"
+		p.ServeHTTP(w, r)
+		ztesting.AssertEqual(t, "status code not match", http.StatusBadRequest, w.Code)
+	})
+	t.Run("connect refused", func(t *testing.T) {
+		ln, _ := net.Listen("tcp", "127.0.0.1:0")
+		addr := ln.Addr().String()
+		ln.Close()
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodConnect, "/", nil)
+		r.Host = addr
+		p.ServeHTTP(w, r)
+		ztesting.AssertEqual(t, "status code not match", http.StatusBadGateway, w.Code)
+	})
+	t.Run("auth header", func(t *testing.T) {
+		hasher, _ := zbcrypt.New(4)
+		p := &ForwardProxy{Realm: "test", Credentials: &BasicCredentials{Hasher: hasher}}
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+		p.ServeHTTP(w, r)
+		ztesting.AssertEqual(t, "status code not match", http.StatusProxyAuthRequired, w.Code)
+		ztesting.AssertEqual(t, "header not match", `Basic realm="test"`, w.Header().Get("Proxy-Authenticate"))
+	})
+}
@@ -0,0 +1,191 @@
+package zhttp
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+)
+
+var (
+	// ErrInvalidJWK is the error returned when a JSON Web Key is invalid.
+	ErrInvalidJWK = errors.New("znet/zhttp: invalid json web key")
+)
+
+// JWK is a JSON Web Key.
+// Supported key types are "oct" for HMAC, "RSA" for RSA,
+// "EC" for ECDSA and "OKP" for Ed25519.
+// Key holds one of the following values depending on the key type.
+//
+//   - []byte for "oct" keys.
+//   - *rsa.PublicKey or *rsa.PrivateKey for "RSA" keys.
+//   - *ecdsa.PublicKey or *ecdsa.PrivateKey for "EC" keys.
+//   - ed25519.PublicKey or ed25519.PrivateKey for "OKP" keys.
+//
+// See https://datatracker.ietf.org/doc/rfc7517/
+type JWK struct {
+	// ID is the key id. It corresponds to the "kid" parameter.
+	ID string
+	// Alg is the algorithm intended for use with the key.
+	// It corresponds to the "alg" parameter.
+	// If non-empty, tokens signed with other algorithms are rejected.
+	Alg string
+	// Key is the public key, private key or secret key.
+	Key any
+}
+
+// jwkJSON is the JSON representation of a JWK.
+// See https://datatracker.ietf.org/doc/rfc7518/ (6. Cryptographic Algorithms for Keys)
+type jwkJSON struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	K   string `json:"k"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+	D   string `json:"d"`
+	P   string `json:"p"`
+	Q   string `json:"q"`
+}
+
+// LoadJWKS loads JSON Web Key Set from the file.
+// See [ParseJWKS].
+func LoadJWKS(path string) ([]*JWK, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseJWKS(b)
+}
+
+// ParseJWKS parses JSON Web Key Set.
+// The b must be a JSON object that has "keys" array.
+// Keys with "use" other than "sig" are ignored.
+// See [JWK] for supported key types.
+// See https://datatracker.ietf.org/doc/rfc7517/ (5. JWK Set Format)
+func ParseJWKS(b []byte) ([]*JWK, error) {
+	var set struct {
+		Keys []*jwkJSON `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+	keys := make([]*JWK, 0, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.parse()
+		if err != nil {
+			return nil, errors.Join(ErrInvalidJWK, errors.New("kid="+k.Kid), err)
+		}
+		keys = append(keys, &JWK{ID: k.Kid, Alg: k.Alg, Key: key})
+	}
+	return keys, nil
+}
+
+func (k *jwkJSON) parse() (any, error) {
+	var p b64Parser
+	switch k.Kty {
+	case "oct":
+		key := p.bytes(k.K)
+		if p.err == nil && len(key) == 0 {
+			return nil, errors.New("empty key")
+		}
+		return key, p.err
+	case "RSA":
+		pub := &rsa.PublicKey{N: p.int(k.N), E: int(p.int(k.E).Int64())}
+		if k.D == "" {
+			return pub, p.err
+		}
+		priv := &rsa.PrivateKey{PublicKey: *pub, D: p.int(k.D), Primes: []*big.Int{p.int(k.P), p.int(k.Q)}}
+		if p.err != nil {
+			return nil, p.err
+		}
+		if err := priv.Validate(); err != nil {
+			return nil, err
+		}
+		priv.Precompute()
+		return priv, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.New("unsupported curve " + k.Crv)
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: p.int(k.X), Y: p.int(k.Y)}
+		if p.err != nil {
+			return nil, p.err
+		}
+		if !curve.IsOnCurve(pub.X, pub.Y) { //nolint:staticcheck // Validate public key.
+			return nil, errors.New("point is not on curve")
+		}
+		if k.D == "" {
+			return pub, nil
+		}
+		priv := &ecdsa.PrivateKey{PublicKey: *pub, D: p.int(k.D)}
+		return priv, p.err
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, errors.New("unsupported curve " + k.Crv)
+		}
+		x := p.bytes(k.X)
+		if p.err == nil && len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid key size")
+		}
+		if k.D == "" {
+			return ed25519.PublicKey(x), p.err
+		}
+		d := p.bytes(k.D)
+		if p.err != nil {
+			return nil, p.err
+		}
+		if len(d) != ed25519.SeedSize {
+			return nil, errors.New("invalid key size")
+		}
+		return ed25519.NewKeyFromSeed(d), nil
+	default:
+		return nil, errors.New("unsupported key type " + k.Kty)
+	}
+}
+
+// b64Parser decodes base64url encoded values.
+// It holds the first error.
+type b64Parser struct {
+	err error
+}
+
+func (p *b64Parser) bytes(s string) []byte {
+	if p.err != nil {
+		return nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		p.err = err
+		return nil
+	}
+	return b
+}
+
+func (p *b64Parser) int(s string) *big.Int {
+	b := p.bytes(s)
+	if p.err == nil && len(b) == 0 {
+		p.err = errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b)
+}
@@ -0,0 +1,454 @@
+package zhttp
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Errors returned by [JWTSigner] and [JWTValidator].
+var (
+	ErrTokenMissing   = errors.New("znet/zhttp: bearer token not found")
+	ErrTokenMalformed = errors.New("znet/zhttp: malformed token")
+	ErrTokenAlg       = errors.New("znet/zhttp: token algorithm not allowed")
+	ErrTokenKey       = errors.New("znet/zhttp: token key not found")
+	ErrTokenSignature = errors.New("znet/zhttp: invalid token signature")
+	ErrTokenExpired   = errors.New("znet/zhttp: token expired")
+	ErrTokenNotBefore = errors.New("znet/zhttp: token not valid yet")
+	ErrTokenClaims    = errors.New("znet/zhttp: invalid token claims")
+)
+
+// jwtAlg is the JWS algorithm.
+// See https://datatracker.ietf.org/doc/rfc7518/ (3. Cryptographic Algorithms for Digital Signatures and MACs)
+type jwtAlg struct {
+	kty  string // Key type.
+	hash crypto.Hash
+	size int // Key size in bytes for ECDSA.
+	pss  bool
+}
+
+var jwtAlgs = map[string]jwtAlg{
+	"HS256": {kty: "oct", hash: crypto.SHA256},
+	"HS384": {kty: "oct", hash: crypto.SHA384},
+	"HS512": {kty: "oct", hash: crypto.SHA512},
+	"RS256": {kty: "RSA", hash: crypto.SHA256},
+	"RS384": {kty: "RSA", hash: crypto.SHA384},
+	"RS512": {kty: "RSA", hash: crypto.SHA512},
+	"PS256": {kty: "RSA", hash: crypto.SHA256, pss: true},
+	"PS384": {kty: "RSA", hash: crypto.SHA384, pss: true},
+	"PS512": {kty: "RSA", hash: crypto.SHA512, pss: true},
+	"ES256": {kty: "EC", hash: crypto.SHA256, size: 32},
+	"ES384": {kty: "EC", hash: crypto.SHA384, size: 48},
+	"ES512": {kty: "EC", hash: crypto.SHA512, size: 66},
+	"EdDSA": {kty: "OKP"},
+}
+
+// keyType returns the JWK key type of the key.
+func keyType(key any) string {
+	switch key.(type) {
+	case []byte:
+		return "oct"
+	case *rsa.PublicKey, *rsa.PrivateKey:
+		return "RSA"
+	case *ecdsa.PublicKey, *ecdsa.PrivateKey:
+		return "EC"
+	case ed25519.PublicKey, ed25519.PrivateKey:
+		return "OKP"
+	}
+	return ""
+}
+
+// jwtSign signs the data with the algorithm.
+// The key must be a private key or a secret key.
+func jwtSign(alg string, key any, data []byte) ([]byte, error) {
+	a, ok := jwtAlgs[alg]
+	if !ok || a.kty != keyType(key) {
+		return nil, ErrTokenAlg
+	}
+	var digest []byte
+	if a.hash != 0 {
+		h := a.hash.New()
+		h.Write(data)
+		digest = h.Sum(nil)
+	}
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(a.hash.New, k)
+		mac.Write(data)
+		return mac.Sum(nil), nil
+	case *rsa.PrivateKey:
+		if a.pss {
+			return rsa.SignPSS(rand.Reader, k, a.hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.SignPKCS1v15(rand.Reader, k, a.hash, digest)
+	case *ecdsa.PrivateKey:
+		if (k.Curve.Params().BitSize+7)/8 != a.size {
+			return nil, ErrTokenAlg // Curve does not match to the algorithm.
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest)
+		if err != nil {
+			return nil, err
+		}
+		sig := make([]byte, 2*a.size) // R || S with fixed length.
+		r.FillBytes(sig[:a.size])
+		s.FillBytes(sig[a.size:])
+		return sig, nil
+	case ed25519.PrivateKey:
+		return ed25519.Sign(k, data), nil
+	}
+	return nil, ErrTokenKey // Public keys cannot sign.
+}
+
+// jwtVerify verifies the signature of the data.
+func jwtVerify(alg string, key any, data, sig []byte) bool {
+	a, ok := jwtAlgs[alg]
+	if !ok || a.kty != keyType(key) {
+		return false
+	}
+	var digest []byte
+	if a.hash != 0 {
+		h := a.hash.New()
+		h.Write(data)
+		digest = h.Sum(nil)
+	}
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(a.hash.New, k)
+		mac.Write(data)
+		return hmac.Equal(sig, mac.Sum(nil))
+	case *rsa.PrivateKey:
+		return jwtVerify(alg, &k.PublicKey, data, sig)
+	case *rsa.PublicKey:
+		if a.pss {
+			return rsa.VerifyPSS(k, a.hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthAuto}) == nil
+		}
+		return rsa.VerifyPKCS1v15(k, a.hash, digest, sig) == nil
+	case *ecdsa.PrivateKey:
+		return jwtVerify(alg, &k.PublicKey, data, sig)
+	case *ecdsa.PublicKey:
+		if len(sig) != 2*a.size || (k.Curve.Params().BitSize+7)/8 != a.size {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:a.size])
+		s := new(big.Int).SetBytes(sig[a.size:])
+		return ecdsa.Verify(k, digest, r, s)
+	case ed25519.PrivateKey:
+		return ed25519.Verify(k.Public().(ed25519.PublicKey), data, sig)
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, data, sig)
+	}
+	return false
+}
+
+// JWTClaims is the claims of JSON Web Token.
+// Numeric values are decoded as float64.
+type JWTClaims map[string]any
+
+// ClaimsFromContext returns the claims stored in the
+// context by [JWTValidator]. It returns nil if not found.
+func ClaimsFromContext(ctx context.Context) JWTClaims {
+	claims, _ := ctx.Value(claimsContextKey).(JWTClaims)
+	return claims
+}
+
+// JWTSigner issues JSON Web Tokens and sets them to the
+// "Authorization" header of requests as bearer tokens.
+// JWTSigner implements [ClientMiddleware].
+// See https://datatracker.ietf.org/doc/rfc7519/
+type JWTSigner struct {
+	// Key is the signing key.
+	// Key.Key must be a private key or a secret key.
+	// Key.ID, if non-empty, is set to the "kid" header.
+	Key *JWK
+	// Alg is the signing algorithm.
+	// If empty, Key.Alg is used.
+	// Supported algorithms are HS256, HS384, HS512, RS256, RS384,
+	// RS512, PS256, PS384, PS512, ES256, ES384, ES512 and EdDSA.
+	Alg string
+	// Issuer is the "iss" claim. Omitted if empty.
+	Issuer string
+	// Subject is the "sub" claim. Omitted if empty.
+	Subject string
+	// Audience is the "aud" claim. Omitted if empty.
+	Audience string
+	// TTL is the lifetime of tokens used for the "exp" claim.
+	// If zero or negative, 5 minutes is used.
+	TTL time.Duration
+	// Claims, if non-nil, returns additional claims for the request.
+	// Returned claims overwrite the standard claims.
+	Claims func(r *http.Request) JWTClaims
+
+	// timeNow returns current time.
+	// If nil, [time.Now] is used.
+	timeNow func() time.Time
+}
+
+// Sign returns a signed token with the claims.
+func (s *JWTSigner) Sign(claims JWTClaims) (string, error) {
+	alg := s.Alg
+	if alg == "" {
+		alg = s.Key.Alg
+	}
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if s.Key.ID != "" {
+		header["kid"] = s.Key.ID
+	}
+	h, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	data := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	sig, err := jwtSign(alg, s.Key.Key, []byte(data))
+	if err != nil {
+		return "", err
+	}
+	return data + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// claims returns claims for the request r.
+func (s *JWTSigner) claims(r *http.Request) JWTClaims {
+	now := time.Now
+	if s.timeNow != nil {
+		now = s.timeNow
+	}
+	ttl := s.TTL
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+	t := now()
+	claims := JWTClaims{
+		"iat": t.Unix(),
+		"exp": t.Add(ttl).Unix(),
+	}
+	for k, v := range map[string]string{"iss": s.Issuer, "sub": s.Subject, "aud": s.Audience} {
+		if v != "" {
+			claims[k] = v
+		}
+	}
+	if s.Claims != nil {
+		for k, v := range s.Claims(r) {
+			claims[k] = v
+		}
+	}
+	return claims
+}
+
+// ClientMiddleware is the implementation of [ClientMiddleware.ClientMiddleware].
+func (s *JWTSigner) ClientMiddleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		token, err := s.Sign(s.claims(r))
+		if err != nil {
+			return nil, err
+		}
+		r = r.Clone(r.Context()) // RoundTripper must not modify the request.
+		r.Header.Set("Authorization", "Bearer "+token)
+		return next.RoundTrip(r)
+	})
+}
+
+// JWTValidator validates JSON Web Tokens.
+// Tokens are obtained from the "Authorization" header
+// with the bearer scheme. Validated claims are stored in the
+// request context and can be obtained with [ClaimsFromContext].
+// JWTValidator implements [ServerMiddleware].
+// See the references.
+//
+// References:
+//   - https://datatracker.ietf.org/doc/rfc7519/
+//   - https://datatracker.ietf.org/doc/rfc6750/
+type JWTValidator struct {
+	// Keys is the list of keys used to verify tokens.
+	// Keys are looked up by the "kid" header if the token has.
+	// Otherwise, all keys are tried in order.
+	// A key is used only when its type matches to the algorithm
+	// and the [JWK.Alg] is empty or the same as the token algorithm.
+	// See also [LoadJWKS].
+	Keys []*JWK
+	// Algorithms is the list of allowed algorithms.
+	// If empty, all supported algorithms are allowed.
+	// The "none" algorithm is never allowed.
+	Algorithms []string
+	// Issuer, if non-empty, must match to the "iss" claim.
+	Issuer string
+	// Audience, if non-empty, must be contained in the "aud" claim.
+	Audience string
+	// Leeway is the allowed clock skew applied to
+	// "exp", "nbf" and "iat" claims.
+	Leeway time.Duration
+	// ErrorHandler is the optional error handler.
+	// If nil, a default error handler is used which
+	// responds the status code of the error.
+	ErrorHandler ErrorHandler[*HTTPError]
+
+	// timeNow returns current time.
+	// If nil, [time.Now] is used.
+	timeNow func() time.Time
+}
+
+// Validate validates the token and returns its claims.
+func (v *JWTValidator) Validate(token string) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if _, ok := jwtAlgs[header.Alg]; !ok {
+		return nil, ErrTokenAlg
+	}
+	if len(v.Algorithms) > 0 && !slices.Contains(v.Algorithms, header.Alg) {
+		return nil, ErrTokenAlg
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	data := []byte(parts[0] + "." + parts[1])
+	found, verified := false, false
+	for _, k := range v.Keys {
+		if header.Kid != "" && k.ID != header.Kid {
+			continue
+		}
+		if (k.Alg != "" && k.Alg != header.Alg) || keyType(k.Key) != jwtAlgs[header.Alg].kty {
+			continue
+		}
+		found = true
+		if jwtVerify(header.Alg, k.Key, data, sig) {
+			verified = true
+			break
+		}
+	}
+	if !found {
+		return nil, ErrTokenKey
+	}
+	if !verified {
+		return nil, ErrTokenSignature
+	}
+
+	var claims JWTClaims
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// validateClaims validates registered claims.
+// See https://datatracker.ietf.org/doc/rfc7519/ (4.1. Registered Claim Names)
+func (v *JWTValidator) validateClaims(claims JWTClaims) error {
+	now := time.Now
+	if v.timeNow != nil {
+		now = v.timeNow
+	}
+	t := now()
+	if exp, ok, err := numericClaim(claims, "exp"); err != nil {
+		return err
+	} else if ok && !t.Before(exp.Add(v.Leeway)) {
+		return ErrTokenExpired
+	}
+	if nbf, ok, err := numericClaim(claims, "nbf"); err != nil {
+		return err
+	} else if ok && t.Add(v.Leeway).Before(nbf) {
+		return ErrTokenNotBefore
+	}
+	if iat, ok, err := numericClaim(claims, "iat"); err != nil {
+		return err
+	} else if ok && t.Add(v.Leeway).Before(iat) {
+		return ErrTokenNotBefore
+	}
+	if v.Issuer != "" && claims["iss"] != v.Issuer {
+		return ErrTokenClaims
+	}
+	if v.Audience != "" {
+		switch aud := claims["aud"].(type) {
+		case string:
+			if aud != v.Audience {
+				return ErrTokenClaims
+			}
+		case []any:
+			if !slices.Contains(aud, any(v.Audience)) {
+				return ErrTokenClaims
+			}
+		default:
+			return ErrTokenClaims
+		}
+	}
+	return nil
+}
+
+// numericClaim returns the NumericDate claim as time.
+// ok is false when the claim does not exist.
+func numericClaim(claims JWTClaims, name string) (t time.Time, ok bool, err error) {
+	v, ok := claims[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	f, isNum := v.(float64)
+	if !isNum || math.IsNaN(f) || math.IsInf(f, 0) {
+		return time.Time{}, false, ErrTokenClaims
+	}
+	// Reject values that can not be represented by int64 seconds.
+	// Note that float64(math.MaxInt64) is rounded up to 2^63.
+	if f < math.MinInt64 || f >= math.MaxInt64 {
+		return time.Time{}, false, ErrTokenClaims
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9)), true, nil
+}
+
+// decodeJWTPart decodes base64url encoded JSON into v.
+func decodeJWTPart(s string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return ErrTokenMalformed
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrTokenMalformed
+	}
+	return nil
+}
+
+// ServerMiddleware is the implementation of [ServerMiddleware.ServerMiddleware].
+func (v *JWTValidator) ServerMiddleware(next http.Handler) http.Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		scheme, token, _ := strings.Cut(auth, " ")
+		if !strings.EqualFold(scheme, "Bearer") || token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			handleAuthError(w, r, v.ErrorHandler, &HTTPError{Err: ErrTokenMissing, Code: http.StatusUnauthorized, Cause: CauseUnauthorized})
+			return
+		}
+		claims, err := v.Validate(token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			handleAuthError(w, r, v.ErrorHandler, &HTTPError{Err: err, Code: http.StatusUnauthorized, Cause: CauseUnauthorized})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsContextKey, claims)))
+	})
+}
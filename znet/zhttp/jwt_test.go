@@ -0,0 +1,309 @@
+package zhttp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+var (
+	testRSAKey, _     = rsa.GenerateKey(rand.Reader, 2048)
+	testECKey, _      = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	testEC384Key, _   = ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	_, testEdKey, _   = ed25519.GenerateKey(rand.Reader)
+	testHMACKey       = []byte("secret")
+	testJWTTime       = time.Unix(1_000_000, 0)
+	testJWTTimeNowFun = func() time.Time { return testJWTTime }
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func b64Int(i *big.Int) string {
+	return b64(i.Bytes())
+}
+
+func TestParseJWKS(t *testing.T) {
+	t.Parallel()
+	jwks := map[string]any{"keys": []map[string]string{
+		{"kty": "oct", "kid": "hmac", "alg": "HS256", "k": b64(testHMACKey)},
+		{"kty": "RSA", "kid": "rsa-pub", "n": b64Int(testRSAKey.N), "e": b64Int(big.NewInt(int64(testRSAKey.E)))},
+		{"kty": "RSA", "kid": "rsa-priv", "n": b64Int(testRSAKey.N), "e": b64Int(big.NewInt(int64(testRSAKey.E))),
+			"d": b64Int(testRSAKey.D), "p": b64Int(testRSAKey.Primes[0]), "q": b64Int(testRSAKey.Primes[1])},
+		{"kty": "EC", "kid": "ec-pub", "crv": "P-256", "x": b64Int(testECKey.X), "y": b64Int(testECKey.Y)},
+		{"kty": "EC", "kid": "ec-priv", "crv": "P-256", "x": b64Int(testECKey.X), "y": b64Int(testECKey.Y), "d": b64Int(testECKey.D)},
+		{"kty": "OKP", "kid": "ed-pub", "crv": "Ed25519", "x": b64(testEdKey.Public().(ed25519.PublicKey))},
+		{"kty": "OKP", "kid": "ed-priv", "crv": "Ed25519", "x": b64(testEdKey.Public().(ed25519.PublicKey)), "d": b64(testEdKey.Seed())},
+		{"kty": "oct", "kid": "enc", "use": "enc", "k": b64(testHMACKey)}, // Ignored.
+	}}
+	b, _ := json.Marshal(jwks)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	_ = os.WriteFile(path, b, 0o600)
+
+	keys, err := LoadJWKS(path)
+	ztesting.AssertEqualErr(t, "error not match", nil, err)
+	ztesting.AssertEqual(t, "number of keys not match", 7, len(keys))
+	ztesting.AssertEqual(t, "alg not match", "HS256", keys[0].Alg)
+	ztesting.AssertEqual(t, "key not match", "secret", string(keys[0].Key.([]byte)))
+	ztesting.AssertEqual(t, "key not match", true, testRSAKey.PublicKey.Equal(keys[1].Key))
+	ztesting.AssertEqual(t, "key not match", true, testRSAKey.Equal(keys[2].Key))
+	ztesting.AssertEqual(t, "key not match", true, testECKey.PublicKey.Equal(keys[3].Key))
+	ztesting.AssertEqual(t, "key not match", true, testECKey.Equal(keys[4].Key))
+	ztesting.AssertEqual(t, "key not match", true, testEdKey.Public().(ed25519.PublicKey).Equal(keys[5].Key))
+	ztesting.AssertEqual(t, "key not match", true, testEdKey.Equal(keys[6].Key))
+
+	_, err = LoadJWKS(filepath.Join(t.TempDir(), "not-exist"))
+	ztesting.AssertEqualErr(t, "error not match", os.ErrNotExist, err)
+}
+
+func TestParseJWKS_error(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		input string
+	}{
+		"invalid json":     {`{`},
+		"unknown kty":      {`{"keys":[{"kty":"foo"}]}`},
+		"empty oct":        {`{"keys":[{"kty":"oct","k":""}]}`},
+		"invalid base64":   {`{"keys":[{"kty":"oct","k":"###"}]}`},
+		"rsa no e":         {`{"keys":[{"kty":"RSA","n":"AQAB"}]}`},
+		"rsa invalid priv": {`{"keys":[{"kty":"RSA","n":"AQAB","e":"AQAB","d":"AQAB","p":"AQAB","q":"AQAB"}]}`},
+		"ec unknown curve": {`{"keys":[{"kty":"EC","crv":"P-192","x":"AQAB","y":"AQAB"}]}`},
+		"ec not on curve":  {`{"keys":[{"kty":"EC","crv":"P-256","x":"AQAB","y":"AQAB"}]}`},
+		"okp unknown":      {`{"keys":[{"kty":"OKP","crv":"X25519","x":"AQAB"}]}`},
+		"okp invalid size": {`{"keys":[{"kty":"OKP","crv":"Ed25519","x":"AQAB"}]}`},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseJWKS([]byte(tc.input))
+			if err == nil {
+				t.Error("error expected but got nil")
+			}
+		})
+	}
+}
+
+func TestJWTSignValidate(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		alg      string
+		signKey  any
+		verifyBy any
+	}{
+		"HS256": {"HS256", testHMACKey, testHMACKey},
+		"HS384": {"HS384", testHMACKey, testHMACKey},
+		"HS512": {"HS512", testHMACKey, testHMACKey},
+		"RS256": {"RS256", testRSAKey, &testRSAKey.PublicKey},
+		"RS384": {"RS384", testRSAKey, &testRSAKey.PublicKey},
+		"RS512": {"RS512", testRSAKey, &testRSAKey.PublicKey},
+		"PS256": {"PS256", testRSAKey, &testRSAKey.PublicKey},
+		"PS512": {"PS512", testRSAKey, testRSAKey},
+		"ES256": {"ES256", testECKey, &testECKey.PublicKey},
+		"ES384": {"ES384", testEC384Key, testEC384Key},
+		"EdDSA": {"EdDSA", testEdKey, testEdKey.Public()},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := &JWTSigner{Key: &JWK{ID: "kid", Key: tc.signKey}, Alg: tc.alg, Issuer: "iss", Audience: "aud", timeNow: testJWTTimeNowFun}
+			token, err := s.Sign(s.claims(nil))
+			ztesting.AssertEqualErr(t, "sign error not match", nil, err)
+			v := &JWTValidator{Keys: []*JWK{{ID: "kid", Key: tc.verifyBy}}, Issuer: "iss", Audience: "aud", timeNow: testJWTTimeNowFun}
+			claims, err := v.Validate(token)
+			ztesting.AssertEqualErr(t, "validate error not match", nil, err)
+			ztesting.AssertEqual(t, "iss not match", "iss", claims["iss"].(string))
+
+			// Tamper the signature.
+			parts := strings.Split(token, ".")
+			sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+			sig[0] ^= 0xff
+			_, err = v.Validate(parts[0] + "." + parts[1] + "." + b64(sig))
+			ztesting.AssertEqualErr(t, "validate error not match", ErrTokenSignature, err)
+		})
+	}
+}
+
+func TestJWTSigner_error(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		alg string
+		key any
+		err error
+	}{
+		"unknown alg":     {"none", testHMACKey, ErrTokenAlg},
+		"key type":        {"RS256", testHMACKey, ErrTokenAlg},
+		"public key":      {"RS256", &testRSAKey.PublicKey, ErrTokenKey},
+		"public ec key":   {"ES256", &testECKey.PublicKey, ErrTokenKey},
+		"ec curve":        {"ES256", testEC384Key, ErrTokenAlg},
+		"ec curve larger": {"ES384", testECKey, ErrTokenAlg},
+		"public okp key":  {"EdDSA", testEdKey.Public(), ErrTokenKey},
+		"alg from key id": {"", testHMACKey, ErrTokenAlg},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := &JWTSigner{Key: &JWK{Key: tc.key}, Alg: tc.alg}
+			_, err := s.Sign(JWTClaims{})
+			ztesting.AssertEqualErr(t, "error not match", tc.err, err)
+		})
+	}
+}
+
+func TestJWTValidator_Validate(t *testing.T) {
+	t.Parallel()
+	sign := func(alg string, key any, kid string, claims JWTClaims) string {
+		s := &JWTSigner{Key: &JWK{ID: kid, Key: key}, Alg: alg}
+		token, err := s.Sign(claims)
+		if err != nil {
+			panic(err)
+		}
+		return token
+	}
+	now := float64(testJWTTime.Unix())
+	hsKey := &JWK{ID: "hs", Key: testHMACKey}
+	rsKey := &JWK{ID: "rs", Alg: "RS256", Key: &testRSAKey.PublicKey}
+
+	testCases := map[string]struct {
+		validator *JWTValidator
+		token     string
+		err       error
+	}{
+		"valid":             {&JWTValidator{Keys: []*JWK{hsKey}}, sign("HS256", testHMACKey, "hs", JWTClaims{"exp": now + 1}), nil},
+		"no kid":            {&JWTValidator{Keys: []*JWK{rsKey, hsKey}}, sign("HS256", testHMACKey, "", JWTClaims{}), nil},
+		"malformed":         {&JWTValidator{Keys: []*JWK{hsKey}}, "foo.bar", ErrTokenMalformed},
+		"invalid header":    {&JWTValidator{Keys: []*JWK{hsKey}}, "###.bar.baz", ErrTokenMalformed},
+		"invalid json":      {&JWTValidator{Keys: []*JWK{hsKey}}, b64([]byte("{")) + ".bar.baz", ErrTokenMalformed},
+		"none alg":          {&JWTValidator{Keys: []*JWK{hsKey}}, b64([]byte(`{"alg":"none"}`)) + "." + b64([]byte(`{}`)) + ".", ErrTokenAlg},
+		"alg not allowed":   {&JWTValidator{Keys: []*JWK{hsKey}, Algorithms: []string{"RS256"}}, sign("HS256", testHMACKey, "hs", JWTClaims{}), ErrTokenAlg},
+		"invalid signature": {&JWTValidator{Keys: []*JWK{hsKey}}, sign("HS256", testHMACKey, "hs", JWTClaims{}) + "###", ErrTokenMalformed},
+		"unknown kid":       {&JWTValidator{Keys: []*JWK{hsKey}}, sign("HS256", testHMACKey, "other", JWTClaims{}), ErrTokenKey},
+		"key alg mismatch":  {&JWTValidator{Keys: []*JWK{{ID: "hs", Alg: "HS512", Key: testHMACKey}}}, sign("HS256", testHMACKey, "hs", JWTClaims{}), ErrTokenKey},
+		"alg confusion":     {&JWTValidator{Keys: []*JWK{{ID: "rs", Key: &testRSAKey.PublicKey}}}, sign("HS256", testHMACKey, "rs", JWTClaims{}), ErrTokenKey},
+		"expired":           {&JWTValidator{Keys: []*JWK{hsKey}}, sign("HS256", testHMACKey, "hs", JWTClaims{"exp": now}), ErrTokenExpired},
+		"expired leeway":    {&JWTValidator{Keys: []*JWK{hsKey}, Leeway: time.Second}, sign("HS256", testHMACKey, "hs", JWTClaims{"exp": now}), nil},
+		"not before":        {&JWTValidator{Keys: []*JWK{hsKey}}, sign("HS256", testHMACKey, "hs", JWTClaims{"nbf": now + 1}), ErrTokenNotBefore},
+		"issued in future":  {&JWTValidator{Keys: []*JWK{hsKey}}, sign("HS256", testHMACKey, "hs", JWTClaims{"iat": now + 1}), ErrTokenNotBefore},
+		"far future exp":    {&JWTValidator{Keys: []*JWK{hsKey}}, sign("HS256", testHMACKey, "hs", JWTClaims{"exp": float64(9999999999)}), nil},
+		"far future nbf":    {&JWTValidator{Keys: []*JWK{hsKey}}, sign("HS256", testHMACKey, "hs", JWTClaims{"nbf": float64(9999999999)}), ErrTokenNotBefore},
+		"exp out of range":  {&JWTValidator{Keys: []*JWK{hsKey}}, sign("HS256", testHMACKey, "hs", JWTClaims{"exp": 1e30}), ErrTokenClaims},
+		"invalid exp":       {&JWTValidator{Keys: []*JWK{hsKey}}, sign("HS256", testHMACKey, "hs", JWTClaims{"exp": "foo"}), ErrTokenClaims},
+		"invalid nbf":       {&JWTValidator{Keys: []*JWK{hsKey}}, sign("HS256", testHMACKey, "hs", JWTClaims{"nbf": "foo"}), ErrTokenClaims},
+		"invalid iat":       {&JWTValidator{Keys: []*JWK{hsKey}}, sign("HS256", testHMACKey, "hs", JWTClaims{"iat": "foo"}), ErrTokenClaims},
+		"issuer":            {&JWTValidator{Keys: []*JWK{hsKey}, Issuer: "foo"}, sign("HS256", testHMACKey, "hs", JWTClaims{"iss": "bar"}), ErrTokenClaims},
+		"audience":          {&JWTValidator{Keys: []*JWK{hsKey}, Audience: "foo"}, sign("HS256", testHMACKey, "hs", JWTClaims{"aud": "bar"}), ErrTokenClaims},
+		"audience list":     {&JWTValidator{Keys: []*JWK{hsKey}, Audience: "foo"}, sign("HS256", testHMACKey, "hs", JWTClaims{"aud": []string{"bar", "foo"}}), nil},
+		"audience not list": {&JWTValidator{Keys: []*JWK{hsKey}, Audience: "foo"}, sign("HS256", testHMACKey, "hs", JWTClaims{"aud": []string{"bar"}}), ErrTokenClaims},
+		"audience missing":  {&JWTValidator{Keys: []*JWK{hsKey}, Audience: "foo"}, sign("HS256", testHMACKey, "hs", JWTClaims{}), ErrTokenClaims},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tc.validator.timeNow = testJWTTimeNowFun
+			_, err := tc.validator.Validate(tc.token)
+			ztesting.AssertEqualErr(t, "error not match", tc.err, err)
+		})
+	}
+}
+
+func TestJWTMiddleware(t *testing.T) {
+	t.Parallel()
+	v := &JWTValidator{Keys: []*JWK{{ID: "ed", Key: testEdKey.Public()}}, Audience: "server"}
+	svr := httptest.NewServer(v.ServerMiddleware(HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := ClaimsFromContext(r.Context())
+		_, _ = w.Write([]byte(claims["sub"].(string) + ":" + claims["role"].(string)))
+	})))
+	defer svr.Close()
+
+	t.Run("signed", func(t *testing.T) {
+		s := &JWTSigner{
+			Key:      &JWK{ID: "ed", Alg: "EdDSA", Key: testEdKey},
+			Subject:  "client",
+			Audience: "server",
+			Claims:   func(r *http.Request) JWTClaims { return JWTClaims{"role": r.Method} },
+		}
+		client := &http.Client{Transport: s.ClientMiddleware(http.DefaultTransport)}
+		res, err := client.Get(svr.URL)
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		ztesting.AssertEqual(t, "status code not match", http.StatusOK, res.StatusCode)
+		ztesting.AssertEqual(t, "body not match", "client:GET", string(body))
+	})
+	t.Run("no token", func(t *testing.T) {
+		res, err := http.Get(svr.URL)
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+		defer res.Body.Close()
+		ztesting.AssertEqual(t, "status code not match", http.StatusUnauthorized, res.StatusCode)
+		ztesting.AssertEqual(t, "header not match", "Bearer", res.Header.Get("WWW-Authenticate"))
+	})
+	t.Run("invalid token", func(t *testing.T) {
+		r, _ := http.NewRequest(http.MethodGet, svr.URL, nil)
+		r.Header.Set("Authorization", "Bearer foo")
+		res, err := http.DefaultClient.Do(r)
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+		defer res.Body.Close()
+		ztesting.AssertEqual(t, "status code not match", http.StatusUnauthorized, res.StatusCode)
+		ztesting.AssertEqual(t, "header not match", `Bearer error="invalid_token"`, res.Header.Get("WWW-Authenticate"))
+	})
+	t.Run("sign error", func(t *testing.T) {
+		s := &JWTSigner{Key: &JWK{Key: &testRSAKey.PublicKey}, Alg: "RS256"}
+		client := &http.Client{Transport: s.ClientMiddleware(http.DefaultTransport)}
+		_, err := client.Get(svr.URL)
+		ztesting.AssertEqualErr(t, "error not match", ErrTokenKey, err)
+	})
+}
+
+func TestClaimsFromContext(t *testing.T) {
+	t.Parallel()
+	ztesting.AssertEqual(t, "claims not match", true, ClaimsFromContext(context.Background()) == nil)
+	ctx := context.WithValue(context.Background(), claimsContextKey, JWTClaims{"foo": "bar"})
+	ztesting.AssertEqual(t, "claims not match", "bar", ClaimsFromContext(ctx)["foo"].(string))
+}
+
+func TestNumericClaim(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		value any
+		t     time.Time
+		ok    bool
+		err   error
+	}{
+		"integer":     {float64(1700000000), time.Unix(1700000000, 0), true, nil},
+		"fraction":    {1700000000.5, time.Unix(1700000000, 500000000), true, nil},
+		"far future":  {float64(9999999999), time.Unix(9999999999, 0), true, nil},
+		"negative":    {float64(-1), time.Unix(-1, 0), true, nil},
+		"NaN":         {math.NaN(), time.Time{}, false, ErrTokenClaims},
+		"+Inf":        {math.Inf(1), time.Time{}, false, ErrTokenClaims},
+		"-Inf":        {math.Inf(-1), time.Time{}, false, ErrTokenClaims},
+		"too large":   {1e19, time.Time{}, false, ErrTokenClaims},
+		"too small":   {-1e19, time.Time{}, false, ErrTokenClaims},
+		"not number":  {"foo", time.Time{}, false, ErrTokenClaims},
+		"not present": {nil, time.Time{}, false, nil},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			claims := JWTClaims{}
+			if tc.value != nil {
+				claims["exp"] = tc.value
+			}
+			tm, ok, err := numericClaim(claims, "exp")
+			ztesting.AssertEqualErr(t, "error not match", tc.err, err)
+			ztesting.AssertEqual(t, "ok not match", tc.ok, ok)
+			ztesting.AssertEqual(t, "time not match", tc.t, tm)
+		})
+	}
+}
@@ -121,6 +121,14 @@ type Proxy struct {
 	// If nil, a default error handler is used.
 	// Given w and r is the same as tha value passed to [Proxy.ServeHTTP].
 	ErrorHandler ErrorHandler[*HTTPError]
+
+	// WebSocket is the optional websocket bridge.
+	// If non-nil, websocket connections are bridged frame by frame
+	// after protocol upgrade, which allows inspecting messages
+	// and applying limits. Sec-WebSocket-Extensions header is
+	// removed from the upgrade request when the bridge is used.
+	// If nil, raw bytes are copied as same as other upgrade protocols.
+	WebSocket *WebSocketBridge
 }
 
 func (p *Proxy) handleError(w http.ResponseWriter, r *http.Request, err *HTTPError) {
@@ -128,6 +136,14 @@ func (p *Proxy) handleError(w http.ResponseWriter, r *http.Request, err *HTTPErr
 		eh(w, r, err)
 		return
 	}
+	defaultErrorHandler(w, r, err)
+}
+
+// defaultErrorHandler writes the status code of the err
+// and its status text as the response body.
+// Nothing is written when the err.Code is -1 or
+// the err is the [context.Canceled].
+func defaultErrorHandler(w http.ResponseWriter, _ *http.Request, err *HTTPError) {
 	if err.Code > 0 && !errors.Is(err, context.Canceled) {
 		w.WriteHeader(err.Code)
 		_, _ = w.Write([]byte(http.StatusText(err.Code)))
@@ -163,6 +179,9 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		// https://developer.mozilla.org/en-US/docs/Web/HTTP/Reference/Headers/Upgrade
 		outReq.Header.Set("Connection", "Upgrade")
 		outReq.Header.Set("Upgrade", r.Header.Get("Upgrade"))
+		if p.WebSocket != nil && strings.EqualFold(upgradeType(outReq.Header), "websocket") {
+			outReq.Header.Del("Sec-WebSocket-Extensions") // Bridge does not support extensions.
+		}
 	}
 
 	if preRT := p.PreRoundTrip; preRT != nil {
@@ -189,7 +208,7 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Deal with 101 Switching Protocols responses: (WebSocket, h2c, etc)
 	if outRes.StatusCode == http.StatusSwitchingProtocols {
-		if err := handleUpgradeResponse(w, outReq, outRes); err != nil {
+		if err := handleUpgradeResponse(w, outReq, outRes, p.WebSocket); err != nil {
 			p.handleError(w, r, err)
 		}
 		return
@@ -227,9 +246,11 @@ func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 // handleUpgradeResponse handles protocol upgrade.
 // This method is called when [net/http.StatusSwitchingProtocols] was detected.
+// If the ws is non-nil and the upgraded protocol is websocket,
+// connections are bridged by the ws instead of copying raw bytes.
 // See also handleUpgradeResponse function in
 // https://go.dev/src/net/http/httputil/reverseproxy.go
-func handleUpgradeResponse(rw http.ResponseWriter, req *http.Request, res *http.Response) *HTTPError {
+func handleUpgradeResponse(rw http.ResponseWriter, req *http.Request, res *http.Response, ws *WebSocketBridge) *HTTPError {
 	reqUpType := upgradeType(req.Header)
 	resUpType := upgradeType(res.Header)
 	if len(reqUpType) != len(resUpType) || !strings.EqualFold(reqUpType, resUpType) {
@@ -278,6 +299,13 @@ func handleUpgradeResponse(rw http.ResponseWriter, req *http.Request, res *http.
 		}
 	}
 
+	if ws != nil && strings.EqualFold(resUpType, "websocket") {
+		if err := ws.serve(conn, backConn); err != nil {
+			return &HTTPError{Err: err, Code: -1, Cause: CauseWebSocket}
+		}
+		return nil
+	}
+
 	errChan := make(chan error, 1)
 	go copyBuf(conn, backConn, errChan)
 	go copyBuf(backConn, conn, errChan)
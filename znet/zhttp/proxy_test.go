@@ -369,7 +369,7 @@ func TestHandleUpgradeResponse(t *testing.T) {
 		body := &readWriteCloser{Reader: strings.NewReader("bar"), Writer: &buf}
 		res := &http.Response{Header: http.Header{"Connection": {"Upgrade"}, "Upgrade": {"test"}}, Body: body}
 
-		err := handleUpgradeResponse(rw, req, res)
+		err := handleUpgradeResponse(rw, req, res, nil)
 		ztesting.AssertEqualErr(t, "error not match", (*HTTPError)(nil), err)
 		ztesting.AssertEqual(t, "copied content not match", "foo", buf.String())
 		ztesting.AssertEqual(t, "copied content not match", "bar", conn.content.String())
@@ -384,7 +384,7 @@ func TestHandleUpgradeResponse(t *testing.T) {
 		body := &readWriteCloser{Reader: strings.NewReader("bar"), Writer: &buf}
 		res := &http.Response{Header: http.Header{"Connection": {"Upgrade"}, "Upgrade": {"test2"}}, Body: body}
 
-		err := handleUpgradeResponse(rw, req, res)
+		err := handleUpgradeResponse(rw, req, res, nil)
 		ztesting.AssertEqualErr(t, "error not match", &HTTPError{Cause: CauseUpgradeMismatch, Code: http.StatusBadRequest}, err)
 		ztesting.AssertEqual(t, "copied content not match", "", buf.String())
 		ztesting.AssertEqual(t, "copied content not match", "", conn.content.String())
@@ -399,7 +399,7 @@ func TestHandleUpgradeResponse(t *testing.T) {
 		body := &readWriteCloser{Reader: strings.NewReader("bar"), Writer: &buf}
 		res := &http.Response{Header: http.Header{"Connection": {""}, "Upgrade": {"test2"}}, Body: body} // No connection header.
 
-		err := handleUpgradeResponse(rw, req, res)
+		err := handleUpgradeResponse(rw, req, res, nil)
 		ztesting.AssertEqualErr(t, "error not match", &HTTPError{Cause: CauseUpgradeMismatch, Code: http.StatusBadRequest}, err)
 		ztesting.AssertEqual(t, "copied content not match", "", buf.String())
 		ztesting.AssertEqual(t, "copied content not match", "", conn.content.String())
@@ -413,7 +413,7 @@ func TestHandleUpgradeResponse(t *testing.T) {
 		var buf bytes.Buffer
 		res := &http.Response{Header: http.Header{"Connection": {"Upgrade"}, "Upgrade": {"test"}}, Body: nil} // Body is nil.
 
-		err := handleUpgradeResponse(rw, req, res)
+		err := handleUpgradeResponse(rw, req, res, nil)
 		ztesting.AssertEqualErr(t, "error not match", &HTTPError{Cause: CauseUpgrade, Code: http.StatusInternalServerError}, err)
 		ztesting.AssertEqual(t, "copied content not match", "", buf.String())
 		ztesting.AssertEqual(t, "copied content not match", "", conn.content.String())
@@ -429,7 +429,7 @@ func TestHandleUpgradeResponse(t *testing.T) {
 		body := &readWriteCloser{Reader: strings.NewReader("bar"), Writer: &buf}
 		res := &http.Response{Header: http.Header{"Connection": {"Upgrade"}, "Upgrade": {"test"}}, Body: body}
 
-		err := handleUpgradeResponse(rw, req, res)
+		err := handleUpgradeResponse(rw, req, res, nil)
 		ztesting.AssertEqualErr(t, "error not match", &HTTPError{Cause: CauseHijack, Code: http.StatusInternalServerError}, err)
 		ztesting.AssertEqual(t, "copied content not match", "", buf.String())
 		ztesting.AssertEqual(t, "copied content not match", "", conn.content.String())
@@ -445,7 +445,7 @@ func TestHandleUpgradeResponse(t *testing.T) {
 		body := &readWriteCloser{Reader: strings.NewReader("bar"), Writer: &buf}
 		res := &http.Response{Header: http.Header{"Connection": {"Upgrade"}, "Upgrade": {"test"}}, Body: body}
 
-		err := handleUpgradeResponse(rw, req, res)
+		err := handleUpgradeResponse(rw, req, res, nil)
 		ztesting.AssertEqualErr(t, "error not match", &HTTPError{Cause: CauseFlushBody, Code: -1}, err)
 		ztesting.AssertEqual(t, "copied content not match", "", buf.String())
 		ztesting.AssertEqual(t, "copied content not match", "", conn.content.String())
@@ -460,7 +460,7 @@ func TestHandleUpgradeResponse(t *testing.T) {
 		body := &readWriteCloser{Reader: strings.NewReader("bar"), Writer: &buf}
 		res := &http.Response{Header: http.Header{"Connection": {"Upgrade"}, "Upgrade": {"test"}}, Body: body}
 
-		err := handleUpgradeResponse(rw, req, res)
+		err := handleUpgradeResponse(rw, req, res, nil)
 		ztesting.AssertEqualErr(t, "error not match", &HTTPError{Cause: CauseCopyResponse, Code: -1}, err)
 		ztesting.AssertEqual(t, "copied content not match", "", buf.String())
 		ztesting.AssertEqual(t, "copied content not match", "", conn.content.String())
@@ -475,7 +475,7 @@ func TestHandleUpgradeResponse(t *testing.T) {
 		body := &readWriteCloser{Reader: strings.NewReader("bar"), Writer: &buf}
 		res := &http.Response{Header: http.Header{"Connection": {"Upgrade"}, "Upgrade": {"test"}}, Body: body}
 
-		err := handleUpgradeResponse(rw, req, res)
+		err := handleUpgradeResponse(rw, req, res, nil)
 		ztesting.AssertEqualErr(t, "error not match", &HTTPError{Cause: CauseCopyResponse, Code: -1}, err)
 		ztesting.AssertEqual(t, "copied content not match", "f", buf.String())
 		// ztesting.AssertEqual(t, "copied content not match", "bar", conn.content.String()) // Do not check.
@@ -490,7 +490,7 @@ func TestHandleUpgradeResponse(t *testing.T) {
 		body := &readWriteCloser{Reader: ziotest.ErrReader(strings.NewReader("bar"), 1), Writer: &buf} // Error reader.
 		res := &http.Response{Header: http.Header{"Connection": {"Upgrade"}, "Upgrade": {"test"}}, Body: body}
 
-		err := handleUpgradeResponse(rw, req, res)
+		err := handleUpgradeResponse(rw, req, res, nil)
 		ztesting.AssertEqualErr(t, "error not match", &HTTPError{Cause: CauseCopyResponse, Code: -1}, err)
 		// ztesting.AssertEqual(t, "copied content not match", "foo", buf.String()) // Do not check.
 		ztesting.AssertEqual(t, "copied content not match", "b", conn.content.String())
@@ -0,0 +1,332 @@
+package zhttp
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aileron-projects/go/zcrypto/zhmac"
+	"github.com/aileron-projects/go/zcrypto/zsha256"
+)
+
+const (
+	// HeaderSignatureTimestamp is the header name of the
+	// unix timestamp in seconds when the request was signed.
+	HeaderSignatureTimestamp = "X-Signature-Timestamp"
+	// HeaderSignatureNonce is the header name of the random
+	// nonce that is used to detect replayed requests.
+	HeaderSignatureNonce = "X-Signature-Nonce"
+	// HeaderContentSHA256 is the header name of the
+	// hex encoded SHA256 hash of the request body.
+	HeaderContentSHA256 = "X-Content-Sha256"
+	// hmacScheme is the authentication scheme used in
+	// the "Authorization" header.
+	hmacScheme = "HMAC"
+)
+
+// Errors returned by [HMACVerifier].
+var (
+	ErrSignatureMissing   = errors.New("znet/zhttp: signature not found")
+	ErrSignatureInvalid   = errors.New("znet/zhttp: invalid signature")
+	ErrSignatureExpired   = errors.New("znet/zhttp: signature timestamp out of range")
+	ErrSignatureReplayed  = errors.New("znet/zhttp: signature replayed")
+	ErrSignatureUnknownID = errors.New("znet/zhttp: unknown signature key id")
+)
+
+// CanonicalRequest returns the canonical string of the
+// request used for HMAC request signing.
+// Header names in the headers must be canonicalized
+// and they are joined in the given order.
+// The body hash must be the hex encoded SHA256 hash of the request body.
+// The canonical request has the following format.
+//
+//	<Method>\n
+//	<Escaped Path>\n
+//	<Sorted Query>\n
+//	<Host>\n
+//	<Timestamp>\n
+//	<Nonce>\n
+//	<Header Name>:<Header Values joined by ",">\n (For each header)
+//	<Body Hash>
+func CanonicalRequest(r *http.Request, timestamp, nonce, bodyHash string, headers []string) string {
+	var b strings.Builder
+	b.WriteString(r.Method + "\n")
+	b.WriteString(r.URL.EscapedPath() + "\n")
+	b.WriteString(r.URL.Query().Encode() + "\n") // Encode sorts by key.
+	b.WriteString(requestHost(r) + "\n")
+	b.WriteString(timestamp + "\n")
+	b.WriteString(nonce + "\n")
+	for _, h := range headers {
+		b.WriteString(strings.ToLower(h) + ":" + strings.Join(r.Header.Values(h), ",") + "\n")
+	}
+	b.WriteString(bodyHash)
+	return b.String()
+}
+
+// requestHost returns r.Host if non-empty.
+// Otherwise it returns r.URL.Host.
+func requestHost(r *http.Request) string {
+	if r.Host != "" {
+		return r.Host
+	}
+	return r.URL.Host
+}
+
+// bodySHA256 returns hex encoded SHA256 hash of the request body.
+// The body is read on memory and r.Body is replaced so that
+// it can be read again.
+func bodySHA256(r *http.Request) (string, error) {
+	body, err := ReadBody(r)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(zsha256.Sum256(body)), nil
+}
+
+// HMACSigner signs requests with HMAC.
+// Signature and some parameters are set in the "Authorization" header like below.
+// See [CanonicalRequest] for the signed string.
+// HMACSigner implements [ClientMiddleware].
+//
+//	Authorization: HMAC keyId="<KeyID>",algorithm="<Hash>",headers="<headers>",signature="<base64 signature>"
+type HMACSigner struct {
+	// KeyID is the identifier of the Key.
+	// The KeyID is sent to the server.
+	KeyID string
+	// Key is the secret key for HMAC.
+	Key []byte
+	// Hash is the hash algorithm used for HMAC.
+	// If zero, [crypto.SHA256] is used.
+	Hash crypto.Hash
+	// Headers is the list of additional header
+	// names to be signed.
+	Headers []string
+
+	// timeNow returns current time.
+	// If nil, [time.Now] is used.
+	timeNow func() time.Time
+}
+
+// Sign signs the request r.
+// It sets the "Authorization", [HeaderSignatureTimestamp],
+// [HeaderSignatureNonce] and [HeaderContentSHA256] headers.
+// Request body is read on memory to calculate its hash.
+func (s *HMACSigner) Sign(r *http.Request) error {
+	now := time.Now
+	if s.timeNow != nil {
+		now = s.timeNow
+	}
+	h := s.Hash
+	if h == 0 {
+		h = crypto.SHA256
+	}
+	bodyHash, err := bodySHA256(r)
+	if err != nil {
+		return err
+	}
+	var nonce [16]byte
+	_, _ = rand.Read(nonce[:])
+	timestamp := strconv.FormatInt(now().Unix(), 10)
+	nonceStr := hex.EncodeToString(nonce[:])
+	r.Header.Set(HeaderSignatureTimestamp, timestamp)
+	r.Header.Set(HeaderSignatureNonce, nonceStr)
+	r.Header.Set(HeaderContentSHA256, bodyHash)
+
+	headers := make([]string, 0, len(s.Headers))
+	for _, name := range s.Headers {
+		headers = append(headers, http.CanonicalHeaderKey(name))
+	}
+	msg := CanonicalRequest(r, timestamp, nonceStr, bodyHash, headers)
+	sum := zhmac.Sum(h, []byte(msg), s.Key)
+	r.Header.Set("Authorization", hmacScheme+" "+
+		`keyId=`+strconv.Quote(s.KeyID)+
+		`,algorithm=`+strconv.Quote(h.String())+
+		`,headers=`+strconv.Quote(strings.ToLower(strings.Join(headers, " ")))+
+		`,signature=`+strconv.Quote(base64.StdEncoding.EncodeToString(sum)))
+	return nil
+}
+
+// ClientMiddleware is the implementation of [ClientMiddleware.ClientMiddleware].
+func (s *HMACSigner) ClientMiddleware(next http.RoundTripper) http.RoundTripper {
+	return RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		r = r.Clone(r.Context()) // RoundTripper must not modify the request.
+		if err := s.Sign(r); err != nil {
+			return nil, err
+		}
+		return next.RoundTrip(r)
+	})
+}
+
+// HMACVerifier verifies requests signed by [HMACSigner].
+// The key id in the request is stored in the request
+// context and can be obtained with [UsernameFromContext].
+// HMACVerifier implements [ServerMiddleware].
+type HMACVerifier struct {
+	// Keys is the map of key ids and secret keys.
+	Keys map[string][]byte
+	// Hash is the hash algorithm used for HMAC.
+	// Requests signed with other algorithms are rejected.
+	// If zero, [crypto.SHA256] is used.
+	Hash crypto.Hash
+	// MaxSkew is the maximum allowed difference between
+	// the signature timestamp and the current time.
+	// If zero or negative, 5 minutes is used.
+	MaxSkew time.Duration
+	// ReplayWindow is the duration to remember nonces to reject
+	// replayed requests. Requests with the same key id and nonce
+	// are rejected within the window.
+	// The window should be larger than 2*MaxSkew.
+	// If zero, 2*MaxSkew is used. If negative, replay
+	// detection is disabled.
+	ReplayWindow time.Duration
+	// ErrorHandler is the optional error handler.
+	// If nil, a default error handler is used which
+	// responds the status code of the error.
+	ErrorHandler ErrorHandler[*HTTPError]
+
+	// timeNow returns current time.
+	// If nil, [time.Now] is used.
+	timeNow func() time.Time
+
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	sweep  time.Time
+}
+
+// Verify verifies the signature of the request r.
+// It returns nil error if the signature is valid.
+// The returned key id is valid only when the error is nil.
+// Request body is read on memory to calculate its hash.
+func (v *HMACVerifier) Verify(r *http.Request) (keyID string, err error) {
+	now := time.Now
+	if v.timeNow != nil {
+		now = v.timeNow
+	}
+	h := v.Hash
+	if h == 0 {
+		h = crypto.SHA256
+	}
+	maxSkew := v.MaxSkew
+	if maxSkew <= 0 {
+		maxSkew = 5 * time.Minute
+	}
+
+	auth := r.Header.Get("Authorization")
+	scheme, params, _ := strings.Cut(auth, " ")
+	if !strings.EqualFold(scheme, hmacScheme) {
+		return "", ErrSignatureMissing
+	}
+	ps := parseAuthParams(params)
+	keyID = ps["keyid"]
+	key, ok := v.Keys[keyID]
+	if !ok {
+		return "", ErrSignatureUnknownID
+	}
+	if ps["algorithm"] != h.String() {
+		return "", ErrSignatureInvalid
+	}
+	sum, err := base64.StdEncoding.DecodeString(ps["signature"])
+	if err != nil {
+		return "", ErrSignatureInvalid
+	}
+
+	timestamp := r.Header.Get(HeaderSignatureTimestamp)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", ErrSignatureInvalid
+	}
+	t := now()
+	if d := t.Sub(time.Unix(unix, 0)); d > maxSkew || d < -maxSkew {
+		return "", ErrSignatureExpired
+	}
+
+	bodyHash, err := bodySHA256(r)
+	if err != nil {
+		return "", err
+	}
+	if bodyHash != r.Header.Get(HeaderContentSHA256) {
+		return "", ErrSignatureInvalid
+	}
+	var headers []string
+	for _, name := range strings.Fields(ps["headers"]) {
+		headers = append(headers, http.CanonicalHeaderKey(name))
+	}
+	nonce := r.Header.Get(HeaderSignatureNonce)
+	msg := CanonicalRequest(r, timestamp, nonce, bodyHash, headers)
+	if !zhmac.Equal(h, []byte(msg), key, sum) {
+		return "", ErrSignatureInvalid
+	}
+
+	window := v.ReplayWindow
+	if window == 0 {
+		window = 2 * maxSkew
+	}
+	if window > 0 && !v.checkNonce(keyID+":"+nonce, t, window) {
+		return "", ErrSignatureReplayed
+	}
+	return keyID, nil
+}
+
+// checkNonce records the nonce and reports if the nonce
+// was not used within the window.
+// Expired nonces are removed periodically.
+func (v *HMACVerifier) checkNonce(nonce string, now time.Time, window time.Duration) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.nonces == nil {
+		v.nonces = map[string]time.Time{}
+	}
+	if now.Sub(v.sweep) > window {
+		for k, t := range v.nonces {
+			if now.Sub(t) > window {
+				delete(v.nonces, k)
+			}
+		}
+		v.sweep = now
+	}
+	if t, ok := v.nonces[nonce]; ok && now.Sub(t) <= window {
+		return false
+	}
+	v.nonces[nonce] = now
+	return true
+}
+
+// ServerMiddleware is the implementation of [ServerMiddleware.ServerMiddleware].
+func (v *HMACVerifier) ServerMiddleware(next http.Handler) http.Handler {
+	return HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keyID, err := v.Verify(r)
+		if err != nil {
+			handleAuthError(w, r, v.ErrorHandler, &HTTPError{Err: err, Code: http.StatusUnauthorized, Cause: CauseUnauthorized})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), usernameContextKey, keyID)))
+	})
+}
+
+// parseAuthParams parses comma separated auth-params
+// such as `foo="bar", baz=qux`.
+// Parameter names are lower cased.
+// Quoted values must not contain commas.
+// See https://datatracker.ietf.org/doc/rfc9110/ (11.2. Authentication Parameters)
+func parseAuthParams(s string) map[string]string {
+	params := map[string]string{}
+	for s != "" {
+		var elem string
+		elem, s = ScanElement(s)
+		if elem == "" {
+			break
+		}
+		key, val, _ := strings.Cut(elem, "=")
+		key = strings.ToLower(trimPrefixOWS(trimSuffixOWS(key)))
+		params[key] = trimDQUOTE(trimPrefixOWS(trimSuffixOWS(val)))
+	}
+	return params
+}
@@ -0,0 +1,140 @@
+package zhttp
+
+import (
+	"crypto"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestCanonicalRequest(t *testing.T) {
+	t.Parallel()
+	r := httptest.NewRequest(http.MethodPost, "http://example.com/foo%20bar?b=2&a=1", nil)
+	r.Header.Set("X-Foo", "foo")
+	r.Header.Add("X-Foo", "bar")
+	got := CanonicalRequest(r, "123", "abc", "hash", []string{"X-Foo", "X-Bar"})
+	want := "POST\n/foo%20bar\na=1&b=2\nexample.com\n123\nabc\nx-foo:foo,bar\nx-bar:\nhash"
+	ztesting.AssertEqual(t, "canonical request not match", want, got)
+}
+
+func TestParseAuthParams(t *testing.T) {
+	t.Parallel()
+	got := parseAuthParams(`keyId="foo", Algorithm=SHA-256,signature="YWJj=",`)
+	ztesting.AssertEqual(t, "keyid not match", "foo", got["keyid"])
+	ztesting.AssertEqual(t, "algorithm not match", "SHA-256", got["algorithm"])
+	ztesting.AssertEqual(t, "signature not match", "YWJj=", got["signature"])
+}
+
+func TestHMACSignVerify(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1_000_000, 0)
+	signer := &HMACSigner{KeyID: "id", Key: []byte("secret"), Headers: []string{"x-foo"}, timeNow: func() time.Time { return now }}
+
+	newRequest := func() *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "http://example.com/test?foo=bar", strings.NewReader("body"))
+		r.Header.Set("X-Foo", "foo")
+		if err := signer.Sign(r); err != nil {
+			panic(err)
+		}
+		return r
+	}
+
+	testCases := map[string]struct {
+		verifier *HMACVerifier
+		modify   func(r *http.Request)
+		err      error
+	}{
+		"valid":             {&HMACVerifier{Keys: map[string][]byte{"id": []byte("secret")}}, nil, nil},
+		"no authorization":  {&HMACVerifier{Keys: map[string][]byte{"id": []byte("secret")}}, func(r *http.Request) { r.Header.Del("Authorization") }, ErrSignatureMissing},
+		"unknown key id":    {&HMACVerifier{Keys: map[string][]byte{"other": []byte("secret")}}, nil, ErrSignatureUnknownID},
+		"wrong key":         {&HMACVerifier{Keys: map[string][]byte{"id": []byte("wrong")}}, nil, ErrSignatureInvalid},
+		"algorithm":         {&HMACVerifier{Keys: map[string][]byte{"id": []byte("secret")}, Hash: crypto.SHA512}, nil, ErrSignatureInvalid},
+		"modified header":   {&HMACVerifier{Keys: map[string][]byte{"id": []byte("secret")}}, func(r *http.Request) { r.Header.Set("X-Foo", "bar") }, ErrSignatureInvalid},
+		"modified path":     {&HMACVerifier{Keys: map[string][]byte{"id": []byte("secret")}}, func(r *http.Request) { r.URL.Path = "/other" }, ErrSignatureInvalid},
+		"modified query":    {&HMACVerifier{Keys: map[string][]byte{"id": []byte("secret")}}, func(r *http.Request) { r.URL.RawQuery = "foo=baz" }, ErrSignatureInvalid},
+		"modified body":     {&HMACVerifier{Keys: map[string][]byte{"id": []byte("secret")}}, func(r *http.Request) { r.Body = io.NopCloser(strings.NewReader("other")); r.GetBody = nil }, ErrSignatureInvalid},
+		"invalid timestamp": {&HMACVerifier{Keys: map[string][]byte{"id": []byte("secret")}}, func(r *http.Request) { r.Header.Set(HeaderSignatureTimestamp, "abc") }, ErrSignatureInvalid},
+		"invalid signature": {&HMACVerifier{Keys: map[string][]byte{"id": []byte("secret")}}, func(r *http.Request) { r.Header.Set("Authorization", `HMAC keyId="id",signature="###"`) }, ErrSignatureInvalid},
+		"too old":           {&HMACVerifier{Keys: map[string][]byte{"id": []byte("secret")}}, func(r *http.Request) { r.Header.Set(HeaderSignatureTimestamp, "999000") }, ErrSignatureExpired},
+		"too new":           {&HMACVerifier{Keys: map[string][]byte{"id": []byte("secret")}}, func(r *http.Request) { r.Header.Set(HeaderSignatureTimestamp, "1001000") }, ErrSignatureExpired},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tc.verifier.timeNow = func() time.Time { return now }
+			r := newRequest()
+			if tc.modify != nil {
+				tc.modify(r)
+			}
+			keyID, err := tc.verifier.Verify(r)
+			ztesting.AssertEqualErr(t, "error not match", tc.err, err)
+			if tc.err == nil {
+				ztesting.AssertEqual(t, "key id not match", "id", keyID)
+				body, _ := io.ReadAll(r.Body)
+				ztesting.AssertEqual(t, "body must be readable", "body", string(body))
+			}
+		})
+	}
+}
+
+func TestHMACVerifier_replay(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1_000_000, 0)
+	signer := &HMACSigner{KeyID: "id", Key: []byte("secret"), timeNow: func() time.Time { return now }}
+	r := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+	_ = signer.Sign(r)
+
+	v := &HMACVerifier{Keys: map[string][]byte{"id": []byte("secret")}, timeNow: func() time.Time { return now }}
+	_, err := v.Verify(r)
+	ztesting.AssertEqualErr(t, "error not match", nil, err)
+	_, err = v.Verify(r)
+	ztesting.AssertEqualErr(t, "error not match", ErrSignatureReplayed, err)
+
+	v = &HMACVerifier{Keys: map[string][]byte{"id": []byte("secret")}, ReplayWindow: -1, timeNow: func() time.Time { return now }}
+	_, err = v.Verify(r)
+	ztesting.AssertEqualErr(t, "error not match", nil, err)
+	_, err = v.Verify(r)
+	ztesting.AssertEqualErr(t, "replay detection must be disabled", nil, err)
+}
+
+func TestHMACVerifier_checkNonce(t *testing.T) {
+	t.Parallel()
+	v := &HMACVerifier{}
+	now := time.Unix(1_000_000, 0)
+	ztesting.AssertEqual(t, "nonce must be accepted", true, v.checkNonce("foo", now, time.Minute))
+	ztesting.AssertEqual(t, "nonce must be rejected", false, v.checkNonce("foo", now.Add(time.Second), time.Minute))
+	ztesting.AssertEqual(t, "nonce must be accepted", true, v.checkNonce("bar", now.Add(2*time.Minute), time.Minute))
+	ztesting.AssertEqual(t, "expired nonce must be removed", 1, len(v.nonces))
+	ztesting.AssertEqual(t, "nonce must be accepted", true, v.checkNonce("foo", now.Add(2*time.Minute), time.Minute))
+}
+
+func TestHMACMiddleware(t *testing.T) {
+	t.Parallel()
+	v := &HMACVerifier{Keys: map[string][]byte{"id": []byte("secret")}}
+	svr := httptest.NewServer(v.ServerMiddleware(HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(UsernameFromContext(r.Context()) + ":" + string(body)))
+	})))
+	defer svr.Close()
+
+	t.Run("signed", func(t *testing.T) {
+		signer := &HMACSigner{KeyID: "id", Key: []byte("secret")}
+		client := &http.Client{Transport: signer.ClientMiddleware(http.DefaultTransport)}
+		res, err := client.Post(svr.URL+"/test", "text/plain", strings.NewReader("hello"))
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		ztesting.AssertEqual(t, "status code not match", http.StatusOK, res.StatusCode)
+		ztesting.AssertEqual(t, "body not match", "id:hello", string(body))
+	})
+	t.Run("not signed", func(t *testing.T) {
+		res, err := http.Post(svr.URL+"/test", "text/plain", strings.NewReader("hello"))
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+		defer res.Body.Close()
+		ztesting.AssertEqual(t, "status code not match", http.StatusUnauthorized, res.StatusCode)
+	})
+}
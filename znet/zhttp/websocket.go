@@ -0,0 +1,496 @@
+package zhttp
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	CauseWebSocket = "znet/zhttp: websocket bridge closed the connection"
+)
+
+var (
+	// ErrSkipMessage can be returned from [WebSocketBridge.OnMessage]
+	// to drop the message without closing the connection.
+	ErrSkipMessage = errors.New("znet/zhttp: skip websocket message")
+
+	errWSProtocol = errors.New("znet/zhttp: websocket protocol error")
+	errWSTooBig   = errors.New("znet/zhttp: websocket message too big")
+	errWSIdle     = errors.New("znet/zhttp: websocket idle timeout")
+)
+
+// WebSocketOpcode is the websocket frame opcode.
+// See https://datatracker.ietf.org/doc/html/rfc6455#section-5.2
+type WebSocketOpcode byte
+
+const (
+	WebSocketContinuation WebSocketOpcode = 0x0
+	WebSocketText         WebSocketOpcode = 0x1
+	WebSocketBinary       WebSocketOpcode = 0x2
+	WebSocketClose        WebSocketOpcode = 0x8
+	WebSocketPing         WebSocketOpcode = 0x9
+	WebSocketPong         WebSocketOpcode = 0xA
+)
+
+// IsControl returns if the opcode is a control frame opcode.
+// Close, Ping and Pong are control opcodes.
+func (o WebSocketOpcode) IsControl() bool {
+	return o&0x8 != 0
+}
+
+// String returns the name of the opcode.
+func (o WebSocketOpcode) String() string {
+	switch o {
+	case WebSocketContinuation:
+		return "continuation"
+	case WebSocketText:
+		return "text"
+	case WebSocketBinary:
+		return "binary"
+	case WebSocketClose:
+		return "close"
+	case WebSocketPing:
+		return "ping"
+	case WebSocketPong:
+		return "pong"
+	default:
+		return "unknown(" + strconv.Itoa(int(o)) + ")"
+	}
+}
+
+// Websocket close status codes used by the bridge.
+// See https://datatracker.ietf.org/doc/html/rfc6455#section-7.4.1
+const (
+	wsCloseProtocolError   = 1002
+	wsClosePolicyViolation = 1008
+	wsCloseMessageTooBig   = 1009
+	wsCloseGoingAway       = 1001
+)
+
+// WebSocketMessage is a websocket message passed to [WebSocketBridge.OnMessage].
+// Fragmented data frames are reassembled into a single message.
+// Control frames (close, ping and pong) are also passed as a message.
+type WebSocketMessage struct {
+	// FromClient is true when the message was sent
+	// by the client and false when sent by the upstream.
+	FromClient bool
+	// Opcode is the opcode of the message.
+	// It is never [WebSocketContinuation].
+	Opcode WebSocketOpcode
+	// Payload is the unmasked payload of the message.
+	// Hooks may replace the payload to modify the message.
+	Payload []byte
+}
+
+// WebSocketStats is the statistics of a [WebSocketBridge].
+type WebSocketStats struct {
+	// Active is the number of websocket connections being bridged.
+	Active int64
+	// Total is the total number of bridged websocket connections.
+	Total int64
+	// ClientMessages is the number of messages sent from clients.
+	ClientMessages int64
+	// ServerMessages is the number of messages sent from upstreams.
+	ServerMessages int64
+	// Dropped is the number of messages dropped by the hook.
+	Dropped int64
+}
+
+// WebSocketBridge bridges websocket connections frame by frame
+// instead of copying raw bytes after protocol upgrade.
+// Frames are parsed following RFC 6455.
+// Set the bridge to [Proxy.WebSocket] to enable it.
+// Other upgrade protocols than websocket are not affected.
+// Websocket extensions such as permessage-deflate are not
+// negotiated when the bridge is used so that the payloads
+// can be inspected.
+// See https://datatracker.ietf.org/doc/html/rfc6455
+type WebSocketBridge struct {
+	// MaxMessageSize is the maximum size of a message in bytes.
+	// Size of fragmented messages are accumulated.
+	// If a message exceeds the limit, the connection is closed
+	// with the status code 1009 (Message Too Big).
+	// Zero or negative value means no limit.
+	MaxMessageSize int64
+	// IdleTimeout is the maximum duration that no frame was
+	// transferred in both directions.
+	// If exceeded, the connection is closed with the status
+	// code 1001 (Going Away).
+	// Zero or negative value means no timeout.
+	IdleTimeout time.Duration
+	// OnMessage is called for every message before forwarding it.
+	// OnMessage can be used for logging or filtering messages.
+	// Returning [ErrSkipMessage] drops the message and
+	// any other non-nil error closes the connection with
+	// the status code 1008 (Policy Violation).
+	// Control messages cannot be dropped.
+	// OnMessage may be called concurrently.
+	OnMessage func(msg *WebSocketMessage) error
+
+	active, total   atomic.Int64
+	clientMsg       atomic.Int64
+	serverMsg       atomic.Int64
+	droppedMessages atomic.Int64
+}
+
+// Stats returns the current statistics of the bridge.
+func (b *WebSocketBridge) Stats() WebSocketStats {
+	return WebSocketStats{
+		Active:         b.active.Load(),
+		Total:          b.total.Load(),
+		ClientMessages: b.clientMsg.Load(),
+		ServerMessages: b.serverMsg.Load(),
+		Dropped:        b.droppedMessages.Load(),
+	}
+}
+
+// serve bridges frames between client and upstream.
+// Both client and upstream are closed before return.
+// Returned error is nil when the connection was closed normally.
+func (b *WebSocketBridge) serve(client, upstream io.ReadWriteCloser) error {
+	b.active.Add(1)
+	b.total.Add(1)
+	defer b.active.Add(-1)
+
+	s := &wsSession{
+		bridge:   b,
+		client:   client,
+		upstream: upstream,
+		errChan:  make(chan error, 2),
+	}
+	s.lastActive.Store(time.Now().UnixNano())
+	if b.IdleTimeout > 0 {
+		timer := time.AfterFunc(b.IdleTimeout, s.checkIdle)
+		s.timer.Store(timer)
+		defer func() { s.timer.Load().Stop() }()
+	}
+
+	go s.pipe(client, upstream, true)
+	go s.pipe(upstream, client, false)
+	err := <-s.errChan
+	s.closeAll()
+	if err2 := <-s.errChan; err == nil {
+		err = err2
+	}
+	if e := s.failure(); e != nil {
+		return e // Report the cause rather than the closed connection error.
+	}
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
+
+// wsSession is a single bridged websocket connection.
+type wsSession struct {
+	bridge   *WebSocketBridge
+	client   io.ReadWriteCloser
+	upstream io.ReadWriteCloser
+	errChan  chan error
+
+	// lastActive is the unix nano time when
+	// the last frame was received.
+	lastActive atomic.Int64
+	timer      atomic.Pointer[time.Timer]
+
+	// clientMu and upstreamMu protect writes to client and upstream.
+	// They are separated so that a slow peer does not block
+	// the traffic in the other direction.
+	clientMu   sync.Mutex
+	upstreamMu sync.Mutex
+
+	closeOnce sync.Once
+	closed    atomic.Bool
+	failed    atomic.Pointer[error]
+}
+
+// pipe reads frames from src and forward them to dst.
+// fromClient must be true when the src is the client.
+func (s *wsSession) pipe(src io.Reader, dst io.Writer, fromClient bool) {
+	var msg *WebSocketMessage
+	var rsv byte
+	for {
+		f, err := readWSFrame(src, fromClient, s.bridge.MaxMessageSize)
+		switch {
+		case errors.Is(err, errWSProtocol):
+			s.fail(err, wsCloseProtocolError)
+		case errors.Is(err, errWSTooBig):
+			s.fail(err, wsCloseMessageTooBig)
+		}
+		if err != nil {
+			s.errChan <- err
+			return
+		}
+		s.lastActive.Store(time.Now().UnixNano())
+
+		if f.opcode.IsControl() {
+			m := &WebSocketMessage{FromClient: fromClient, Opcode: f.opcode, Payload: f.payload}
+			if err := s.forward(dst, m, f.rsv, fromClient); err != nil {
+				s.errChan <- err
+				return
+			}
+			continue
+		}
+
+		switch {
+		case msg == nil && f.opcode == WebSocketContinuation:
+			err = errWSProtocol // Continuation without initial frame.
+		case msg != nil && f.opcode != WebSocketContinuation:
+			err = errWSProtocol // New message before the last one completes.
+		case msg == nil:
+			msg = &WebSocketMessage{FromClient: fromClient, Opcode: f.opcode}
+			rsv = f.rsv
+		}
+		if err != nil {
+			s.fail(err, wsCloseProtocolError)
+			s.errChan <- err
+			return
+		}
+		msg.Payload = append(msg.Payload, f.payload...)
+		if limit := s.bridge.MaxMessageSize; limit > 0 && int64(len(msg.Payload)) > limit {
+			s.fail(errWSTooBig, wsCloseMessageTooBig)
+			s.errChan <- errWSTooBig
+			return
+		}
+		if !f.fin {
+			continue
+		}
+		if err := s.forward(dst, msg, rsv, fromClient); err != nil {
+			s.errChan <- err
+			return
+		}
+		msg = nil
+	}
+}
+
+// checkIdle closes the session if no frames were received
+// during the idle timeout. Otherwise, it re-arms the timer.
+func (s *wsSession) checkIdle() {
+	if s.closed.Load() {
+		return
+	}
+	idle := time.Since(time.Unix(0, s.lastActive.Load()))
+	if idle >= s.bridge.IdleTimeout {
+		s.fail(errWSIdle, wsCloseGoingAway)
+		return
+	}
+	s.timer.Store(time.AfterFunc(s.bridge.IdleTimeout-idle, s.checkIdle))
+}
+
+// forward passes the msg to the hook and write it to the dst.
+func (s *wsSession) forward(dst io.Writer, msg *WebSocketMessage, rsv byte, mask bool) error {
+	if msg.FromClient {
+		s.bridge.clientMsg.Add(1)
+	} else {
+		s.bridge.serverMsg.Add(1)
+	}
+	if hook := s.bridge.OnMessage; hook != nil {
+		if err := hook(msg); err != nil {
+			if errors.Is(err, ErrSkipMessage) && !msg.Opcode.IsControl() {
+				s.bridge.droppedMessages.Add(1)
+				return nil
+			}
+			s.fail(err, wsClosePolicyViolation)
+			return err
+		}
+	}
+	mu := s.writeMu(dst)
+	mu.Lock()
+	defer mu.Unlock()
+	return writeWSFrame(dst, &wsFrame{fin: true, rsv: rsv, opcode: msg.Opcode, payload: msg.Payload}, mask)
+}
+
+// writeMu returns the mutex that protects writes to the dst.
+func (s *wsSession) writeMu(dst io.Writer) *sync.Mutex {
+	if dst == s.upstream {
+		return &s.upstreamMu
+	}
+	return &s.clientMu
+}
+
+// fail records the err as the failure reason and
+// sends close frames with the code to both sides.
+// Connections are closed after that.
+// Only the first failure is recorded.
+func (s *wsSession) fail(err error, code uint16) {
+	if !s.failed.CompareAndSwap(nil, &err) {
+		return
+	}
+	payload := binary.BigEndian.AppendUint16(nil, code)
+	writers := []io.Writer{s.client, s.upstream}
+	for _, c := range writers {
+		// Set deadlines before taking the write locks so that
+		// writers blocked by a peer that is not reading are released.
+		if dc, ok := c.(interface{ SetWriteDeadline(time.Time) error }); ok {
+			_ = dc.SetWriteDeadline(time.Now().Add(time.Second))
+		}
+	}
+	for _, c := range writers {
+		mu := s.writeMu(c)
+		mu.Lock()
+		_ = writeWSFrame(c, &wsFrame{fin: true, opcode: WebSocketClose, payload: payload}, c == s.upstream)
+		mu.Unlock()
+	}
+	s.closeAll()
+}
+
+// failure returns the failure reason if any.
+func (s *wsSession) failure() error {
+	if p := s.failed.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+func (s *wsSession) closeAll() {
+	s.closeOnce.Do(func() {
+		s.closed.Store(true)
+		_ = s.client.Close()
+		_ = s.upstream.Close()
+	})
+}
+
+// wsFrame is a single websocket frame.
+type wsFrame struct {
+	fin     bool
+	rsv     byte // RSV1-3 bits placed at the same position as the header.
+	opcode  WebSocketOpcode
+	payload []byte // Unmasked payload.
+}
+
+// readWSFrame reads a frame from r.
+// Frames sent from clients must be masked and frames sent
+// from servers must not be masked. Set the masked true
+// when reading from clients.
+// The payload of the returned frame is unmasked.
+// If the limit is positive, frames with larger payload than
+// the limit result in an error.
+//
+//	 0                   1                   2                   3
+//	 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1 2 3 4 5 6 7 8 9 0 1
+//	+-+-+-+-+-------+-+-------------+-------------------------------+
+//	|F|R|R|R| opcode|M| Payload len |    Extended payload length    |
+//	|I|S|S|S|  (4)  |A|     (7)     |             (16/64)           |
+//	|N|V|V|V|       |S|             |   (if payload len==126/127)   |
+//	| |1|2|3|       |K|             |                               |
+//	+-+-+-+-+-------+-+-------------+ - - - - - - - - - - - - - - - +
+//	|     Extended payload length continued, if payload len == 127  |
+//	+ - - - - - - - - - - - - - - - +-------------------------------+
+//	|                               |Masking-key, if MASK set to 1  |
+//	+-------------------------------+-------------------------------+
+//	| Masking-key (continued)       |          Payload Data         |
+//	+-------------------------------- - - - - - - - - - - - - - - - +
+func readWSFrame(r io.Reader, masked bool, limit int64) (*wsFrame, error) {
+	var header [14]byte
+	if _, err := io.ReadFull(r, header[:2]); err != nil {
+		return nil, err
+	}
+	f := &wsFrame{
+		fin:    header[0]&0x80 != 0,
+		rsv:    header[0] & 0x70,
+		opcode: WebSocketOpcode(header[0] & 0x0f),
+	}
+	if (header[1]&0x80 != 0) != masked {
+		return nil, errWSProtocol // Invalid masking.
+	}
+
+	var length uint64
+	switch n := header[1] & 0x7f; n {
+	case 126:
+		if _, err := io.ReadFull(r, header[2:4]); err != nil {
+			return nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(header[2:4]))
+	case 127:
+		if _, err := io.ReadFull(r, header[2:10]); err != nil {
+			return nil, err
+		}
+		length = binary.BigEndian.Uint64(header[2:10])
+		if length > 1<<63-1 {
+			return nil, errWSProtocol // Most significant bit must be 0.
+		}
+	default:
+		length = uint64(n)
+	}
+	if f.opcode.IsControl() && (length > 125 || !f.fin) {
+		return nil, errWSProtocol // Control frames must not be fragmented.
+	}
+	if limit > 0 && length > uint64(limit) {
+		return nil, errWSTooBig
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, key[:]); err != nil {
+			return nil, err
+		}
+	}
+
+	// Read payload incrementally so that a huge length field
+	// does not allocate a huge buffer at once.
+	buf, err := io.ReadAll(io.LimitReader(r, int64(length)))
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(buf)) != length {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if masked {
+		maskWSPayload(buf, key)
+	}
+	f.payload = buf
+	return f, nil
+}
+
+// writeWSFrame writes the frame f to w.
+// Payload is masked with a random key when mask is true.
+// The payload of f is not modified.
+func writeWSFrame(w io.Writer, f *wsFrame, mask bool) error {
+	length := len(f.payload)
+	buf := make([]byte, 0, 14+length)
+	b0 := f.rsv | byte(f.opcode)
+	if f.fin {
+		b0 |= 0x80
+	}
+	var b1 byte
+	if mask {
+		b1 = 0x80
+	}
+	switch {
+	case length <= 125:
+		buf = append(buf, b0, b1|byte(length))
+	case length <= 0xffff:
+		buf = append(buf, b0, b1|126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(length))
+	default:
+		buf = append(buf, b0, b1|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(length))
+	}
+	if !mask {
+		buf = append(buf, f.payload...)
+		_, err := w.Write(buf)
+		return err
+	}
+	var key [4]byte
+	_, _ = rand.Read(key[:])
+	buf = append(buf, key[:]...)
+	start := len(buf)
+	buf = append(buf, f.payload...)
+	maskWSPayload(buf[start:], key)
+	_, err := w.Write(buf)
+	return err
+}
+
+// maskWSPayload masks or unmasks the b with the key.
+// See https://datatracker.ietf.org/doc/html/rfc6455#section-5.3
+func maskWSPayload(b []byte, key [4]byte) {
+	for i := range b {
+		b[i] ^= key[i%4]
+	}
+}
@@ -0,0 +1,255 @@
+package zhttp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestWebSocketOpcode(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		op      WebSocketOpcode
+		control bool
+		str     string
+	}{
+		"continuation": {WebSocketContinuation, false, "continuation"},
+		"text":         {WebSocketText, false, "text"},
+		"binary":       {WebSocketBinary, false, "binary"},
+		"close":        {WebSocketClose, true, "close"},
+		"ping":         {WebSocketPing, true, "ping"},
+		"pong":         {WebSocketPong, true, "pong"},
+		"unknown":      {WebSocketOpcode(0x3), false, "unknown(3)"},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ztesting.AssertEqual(t, "control not match", tc.control, tc.op.IsControl())
+			ztesting.AssertEqual(t, "string not match", tc.str, tc.op.String())
+		})
+	}
+}
+
+func TestReadWriteWSFrame(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		frame *wsFrame
+		mask  bool
+	}{
+		"empty":           {&wsFrame{fin: true, opcode: WebSocketText}, false},
+		"empty masked":    {&wsFrame{fin: true, opcode: WebSocketText}, true},
+		"125 bytes":       {&wsFrame{fin: true, opcode: WebSocketBinary, payload: bytes.Repeat([]byte("a"), 125)}, true},
+		"126 bytes":       {&wsFrame{fin: true, opcode: WebSocketBinary, payload: bytes.Repeat([]byte("a"), 126)}, true},
+		"65536 bytes":     {&wsFrame{fin: true, opcode: WebSocketBinary, payload: bytes.Repeat([]byte("a"), 65536)}, false},
+		"not fin":         {&wsFrame{fin: false, opcode: WebSocketText, payload: []byte("foo")}, false},
+		"rsv":             {&wsFrame{fin: true, rsv: 0x40, opcode: WebSocketText, payload: []byte("foo")}, true},
+		"control message": {&wsFrame{fin: true, opcode: WebSocketPing, payload: []byte("ping")}, true},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			payload := bytes.Clone(tc.frame.payload)
+			err := writeWSFrame(&buf, tc.frame, tc.mask)
+			ztesting.AssertEqualErr(t, "write error not match", nil, err)
+			ztesting.AssertEqual(t, "payload modified", string(payload), string(tc.frame.payload))
+			f, err := readWSFrame(&buf, tc.mask, 0)
+			ztesting.AssertEqualErr(t, "read error not match", nil, err)
+			ztesting.AssertEqual(t, "fin not match", tc.frame.fin, f.fin)
+			ztesting.AssertEqual(t, "rsv not match", tc.frame.rsv, f.rsv)
+			ztesting.AssertEqual(t, "opcode not match", tc.frame.opcode, f.opcode)
+			ztesting.AssertEqual(t, "payload not match", string(tc.frame.payload), string(f.payload))
+		})
+	}
+}
+
+func TestReadWSFrame_error(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		input  []byte
+		masked bool
+		limit  int64
+		err    error
+	}{
+		"empty":              {nil, false, 0, io.EOF},
+		"short header":       {[]byte{0x81}, false, 0, io.ErrUnexpectedEOF},
+		"mask required":      {[]byte{0x81, 0x00}, true, 0, errWSProtocol},
+		"mask not allowed":   {[]byte{0x81, 0x80, 0, 0, 0, 0}, false, 0, errWSProtocol},
+		"short length 16":    {[]byte{0x82, 126, 0x00}, false, 0, io.ErrUnexpectedEOF},
+		"short length 64":    {[]byte{0x82, 127, 0x00}, false, 0, io.ErrUnexpectedEOF},
+		"invalid length 64":  {[]byte{0x82, 127, 0x80, 0, 0, 0, 0, 0, 0, 0}, false, 0, errWSProtocol},
+		"fragmented control": {[]byte{0x09, 0x00}, false, 0, errWSProtocol},
+		"large control":      {[]byte{0x89, 126, 0x00, 0x7e}, false, 0, errWSProtocol},
+		"short mask key":     {[]byte{0x81, 0x80, 0x00}, true, 0, io.ErrUnexpectedEOF},
+		"short payload":      {[]byte{0x81, 0x03, 'a'}, false, 0, io.ErrUnexpectedEOF},
+		"exceeds limit":      {[]byte{0x81, 0x03, 'a', 'b', 'c'}, false, 2, errWSTooBig},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := readWSFrame(bytes.NewReader(tc.input), tc.masked, tc.limit)
+			ztesting.AssertEqualErr(t, "error not match", tc.err, err)
+		})
+	}
+}
+
+// testWSPeers returns connections connected to a running bridge.
+// The client must write masked frames and the upstream
+// must write unmasked frames.
+func testWSPeers(t *testing.T, b *WebSocketBridge) (client, upstream net.Conn, errChan <-chan error) {
+	t.Helper()
+	c1, c2 := net.Pipe()
+	u1, u2 := net.Pipe()
+	ch := make(chan error, 1)
+	go func() { ch <- b.serve(c2, u2) }()
+	t.Cleanup(func() {
+		c1.Close()
+		u1.Close()
+	})
+	return c1, u1, ch
+}
+
+func closeCode(t *testing.T, r io.Reader, masked bool) uint16 {
+	t.Helper()
+	for {
+		f, err := readWSFrame(r, masked, 0)
+		if err != nil {
+			t.Errorf("reading close frame failed: %v", err)
+			return 0
+		}
+		if f.opcode == WebSocketClose {
+			return binary.BigEndian.Uint16(f.payload)
+		}
+	}
+}
+
+func TestWebSocketBridge(t *testing.T) {
+	t.Parallel()
+	t.Run("forward messages", func(t *testing.T) {
+		var msgs []string
+		b := &WebSocketBridge{OnMessage: func(msg *WebSocketMessage) error {
+			msgs = append(msgs, msg.Opcode.String()+":"+string(msg.Payload))
+			return nil
+		}}
+		client, upstream, errChan := testWSPeers(t, b)
+
+		go func() {
+			_ = writeWSFrame(client, &wsFrame{fin: false, opcode: WebSocketText, payload: []byte("hello ")}, true)
+			_ = writeWSFrame(client, &wsFrame{fin: true, opcode: WebSocketPing, payload: []byte("ping")}, true)
+			_ = writeWSFrame(client, &wsFrame{fin: true, opcode: WebSocketContinuation, payload: []byte("world")}, true)
+		}()
+		f, err := readWSFrame(upstream, true, 0)
+		ztesting.AssertEqualErr(t, "read error not match", nil, err)
+		ztesting.AssertEqual(t, "opcode not match", WebSocketPing, f.opcode)
+		f, err = readWSFrame(upstream, true, 0)
+		ztesting.AssertEqualErr(t, "read error not match", nil, err)
+		ztesting.AssertEqual(t, "opcode not match", WebSocketText, f.opcode)
+		ztesting.AssertEqual(t, "fin not match", true, f.fin)
+		ztesting.AssertEqual(t, "payload not match", "hello world", string(f.payload))
+
+		go func() {
+			_ = writeWSFrame(upstream, &wsFrame{fin: true, opcode: WebSocketBinary, payload: []byte("bar")}, false)
+		}()
+		f, err = readWSFrame(client, false, 0)
+		ztesting.AssertEqualErr(t, "read error not match", nil, err)
+		ztesting.AssertEqual(t, "payload not match", "bar", string(f.payload))
+
+		upstream.Close()
+		ztesting.AssertEqualErr(t, "serve error not match", nil, <-errChan)
+		ztesting.AssertEqual(t, "messages not match", "ping:ping,text:hello world,binary:bar", strings.Join(msgs, ","))
+		stats := b.Stats()
+		ztesting.AssertEqual(t, "active not match", int64(0), stats.Active)
+		ztesting.AssertEqual(t, "total not match", int64(1), stats.Total)
+		ztesting.AssertEqual(t, "client messages not match", int64(2), stats.ClientMessages)
+		ztesting.AssertEqual(t, "server messages not match", int64(1), stats.ServerMessages)
+	})
+	t.Run("skip message", func(t *testing.T) {
+		b := &WebSocketBridge{OnMessage: func(msg *WebSocketMessage) error {
+			if string(msg.Payload) == "skip" {
+				return ErrSkipMessage
+			}
+			return nil
+		}}
+		client, upstream, errChan := testWSPeers(t, b)
+		go func() {
+			_ = writeWSFrame(client, &wsFrame{fin: true, opcode: WebSocketText, payload: []byte("skip")}, true)
+			_ = writeWSFrame(client, &wsFrame{fin: true, opcode: WebSocketText, payload: []byte("pass")}, true)
+		}()
+		f, err := readWSFrame(upstream, true, 0)
+		ztesting.AssertEqualErr(t, "read error not match", nil, err)
+		ztesting.AssertEqual(t, "payload not match", "pass", string(f.payload))
+		client.Close()
+		ztesting.AssertEqualErr(t, "serve error not match", nil, <-errChan)
+		ztesting.AssertEqual(t, "dropped not match", int64(1), b.Stats().Dropped)
+	})
+	t.Run("hook error", func(t *testing.T) {
+		hookErr := errors.New("denied")
+		b := &WebSocketBridge{OnMessage: func(msg *WebSocketMessage) error { return hookErr }}
+		client, upstream, errChan := testWSPeers(t, b)
+		go func() {
+			_ = writeWSFrame(client, &wsFrame{fin: true, opcode: WebSocketText, payload: []byte("foo")}, true)
+		}()
+		go func() {
+			ztesting.AssertEqual(t, "close code not match", uint16(wsClosePolicyViolation), closeCode(t, upstream, true))
+		}()
+		ztesting.AssertEqual(t, "close code not match", uint16(wsClosePolicyViolation), closeCode(t, client, false))
+		ztesting.AssertEqualErr(t, "serve error not match", hookErr, <-errChan)
+	})
+	t.Run("message too big", func(t *testing.T) {
+		b := &WebSocketBridge{MaxMessageSize: 5}
+		client, upstream, errChan := testWSPeers(t, b)
+		go func() {
+			_ = writeWSFrame(client, &wsFrame{fin: false, opcode: WebSocketText, payload: []byte("foo")}, true)
+			_ = writeWSFrame(client, &wsFrame{fin: true, opcode: WebSocketContinuation, payload: []byte("bar")}, true)
+		}()
+		go func() {
+			ztesting.AssertEqual(t, "close code not match", uint16(wsCloseMessageTooBig), closeCode(t, upstream, true))
+		}()
+		ztesting.AssertEqual(t, "close code not match", uint16(wsCloseMessageTooBig), closeCode(t, client, false))
+		ztesting.AssertEqualErr(t, "serve error not match", errWSTooBig, <-errChan)
+	})
+	t.Run("protocol error", func(t *testing.T) {
+		b := &WebSocketBridge{}
+		client, upstream, errChan := testWSPeers(t, b)
+		go func() {
+			_ = writeWSFrame(client, &wsFrame{fin: true, opcode: WebSocketContinuation, payload: []byte("foo")}, true)
+		}()
+		go func() {
+			ztesting.AssertEqual(t, "close code not match", uint16(wsCloseProtocolError), closeCode(t, upstream, true))
+		}()
+		ztesting.AssertEqual(t, "close code not match", uint16(wsCloseProtocolError), closeCode(t, client, false))
+		ztesting.AssertEqualErr(t, "serve error not match", errWSProtocol, <-errChan)
+	})
+	t.Run("idle timeout", func(t *testing.T) {
+		b := &WebSocketBridge{IdleTimeout: 50 * time.Millisecond}
+		client, upstream, errChan := testWSPeers(t, b)
+		go func() {
+			ztesting.AssertEqual(t, "close code not match", uint16(wsCloseGoingAway), closeCode(t, upstream, true))
+		}()
+		ztesting.AssertEqual(t, "close code not match", uint16(wsCloseGoingAway), closeCode(t, client, false))
+		ztesting.AssertEqualErr(t, "serve error not match", errWSIdle, <-errChan)
+	})
+	t.Run("idle timeout with stalled peer", func(t *testing.T) {
+		b := &WebSocketBridge{IdleTimeout: 200 * time.Millisecond}
+		client, upstream, errChan := testWSPeers(t, b)
+		// The upstream never reads, so the write to the upstream blocks.
+		_ = writeWSFrame(client, &wsFrame{fin: true, opcode: WebSocketText, payload: []byte("stuck")}, true)
+		go func() {
+			_ = writeWSFrame(upstream, &wsFrame{fin: true, opcode: WebSocketText, payload: []byte("bar")}, false)
+		}()
+		f, err := readWSFrame(client, false, 0) // The other direction is not blocked.
+		ztesting.AssertEqualErr(t, "read error not match", nil, err)
+		ztesting.AssertEqual(t, "payload not match", "bar", string(f.payload))
+		ztesting.AssertEqual(t, "close code not match", uint16(wsCloseGoingAway), closeCode(t, client, false))
+		select {
+		case err := <-errChan:
+			ztesting.AssertEqualErr(t, "serve error not match", errWSIdle, err)
+		case <-time.After(5 * time.Second):
+			t.Error("session not closed by the idle timeout")
+		}
+	})
+}
@@ -38,10 +38,29 @@ func NewProxy(targets ...string) *Proxy {
 		panic(ErrNoTarget)
 	}
 	return &Proxy{
-		Dial: (&roundRobinDialer{addrs: targets, index: -1}).dial,
+		Dial: RoundRobinDial(nil, targets...),
 	}
 }
 
+// RoundRobinDial returns a function for [Proxy.Dial] that dials to
+// the targets with round-robin algorithm using the dialer d.
+// The format of targets is the same as [NewProxy].
+// If d is nil, a zero [net.Dialer] is used.
+// Use the Dialer method of zsyscall.SockOption to apply socket options to
+// upstream connections as shown below.
+// If no targets specified, RoundRobinDial panics [ErrNoTarget].
+//
+//	opt := zsyscall.LowLatencySockOption()
+//	p := &Proxy{
+//		Dial: RoundRobinDial(opt.Dialer(zsyscall.SockOptForTCP), "127.0.0.1:8080"),
+//	}
+func RoundRobinDial(d *net.Dialer, targets ...string) func(ctx context.Context, dc net.Conn) (net.Conn, error) {
+	if len(targets) == 0 {
+		panic(ErrNoTarget)
+	}
+	return (&roundRobinDialer{dialer: d, addrs: targets, index: -1}).dial
+}
+
 // Proxy is a TCP proxy.
 type Proxy struct {
 	// Dial returns a new upstream connection
@@ -56,6 +75,21 @@ type Proxy struct {
 	// provided as dc and uc each.
 	// uc can be nil when Dial returned an error.
 	ErrorHandler func(dc, uc net.Conn, err error)
+	// SessionEnd optionally handles the end of a proxy session.
+	// It is called after the session ended and before the upstream
+	// connection uc is closed. It is not called when Dial failed.
+	// For example, statistics of the upstream connection
+	// such as RTT can be logged using ReadTCPInfo of zsyscall.
+	//
+	//	p.SessionEnd = func(dc, uc net.Conn) {
+	//		if tc, ok := uc.(*net.TCPConn); ok {
+	//			info, err := zsyscall.ReadTCPInfo(tc)
+	//			if err == nil {
+	//				log.Println(dc.RemoteAddr(), uc.RemoteAddr(), info.RTT)
+	//			}
+	//		}
+	//	}
+	SessionEnd func(dc, uc net.Conn)
 }
 
 func (p *Proxy) handleError(dc, uc net.Conn, err error) {
@@ -74,6 +108,9 @@ func (p *Proxy) ServeTCP(ctx context.Context, conn net.Conn) {
 		return
 	}
 	defer upConn.Close() // Ensure close upstream connection.
+	if se := p.SessionEnd; se != nil {
+		defer se(conn, upConn) // Called before closing upstream connection.
+	}
 
 	errChan := make(chan error)
 	go copyBuf(conn, upConn, errChan) // downstream --> proxy --> upstream
@@ -107,9 +144,10 @@ func copyBuf(dst io.Writer, src io.Reader, errChan chan<- error) {
 // roundRobinDialer dials to the address
 // in addrs with round-robin algorithm.
 type roundRobinDialer struct {
-	mu    sync.Mutex
-	index int
-	addrs []string
+	mu     sync.Mutex
+	index  int
+	addrs  []string
+	dialer *net.Dialer // Zero dialer is used if nil.
 }
 
 func (d *roundRobinDialer) next() string {
@@ -122,7 +160,11 @@ func (d *roundRobinDialer) next() string {
 	return d.addrs[d.index]
 }
 
-func (d *roundRobinDialer) dial(_ context.Context, _ net.Conn) (net.Conn, error) {
+func (d *roundRobinDialer) dial(ctx context.Context, _ net.Conn) (net.Conn, error) {
+	dialer := d.dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
 	addr := d.next()
 	network, address := znet.ParseNetAddr(addr)
 	switch network {
@@ -131,11 +173,10 @@ func (d *roundRobinDialer) dial(_ context.Context, _ net.Conn) (net.Conn, error)
 		if err != nil {
 			return nil, err
 		}
-		return net.DialTCP(network, nil, raddr)
+		return dialer.DialContext(ctx, network, raddr.String())
 	case "unix", "unixpacket":
-		raddr := &net.UnixAddr{Name: address, Net: network}
-		return net.DialUnix(network, nil, raddr)
+		return dialer.DialContext(ctx, network, address)
 	default:
-		return net.Dial("tcp", addr) // Fallback.
+		return dialer.DialContext(ctx, "tcp", addr) // Fallback.
 	}
 }
@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"cmp"
 	"context"
+	"errors"
 	"io"
 	"net"
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -31,6 +33,25 @@ func TestNewProxy(t *testing.T) {
 	})
 }
 
+func TestRoundRobinDial(t *testing.T) {
+	t.Parallel()
+	t.Run("no targets", func(t *testing.T) {
+		defer func() {
+			r := recover()
+			ztesting.AssertEqual(t, "recovered value not match", r.(error), ErrNoTarget)
+		}()
+		_ = RoundRobinDial(nil)
+	})
+	t.Run("with dialer", func(t *testing.T) {
+		testErr := errors.New("control error")
+		d := &net.Dialer{Control: func(string, string, syscall.RawConn) error { return testErr }}
+		dial := RoundRobinDial(d, "tcp4://127.0.0.1:12345")
+		conn, err := dial(context.Background(), nil)
+		ztesting.AssertEqual(t, "conn should be nil", nil, conn)
+		ztesting.AssertEqualErr(t, "error not match", testErr, err)
+	})
+}
+
 func TestRoundRobinDialer(t *testing.T) {
 	t.Parallel()
 	t.Run("test next", func(t *testing.T) {
@@ -143,11 +164,16 @@ func TestProxy(t *testing.T) {
 			writer: uWriter,
 		}
 		var handledErr error
+		sessionEnd := 0
 		p := &Proxy{
 			Dial: func(ctx context.Context, dc net.Conn) (uc net.Conn, err error) {
 				return uConn, nil
 			},
 			ErrorHandler: func(dc, uc net.Conn, err error) { handledErr = err },
+			SessionEnd: func(dc, uc net.Conn) {
+				ztesting.AssertEqual(t, "upstream conn was closed", false, uConn.closed)
+				sessionEnd++
+			},
 		}
 		p.ServeTCP(context.Background(), dConn)
 		ztesting.AssertEqual(t, "upstream data was not written", "upstream data", dWriter.String())
@@ -155,6 +181,7 @@ func TestProxy(t *testing.T) {
 		ztesting.AssertEqual(t, "upstream conn was not closed", true, uConn.closed)
 		ztesting.AssertEqual(t, "downstream conn was closed", false, dConn.closed)
 		ztesting.AssertEqualErr(t, "error not match", nil, handledErr)
+		ztesting.AssertEqual(t, "session end not called", 1, sessionEnd)
 	})
 	t.Run("dial error", func(t *testing.T) {
 		var handledErr error
@@ -163,6 +190,7 @@ func TestProxy(t *testing.T) {
 				return nil, net.ErrClosed
 			},
 			ErrorHandler: func(dc, uc net.Conn, err error) { handledErr = err },
+			SessionEnd:   func(dc, uc net.Conn) { t.Error("session end must not be called") },
 		}
 		p.ServeTCP(context.Background(), nil)
 		ztesting.AssertEqualErr(t, "error not match", net.ErrClosed, handledErr)
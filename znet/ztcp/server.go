@@ -6,15 +6,18 @@ import (
 	"crypto/tls"
 	"errors"
 	"log"
+	"maps"
 	"net"
 	"os"
 	"runtime"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/aileron-projects/go/znet"
 	"github.com/aileron-projects/go/znet/internal"
+	"github.com/aileron-projects/go/zsyscall"
 )
 
 var (
@@ -32,6 +35,7 @@ var (
 //
 // ServeTCP is invoked in a new goroutine for each new incoming connections.
 // ServeTCP does not need to close the connection because the [Server] ensures to.
+// The connection implements [Hijacker], [znet.IdleSetter] and [znet.ConnMetrics].
 // If ServeTCP panics, the server (the caller of ServeTCP) assumes that
 // the effect of the panic was isolated to the active connections.
 // It recovers the panic, logs a stack trace to the server error log,
@@ -48,6 +52,31 @@ func (f HandlerFunc) ServeTCP(ctx context.Context, conn net.Conn) {
 	f(ctx, conn)
 }
 
+// Hijacker is implemented by the connections given to [Handler].
+// Hijack lets the handler take over the connection.
+// After Hijack, the [Server] no longer tracks nor closes the connection.
+// Hijacked connections are not waited by [Server.Shutdown]
+// and not closed by [Server.Close].
+// The returned connection is the one accepted from the listener.
+type Hijacker interface {
+	Hijack() (net.Conn, error)
+}
+
+// ErrHijacked is returned by [Hijacker.Hijack] when
+// the connection has already been hijacked or closed.
+var ErrHijacked = errors.New("znet/ztcp: connection already hijacked or closed")
+
+type tlsContextKey struct{}
+
+// TLSStateFromContext returns the TLS connection state stored in the context.
+// The [Server] stores the state to the context given to the [Handler]
+// when the connection is a TLS connection.
+// It returns nil if not found.
+func TLSStateFromContext(ctx context.Context) *tls.ConnectionState {
+	cs, _ := ctx.Value(tlsContextKey{}).(*tls.ConnectionState)
+	return cs
+}
+
 // Server is a TCP server.
 type Server struct {
 	// Addr is the address to listen to.
@@ -55,9 +84,18 @@ type Server struct {
 	// can be specified with the form of "<PREFIX>://<ADDRESS>".
 	// For example "tcp4://localhost:8080".
 	// "tcp" is assumed when no network prefix found.
+	// Inherited listeners can be used with "fd://<FD>" or
+	// "systemd://<NAME>". See [znet.ListenFile].
 	// Addr is used by [Server.ListenAndServe] and [Server.ListenAndServeTLS].
 	Addr string
 
+	// SockOption optionally specifies socket options applied to
+	// listeners created by [Server.ListenAndServe] and [Server.ListenAndServeTLS].
+	// Options are applied with [zsyscall.SockOptForTCP].
+	// It is not applied to inherited listeners.
+	// Presets such as [zsyscall.LowLatencySockOption] can be used.
+	SockOption *zsyscall.SockOption
+
 	// Handler to invoke.
 	// Handler must not be nil.
 	Handler Handler
@@ -70,6 +108,24 @@ type Server struct {
 	// SetSessionTicketKeys, use Server.Serve with a TLS Listener instead.
 	TLSConfig *tls.Config
 
+	// TLSHandshakeTimeout is the maximum duration for
+	// TLS handshake of incoming TLS connections.
+	// The Server completes the handshake before calling the handler
+	// and closes connections that failed the handshake.
+	// They are counted as rejected connections in [Server.Stats].
+	// If zero, 10 seconds is used. If negative, there is no timeout.
+	TLSHandshakeTimeout time.Duration
+
+	// ALPNHandlers optionally specifies handlers for each
+	// application protocol negotiated by TLS ALPN.
+	// When the negotiated protocol is found in the map, the
+	// handler is called instead of the Handler.
+	// Keys of the map are added to the TLSConfig.NextProtos
+	// by ServeTLS and ListenAndServeTLS if not present.
+	// For example, "h2" can be served with another handler
+	// on the same port with a custom protocol.
+	ALPNHandlers map[string]Handler
+
 	// BaseContext optionally specifies a function that returns
 	// the base context for incoming requests on this server.
 	// The provided Listener is the specific Listener that's
@@ -84,10 +140,35 @@ type Server struct {
 	// the PanicHandler. It bypasses default logging of stacktraces.
 	PanicHandler func(recovered any, remote, local net.Addr)
 
+	// ConnState specifies an optional callback function that is
+	// called when a client connection changes state.
+	// The conn implements [znet.ConnMetrics] that reports the
+	// bytes read and written and the time it was accepted.
+	// See the [znet.ConnState] type for details.
+	ConnState func(conn net.Conn, state znet.ConnState)
+
+	// SourceLimit optionally limits the concurrent connections
+	// and the accept rate for each client source.
+	// Listeners given to [Server.Serve] and [Server.ServeTLS] are wrapped with
+	// [znet.SourceLimitListener.Wrap], so the limits are applied
+	// to each listener separately. The Listener field is not used.
+	// For ServeTLS, the limits are applied before the TLS handshake.
+	// Connections rejected by the SourceLimit are reported to
+	// its OnReject and not counted in [Server.Stats].
+	SourceLimit *znet.SourceLimitListener
+
 	shutdown  atomic.Bool
 	listeners internal.CloserStore[*ocListener]
 	conns     internal.CloserStore[*ocConn]
 
+	// Connection counters.
+	// See [Server.Stats].
+	accepted atomic.Uint64
+	rejected atomic.Uint64
+	hijacked atomic.Uint64
+	active   atomic.Int64
+	idle     atomic.Int64
+
 	// serveNotify notifies the inner listener is working
 	// and the Server.Serve is called.
 	// serveNotify is used for testing.
@@ -103,7 +184,7 @@ func (s *Server) ListenAndServe() error {
 	if s.shutdown.Load() {
 		return net.ErrClosed
 	}
-	ln, err := newListener(s.Addr)
+	ln, err := newListener(s.Addr, s.SockOption.ListenConfig(zsyscall.SockOptForTCP))
 	if err != nil {
 		return err
 	}
@@ -124,7 +205,7 @@ func (s *Server) ListenAndServeTLS(certFile, keyFile string) error {
 	if s.shutdown.Load() {
 		return net.ErrClosed
 	}
-	ln, err := newListener(s.Addr)
+	ln, err := newListener(s.Addr, s.SockOption.ListenConfig(zsyscall.SockOptForTCP))
 	if err != nil {
 		return err
 	}
@@ -132,8 +213,10 @@ func (s *Server) ListenAndServeTLS(certFile, keyFile string) error {
 }
 
 // ServeTLS accepts incoming connections on the Listener l, creating a new
-// service goroutine for each. The service goroutines perform TLS setup and then
-// read requests, calling s.Handler to reply to them.
+// service goroutine for each. The service goroutines perform TLS handshake and then
+// read requests, calling s.Handler or one of s.ALPNHandlers to reply to them.
+// The negotiated TLS connection state can be obtained from the context
+// given to the handlers with [TLSStateFromContext].
 //
 // Filenames containing a certificate and matching private key for the server
 // must be provided if neither the Server's TLSConfig.Certificates nor TLSConfig.GetCertificate
@@ -159,8 +242,17 @@ func (s *Server) ServeTLS(l net.Listener, certFile, keyFile string) error {
 		}
 		config.Certificates = append(config.Certificates, cert)
 	}
+	for _, proto := range slices.Sorted(maps.Keys(s.ALPNHandlers)) {
+		if !slices.Contains(config.NextProtos, proto) {
+			config.NextProtos = append(config.NextProtos, proto)
+		}
+	}
+	if s.SourceLimit != nil {
+		// Apply limits to the raw connections before TLS handshake.
+		l = s.SourceLimit.Wrap(l)
+	}
 	l = tls.NewListener(l, config)
-	return s.Serve(l)
+	return s.serveListener(l)
 }
 
 // Serve accepts incoming connections on the Listener l, creating a new
@@ -170,6 +262,15 @@ func (s *Server) ServeTLS(l net.Listener, certFile, keyFile string) error {
 // Serve always returns a non-nil error.
 // After [Server.Shutdown] or [Server.Close], the returned error is [net.ErrClosed].
 func (s *Server) Serve(l net.Listener) error {
+	if s.SourceLimit != nil {
+		l = s.SourceLimit.Wrap(l)
+	}
+	return s.serveListener(l)
+}
+
+// serveListener accepts incoming connections on the Listener l.
+// Unlike [Server.Serve], l is not wrapped with the s.SourceLimit.
+func (s *Server) serveListener(l net.Listener) error {
 	if s.shutdown.Load() {
 		return net.ErrClosed
 	}
@@ -196,6 +297,7 @@ func (s *Server) Serve(l net.Listener) error {
 		if err != nil {
 			if err == ErrSkipHandler {
 				_ = conn.Close()
+				s.rejected.Add(1)
 				continue
 			}
 			if s.shutdown.Load() { // Error is caused by shutdown.
@@ -231,13 +333,74 @@ func (s *Server) serve(ctx context.Context, conn net.Conn) {
 		}
 	}()
 
-	occ := &ocConn{Conn: conn}
+	occ := &ocConn{Conn: conn, srv: s, acceptedAt: time.Now()}
+	s.accepted.Add(1)
+	s.active.Add(1)
 	s.conns.Store(occ)
 	defer func() {
 		_ = occ.Close()
 		s.conns.Delete(occ) // Delete after close.
 	}()
-	s.Handler.ServeTCP(ctx, occ)
+	if cs := s.ConnState; cs != nil {
+		cs(occ, znet.StateNew)
+	}
+	h := s.Handler
+	if tc, ok := tlsConn(conn); ok {
+		if err := s.handshake(ctx, tc); err != nil {
+			s.rejected.Add(1)
+			return
+		}
+		state := tc.ConnectionState()
+		ctx = context.WithValue(ctx, tlsContextKey{}, &state)
+		if ah, ok := s.ALPNHandlers[state.NegotiatedProtocol]; ok && state.NegotiatedProtocol != "" {
+			h = ah
+		}
+	}
+	occ.setState(znet.StateActive)
+	h.ServeTCP(ctx, occ)
+}
+
+// tlsConn returns the [tls.Conn] of the conn.
+// It unwraps the connections that have NetConn method
+// such as the ones returned by the [znet.SourceLimitListener].
+func tlsConn(conn net.Conn) (*tls.Conn, bool) {
+	for conn != nil {
+		if tc, ok := conn.(*tls.Conn); ok {
+			return tc, true
+		}
+		nc, ok := conn.(interface{ NetConn() net.Conn })
+		if !ok {
+			return nil, false
+		}
+		conn = nc.NetConn()
+	}
+	return nil, false
+}
+
+// handshake runs the TLS handshake of the conn
+// with the timeout of s.TLSHandshakeTimeout.
+func (s *Server) handshake(ctx context.Context, conn *tls.Conn) error {
+	timeout := s.TLSHandshakeTimeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return conn.HandshakeContext(ctx)
+}
+
+// Stats returns the statistics of connections.
+func (s *Server) Stats() znet.ConnStats {
+	return znet.ConnStats{
+		Accepted: s.accepted.Load(),
+		Rejected: s.rejected.Load(),
+		Hijacked: s.hijacked.Load(),
+		Active:   s.active.Load(),
+		Idle:     s.idle.Load(),
+	}
 }
 
 // Close immediately closes all active net.Listeners and any connections.
@@ -256,8 +419,9 @@ func (s *Server) Close() error {
 }
 
 // Shutdown gracefully shuts down the server without interrupting any active connections.
-// Shutdown works by first closing all open [net.Listener]s,
-// and then waiting for connections to be closed and then shut down.
+// Shutdown works by first closing all open [net.Listener]s, then closing all idle
+// connections, and then waiting for connections to be closed and then shut down.
+// Connections marked idle by [znet.IdleSetter] while waiting are also closed.
 // If the provided context expires before the shutdown is complete, Shutdown returns
 // the context's error, otherwise it returns all errors returned from closing the
 // Server's underlying Listener(s). Non-nil errors occurred while shutting down
@@ -274,7 +438,11 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		return net.ErrClosed
 	}
 	err := s.listeners.CloseAll()
-	for s.conns.Length() > 0 {
+	for {
+		_ = s.conns.CloseIf((*ocConn).isIdle)
+		if s.conns.Length() == 0 {
+			break
+		}
 		select {
 		case <-time.After(100 * time.Millisecond):
 		case <-ctx.Done():
@@ -284,7 +452,10 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	return err
 }
 
-func newListener(addr string) (ln net.Listener, err error) {
+func newListener(addr string, lc *net.ListenConfig) (ln net.Listener, err error) {
+	if znet.IsFileAddr(addr) {
+		return znet.ListenFile(addr)
+	}
 	network, address := znet.ParseNetAddr(addr)
 	switch network {
 	case "":
@@ -295,12 +466,11 @@ func newListener(addr string) (ln net.Listener, err error) {
 		if resolvErr != nil {
 			return nil, resolvErr
 		}
-		return net.ListenTCP(network, laddr)
+		return lc.Listen(context.Background(), network, laddr.String())
 	case "unix", "unixpacket":
-		laddr := &net.UnixAddr{Name: address, Net: network}
-		return net.ListenUnix(network, laddr)
+		return lc.Listen(context.Background(), network, address)
 	default:
-		return net.Listen("tcp", addr) // Fallback. May be invalid addr.
+		return lc.Listen(context.Background(), "tcp", addr) // Fallback. May be invalid addr.
 	}
 }
 
@@ -330,15 +500,116 @@ func (oc *ocListener) Close() error {
 
 // ocConn is once close Conn that wraps a [net.Conn],
 // protecting it from multiple Close calls.
+// ocConn also tracks the state and the number of bytes
+// read and written of the connection.
 type ocConn struct {
 	net.Conn
 	once     sync.Once
 	closeErr error
+
+	srv        *Server // srv is the server that accepted the conn. It can be nil.
+	acceptedAt time.Time
+	state      atomic.Int32 // [znet.ConnState]
+	read       atomic.Int64
+	written    atomic.Int64
+}
+
+func (oc *ocConn) Read(b []byte) (int, error) {
+	n, err := oc.Conn.Read(b)
+	oc.read.Add(int64(n))
+	return n, err
+}
+
+func (oc *ocConn) Write(b []byte) (int, error) {
+	n, err := oc.Conn.Write(b)
+	oc.written.Add(int64(n))
+	return n, err
 }
 
 func (oc *ocConn) Close() error {
+	if znet.ConnState(oc.state.Load()) == znet.StateHijacked {
+		return nil // Hijacked conn is managed by the handler.
+	}
 	oc.once.Do(func() {
 		oc.closeErr = oc.Conn.Close()
+		oc.setState(znet.StateClosed)
 	})
 	return oc.closeErr
 }
+
+// NetConn returns the underlying connection.
+// Unlike Hijack, the returned connection is still managed by the server.
+func (oc *ocConn) NetConn() net.Conn {
+	return oc.Conn
+}
+
+func (oc *ocConn) BytesRead() int64 {
+	return oc.read.Load()
+}
+
+func (oc *ocConn) BytesWritten() int64 {
+	return oc.written.Load()
+}
+
+func (oc *ocConn) AcceptedAt() time.Time {
+	return oc.acceptedAt
+}
+
+func (oc *ocConn) SetIdle(idle bool) {
+	if idle {
+		oc.setState(znet.StateIdle)
+	} else {
+		oc.setState(znet.StateActive)
+	}
+}
+
+func (oc *ocConn) Hijack() (net.Conn, error) {
+	if !oc.setState(znet.StateHijacked) {
+		return nil, ErrHijacked
+	}
+	if oc.srv != nil {
+		oc.srv.conns.Delete(oc)
+	}
+	return oc.Conn, nil
+}
+
+func (oc *ocConn) isIdle() bool {
+	return znet.ConnState(oc.state.Load()) == znet.StateIdle
+}
+
+// setState changes the state of the conn.
+// It updates counters of the server and calls the ConnState hook.
+// It returns false if the state was not changed.
+// Terminal states, hijacked and closed, are never changed.
+func (oc *ocConn) setState(to znet.ConnState) bool {
+	var from znet.ConnState
+	for {
+		from = znet.ConnState(oc.state.Load())
+		if from == to || from == znet.StateHijacked || from == znet.StateClosed {
+			return false
+		}
+		if oc.state.CompareAndSwap(int32(from), int32(to)) {
+			break
+		}
+	}
+	s := oc.srv
+	if s == nil {
+		return true
+	}
+	if from == znet.StateIdle {
+		s.idle.Add(-1)
+	}
+	switch to {
+	case znet.StateIdle:
+		s.idle.Add(1)
+	case znet.StateHijacked:
+		s.hijacked.Add(1)
+		s.active.Add(-1)
+	case znet.StateClosed:
+		s.active.Add(-1)
+	}
+	if cs := s.ConnState; cs != nil {
+		cs(oc, to)
+	}
+	return true
+}
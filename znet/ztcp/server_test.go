@@ -3,14 +3,18 @@ package ztcp
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"io"
 	"io/fs"
 	"net"
 	"os"
 	"path/filepath"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/aileron-projects/go/znet"
 	"github.com/aileron-projects/go/znet/internal"
 	"github.com/aileron-projects/go/ztesting"
 )
@@ -118,6 +122,72 @@ func TestServer_ServeTLS(t *testing.T) {
 	})
 }
 
+func TestServer_TLSHandshake(t *testing.T) {
+	t.Parallel()
+	// serve starts a TLS server and returns its address.
+	serve := func(t *testing.T, s *Server) string {
+		t.Helper()
+		ln, _ := net.Listen("tcp", "127.0.0.1:0")
+		go func() { _ = s.ServeTLS(ln, "./testdata/cert.pem", "./testdata/key.pem") }()
+		t.Cleanup(func() { s.Close() })
+		return ln.Addr().String()
+	}
+	// echoProto writes negotiated protocol obtained from the context.
+	echoProto := func(prefix string) Handler {
+		return HandlerFunc(func(ctx context.Context, conn net.Conn) {
+			cs := TLSStateFromContext(ctx)
+			_, _ = conn.Write([]byte(prefix + ":" + cs.NegotiatedProtocol))
+		})
+	}
+	testCases := map[string]struct {
+		protos []string
+		want   string
+	}{
+		"custom protocol": {[]string{"custom"}, "custom:custom"},
+		"h2":              {[]string{"h2"}, "h2:h2"},
+		"no alpn":         {nil, "default:"},
+	}
+	s := &Server{
+		Handler: echoProto("default"),
+		ALPNHandlers: map[string]Handler{
+			"custom": echoProto("custom"),
+			"h2":     echoProto("h2"),
+		},
+	}
+	addr := serve(t, s)
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true, NextProtos: tc.protos})
+			ztesting.AssertEqualErr(t, "dial error not match", nil, err)
+			defer conn.Close()
+			b, _ := io.ReadAll(conn)
+			ztesting.AssertEqual(t, "response not match", tc.want, string(b))
+		})
+	}
+
+	t.Run("handshake timeout", func(t *testing.T) {
+		handled := make(chan struct{}, 1)
+		s := &Server{
+			TLSHandshakeTimeout: 50 * time.Millisecond,
+			Handler:             HandlerFunc(func(context.Context, net.Conn) { handled <- struct{}{} }),
+		}
+		addr := serve(t, s)
+		conn, _ := net.Dial("tcp", addr) // Never start handshake.
+		defer conn.Close()
+		_, err := conn.Read(make([]byte, 1))
+		ztesting.AssertEqualErr(t, "connection must be closed", io.EOF, err)
+		for s.Stats().Rejected == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		ztesting.AssertEqual(t, "stats not match", znet.ConnStats{Accepted: 1, Rejected: 1}, s.Stats())
+		select {
+		case <-handled:
+			t.Error("handler must not be called")
+		default:
+		}
+	})
+}
+
 type timeoutError bool
 
 func (e timeoutError) Error() string {
@@ -219,6 +289,8 @@ func TestServer_Serve(t *testing.T) {
 		}()
 		err := s.Serve(ln)
 		ztesting.AssertEqual(t, "error not match", net.ErrClosed, err)
+		ztesting.AssertEqual(t, "rejected not match", true, s.Stats().Rejected > 0)
+		ztesting.AssertEqual(t, "accepted not match", uint64(0), s.Stats().Accepted)
 	})
 	t.Run("timeout error", func(t *testing.T) {
 		cn := &testConn{Conn: &net.TCPConn{}}
@@ -370,10 +442,183 @@ func TestServer_Shutdown(t *testing.T) {
 	})
 }
 
+// stateRecorder records connection states.
+type stateRecorder struct {
+	mu     sync.Mutex
+	states []znet.ConnState
+}
+
+func (r *stateRecorder) hook(_ net.Conn, state znet.ConnState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states = append(r.states, state)
+}
+
+func (r *stateRecorder) get() []znet.ConnState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]znet.ConnState{}, r.states...)
+}
+
+func TestServer_ConnState(t *testing.T) {
+	t.Parallel()
+	t.Run("idle closed by shutdown", func(t *testing.T) {
+		rec := &stateRecorder{}
+		metrics := make(chan znet.ConnMetrics, 1)
+		ln, _ := net.Listen("tcp", "127.0.0.1:0")
+		s := &Server{
+			ConnState: rec.hook,
+			Handler: HandlerFunc(func(ctx context.Context, conn net.Conn) {
+				metrics <- conn.(znet.ConnMetrics)
+				buf := make([]byte, 4)
+				_, _ = io.ReadFull(conn, buf)
+				_, _ = conn.Write([]byte("pong"))
+				conn.(znet.IdleSetter).SetIdle(true)
+				_, _ = conn.Read(buf) // Wait next request.
+			}),
+		}
+		errCh := make(chan error)
+		go func() { errCh <- s.Serve(ln) }()
+
+		conn, _ := net.Dial("tcp", ln.Addr().String())
+		defer conn.Close()
+		_, _ = conn.Write([]byte("ping"))
+		buf := make([]byte, 4)
+		_, _ = io.ReadFull(conn, buf)
+		ztesting.AssertEqual(t, "response not match", "pong", string(buf))
+		for s.Stats().Idle == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		ztesting.AssertEqual(t, "stats not match", znet.ConnStats{Accepted: 1, Active: 1, Idle: 1}, s.Stats())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		ztesting.AssertEqualErr(t, "shutdown error not match", nil, s.Shutdown(ctx))
+		ztesting.AssertEqualErr(t, "serve error not match", net.ErrClosed, <-errCh)
+
+		m := <-metrics
+		ztesting.AssertEqual(t, "bytes read not match", int64(4), m.BytesRead())
+		ztesting.AssertEqual(t, "bytes written not match", int64(4), m.BytesWritten())
+		ztesting.AssertEqual(t, "accepted time must be set", false, m.AcceptedAt().IsZero())
+		ztesting.AssertEqual(t, "stats not match", znet.ConnStats{Accepted: 1}, s.Stats())
+		want := []znet.ConnState{znet.StateNew, znet.StateActive, znet.StateIdle, znet.StateClosed}
+		ztesting.AssertEqual(t, "states not match", want, rec.get())
+	})
+	t.Run("hijack", func(t *testing.T) {
+		rec := &stateRecorder{}
+		hijacked := make(chan net.Conn, 1)
+		ln, _ := net.Listen("tcp", "127.0.0.1:0")
+		s := &Server{
+			ConnState: rec.hook,
+			Handler: HandlerFunc(func(ctx context.Context, conn net.Conn) {
+				c, err := conn.(Hijacker).Hijack()
+				ztesting.AssertEqualErr(t, "hijack error not match", nil, err)
+				_, err = conn.(Hijacker).Hijack()
+				ztesting.AssertEqualErr(t, "hijack error not match", ErrHijacked, err)
+				hijacked <- c
+			}),
+		}
+		errCh := make(chan error)
+		go func() { errCh <- s.Serve(ln) }()
+
+		conn, _ := net.Dial("tcp", ln.Addr().String())
+		defer conn.Close()
+		c := <-hijacked
+		defer c.Close()
+		for s.conns.Length() > 0 {
+			time.Sleep(time.Millisecond)
+		}
+		ztesting.AssertEqualErr(t, "close error not match", nil, s.Close())
+		ztesting.AssertEqualErr(t, "serve error not match", net.ErrClosed, <-errCh)
+
+		// Hijacked conn is not closed by the server.
+		_, _ = c.Write([]byte("test"))
+		buf := make([]byte, 4)
+		_, _ = io.ReadFull(conn, buf)
+		ztesting.AssertEqual(t, "response not match", "test", string(buf))
+		ztesting.AssertEqual(t, "stats not match", znet.ConnStats{Accepted: 1, Hijacked: 1}, s.Stats())
+		want := []znet.ConnState{znet.StateNew, znet.StateActive, znet.StateHijacked}
+		ztesting.AssertEqual(t, "states not match", want, rec.get())
+	})
+}
+
+func TestServer_SourceLimit(t *testing.T) {
+	t.Parallel()
+	rejected := make(chan error, 1)
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	s := &Server{
+		Handler: HandlerFunc(func(ctx context.Context, conn net.Conn) {
+			_, _ = conn.Write([]byte("ok"))
+			_, _ = conn.Read(make([]byte, 1)) // Wait client close.
+		}),
+		SourceLimit: &znet.SourceLimitListener{
+			MaxConns:      1,
+			RejectMessage: []byte("ng"),
+			OnReject:      func(_ net.Conn, err error) { rejected <- err },
+		},
+	}
+	errCh := make(chan error)
+	go func() { errCh <- s.Serve(ln) }()
+
+	conn1, _ := net.Dial("tcp", ln.Addr().String())
+	defer conn1.Close()
+	buf := make([]byte, 2)
+	_, _ = io.ReadFull(conn1, buf)
+	ztesting.AssertEqual(t, "response not match", "ok", string(buf))
+
+	conn2, _ := net.Dial("tcp", ln.Addr().String())
+	defer conn2.Close()
+	b, _ := io.ReadAll(conn2)
+	ztesting.AssertEqual(t, "response not match", "ng", string(b))
+	ztesting.AssertEqualErr(t, "reject reason not match", znet.ErrSourceConnLimit, <-rejected)
+
+	ztesting.AssertEqualErr(t, "close error not match", nil, s.Close())
+	ztesting.AssertEqualErr(t, "serve error not match", net.ErrClosed, <-errCh)
+	ztesting.AssertEqual(t, "stats not match", znet.ConnStats{Accepted: 1}, s.Stats())
+}
+
+func TestServer_SourceLimitTLS(t *testing.T) {
+	t.Parallel()
+	echoProto := HandlerFunc(func(ctx context.Context, conn net.Conn) {
+		cs := TLSStateFromContext(ctx)
+		_, _ = conn.Write([]byte("custom:" + cs.NegotiatedProtocol))
+	})
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	s := &Server{
+		Handler:             HandlerFunc(func(context.Context, net.Conn) {}),
+		ALPNHandlers:        map[string]Handler{"custom": echoProto},
+		TLSHandshakeTimeout: 50 * time.Millisecond,
+		SourceLimit:         &znet.SourceLimitListener{MaxConns: 1},
+	}
+	errCh := make(chan error)
+	go func() { errCh <- s.ServeTLS(ln, "./testdata/cert.pem", "./testdata/key.pem") }()
+
+	t.Run("alpn", func(t *testing.T) {
+		conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"custom"}})
+		ztesting.AssertEqualErr(t, "dial error not match", nil, err)
+		defer conn.Close()
+		b, _ := io.ReadAll(conn)
+		ztesting.AssertEqual(t, "response not match", "custom:custom", string(b))
+	})
+	t.Run("handshake timeout", func(t *testing.T) {
+		conn, _ := net.Dial("tcp", ln.Addr().String()) // Never start handshake.
+		defer conn.Close()
+		_, err := conn.Read(make([]byte, 1))
+		ztesting.AssertEqualErr(t, "connection must be closed", io.EOF, err)
+		for s.Stats().Rejected == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		ztesting.AssertEqual(t, "stats not match", znet.ConnStats{Accepted: 2, Rejected: 1}, s.Stats())
+	})
+
+	ztesting.AssertEqualErr(t, "close error not match", nil, s.Close())
+	ztesting.AssertEqualErr(t, "serve error not match", net.ErrClosed, <-errCh)
+}
+
 func TestNewListener(t *testing.T) {
 	t.Parallel()
 	t.Run("listen tcp without prefix", func(t *testing.T) {
-		ln, err := newListener(":0")
+		ln, err := newListener(":0", &net.ListenConfig{})
 		ztesting.AssertEqual(t, "non nil error returned", nil, err)
 		defer ln.Close()
 		cn, err := net.Dial("tcp", ln.Addr().String())
@@ -381,7 +626,7 @@ func TestNewListener(t *testing.T) {
 		cn.Close()
 	})
 	t.Run("listen tcp4 success", func(t *testing.T) {
-		ln, err := newListener("tcp4://:0")
+		ln, err := newListener("tcp4://:0", &net.ListenConfig{})
 		ztesting.AssertEqual(t, "non nil error returned", nil, err)
 		defer ln.Close()
 		cn, err := net.Dial("tcp4", ln.Addr().String())
@@ -389,13 +634,13 @@ func TestNewListener(t *testing.T) {
 		cn.Close()
 	})
 	t.Run("listen tcp4 failed", func(t *testing.T) {
-		_, err := newListener("tcp4://1234567890")
+		_, err := newListener("tcp4://1234567890", &net.ListenConfig{})
 		_, ok := err.(*net.AddrError)
 		ztesting.AssertEqual(t, "addr error should be returned", true, ok)
 	})
 	t.Run("listen unix success", func(t *testing.T) {
 		s := filepath.Join(os.TempDir(), "TestNewListener_test.sock") // Socket path must not be too long.
-		ln, err := newListener("unix://" + s)
+		ln, err := newListener("unix://"+s, &net.ListenConfig{})
 		ztesting.AssertEqual(t, "non nil error returned", nil, err)
 		cn, err := net.Dial("unix", s)
 		ztesting.AssertEqual(t, "dial failed", nil, err)
@@ -404,8 +649,18 @@ func TestNewListener(t *testing.T) {
 		_, err = os.Stat(s) // Check socket file removed
 		ztesting.AssertEqual(t, "socket not removed", true, os.IsNotExist(err))
 	})
+	t.Run("socket option error", func(t *testing.T) {
+		testErr := errors.New("control error")
+		lc := &net.ListenConfig{Control: func(string, string, syscall.RawConn) error { return testErr }}
+		_, err := newListener("tcp4://127.0.0.1:0", lc)
+		ztesting.AssertEqualErr(t, "error not match", testErr, err)
+	})
+	t.Run("listen systemd not found", func(t *testing.T) {
+		_, err := newListener("systemd://not-exist", &net.ListenConfig{})
+		ztesting.AssertEqualErr(t, "error not match", znet.ErrNoInheritedFile, err)
+	})
 	t.Run("fallback to tcp", func(t *testing.T) {
-		_, err := newListener("udp://1234567890")
+		_, err := newListener("udp://1234567890", &net.ListenConfig{})
 		_, ok := err.(*net.OpError)
 		t.Logf("%#v\n", err)
 		ztesting.AssertEqual(t, "net op error should be returned", true, ok)
@@ -0,0 +1,92 @@
+package ztcp
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"syscall"
+
+	"github.com/aileron-projects/go/znet/internal"
+	"github.com/aileron-projects/go/zsyscall"
+)
+
+// TransparentDialer dials to the original destination of downstream
+// connections for transparent proxies. Use [TransparentDialer.Dial] as
+// the [Proxy.Dial]. The original destination is obtained with
+// SO_ORIGINAL_DST when Redirect is true, which is the case for connections
+// redirected by iptables REDIRECT or DNAT targets. Otherwise, connections
+// are assumed to be accepted with iptables TPROXY target and the local
+// address of the downstream connection is used.
+// Listeners for TPROXY must have IP_TRANSPARENT socket option.
+// Use the zsyscall.TransparentProxySockOption for the [Server.SockOption].
+// Transparent proxies are supported only on Linux.
+//
+// Example:
+//
+//	svr := &ztcp.Server{
+//		Addr:       "0.0.0.0:15001",
+//		SockOption: zsyscall.TransparentProxySockOption(),
+//		Handler: &ztcp.Proxy{
+//			Dial: (&ztcp.TransparentDialer{SpoofSource: true}).Dial,
+//		},
+//	}
+//
+// Note that connections destined to the proxy itself
+// are proxied to the proxy itself.
+type TransparentDialer struct {
+	// Dialer is the dialer used to dial to the original destination.
+	// If nil, a zero [net.Dialer] is used.
+	Dialer *net.Dialer
+	// Redirect, if true, obtains the original destination
+	// with SO_ORIGINAL_DST socket option.
+	// It should be true for connections redirected by
+	// iptables REDIRECT or DNAT targets.
+	Redirect bool
+	// SpoofSource, if true, dials to the original destination
+	// from the IP address of the downstream client.
+	// The source port is chosen by the system.
+	// It requires CAP_NET_ADMIN capability and routing
+	// rules that deliver response packets to the proxy.
+	SpoofSource bool
+}
+
+// Dial dials to the original destination of the downstream connection dc.
+// The dc must be a [*net.TCPConn] or a connection that returns it with
+// NetConn method such as [crypto/tls.Conn] when Redirect is true,
+// otherwise [zsyscall.ErrNoOriginalDst] is returned.
+func (d *TransparentDialer) Dial(ctx context.Context, dc net.Conn) (net.Conn, error) {
+	dst, err := d.originalDst(dc)
+	if err != nil {
+		return nil, err
+	}
+	dialer := d.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	if d.SpoofSource {
+		src, err := netip.ParseAddrPort(dc.RemoteAddr().String())
+		if err != nil {
+			return nil, err
+		}
+		laddr := net.TCPAddrFromAddrPort(netip.AddrPortFrom(src.Addr(), 0))
+		dialer = internal.TransparentDialer(dialer, laddr)
+	}
+	return dialer.DialContext(ctx, "tcp", dst.String())
+}
+
+func (d *TransparentDialer) originalDst(dc net.Conn) (netip.AddrPort, error) {
+	if !d.Redirect {
+		return netip.ParseAddrPort(dc.LocalAddr().String())
+	}
+	for dc != nil {
+		if sc, ok := dc.(syscall.Conn); ok {
+			return zsyscall.OriginalDst(sc)
+		}
+		nc, ok := dc.(interface{ NetConn() net.Conn })
+		if !ok {
+			break
+		}
+		dc = nc.NetConn()
+	}
+	return netip.AddrPort{}, zsyscall.ErrNoOriginalDst
+}
@@ -0,0 +1,91 @@
+package ztcp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"runtime"
+	"syscall"
+	"testing"
+
+	"github.com/aileron-projects/go/zsyscall"
+	"github.com/aileron-projects/go/ztesting"
+)
+
+// addrConn is a [net.Conn] with the given addresses.
+type addrConn struct {
+	net.Conn
+	local, remote net.Addr
+}
+
+func (c *addrConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+func (c *addrConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func TestTransparentDialer(t *testing.T) {
+	t.Parallel()
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	ztesting.AssertEqualErr(t, "listen error", nil, err)
+	defer ln.Close()
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+
+	t.Run("tproxy", func(t *testing.T) {
+		dc := &addrConn{local: ln.Addr(), remote: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 12345}}
+		uc, err := (&TransparentDialer{}).Dial(context.Background(), dc)
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+		defer uc.Close()
+		ztesting.AssertEqual(t, "remote address not match", ln.Addr().String(), uc.RemoteAddr().String())
+	})
+	t.Run("tproxy with dialer", func(t *testing.T) {
+		testErr := errors.New("control error")
+		d := &TransparentDialer{
+			Dialer: &net.Dialer{Control: func(string, string, syscall.RawConn) error { return testErr }},
+		}
+		dc := &addrConn{local: ln.Addr(), remote: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 12345}}
+		uc, err := d.Dial(context.Background(), dc)
+		ztesting.AssertEqual(t, "conn should be nil", nil, uc)
+		ztesting.AssertEqualErr(t, "error not match", testErr, err)
+	})
+	t.Run("spoof source", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("transparent proxy is supported only on linux")
+		}
+		d := &TransparentDialer{SpoofSource: true}
+		dc := &addrConn{local: ln.Addr(), remote: &net.TCPAddr{IP: net.ParseIP("127.0.0.2"), Port: 12345}}
+		uc, err := d.Dial(context.Background(), dc)
+		if errors.Is(err, syscall.EPERM) {
+			t.Skip("CAP_NET_ADMIN is required")
+		}
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+		defer uc.Close()
+		ztesting.AssertEqual(t, "local address not match", "127.0.0.2", uc.LocalAddr().(*net.TCPAddr).IP.String())
+	})
+	t.Run("redirect non syscall conn", func(t *testing.T) {
+		d := &TransparentDialer{Redirect: true}
+		dc := &ocConn{Conn: &addrConn{local: ln.Addr()}}
+		uc, err := d.Dial(context.Background(), dc)
+		ztesting.AssertEqual(t, "conn should be nil", nil, uc)
+		ztesting.AssertEqualErr(t, "error not match", zsyscall.ErrNoOriginalDst, err)
+	})
+	t.Run("redirect not redirected", func(t *testing.T) {
+		c, err := net.Dial("tcp4", ln.Addr().String())
+		ztesting.AssertEqualErr(t, "dial error", nil, err)
+		defer c.Close()
+		d := &TransparentDialer{Redirect: true}
+		uc, err := d.Dial(context.Background(), &ocConn{Conn: c})
+		ztesting.AssertEqual(t, "conn should be nil", nil, uc)
+		ztesting.AssertEqual(t, "error should not be nil", true, err != nil)
+	})
+}
@@ -1,6 +1,7 @@
 package zudp
 
 import (
+	"cmp"
 	"net"
 	"sync"
 	"sync/atomic"
@@ -48,6 +49,14 @@ type conn struct {
 	// The entry must be deleted from the map when this connection was
 	// closed.
 	channels *sync.Map
+	// key is the key of channels.
+	// If empty, raddr.String() is used.
+	key string
+	// uc, if non-nil, is the connected socket that this connection
+	// writes to instead of pc. It is used by transparent proxies to
+	// send packets from the original destination address.
+	// uc is closed when this connection is closed.
+	uc net.Conn
 }
 
 func (c *conn) Read(b []byte) (n int, err error) {
@@ -62,10 +71,16 @@ func (c *conn) Write(b []byte) (n int, err error) {
 	if c.closed.Load() {
 		return 0, net.ErrClosed
 	}
+	if c.uc != nil {
+		return c.uc.Write(b)
+	}
 	return c.pc.WriteTo(b, c.raddr)
 }
 
 func (c *conn) LocalAddr() net.Addr {
+	if c.uc != nil {
+		return c.uc.LocalAddr()
+	}
 	return c.pc.LocalAddr()
 }
 
@@ -77,6 +92,9 @@ func (c *conn) Close() error {
 	if c.closed.Swap(true) {
 		return nil
 	}
-	c.channels.Delete(c.raddr.String())
+	c.channels.Delete(cmp.Or(c.key, c.raddr.String()))
+	if c.uc != nil {
+		return c.uc.Close()
+	}
 	return nil
 }
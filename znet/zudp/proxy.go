@@ -41,10 +41,29 @@ func NewProxy(targets ...string) *Proxy {
 		panic(ErrNoTarget)
 	}
 	return &Proxy{
-		Dial: (&roundRobinDialer{addrs: targets, index: -1}).dial,
+		Dial: RoundRobinDial(nil, targets...),
 	}
 }
 
+// RoundRobinDial returns a function for [Proxy.Dial] that dials to
+// the targets with round-robin algorithm using the dialer d.
+// The format of targets is the same as [NewProxy].
+// If d is nil, a zero [net.Dialer] is used.
+// Use the Dialer method of zsyscall.SockOption to apply socket options to
+// upstream connections as shown below.
+// If no targets specified, RoundRobinDial panics [ErrNoTarget].
+//
+//	opt := zsyscall.LowLatencySockOption()
+//	p := &Proxy{
+//		Dial: RoundRobinDial(opt.Dialer(zsyscall.SockOptForUDP), "127.0.0.1:8080"),
+//	}
+func RoundRobinDial(d *net.Dialer, targets ...string) func(ctx context.Context, dc Conn) (net.Conn, error) {
+	if len(targets) == 0 {
+		panic(ErrNoTarget)
+	}
+	return (&roundRobinDialer{dialer: d, addrs: targets, index: -1}).dial
+}
+
 // Proxy is the UDP proxy.
 type Proxy struct {
 	// Dial returns a new upstream connection dc for the
@@ -136,9 +155,10 @@ func copyBuf(dst io.Writer, src io.Reader, errChan chan<- error, active *atomic.
 // with round-robin algorithm.
 // It does not do any health checks.
 type roundRobinDialer struct {
-	mu    sync.Mutex
-	index int
-	addrs []string
+	mu     sync.Mutex
+	index  int
+	addrs  []string
+	dialer *net.Dialer // Zero dialer is used if nil.
 }
 
 func (d *roundRobinDialer) next() string {
@@ -151,7 +171,11 @@ func (d *roundRobinDialer) next() string {
 	return d.addrs[d.index]
 }
 
-func (d *roundRobinDialer) dial(_ context.Context, _ Conn) (net.Conn, error) {
+func (d *roundRobinDialer) dial(ctx context.Context, _ Conn) (net.Conn, error) {
+	dialer := d.dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
 	addr := d.next()
 	network, address := znet.ParseNetAddr(addr)
 	switch network {
@@ -160,12 +184,10 @@ func (d *roundRobinDialer) dial(_ context.Context, _ Conn) (net.Conn, error) {
 		if err != nil {
 			return nil, err
 		}
-		return net.DialUDP(network, nil, raddr)
+		return dialer.DialContext(ctx, network, raddr.String())
 	case "unix", "unixgram":
-		network = "unixgram"
-		raddr := &net.UnixAddr{Name: address, Net: network}
-		return net.DialUnix(network, nil, raddr)
+		return dialer.DialContext(ctx, "unixgram", address)
 	default:
-		return net.Dial("udp", addr) // Fallback.
+		return dialer.DialContext(ctx, "udp", addr) // Fallback.
 	}
 }
@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"cmp"
 	"context"
+	"errors"
 	"io"
 	"net"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
@@ -32,6 +34,25 @@ func TestNewProxy(t *testing.T) {
 	})
 }
 
+func TestRoundRobinDial(t *testing.T) {
+	t.Parallel()
+	t.Run("no targets", func(t *testing.T) {
+		defer func() {
+			r := recover()
+			ztesting.AssertEqual(t, "recovered value not match", r.(error), ErrNoTarget)
+		}()
+		_ = RoundRobinDial(nil)
+	})
+	t.Run("with dialer", func(t *testing.T) {
+		testErr := errors.New("control error")
+		d := &net.Dialer{Control: func(string, string, syscall.RawConn) error { return testErr }}
+		dial := RoundRobinDial(d, "udp4://127.0.0.1:12345")
+		conn, err := dial(context.Background(), nil)
+		ztesting.AssertEqual(t, "conn should be nil", nil, conn)
+		ztesting.AssertEqualErr(t, "error not match", testErr, err)
+	})
+}
+
 func TestRoundRobinDialer(t *testing.T) {
 	t.Parallel()
 	t.Run("test next", func(t *testing.T) {
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"log"
 	"net"
+	"net/netip"
 	"os"
 	"runtime"
 	"sync"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/aileron-projects/go/znet"
 	"github.com/aileron-projects/go/znet/internal"
+	"github.com/aileron-projects/go/zsyscall"
 )
 
 const mtu = 65535 // UDP maximum transmission unit
@@ -27,6 +29,10 @@ var (
 	// If ErrSkipHandler is returned from the net.Listener, received packet is
 	// immediately discarded and not provided to ServeUDP.
 	ErrSkipHandler = errors.New("znet/zudp: skip Handler")
+	// ErrNotTransparent is returned from [Server.Serve] when the
+	// transparent mode is enabled but the packet conn does not
+	// support reading the original destination of packets.
+	ErrNotTransparent = errors.New("znet/zudp: packet conn does not support transparent mode")
 )
 
 // A Handler responds to a UDP request.
@@ -34,6 +40,7 @@ var (
 // ServeUDP is invoked in a new goroutine for each new incoming connections.
 // A connection is created for each client's address:port pair.
 // ServeUDP does not need to close the connection because the [Server] ensures to.
+// The connection implements [znet.IdleSetter] and [znet.ConnMetrics].
 // If ServeUDP panics, the server (the caller of ServeUDP) assumes that
 // the effect of the panic was isolated to the active connections.
 // It recovers the panic, logs a stack trace to the server error log,
@@ -57,9 +64,33 @@ type Server struct {
 	// can be specified with the form of "<PREFIX>://<ADDRESS>".
 	// For example "udp4://localhost:8080".
 	// "udp" is assumed when no network prefix found.
+	// Inherited sockets can be used with "fd://<FD>" or
+	// "systemd://<NAME>". See [znet.ListenFilePacket].
 	// Addr is used by [Server.ListenAndServe].
 	Addr string
 
+	// SockOption optionally specifies socket options applied to
+	// packet conns created by [Server.ListenAndServe].
+	// Options are applied with [zsyscall.SockOptForUDP].
+	// It is not applied to inherited sockets.
+	// Presets such as [zsyscall.HighThroughputSockOption] can be used.
+	SockOption *zsyscall.SockOption
+
+	// Transparent, if true, enables the transparent proxy mode for
+	// packets delivered by iptables TPROXY target.
+	// Connections are created for each pair of the source address and
+	// the original destination address of packets. LocalAddr of the
+	// connections returns the original destination address and
+	// the response packets are sent from the address.
+	// The packet conn must be a [*net.UDPConn] with IP_TRANSPARENT
+	// and IP_RECVORIGDSTADDR socket options, or their IPV6 variants
+	// for IPv6 sockets, otherwise [Server.Serve]
+	// returns [ErrNotTransparent]. Use [zsyscall.TransparentProxySockOption]
+	// for the SockOption. Packets without the original destination are
+	// discarded and counted as rejected.
+	// Transparent mode is supported only on Linux.
+	Transparent bool
+
 	// Handler to invoke.
 	// Handler must not be nil.
 	Handler Handler
@@ -78,10 +109,25 @@ type Server struct {
 	// the PanicHandler. It bypasses default logging of stacktraces.
 	PanicHandler func(recovered any, local, remote net.Addr)
 
+	// ConnState specifies an optional callback function that is
+	// called when a client connection changes state.
+	// The conn implements [znet.ConnMetrics] that reports the
+	// bytes read and written and the time it was accepted.
+	// UDP connections are never hijacked.
+	// See the [znet.ConnState] type for details.
+	ConnState func(conn Conn, state znet.ConnState)
+
 	shutdown    atomic.Bool
 	packetConns internal.CloserStore[*ocPacketConn]
 	conns       internal.CloserStore[*ocConn]
 
+	// Connection counters.
+	// See [Server.Stats].
+	accepted atomic.Uint64
+	rejected atomic.Uint64
+	active   atomic.Int64
+	idle     atomic.Int64
+
 	// serveNotify notifies the inner listener is working
 	// and the Server.Serve is called.
 	// serveNotify is used for testing.
@@ -92,7 +138,7 @@ func (s *Server) ListenAndServe() error {
 	if s.shutdown.Load() {
 		return net.ErrClosed
 	}
-	pc, err := newPacketConn(s.Addr)
+	pc, err := newPacketConn(s.Addr, s.SockOption.ListenConfig(zsyscall.SockOptForUDP))
 	if err != nil {
 		return err
 	}
@@ -109,6 +155,10 @@ func (s *Server) Serve(p net.PacketConn) error {
 	if s.shutdown.Load() {
 		return net.ErrClosed
 	}
+	mr, ok := p.(msgReader)
+	if s.Transparent && !ok {
+		return ErrNotTransparent
+	}
 
 	ctx := context.Background()
 	if bc := s.BaseContext; bc != nil {
@@ -127,12 +177,22 @@ func (s *Server) Serve(p net.PacketConn) error {
 	}
 
 	var channels sync.Map
+	var dst netip.AddrPort // Original destination of the packet in transparent mode.
+	readFrom := ocp.ReadFrom
+	if s.Transparent {
+		oob := make([]byte, 64) // Enough for a sockaddr_in6.
+		readFrom = func(b []byte) (n int, addr net.Addr, err error) {
+			n, addr, dst, err = readOrigDst(mr, b, oob)
+			return n, addr, err
+		}
+	}
 	wait := int64(1)
 	buf := make([]byte, mtu)
 	for {
-		n, addr, err := ocp.ReadFrom(buf)
+		n, addr, err := readFrom(buf)
 		if err != nil {
 			if err == ErrSkipHandler {
+				s.rejected.Add(1)
 				continue
 			}
 			if s.shutdown.Load() { // Error is caused by shutdown.
@@ -140,18 +200,20 @@ func (s *Server) Serve(p net.PacketConn) error {
 			}
 		}
 		if n > 0 {
-			c, isNew := getChannel(&channels, addr.String())
-			if isNew {
-				conn := &conn{pc: p, raddr: addr, packets: c, channels: &channels}
-				go s.serve(ctx, p.LocalAddr(), addr, conn)
+			key := addr.String()
+			if s.Transparent {
+				key += "-" + dst.String()
 			}
-			packet := make([]byte, n)
-			copy(packet, buf[:n])
-			select {
-			case c <- packet:
-			default:
-				// Channel is full. Discard the packet.
+			c, isNew := getChannel(&channels, key)
+			if isNew {
+				conn := &conn{pc: p, raddr: addr, packets: c, channels: &channels, key: key}
+				if s.Transparent {
+					go s.serveTransparent(ctx, dst, conn, c)
+				} else {
+					go s.serve(ctx, conn.LocalAddr(), addr, conn)
+				}
 			}
+			sendPacket(c, buf[:n])
 			wait = 1 // Reset
 		}
 		if err != nil {
@@ -183,15 +245,32 @@ func (s *Server) serve(ctx context.Context, laddr, raddr net.Addr, conn *conn) {
 		}
 	}()
 
-	occ := &ocConn{Conn: conn}
+	occ := &ocConn{Conn: conn, srv: s, acceptedAt: time.Now()}
+	s.accepted.Add(1)
+	s.active.Add(1)
 	s.conns.Store(occ)
 	defer func() {
 		_ = occ.Close()
 		s.conns.Delete(occ) // Delete after close.
 	}()
+	if cs := s.ConnState; cs != nil {
+		cs(occ, znet.StateNew)
+	}
+	occ.setState(znet.StateActive)
 	s.Handler.ServeUDP(ctx, occ)
 }
 
+// Stats returns the statistics of connections.
+// Rejected is the number of packets skipped by [ErrSkipHandler].
+func (s *Server) Stats() znet.ConnStats {
+	return znet.ConnStats{
+		Accepted: s.accepted.Load(),
+		Rejected: s.rejected.Load(),
+		Active:   s.active.Load(),
+		Idle:     s.idle.Load(),
+	}
+}
+
 // Close immediately closes all active [net.PacketConn] and any connections.
 // For a graceful shutdown, use [Server.Shutdown].
 //
@@ -208,8 +287,9 @@ func (s *Server) Close() error {
 }
 
 // Shutdown gracefully shuts down the server without interrupting any active connections.
-// Shutdown works by first closing all open [net.PacketConn]s,
-// and then waiting for connections to be closed and then shut down.
+// Shutdown works by first closing all open [net.PacketConn]s, then closing all idle
+// connections, and then waiting for connections to be closed and then shut down.
+// Connections marked idle by [znet.IdleSetter] while waiting are also closed.
 // If the provided context expires before the shutdown is complete, Shutdown returns
 // the context's error, otherwise it returns all errors returned from closing the
 // Server's underlying PacketConn(s). Non-nil errors occurred while shutting down
@@ -226,7 +306,11 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		return net.ErrClosed
 	}
 	err := s.packetConns.CloseAll()
-	for s.conns.Length() > 0 {
+	for {
+		_ = s.conns.CloseIf((*ocConn).isIdle)
+		if s.conns.Length() == 0 {
+			break
+		}
 		select {
 		case <-time.After(100 * time.Millisecond):
 		case <-ctx.Done():
@@ -236,7 +320,10 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	return err
 }
 
-func newPacketConn(addr string) (pc net.PacketConn, err error) {
+func newPacketConn(addr string, lc *net.ListenConfig) (pc net.PacketConn, err error) {
+	if znet.IsFileAddr(addr) {
+		return znet.ListenFilePacket(addr)
+	}
 	network, address := znet.ParseNetAddr(addr)
 	switch network {
 	case "":
@@ -247,12 +334,11 @@ func newPacketConn(addr string) (pc net.PacketConn, err error) {
 		if resolvErr != nil {
 			return nil, resolvErr
 		}
-		return net.ListenUDP(network, laddr)
+		return lc.ListenPacket(context.Background(), network, laddr.String())
 	case "unixgram":
-		laddr := &net.UnixAddr{Name: address, Net: network}
-		return net.ListenUnixgram(network, laddr)
+		return lc.ListenPacket(context.Background(), network, address)
 	default:
-		return net.ListenPacket("udp", addr) // Fallback. May be invalid addr.
+		return lc.ListenPacket(context.Background(), "udp", addr) // Fallback. May be invalid addr.
 	}
 }
 
@@ -282,19 +368,110 @@ func (oc *ocPacketConn) Close() error {
 
 // ocConn is once close Conn that wraps a [Conn],
 // protecting it from multiple Close calls.
+// ocConn also tracks the state and the number of bytes
+// read and written of the connection.
 type ocConn struct {
 	Conn
 	once     sync.Once
 	closeErr error
+
+	srv        *Server // srv is the server that accepted the conn. It can be nil.
+	acceptedAt time.Time
+	state      atomic.Int32 // [znet.ConnState]
+	read       atomic.Int64
+	written    atomic.Int64
+}
+
+func (oc *ocConn) Read(b []byte) (int, error) {
+	n, err := oc.Conn.Read(b)
+	oc.read.Add(int64(n))
+	return n, err
+}
+
+func (oc *ocConn) Write(b []byte) (int, error) {
+	n, err := oc.Conn.Write(b)
+	oc.written.Add(int64(n))
+	return n, err
 }
 
 func (oc *ocConn) Close() error {
 	oc.once.Do(func() {
 		oc.closeErr = oc.Conn.Close()
+		oc.setState(znet.StateClosed)
 	})
 	return oc.closeErr
 }
 
+func (oc *ocConn) BytesRead() int64 {
+	return oc.read.Load()
+}
+
+func (oc *ocConn) BytesWritten() int64 {
+	return oc.written.Load()
+}
+
+func (oc *ocConn) AcceptedAt() time.Time {
+	return oc.acceptedAt
+}
+
+func (oc *ocConn) SetIdle(idle bool) {
+	if idle {
+		oc.setState(znet.StateIdle)
+	} else {
+		oc.setState(znet.StateActive)
+	}
+}
+
+func (oc *ocConn) isIdle() bool {
+	return znet.ConnState(oc.state.Load()) == znet.StateIdle
+}
+
+// setState changes the state of the conn.
+// It updates counters of the server and calls the ConnState hook.
+// It returns false if the state was not changed.
+// The terminal state, closed, is never changed.
+func (oc *ocConn) setState(to znet.ConnState) bool {
+	var from znet.ConnState
+	for {
+		from = znet.ConnState(oc.state.Load())
+		if from == to || from == znet.StateClosed {
+			return false
+		}
+		if oc.state.CompareAndSwap(int32(from), int32(to)) {
+			break
+		}
+	}
+	s := oc.srv
+	if s == nil {
+		return true
+	}
+	if from == znet.StateIdle {
+		s.idle.Add(-1)
+	}
+	switch to {
+	case znet.StateIdle:
+		s.idle.Add(1)
+	case znet.StateClosed:
+		s.active.Add(-1)
+	}
+	if cs := s.ConnState; cs != nil {
+		cs(oc, to)
+	}
+	return true
+}
+
+// sendPacket sends a copy of the packet to the channel c.
+// The packet is discarded if the channel is full.
+func sendPacket(c chan<- []byte, packet []byte) {
+	p := make([]byte, len(packet))
+	copy(p, packet)
+	select {
+	case c <- p:
+	default:
+		// Channel is full. Discard the packet.
+	}
+}
+
 // getChannel returns a bytes channel.
 // If a channel found in the store with the key addr, getChannel returns it and false.
 // If not found, getChannel creates a new byte channel and register it to the
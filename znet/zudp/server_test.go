@@ -2,13 +2,16 @@ package zudp
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net"
 	"os"
 	"sync"
+	"syscall"
 	"testing"
 	"time"
 
+	"github.com/aileron-projects/go/znet"
 	"github.com/aileron-projects/go/znet/internal"
 	"github.com/aileron-projects/go/ztesting"
 )
@@ -132,6 +135,7 @@ func TestServer_Serve(t *testing.T) {
 		}()
 		err := s.Serve(pc)
 		ztesting.AssertEqual(t, "error not match", net.ErrClosed, err)
+		ztesting.AssertEqual(t, "rejected not match", true, s.Stats().Rejected > 0)
 	})
 	t.Run("timeout error", func(t *testing.T) {
 		pc := &testPacketConn{PacketConn: dpc, raddr: &net.UDPAddr{}, readErr: timeoutError(true)}
@@ -284,10 +288,65 @@ func TestServer_Shutdown(t *testing.T) {
 	})
 }
 
+func TestServer_ConnState(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var states []znet.ConnState
+	closed := make(chan struct{})
+	metrics := make(chan znet.ConnMetrics, 1)
+	pc, _ := net.ListenPacket("udp", "127.0.0.1:0")
+	s := &Server{
+		ConnState: func(_ Conn, state znet.ConnState) {
+			mu.Lock()
+			defer mu.Unlock()
+			states = append(states, state)
+			if state == znet.StateClosed {
+				close(closed)
+			}
+		},
+		Handler: HandlerFunc(func(ctx context.Context, conn Conn) {
+			metrics <- conn.(znet.ConnMetrics)
+			buf := make([]byte, 64)
+			n, _ := conn.Read(buf)
+			_, _ = conn.Write(buf[:n])
+			conn.(znet.IdleSetter).SetIdle(true)
+			<-closed // Wait until closed by shutdown.
+		}),
+	}
+	errCh := make(chan error)
+	go func() { errCh <- s.Serve(pc) }()
+
+	conn, _ := net.Dial("udp", pc.LocalAddr().String())
+	defer conn.Close()
+	_, _ = conn.Write([]byte("test"))
+	buf := make([]byte, 64)
+	n, _ := conn.Read(buf)
+	ztesting.AssertEqual(t, "response not match", "test", string(buf[:n]))
+	for s.Stats().Idle == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	ztesting.AssertEqual(t, "stats not match", znet.ConnStats{Accepted: 1, Active: 1, Idle: 1}, s.Stats())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ztesting.AssertEqualErr(t, "shutdown error not match", nil, s.Shutdown(ctx))
+	ztesting.AssertEqualErr(t, "serve error not match", net.ErrClosed, <-errCh)
+
+	m := <-metrics
+	ztesting.AssertEqual(t, "bytes read not match", int64(4), m.BytesRead())
+	ztesting.AssertEqual(t, "bytes written not match", int64(4), m.BytesWritten())
+	ztesting.AssertEqual(t, "accepted time must be set", false, m.AcceptedAt().IsZero())
+	ztesting.AssertEqual(t, "stats not match", znet.ConnStats{Accepted: 1}, s.Stats())
+	mu.Lock()
+	defer mu.Unlock()
+	want := []znet.ConnState{znet.StateNew, znet.StateActive, znet.StateIdle, znet.StateClosed}
+	ztesting.AssertEqual(t, "states not match", want, states)
+}
+
 func TestNewPacketConn(t *testing.T) {
 	t.Parallel()
 	t.Run("udp without prefix", func(t *testing.T) {
-		ln, err := newPacketConn(":0")
+		ln, err := newPacketConn(":0", &net.ListenConfig{})
 		ztesting.AssertEqual(t, "non nil error returned", nil, err)
 		defer ln.Close()
 		cn, err := net.Dial("udp", ln.LocalAddr().String())
@@ -295,7 +354,7 @@ func TestNewPacketConn(t *testing.T) {
 		cn.Close()
 	})
 	t.Run("listen udp4 success", func(t *testing.T) {
-		ln, err := newPacketConn("udp4://:0")
+		ln, err := newPacketConn("udp4://:0", &net.ListenConfig{})
 		ztesting.AssertEqual(t, "non nil error returned", nil, err)
 		defer ln.Close()
 		cn, err := net.Dial("udp4", ln.LocalAddr().String())
@@ -303,19 +362,29 @@ func TestNewPacketConn(t *testing.T) {
 		cn.Close()
 	})
 	t.Run("listen udp4 failed", func(t *testing.T) {
-		_, err := newPacketConn("udp4://1234567890")
+		_, err := newPacketConn("udp4://1234567890", &net.ListenConfig{})
 		_, ok := err.(*net.AddrError)
 		ztesting.AssertEqual(t, "addr error should be returned", true, ok)
 	})
 	t.Run("listen unixgram", func(t *testing.T) {
 		s := t.TempDir() + "/not-exist/test.sock"
-		_, err := newPacketConn("unixgram://" + s) // Make error because windows not support it.
+		_, err := newPacketConn("unixgram://"+s, &net.ListenConfig{}) // Make error because windows not support it.
 		_, ok := err.(*net.OpError)
 		t.Logf("%#v\n", err)
 		ztesting.AssertEqual(t, "net op error should be returned", true, ok)
 	})
+	t.Run("socket option error", func(t *testing.T) {
+		testErr := errors.New("control error")
+		lc := &net.ListenConfig{Control: func(string, string, syscall.RawConn) error { return testErr }}
+		_, err := newPacketConn("udp4://127.0.0.1:0", lc)
+		ztesting.AssertEqualErr(t, "error not match", testErr, err)
+	})
+	t.Run("listen systemd not found", func(t *testing.T) {
+		_, err := newPacketConn("systemd://not-exist", &net.ListenConfig{})
+		ztesting.AssertEqualErr(t, "error not match", znet.ErrNoInheritedFile, err)
+	})
 	t.Run("fallback to udp", func(t *testing.T) {
-		_, err := newPacketConn("tcp://1234567890")
+		_, err := newPacketConn("tcp://1234567890", &net.ListenConfig{})
 		_, ok := err.(*net.OpError)
 		t.Logf("%#v\n", err)
 		ztesting.AssertEqual(t, "net op error should be returned", true, ok)
@@ -0,0 +1,123 @@
+package zudp
+
+import (
+	"context"
+	"net"
+	"net/netip"
+
+	"github.com/aileron-projects/go/znet/internal"
+	"github.com/aileron-projects/go/zsyscall"
+)
+
+// msgReader reads packets with control messages.
+// [*net.UDPConn] implements this interface.
+type msgReader interface {
+	ReadMsgUDPAddrPort(b, oob []byte) (n, oobn, flags int, addr netip.AddrPort, err error)
+}
+
+// readOrigDst reads a packet and its original destination address from mr.
+// The oob is the buffer for control messages.
+// It returns [ErrSkipHandler] when the original destination is not found.
+func readOrigDst(mr msgReader, b, oob []byte) (int, net.Addr, netip.AddrPort, error) {
+	n, oobn, _, src, err := mr.ReadMsgUDPAddrPort(b, oob)
+	if err != nil {
+		return 0, nil, netip.AddrPort{}, err
+	}
+	dst, err := zsyscall.ParseOrigDstAddr(oob[:oobn])
+	if err != nil {
+		return 0, nil, netip.AddrPort{}, ErrSkipHandler
+	}
+	return n, net.UDPAddrFromAddrPort(src), dst, nil
+}
+
+// dialOrigDst returns a UDP socket bound to the original destination
+// address dst and connected to the remote address raddr.
+// Packets from the raddr to the dst are delivered to the socket
+// after it is created.
+func dialOrigDst(ctx context.Context, dst netip.AddrPort, raddr net.Addr) (net.Conn, error) {
+	d := internal.TransparentDialer(nil, net.UDPAddrFromAddrPort(dst))
+	return d.DialContext(ctx, "udp", raddr.String())
+}
+
+// serveTransparent creates the socket for the conn with [dialOrigDst]
+// and then serves the conn. It is called in a new goroutine for each
+// conn so that the read loop of [Server.Serve] is not blocked while
+// creating the socket. Packets received in the meantime are buffered
+// in the channel c. The conn is discarded and counted as rejected
+// when the socket cannot be created.
+func (s *Server) serveTransparent(ctx context.Context, dst netip.AddrPort, conn *conn, c chan<- []byte) {
+	uc, err := dialOrigDst(ctx, dst, conn.raddr)
+	if err != nil {
+		conn.channels.Delete(conn.key)
+		s.rejected.Add(1)
+		return
+	}
+	conn.uc = uc
+	go readConn(uc, c)
+	s.serve(ctx, conn.LocalAddr(), conn.raddr, conn)
+}
+
+// readConn reads packets from the conn and send them to the channel c
+// until the conn is closed.
+func readConn(conn net.Conn, c chan<- []byte) {
+	buf := make([]byte, mtu)
+	for {
+		n, err := conn.Read(buf)
+		if n > 0 {
+			sendPacket(c, buf[:n])
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// TransparentDialer dials to the original destination of downstream
+// connections for transparent proxies. Use [TransparentDialer.Dial] as
+// the [Proxy.Dial] of servers with the transparent mode enabled.
+// See [Server.Transparent].
+// Transparent proxies are supported only on Linux.
+//
+// Example:
+//
+//	svr := &zudp.Server{
+//		Addr:        "0.0.0.0:15001",
+//		SockOption:  zsyscall.TransparentProxySockOption(),
+//		Transparent: true,
+//		Handler: &zudp.Proxy{
+//			Dial: (&zudp.TransparentDialer{SpoofSource: true}).Dial,
+//		},
+//	}
+type TransparentDialer struct {
+	// Dialer is the dialer used to dial to the original destination.
+	// If nil, a zero [net.Dialer] is used.
+	Dialer *net.Dialer
+	// SpoofSource, if true, dials to the original destination
+	// from the IP address of the downstream client.
+	// The source port is chosen by the system.
+	// It requires CAP_NET_ADMIN capability and routing
+	// rules that deliver response packets to the proxy.
+	SpoofSource bool
+}
+
+// Dial dials to the original destination of the downstream connection dc,
+// which is the local address of the dc.
+func (d *TransparentDialer) Dial(ctx context.Context, dc Conn) (net.Conn, error) {
+	dst, err := netip.ParseAddrPort(dc.LocalAddr().String())
+	if err != nil {
+		return nil, err
+	}
+	dialer := d.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	if d.SpoofSource {
+		src, err := netip.ParseAddrPort(dc.RemoteAddr().String())
+		if err != nil {
+			return nil, err
+		}
+		laddr := net.UDPAddrFromAddrPort(netip.AddrPortFrom(src.Addr(), 0))
+		dialer = internal.TransparentDialer(dialer, laddr)
+	}
+	return dialer.DialContext(ctx, "udp", dst.String())
+}
@@ -0,0 +1,133 @@
+package zudp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/zsyscall"
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestServer_Transparent(t *testing.T) {
+	t.Parallel()
+	t.Run("not transparent conn", func(t *testing.T) {
+		pc, _ := net.ListenPacket("udp", "127.0.0.1:0")
+		defer pc.Close()
+		s := &Server{Transparent: true}
+		err := s.Serve(&testPacketConn{PacketConn: pc})
+		ztesting.AssertEqualErr(t, "error not match", ErrNotTransparent, err)
+	})
+	t.Run("no original destination", func(t *testing.T) {
+		pc, _ := net.ListenPacket("udp4", "127.0.0.1:0")
+		s := &Server{
+			Transparent: true,
+			Handler:     HandlerFunc(func(_ context.Context, _ Conn) { t.Error("handler must not be called") }),
+		}
+		errCh := make(chan error)
+		go func() { errCh <- s.Serve(pc) }()
+		conn, _ := net.Dial("udp4", pc.LocalAddr().String())
+		defer conn.Close()
+		_, _ = conn.Write([]byte("test"))
+		for s.Stats().Rejected == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		s.Close()
+		ztesting.AssertEqualErr(t, "error not match", net.ErrClosed, <-errCh)
+	})
+	t.Run("echo", func(t *testing.T) {
+		testTransparentEcho(t, "udp4", "127.0.0.1:0")
+	})
+	t.Run("echo ipv6", func(t *testing.T) {
+		if pc, err := net.ListenPacket("udp6", "[::1]:0"); err != nil {
+			t.Skip("ipv6 is not available")
+		} else {
+			pc.Close()
+		}
+		testTransparentEcho(t, "udp6", "[::1]:0")
+	})
+}
+
+// testTransparentEcho tests the transparent mode with an echo handler
+// listening on the address.
+func testTransparentEcho(t *testing.T, network, address string) {
+	t.Helper()
+	if runtime.GOOS != "linux" {
+		t.Skip("transparent proxy is supported only on linux")
+	}
+	lc := zsyscall.TransparentProxySockOption().ListenConfig(zsyscall.SockOptForUDP)
+	pc, err := lc.ListenPacket(context.Background(), network, address)
+	if errors.Is(err, syscall.EPERM) {
+		t.Skip("CAP_NET_ADMIN is required")
+	}
+	ztesting.AssertEqualErr(t, "listen error", nil, err)
+	s := &Server{
+		Transparent: true,
+		Handler: HandlerFunc(func(_ context.Context, conn Conn) {
+			ztesting.AssertEqual(t, "local address not match", pc.LocalAddr().String(), conn.LocalAddr().String())
+			buf := make([]byte, 64)
+			for {
+				n, _ := conn.Read(buf)
+				if _, err := conn.Write(buf[:n]); err != nil {
+					return
+				}
+			}
+		}),
+	}
+	errCh := make(chan error)
+	go func() { errCh <- s.Serve(pc) }()
+
+	conn, _ := net.Dial(network, pc.LocalAddr().String())
+	defer conn.Close()
+	buf := make([]byte, 64)
+	for _, msg := range []string{"foo", "bar"} {
+		_, _ = conn.Write([]byte(msg))
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, err := conn.Read(buf)
+		ztesting.AssertEqualErr(t, "read error", nil, err)
+		ztesting.AssertEqual(t, "response not match", msg, string(buf[:n]))
+	}
+	ztesting.AssertEqual(t, "accepted not match", uint64(1), s.Stats().Accepted)
+	s.Close()
+	ztesting.AssertEqualErr(t, "error not match", net.ErrClosed, <-errCh)
+}
+
+func TestTransparentDialer(t *testing.T) {
+	t.Parallel()
+	up, _ := net.ListenPacket("udp4", "127.0.0.1:0")
+	defer up.Close()
+	t.Run("dial", func(t *testing.T) {
+		dc := &conn{pc: up, raddr: &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 12345}}
+		uc, err := (&TransparentDialer{}).Dial(context.Background(), dc)
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+		defer uc.Close()
+		ztesting.AssertEqual(t, "remote address not match", up.LocalAddr().String(), uc.RemoteAddr().String())
+	})
+	t.Run("dial with dialer", func(t *testing.T) {
+		testErr := errors.New("control error")
+		d := &TransparentDialer{
+			Dialer: &net.Dialer{Control: func(string, string, syscall.RawConn) error { return testErr }},
+		}
+		dc := &conn{pc: up, raddr: &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: 12345}}
+		uc, err := d.Dial(context.Background(), dc)
+		ztesting.AssertEqual(t, "conn should be nil", nil, uc)
+		ztesting.AssertEqualErr(t, "error not match", testErr, err)
+	})
+	t.Run("spoof source", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("transparent proxy is supported only on linux")
+		}
+		dc := &conn{pc: up, raddr: &net.UDPAddr{IP: net.ParseIP("127.0.0.2"), Port: 12345}}
+		uc, err := (&TransparentDialer{SpoofSource: true}).Dial(context.Background(), dc)
+		if errors.Is(err, syscall.EPERM) {
+			t.Skip("CAP_NET_ADMIN is required")
+		}
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+		defer uc.Close()
+		ztesting.AssertEqual(t, "local address not match", "127.0.0.2", uc.LocalAddr().(*net.UDPAddr).IP.String())
+	})
+}
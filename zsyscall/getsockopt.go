@@ -0,0 +1,85 @@
+package zsyscall
+
+import (
+	"cmp"
+	"syscall"
+	"time"
+)
+
+// ReadSockOption reads socket options currently applied to the conn.
+// Only the options specified by opts are read.
+// See [SockOption.ControlFunc] for opts.
+// For example, [*net.TCPConn], [*net.UDPConn] and [*net.TCPListener]
+// can be given as the conn.
+//
+// ReadSockOption can be used to verify the values actually applied
+// by the kernel. For example, Linux doubles the value of SO_RCVBUF.
+// Options that are not supported by the socket or the kernel are
+// left zero. Options that cannot be read such as SO_RCVBUFFORCE
+// and SO_SNDBUFFORCE are always zero.
+// On Windows, only the options that can be set on Windows are read.
+// On other platforms, it returns [errors.ErrUnsupported].
+func ReadSockOption(conn syscall.Conn, opts uint) (*SockOption, error) {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var o *SockOption
+	ctrErr := rc.Control(func(fd uintptr) {
+		o, err = readSockOption(fd, opts)
+	})
+	if err := cmp.Or(err, ctrErr); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// TCPInfo is the statistics of a TCP connection.
+// It is a subset of struct tcp_info of Linux.
+// Fields that are not supported by the kernel are left zero.
+//   - https://man7.org/linux/man-pages/man7/tcp.7.html
+type TCPInfo struct {
+	State        uint8         // tcpi_state. e.g. 1 for TCP_ESTABLISHED.
+	CAState      uint8         // tcpi_ca_state. Congestion control state.
+	Retransmits  uint8         // tcpi_retransmits. Number of unrecovered RTO timeouts.
+	Probes       uint8         // tcpi_probes. Number of unanswered zero window probes.
+	Backoff      uint8         // tcpi_backoff. Exponential backoff counter of RTO.
+	RTO          time.Duration // tcpi_rto. Retransmission timeout.
+	SndMSS       uint32        // tcpi_snd_mss. Sender maximum segment size.
+	RcvMSS       uint32        // tcpi_rcv_mss. Receiver maximum segment size.
+	Unacked      uint32        // tcpi_unacked. Number of unacknowledged segments.
+	Lost         uint32        // tcpi_lost. Number of segments considered lost.
+	Retrans      uint32        // tcpi_retrans. Number of retransmitted segments in flight.
+	PMTU         uint32        // tcpi_pmtu. Path MTU.
+	RTT          time.Duration // tcpi_rtt. Smoothed round trip time.
+	RTTVar       time.Duration // tcpi_rttvar. Variance of the round trip time.
+	SndSsthresh  uint32        // tcpi_snd_ssthresh. Slow start threshold.
+	SndCwnd      uint32        // tcpi_snd_cwnd. Congestion window in segments.
+	TotalRetrans uint32        // tcpi_total_retrans. Total number of retransmitted segments.
+	PacingRate   uint64        // tcpi_pacing_rate. Pacing rate in bytes per second.
+	BytesAcked   uint64        // tcpi_bytes_acked. Number of bytes acknowledged.
+	BytesRecv    uint64        // tcpi_bytes_received. Number of bytes received.
+	SegsOut      uint32        // tcpi_segs_out. Number of segments sent.
+	SegsIn       uint32        // tcpi_segs_in. Number of segments received.
+	MinRTT       time.Duration // tcpi_min_rtt. Minimum round trip time observed.
+	DeliveryRate uint64        // tcpi_delivery_rate. Delivery rate in bytes per second.
+}
+
+// ReadTCPInfo reads the statistics of the TCP connection
+// using TCP_INFO socket option. Typically, the conn is a [*net.TCPConn].
+// ReadTCPInfo is supported only on Linux.
+// On other platforms, it returns [errors.ErrUnsupported].
+func ReadTCPInfo(conn syscall.Conn) (*TCPInfo, error) {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	var info *TCPInfo
+	ctrErr := rc.Control(func(fd uintptr) {
+		info, err = readTCPInfo(fd)
+	})
+	if err := cmp.Or(err, ctrErr); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
@@ -0,0 +1,254 @@
+//go:build linux
+
+package zsyscall
+
+import (
+	"errors"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+const (
+	TCP_INFO = 0xb
+	IFNAMSIZ = 0x10
+)
+
+var (
+	// getsockoptInt and getsockopt are the re-mapped
+	// functions of getsockopt for testing.
+	getsockoptInt = syscall.GetsockoptInt
+	getsockopt    = rawGetsockopt
+)
+
+// sockReader reads socket options.
+// It keeps the first error occurred.
+// Options that are not supported by the socket or the kernel are ignored.
+type sockReader struct {
+	fd  int
+	err error
+}
+
+func (r *sockReader) handle(err error, opts string) {
+	if err == nil || errors.Is(err, syscall.ENOPROTOOPT) || errors.Is(err, syscall.EOPNOTSUPP) {
+		return
+	}
+	r.err = &SocketError{Err: err, Opts: opts}
+}
+
+func (r *sockReader) int(level, opt int, opts string) int {
+	if r.err != nil {
+		return 0
+	}
+	v, err := getsockoptInt(r.fd, level, opt)
+	r.handle(err, opts)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+func (r *sockReader) bool(level, opt int, opts string) bool {
+	return r.int(level, opt, opts) != 0
+}
+
+func (r *sockReader) string(level, opt int, opts string) string {
+	if r.err != nil {
+		return ""
+	}
+	var buf [IFNAMSIZ]byte
+	n := uint32(len(buf))
+	err := getsockopt(r.fd, level, opt, unsafe.Pointer(&buf[0]), &n)
+	r.handle(err, opts)
+	if err != nil {
+		return ""
+	}
+	b := buf[:min(int(n), len(buf))]
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+// linger returns the linger seconds or 0 if disabled.
+func (r *sockReader) linger(level, opt int, opts string) int32 {
+	if r.err != nil {
+		return 0
+	}
+	var l syscall.Linger
+	n := uint32(unsafe.Sizeof(l))
+	err := getsockopt(r.fd, level, opt, unsafe.Pointer(&l), &n)
+	r.handle(err, opts)
+	if err != nil || l.Onoff == 0 {
+		return 0
+	}
+	return l.Linger
+}
+
+func (r *sockReader) timeval(level, opt int, opts string) time.Duration {
+	if r.err != nil {
+		return 0
+	}
+	var tv syscall.Timeval
+	n := uint32(unsafe.Sizeof(tv))
+	err := getsockopt(r.fd, level, opt, unsafe.Pointer(&tv), &n)
+	r.handle(err, opts)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(tv.Nano())
+}
+
+func readSockOption(fd uintptr, opts uint) (*SockOption, error) {
+	r := &sockReader{fd: int(fd)}
+	o := &SockOption{}
+	if opts&SockOptSO != 0 {
+		o.SO = &SockSOOption{
+			BindToIFindex:  r.int(syscall.SOL_SOCKET, SO_BINDTOIFINDEX, "SOL_SOCKET.SO_BINDTOIFINDEX"),
+			BindToDevice:   r.string(syscall.SOL_SOCKET, syscall.SO_BINDTODEVICE, "SOL_SOCKET.SO_BINDTODEVICE"),
+			Debug:          r.bool(syscall.SOL_SOCKET, syscall.SO_DEBUG, "SOL_SOCKET.SO_DEBUG"),
+			KeepAlive:      r.bool(syscall.SOL_SOCKET, syscall.SO_KEEPALIVE, "SOL_SOCKET.SO_KEEPALIVE"),
+			Linger:         r.linger(syscall.SOL_SOCKET, syscall.SO_LINGER, "SOL_SOCKET.SO_LINGER"),
+			Mark:           r.int(syscall.SOL_SOCKET, syscall.SO_MARK, "SOL_SOCKET.SO_MARK"),
+			ReceiveBuffer:  r.int(syscall.SOL_SOCKET, syscall.SO_RCVBUF, "SOL_SOCKET.SO_RCVBUF"),
+			ReceiveTimeout: r.timeval(syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, "SOL_SOCKET.SO_RCVTIMEO"),
+			SendTimeout:    r.timeval(syscall.SOL_SOCKET, syscall.SO_SNDTIMEO, "SOL_SOCKET.SO_SNDTIMEO"),
+			ReuseAddr:      r.bool(syscall.SOL_SOCKET, syscall.SO_REUSEADDR, "SOL_SOCKET.SO_REUSEADDR"),
+			ReusePort:      r.bool(syscall.SOL_SOCKET, SO_REUSEPORT, "SOL_SOCKET.SO_REUSEPORT"),
+			SendBuffer:     r.int(syscall.SOL_SOCKET, syscall.SO_SNDBUF, "SOL_SOCKET.SO_SNDBUF"),
+		}
+	}
+	if opts&SockOptIP != 0 {
+		portRange := uint32(r.int(syscall.IPPROTO_IP, IP_LOCAL_PORT_RANGE, "IPPROTO_IP.IP_LOCAL_PORT_RANGE"))
+		o.IP = &SockIPOption{
+			BindAddressNoPort:   r.bool(syscall.IPPROTO_IP, IP_BIND_ADDRESS_NO_PORT, "IPPROTO_IP.IP_BIND_ADDRESS_NO_PORT"),
+			FreeBind:            r.bool(syscall.IPPROTO_IP, syscall.IP_FREEBIND, "IPPROTO_IP.IP_FREEBIND"),
+			LocalPortRangeUpper: uint16(portRange >> 16),
+			LocalPortRangeLower: uint16(portRange),
+			RecvOrigDstAddr:     r.bool(syscall.IPPROTO_IP, IP_RECVORIGDSTADDR, "IPPROTO_IP.IP_RECVORIGDSTADDR"),
+			Transparent:         r.bool(syscall.IPPROTO_IP, syscall.IP_TRANSPARENT, "IPPROTO_IP.IP_TRANSPARENT"),
+			TTL:                 r.int(syscall.IPPROTO_IP, syscall.IP_TTL, "IPPROTO_IP.IP_TTL"),
+		}
+	}
+	if opts&SockOptIPV6 != 0 {
+		o.IPV6 = &SockIPV6Option{
+			RecvOrigDstAddr: r.bool(syscall.IPPROTO_IPV6, IPV6_RECVORIGDSTADDR, "IPPROTO_IPV6.IPV6_RECVORIGDSTADDR"),
+			Transparent:     r.bool(syscall.IPPROTO_IPV6, IPV6_TRANSPARENT, "IPPROTO_IPV6.IPV6_TRANSPARENT"),
+			V6Only:          r.bool(syscall.IPPROTO_IPV6, syscall.IPV6_V6ONLY, "IPPROTO_IPV6.IPV6_V6ONLY"),
+		}
+	}
+	if opts&SockOptTCP != 0 {
+		o.TCP = &SockTCPOption{
+			CORK:            r.bool(syscall.IPPROTO_TCP, syscall.TCP_CORK, "IPPROTO_TCP.TCP_CORK"),
+			DeferAccept:     r.int(syscall.IPPROTO_TCP, syscall.TCP_DEFER_ACCEPT, "IPPROTO_TCP.TCP_DEFER_ACCEPT"),
+			KeepCount:       r.int(syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT, "IPPROTO_TCP.TCP_KEEPCNT"),
+			KeepIdle:        r.int(syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE, "IPPROTO_TCP.TCP_KEEPIDLE"),
+			KeepInterval:    r.int(syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL, "IPPROTO_TCP.TCP_KEEPINTVL"),
+			Linger2:         int32(r.int(syscall.IPPROTO_TCP, syscall.TCP_LINGER2, "IPPROTO_TCP.TCP_LINGER2")),
+			MaxSegment:      r.int(syscall.IPPROTO_TCP, syscall.TCP_MAXSEG, "IPPROTO_TCP.TCP_MAXSEG"),
+			NoDelay:         r.bool(syscall.IPPROTO_TCP, syscall.TCP_NODELAY, "IPPROTO_TCP.TCP_NODELAY"),
+			QuickAck:        r.bool(syscall.IPPROTO_TCP, syscall.TCP_QUICKACK, "IPPROTO_TCP.TCP_QUICKACK"),
+			SynCount:        r.int(syscall.IPPROTO_TCP, syscall.TCP_SYNCNT, "IPPROTO_TCP.TCP_SYNCNT"),
+			UserTimeout:     r.int(syscall.IPPROTO_TCP, TCP_USER_TIMEOUT, "IPPROTO_TCP.TCP_USER_TIMEOUT"),
+			WindowClamp:     r.int(syscall.IPPROTO_TCP, syscall.TCP_WINDOW_CLAMP, "IPPROTO_TCP.TCP_WINDOW_CLAMP"),
+			FastOpen:        r.bool(syscall.IPPROTO_TCP, TCP_FASTOPEN, "IPPROTO_TCP.TCP_FASTOPEN"),
+			FastOpenConnect: r.bool(syscall.IPPROTO_TCP, TCP_FASTOPEN_CONNECT, "IPPROTO_TCP.TCP_FASTOPEN_CONNECT"),
+		}
+	}
+	if opts&SockOptUDP != 0 {
+		o.UDP = &SockUDPOption{
+			CORK:    r.bool(syscall.IPPROTO_UDP, UDP_CORK, "IPPROTO_UDP.UDP_CORK"),
+			Segment: r.int(syscall.IPPROTO_UDP, UDP_SEGMENT, "IPPROTO_UDP.UDP_SEGMENT"),
+			GRO:     r.bool(syscall.IPPROTO_UDP, UDP_GRO, "IPPROTO_UDP.UDP_GRO"),
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return o, nil
+}
+
+// rawTCPInfo is the struct tcp_info defined in linux/tcp.h.
+// Fields after tcpi_delivery_rate are omitted.
+type rawTCPInfo struct {
+	State         uint8
+	CAState       uint8
+	Retransmits   uint8
+	Probes        uint8
+	Backoff       uint8
+	Options       uint8
+	WScale        uint8
+	Flags         uint8
+	RTO           uint32
+	ATO           uint32
+	SndMSS        uint32
+	RcvMSS        uint32
+	Unacked       uint32
+	Sacked        uint32
+	Lost          uint32
+	Retrans       uint32
+	Fackets       uint32
+	LastDataSent  uint32
+	LastAckSent   uint32
+	LastDataRecv  uint32
+	LastAckRecv   uint32
+	PMTU          uint32
+	RcvSsthresh   uint32
+	RTT           uint32
+	RTTVar        uint32
+	SndSsthresh   uint32
+	SndCwnd       uint32
+	AdvMSS        uint32
+	Reordering    uint32
+	RcvRTT        uint32
+	RcvSpace      uint32
+	TotalRetrans  uint32
+	PacingRate    uint64
+	MaxPacingRate uint64
+	BytesAcked    uint64
+	BytesReceived uint64
+	SegsOut       uint32
+	SegsIn        uint32
+	NotsentBytes  uint32
+	MinRTT        uint32
+	DataSegsIn    uint32
+	DataSegsOut   uint32
+	DeliveryRate  uint64
+}
+
+func readTCPInfo(fd uintptr) (*TCPInfo, error) {
+	var raw rawTCPInfo
+	n := uint32(unsafe.Sizeof(raw))
+	if err := getsockopt(int(fd), syscall.IPPROTO_TCP, TCP_INFO, unsafe.Pointer(&raw), &n); err != nil {
+		return nil, &SocketError{Err: err, Opts: "IPPROTO_TCP.TCP_INFO"}
+	}
+	us := func(v uint32) time.Duration { return time.Duration(v) * time.Microsecond }
+	return &TCPInfo{
+		State:        raw.State,
+		CAState:      raw.CAState,
+		Retransmits:  raw.Retransmits,
+		Probes:       raw.Probes,
+		Backoff:      raw.Backoff,
+		RTO:          us(raw.RTO),
+		SndMSS:       raw.SndMSS,
+		RcvMSS:       raw.RcvMSS,
+		Unacked:      raw.Unacked,
+		Lost:         raw.Lost,
+		Retrans:      raw.Retrans,
+		PMTU:         raw.PMTU,
+		RTT:          us(raw.RTT),
+		RTTVar:       us(raw.RTTVar),
+		SndSsthresh:  raw.SndSsthresh,
+		SndCwnd:      raw.SndCwnd,
+		TotalRetrans: raw.TotalRetrans,
+		PacingRate:   raw.PacingRate,
+		BytesAcked:   raw.BytesAcked,
+		BytesRecv:    raw.BytesReceived,
+		SegsOut:      raw.SegsOut,
+		SegsIn:       raw.SegsIn,
+		MinRTT:       us(raw.MinRTT),
+		DeliveryRate: raw.DeliveryRate,
+	}, nil
+}
@@ -0,0 +1,22 @@
+//go:build linux && 386
+
+package zsyscall
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// sysGetsockopt is the call number of socketcall for getsockopt.
+const sysGetsockopt = 15
+
+// rawGetsockopt calls getsockopt system call.
+// Linux on 386 multiplexes socket system calls through socketcall.
+func rawGetsockopt(fd, level, opt int, val unsafe.Pointer, vallen *uint32) error {
+	args := [5]uintptr{uintptr(fd), uintptr(level), uintptr(opt), uintptr(val), uintptr(unsafe.Pointer(vallen))}
+	_, _, e := syscall.RawSyscall(syscall.SYS_SOCKETCALL, sysGetsockopt, uintptr(unsafe.Pointer(&args)), 0)
+	if e != 0 {
+		return e
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build linux && !386
+
+package zsyscall
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// rawGetsockopt calls getsockopt system call.
+func rawGetsockopt(fd, level, opt int, val unsafe.Pointer, vallen *uint32) error {
+	_, _, e := syscall.Syscall6(syscall.SYS_GETSOCKOPT, uintptr(fd), uintptr(level), uintptr(opt), uintptr(val), uintptr(unsafe.Pointer(vallen)), 0)
+	if e != 0 {
+		return e
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+//go:build linux
+
+package zsyscall
+
+import (
+	"io"
+	"net"
+	"syscall"
+	"testing"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+// tcpPair returns a connected pair of TCP connections.
+// The client connection is dialed with the opt.
+func tcpPair(t *testing.T, opt *SockOption) (client, server *net.TCPConn) {
+	t.Helper()
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	ztesting.AssertEqualErr(t, "listen error", nil, err)
+	defer ln.Close()
+	c, err := opt.Dialer(SockOptForTCP).Dial("tcp4", ln.Addr().String())
+	ztesting.AssertEqualErr(t, "dial error", nil, err)
+	s, err := ln.Accept()
+	ztesting.AssertEqualErr(t, "accept error", nil, err)
+	t.Cleanup(func() {
+		c.Close()
+		s.Close()
+	})
+	return c.(*net.TCPConn), s.(*net.TCPConn)
+}
+
+func TestReadSockOption(t *testing.T) {
+	t.Parallel()
+	t.Run("tcp", func(t *testing.T) {
+		opt := &SockOption{
+			SO:  &SockSOOption{KeepAlive: true, ReceiveBuffer: 1 << 16},
+			IP:  &SockIPOption{TTL: 42},
+			TCP: &SockTCPOption{NoDelay: true, UserTimeout: 1000},
+		}
+		conn, _ := tcpPair(t, opt)
+		got, err := ReadSockOption(conn, SockOptForTCP)
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+		ztesting.AssertEqual(t, "keep alive not match", true, got.SO.KeepAlive)
+		ztesting.AssertEqual(t, "receive buffer is smaller", true, got.SO.ReceiveBuffer >= 1<<16)
+		ztesting.AssertEqual(t, "ttl not match", 42, got.IP.TTL)
+		ztesting.AssertEqual(t, "no delay not match", true, got.TCP.NoDelay)
+		ztesting.AssertEqual(t, "user timeout not match", 1000, got.TCP.UserTimeout)
+		ztesting.AssertEqual(t, "ipv6 option must be read", true, got.IPV6 != nil)
+		ztesting.AssertEqual(t, "udp option must not be read", true, got.UDP == nil)
+	})
+	t.Run("udp", func(t *testing.T) {
+		opt := &SockOption{SO: &SockSOOption{ReuseAddr: true}}
+		conn, err := opt.Dialer(SockOptForUDP).Dial("udp4", "127.0.0.1:12345")
+		ztesting.AssertEqualErr(t, "dial error", nil, err)
+		defer conn.Close()
+		got, err := ReadSockOption(conn.(*net.UDPConn), SockOptForTCP|SockOptUDP)
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+		ztesting.AssertEqual(t, "reuse addr not match", true, got.SO.ReuseAddr)
+		ztesting.AssertEqual(t, "tcp option must be zero", SockTCPOption{}, *got.TCP)
+	})
+}
+
+func TestReadSockOption_getsockoptError(t *testing.T) {
+	defer func() {
+		getsockoptInt = syscall.GetsockoptInt // Reset
+	}()
+	getsockoptInt = func(fd, level, opt int) (int, error) {
+		return 0, syscall.EBADF
+	}
+	conn, _ := tcpPair(t, nil)
+	got, err := ReadSockOption(conn, SockOptSO)
+	ztesting.AssertEqual(t, "option should be nil", true, got == nil)
+	want := &SocketError{Opts: "SOL_SOCKET.SO_BINDTOIFINDEX"}
+	ztesting.AssertEqualErr(t, "error not match", want, err)
+}
+
+func TestReadTCPInfo(t *testing.T) {
+	t.Parallel()
+	t.Run("tcp", func(t *testing.T) {
+		client, server := tcpPair(t, nil)
+		_, _ = client.Write([]byte("test"))
+		_, _ = io.ReadFull(server, make([]byte, 4))
+		info, err := ReadTCPInfo(client)
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+		ztesting.AssertEqual(t, "state not match", uint8(1), info.State) // TCP_ESTABLISHED
+		ztesting.AssertEqual(t, "mss not set", true, info.SndMSS > 0)
+		ztesting.AssertEqual(t, "cwnd not set", true, info.SndCwnd > 0)
+		ztesting.AssertEqual(t, "rtt not set", true, info.RTT > 0)
+	})
+	t.Run("udp", func(t *testing.T) {
+		conn, _ := net.Dial("udp4", "127.0.0.1:12345")
+		defer conn.Close()
+		_, err := ReadTCPInfo(conn.(*net.UDPConn))
+		want := &SocketError{Opts: "IPPROTO_TCP.TCP_INFO"}
+		ztesting.AssertEqualErr(t, "error not match", want, err)
+	})
+}
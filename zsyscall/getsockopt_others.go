@@ -0,0 +1,15 @@
+//go:build !linux && !windows
+
+package zsyscall
+
+import (
+	"errors"
+)
+
+func readSockOption(_ uintptr, _ uint) (*SockOption, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func readTCPInfo(_ uintptr) (*TCPInfo, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,34 @@
+package zsyscall
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+type testSyscallConn struct {
+	rc  syscall.RawConn
+	err error
+}
+
+func (c *testSyscallConn) SyscallConn() (syscall.RawConn, error) {
+	return c.rc, c.err
+}
+
+func TestReadSockOption_error(t *testing.T) {
+	t.Parallel()
+	testErr := errors.New("test error")
+	o, err := ReadSockOption(&testSyscallConn{err: testErr}, SockOptForTCP)
+	ztesting.AssertEqual(t, "option should be nil", true, o == nil)
+	ztesting.AssertEqualErr(t, "error not match", testErr, err)
+}
+
+func TestReadTCPInfo_error(t *testing.T) {
+	t.Parallel()
+	testErr := errors.New("test error")
+	info, err := ReadTCPInfo(&testSyscallConn{err: testErr})
+	ztesting.AssertEqual(t, "info should be nil", true, info == nil)
+	ztesting.AssertEqualErr(t, "error not match", testErr, err)
+}
@@ -0,0 +1,101 @@
+//go:build windows
+
+package zsyscall
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	// getsockoptInt and getsockopt are the re-mapped
+	// functions of getsockopt for testing.
+	getsockoptInt = syscall.GetsockoptInt
+	getsockopt    = syscall.Getsockopt
+)
+
+// sockReader reads socket options.
+// It keeps the first error occurred.
+// Options that are not supported by the socket are ignored.
+type sockReader struct {
+	fd  syscall.Handle
+	err error
+}
+
+func (r *sockReader) handle(err error, opts string) {
+	if err == nil || errors.Is(err, syscall.WSAENOPROTOOPT) {
+		return
+	}
+	r.err = &SocketError{Err: err, Opts: opts}
+}
+
+func (r *sockReader) int(level, opt int, opts string) int {
+	if r.err != nil {
+		return 0
+	}
+	v, err := getsockoptInt(r.fd, level, opt)
+	r.handle(err, opts)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+func (r *sockReader) bool(level, opt int, opts string) bool {
+	return r.int(level, opt, opts) != 0
+}
+
+// linger returns the linger seconds or 0 if disabled.
+func (r *sockReader) linger(level, opt int, opts string) int32 {
+	if r.err != nil {
+		return 0
+	}
+	var l syscall.Linger
+	n := int32(unsafe.Sizeof(l))
+	err := getsockopt(r.fd, int32(level), int32(opt), (*byte)(unsafe.Pointer(&l)), &n)
+	r.handle(err, opts)
+	if err != nil || l.Onoff == 0 {
+		return 0
+	}
+	return l.Linger
+}
+
+func readSockOption(fd uintptr, opts uint) (*SockOption, error) {
+	r := &sockReader{fd: syscall.Handle(fd)}
+	o := &SockOption{}
+	if opts&SockOptSO != 0 {
+		o.SO = &SockSOOption{
+			Debug:         r.bool(syscall.SOL_SOCKET, SO_DEBUG, "SOL_SOCKET.SO_DEBUG"),
+			KeepAlive:     r.bool(syscall.SOL_SOCKET, syscall.SO_KEEPALIVE, "SOL_SOCKET.SO_KEEPALIVE"),
+			Linger:        r.linger(syscall.SOL_SOCKET, syscall.SO_LINGER, "SOL_SOCKET.SO_LINGER"),
+			ReceiveBuffer: r.int(syscall.SOL_SOCKET, syscall.SO_RCVBUF, "SOL_SOCKET.SO_RCVBUF"),
+			ReuseAddr:     r.bool(syscall.SOL_SOCKET, syscall.SO_REUSEADDR, "SOL_SOCKET.SO_REUSEADDR"),
+			SendBuffer:    r.int(syscall.SOL_SOCKET, syscall.SO_SNDBUF, "SOL_SOCKET.SO_SNDBUF"),
+		}
+	}
+	if opts&SockOptIP != 0 {
+		o.IP = &SockIPOption{
+			TTL: r.int(syscall.IPPROTO_IP, syscall.IP_TTL, "IPPROTO_IP.IP_TTL"),
+		}
+	}
+	if opts&SockOptIPV6 != 0 {
+		o.IPV6 = &SockIPV6Option{}
+	}
+	if opts&SockOptTCP != 0 {
+		o.TCP = &SockTCPOption{
+			NoDelay: r.bool(syscall.IPPROTO_TCP, syscall.TCP_NODELAY, "IPPROTO_TCP.TCP_NODELAY"),
+		}
+	}
+	if opts&SockOptUDP != 0 {
+		o.UDP = &SockUDPOption{}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return o, nil
+}
+
+func readTCPInfo(_ uintptr) (*TCPInfo, error) {
+	return nil, errors.ErrUnsupported
+}
@@ -0,0 +1,38 @@
+//go:build windows
+
+package zsyscall
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestReadSockOption(t *testing.T) {
+	t.Parallel()
+	opt := &SockOption{
+		SO:  &SockSOOption{KeepAlive: true},
+		TCP: &SockTCPOption{NoDelay: true},
+	}
+	ln, _ := net.Listen("tcp4", "127.0.0.1:0")
+	defer ln.Close()
+	conn, err := opt.Dialer(SockOptForTCP).Dial("tcp4", ln.Addr().String())
+	ztesting.AssertEqualErr(t, "dial error", nil, err)
+	defer conn.Close()
+	got, err := ReadSockOption(conn.(*net.TCPConn), SockOptForTCP)
+	ztesting.AssertEqualErr(t, "error not match", nil, err)
+	ztesting.AssertEqual(t, "keep alive not match", true, got.SO.KeepAlive)
+	ztesting.AssertEqual(t, "no delay not match", true, got.TCP.NoDelay)
+}
+
+func TestReadTCPInfo(t *testing.T) {
+	t.Parallel()
+	ln, _ := net.Listen("tcp4", "127.0.0.1:0")
+	defer ln.Close()
+	conn, _ := net.Dial("tcp4", ln.Addr().String())
+	defer conn.Close()
+	_, err := ReadTCPInfo(conn.(*net.TCPConn))
+	ztesting.AssertEqualErr(t, "error not match", errors.ErrUnsupported, err)
+}
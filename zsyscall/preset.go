@@ -0,0 +1,62 @@
+package zsyscall
+
+// LowLatencySockOption returns a new socket option
+// preset for latency sensitive connections.
+// It disables Nagle's algorithm with TCP_NODELAY and
+// delayed acknowledgement with TCP_QUICKACK.
+// Use [SockOptForTCP] to apply the options.
+func LowLatencySockOption() *SockOption {
+	return &SockOption{
+		SO: &SockSOOption{
+			KeepAlive: true,
+		},
+		TCP: &SockTCPOption{
+			NoDelay:  true,
+			QuickAck: true,
+		},
+	}
+}
+
+// HighThroughputSockOption returns a new socket option
+// preset for bulk data transfer.
+// It enlarges socket send and receive buffers to 4MiB.
+// Note that the actual buffer size is limited by the system
+// configuration such as net.core.rmem_max and net.core.wmem_max
+// on Linux. Use [SockOptForTCP] or [SockOptForUDP] to apply the options.
+func HighThroughputSockOption() *SockOption {
+	return &SockOption{
+		SO: &SockSOOption{
+			KeepAlive:     true,
+			ReceiveBuffer: 4 << 20,
+			SendBuffer:    4 << 20,
+		},
+	}
+}
+
+// TransparentProxySockOption returns a new socket option
+// preset for transparent proxies.
+// It enables IP_TRANSPARENT, IPV6_TRANSPARENT and IP_FREEBIND so that
+// the sockets can accept connections destined to non-local addresses
+// and dial upstreams from the client's address.
+// It also enables IP_RECVORIGDSTADDR and IPV6_RECVORIGDSTADDR so that
+// UDP sockets can learn the original destination of packets with [ParseOrigDstAddr].
+// IPV6 options are applied only to IPv6 sockets.
+// IP_TRANSPARENT and IPV6_TRANSPARENT require CAP_NET_ADMIN capability.
+// This preset works only on Linux.
+// Use [SockOptForTCP] or [SockOptForUDP] to apply the options.
+func TransparentProxySockOption() *SockOption {
+	return &SockOption{
+		SO: &SockSOOption{
+			ReuseAddr: true,
+		},
+		IP: &SockIPOption{
+			FreeBind:        true,
+			Transparent:     true,
+			RecvOrigDstAddr: true,
+		},
+		IPV6: &SockIPV6Option{
+			Transparent:     true,
+			RecvOrigDstAddr: true,
+		},
+	}
+}
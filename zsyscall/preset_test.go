@@ -0,0 +1,38 @@
+package zsyscall
+
+import (
+	"testing"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestPresets(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		preset func() *SockOption
+		check  func(*SockOption) bool
+	}{
+		"low latency": {
+			LowLatencySockOption,
+			func(o *SockOption) bool { return o.TCP.NoDelay && o.TCP.QuickAck },
+		},
+		"high throughput": {
+			HighThroughputSockOption,
+			func(o *SockOption) bool { return o.SO.ReceiveBuffer > 0 && o.SO.SendBuffer > 0 },
+		},
+		"transparent proxy": {
+			TransparentProxySockOption,
+			func(o *SockOption) bool {
+				return o.IP.Transparent && o.IP.FreeBind && o.IP.RecvOrigDstAddr &&
+					o.IPV6.Transparent && o.IPV6.RecvOrigDstAddr
+			},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			o1, o2 := tc.preset(), tc.preset()
+			ztesting.AssertEqual(t, "preset not match", true, tc.check(o1))
+			ztesting.AssertEqual(t, "preset must return new option", true, o1 != o2)
+		})
+	}
+}
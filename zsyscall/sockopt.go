@@ -3,6 +3,9 @@ package zsyscall
 import (
 	"cmp"
 	"errors"
+	"net"
+	"slices"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -15,6 +18,15 @@ const (
 	SockOptUDP
 )
 
+const (
+	// SockOptForTCP enables options that can be
+	// applied to TCP sockets.
+	SockOptForTCP = SockOptSO | SockOptIP | SockOptIPV6 | SockOptTCP
+	// SockOptForUDP enables options that can be
+	// applied to UDP sockets.
+	SockOptForUDP = SockOptSO | SockOptIP | SockOptIPV6 | SockOptUDP
+)
+
 var (
 	setsockoptInt     = syscall.SetsockoptInt
 	setsockoptLinger  = syscall.SetsockoptLinger
@@ -45,13 +57,16 @@ type SockOption struct {
 // Only specified options are enabled.
 // For example, pass the [SockOptSO] and [SockOptIP] to enable
 // both options as shown below.
+// IPV6 options are not applied to IPv4 sockets, which have
+// the network of "tcp4", "udp4" or "ip4", so that the same
+// options can be used for both IPv4 and IPv6 sockets.
 //
 //	ControlFunc(zsyscall.SockOptSO|zsyscall.SockOptIP)
 func (o *SockOption) ControlFunc(opts uint) ControlFunc {
 	if o == nil {
 		return nil
 	}
-	var cs []Controller
+	var cs, cs6 []Controller
 	if o.SO != nil && opts&SockOptSO != 0 {
 		cs = append(cs, o.SO.Controllers()...)
 	}
@@ -59,7 +74,7 @@ func (o *SockOption) ControlFunc(opts uint) ControlFunc {
 		cs = append(cs, o.IP.Controllers()...)
 	}
 	if o.IPV6 != nil && opts&SockOptIPV6 != 0 {
-		cs = append(cs, o.IPV6.Controllers()...)
+		cs6 = append(cs6, o.IPV6.Controllers()...)
 	}
 	if o.TCP != nil && opts&SockOptTCP != 0 {
 		cs = append(cs, o.TCP.Controllers()...)
@@ -67,10 +82,35 @@ func (o *SockOption) ControlFunc(opts uint) ControlFunc {
 	if o.UDP != nil && opts&SockOptUDP != 0 {
 		cs = append(cs, o.UDP.Controllers()...)
 	}
-	if len(cs) == 0 {
-		return nil
+	if len(cs6) == 0 {
+		if len(cs) == 0 {
+			return nil
+		}
+		return controllers(cs).control
 	}
-	return controllers(cs).control
+	all := controllers(append(slices.Clone(cs), cs6...))
+	return func(network, address string, conn syscall.RawConn) error {
+		if strings.HasSuffix(network, "4") {
+			return controllers(cs).control(network, address, conn)
+		}
+		return all.control(network, address, conn)
+	}
+}
+
+// ListenConfig returns a new [net.ListenConfig] that applies
+// the options specified by opts to listening sockets.
+// See [SockOption.ControlFunc] for opts.
+// The returned config can be used even when o is nil.
+func (o *SockOption) ListenConfig(opts uint) *net.ListenConfig {
+	return &net.ListenConfig{Control: o.ControlFunc(opts)}
+}
+
+// Dialer returns a new [net.Dialer] that applies
+// the options specified by opts to dialing sockets.
+// See [SockOption.ControlFunc] for opts.
+// The returned dialer can be used even when o is nil.
+func (o *SockOption) Dialer(opts uint) *net.Dialer {
+	return &net.Dialer{Control: o.ControlFunc(opts)}
 }
 
 type controllers []Controller
@@ -114,6 +154,7 @@ type SockIPOption struct {
 	FreeBind            bool   // IP_FREEBIND (since Linux 2.4)
 	LocalPortRangeUpper uint16 // IP_LOCAL_PORT_RANGE (since Linux 6.3)
 	LocalPortRangeLower uint16 // IP_LOCAL_PORT_RANGE (since Linux 6.3)
+	RecvOrigDstAddr     bool   // IP_RECVORIGDSTADDR (since Linux 2.6.29)
 	Transparent         bool   // IP_TRANSPARENT (since Linux 2.6.24)
 	TTL                 int    // IP_TTL (since Linux 1.0)
 }
@@ -121,7 +162,9 @@ type SockIPOption struct {
 // SockIPV6Option is socket options for IPPROTO_IPV6 level.
 //   - https://man7.org/linux/man-pages/man7/ipv6.7.html
 type SockIPV6Option struct {
-	V6Only bool // IPV6_V6ONLY (since Linux 2.4.21 and 2.6)
+	RecvOrigDstAddr bool // IPV6_RECVORIGDSTADDR (since Linux 2.6.37)
+	Transparent     bool // IPV6_TRANSPARENT (since Linux 2.6.37)
+	V6Only          bool // IPV6_V6ONLY (since Linux 2.4.21 and 2.6)
 }
 
 // SockTCPOption is socket options for IPPROTO_TCP level.
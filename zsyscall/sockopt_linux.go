@@ -18,6 +18,12 @@ const (
 
 	IP_BIND_ADDRESS_NO_PORT = 0x18
 	IP_LOCAL_PORT_RANGE     = 0x33
+	IP_RECVORIGDSTADDR      = 0x14
+	IP_ORIGDSTADDR          = 0x14
+
+	IPV6_RECVORIGDSTADDR = 0x4a
+	IPV6_ORIGDSTADDR     = 0x4a
+	IPV6_TRANSPARENT     = 0x4b
 
 	TCP_FASTOPEN         = 0x17
 	TCP_FASTOPEN_CONNECT = 0x1e
@@ -234,6 +240,7 @@ func (c *SockIPOption) Controllers() []Controller {
 	controllers = appendNonNil(controllers, ipBindAddressNoPort(c.BindAddressNoPort))
 	controllers = appendNonNil(controllers, ipFreeBind(c.FreeBind))
 	controllers = appendNonNil(controllers, ipLocalPortRange(c.LocalPortRangeUpper, c.LocalPortRangeLower))
+	controllers = appendNonNil(controllers, ipRecvOrigDstAddr(c.RecvOrigDstAddr))
 	controllers = appendNonNil(controllers, ipTransparent(c.Transparent))
 	controllers = appendNonNil(controllers, ipTTL(c.TTL))
 	return controllers
@@ -278,6 +285,18 @@ func ipLocalPortRange(upper, lower uint16) Controller {
 	}
 }
 
+func ipRecvOrigDstAddr(enabled bool) Controller {
+	if !enabled {
+		return nil
+	}
+	return func(fd uintptr) error {
+		if err := setsockoptInt(int(fd), syscall.IPPROTO_IP, IP_RECVORIGDSTADDR, 1); err != nil {
+			return &SocketError{Err: err, Opts: "IPPROTO_IP.IP_RECVORIGDSTADDR"}
+		}
+		return nil
+	}
+}
+
 func ipTransparent(enabled bool) Controller {
 	if !enabled {
 		return nil
@@ -304,10 +323,36 @@ func ipTTL(ttl int) Controller {
 
 func (c *SockIPV6Option) Controllers() []Controller {
 	var controllers []Controller
+	controllers = appendNonNil(controllers, ipv6RecvOrigDstAddr(c.RecvOrigDstAddr))
+	controllers = appendNonNil(controllers, ipv6Transparent(c.Transparent))
 	controllers = appendNonNil(controllers, ipv6V6Only(c.V6Only))
 	return controllers
 }
 
+func ipv6RecvOrigDstAddr(enabled bool) Controller {
+	if !enabled {
+		return nil
+	}
+	return func(fd uintptr) error {
+		if err := setsockoptInt(int(fd), syscall.IPPROTO_IPV6, IPV6_RECVORIGDSTADDR, 1); err != nil {
+			return &SocketError{Err: err, Opts: "IPPROTO_IPV6.IPV6_RECVORIGDSTADDR"}
+		}
+		return nil
+	}
+}
+
+func ipv6Transparent(enabled bool) Controller {
+	if !enabled {
+		return nil
+	}
+	return func(fd uintptr) error {
+		if err := setsockoptInt(int(fd), syscall.IPPROTO_IPV6, IPV6_TRANSPARENT, 1); err != nil {
+			return &SocketError{Err: err, Opts: "IPPROTO_IPV6.IPV6_TRANSPARENT"}
+		}
+		return nil
+	}
+}
+
 func ipv6V6Only(enabled bool) Controller {
 	if !enabled {
 		return nil
@@ -40,20 +40,23 @@ func TestSockIPOption_Controllers(t *testing.T) {
 		FreeBind:            true,
 		LocalPortRangeUpper: 10,
 		LocalPortRangeLower: 11,
+		RecvOrigDstAddr:     true,
 		Transparent:         true,
 		TTL:                 12,
 	}
 	cs := opt.Controllers()
-	ztesting.AssertEqual(t, "number of controllers not match", 5, len(cs))
+	ztesting.AssertEqual(t, "number of controllers not match", 6, len(cs))
 }
 
 func TestSockIPV6Option_Controllers(t *testing.T) {
 	t.Parallel()
 	opt := &SockIPV6Option{
-		V6Only: true,
+		RecvOrigDstAddr: true,
+		Transparent:     true,
+		V6Only:          true,
 	}
 	cs := opt.Controllers()
-	ztesting.AssertEqual(t, "number of controllers not match", 1, len(cs))
+	ztesting.AssertEqual(t, "number of controllers not match", 3, len(cs))
 }
 
 func TestSockTCPOption_Controllers(t *testing.T) {
@@ -577,6 +580,34 @@ func TestIPLocalPortRange(t *testing.T) {
 	})
 }
 
+func TestIPRecvOrigDstAddr(t *testing.T) {
+	defer func() {
+		setsockoptInt = syscall.SetsockoptInt // Reset
+	}()
+	t.Run("disabled", func(t *testing.T) {
+		c := ipRecvOrigDstAddr(false)
+		ztesting.AssertEqual(t, "controller should be nil", true, c == nil)
+	})
+	t.Run("enabled", func(t *testing.T) {
+		setsockoptInt = func(fd int, level, opt, value int) (err error) {
+			ztesting.AssertEqual(t, "level not match", syscall.IPPROTO_IP, level)
+			ztesting.AssertEqual(t, "option not match", IP_RECVORIGDSTADDR, opt)
+			ztesting.AssertEqual(t, "value not match", 1, value)
+			return nil
+		}
+		c := ipRecvOrigDstAddr(true)
+		ztesting.AssertEqualErr(t, "error not match", nil, c(0))
+	})
+	t.Run("error", func(t *testing.T) {
+		setsockoptInt = func(fd int, level, opt, value int) (err error) {
+			return fs.ErrClosed // Dummy error.
+		}
+		c := ipRecvOrigDstAddr(true)
+		want := &SocketError{Opts: "IPPROTO_IP.IP_RECVORIGDSTADDR"}
+		ztesting.AssertEqualErr(t, "error not match", want, c(0))
+	})
+}
+
 func TestIPTransparent(t *testing.T) {
 	defer func() {
 		setsockoptInt = syscall.SetsockoptInt // Reset
@@ -633,6 +664,62 @@ func TestIPTTL(t *testing.T) {
 	})
 }
 
+func TestIPV6RecvOrigDstAddr(t *testing.T) {
+	defer func() {
+		setsockoptInt = syscall.SetsockoptInt // Reset
+	}()
+	t.Run("disabled", func(t *testing.T) {
+		c := ipv6RecvOrigDstAddr(false)
+		ztesting.AssertEqual(t, "controller should be nil", true, c == nil)
+	})
+	t.Run("enabled", func(t *testing.T) {
+		setsockoptInt = func(fd int, level, opt, value int) (err error) {
+			ztesting.AssertEqual(t, "level not match", syscall.IPPROTO_IPV6, level)
+			ztesting.AssertEqual(t, "option not match", IPV6_RECVORIGDSTADDR, opt)
+			ztesting.AssertEqual(t, "value not match", 1, value)
+			return nil
+		}
+		c := ipv6RecvOrigDstAddr(true)
+		ztesting.AssertEqualErr(t, "error not match", nil, c(0))
+	})
+	t.Run("error", func(t *testing.T) {
+		setsockoptInt = func(fd int, level, opt, value int) (err error) {
+			return fs.ErrClosed // Dummy error.
+		}
+		c := ipv6RecvOrigDstAddr(true)
+		want := &SocketError{Opts: "IPPROTO_IPV6.IPV6_RECVORIGDSTADDR"}
+		ztesting.AssertEqualErr(t, "error not match", want, c(0))
+	})
+}
+
+func TestIPV6Transparent(t *testing.T) {
+	defer func() {
+		setsockoptInt = syscall.SetsockoptInt // Reset
+	}()
+	t.Run("disabled", func(t *testing.T) {
+		c := ipv6Transparent(false)
+		ztesting.AssertEqual(t, "controller should be nil", true, c == nil)
+	})
+	t.Run("enabled", func(t *testing.T) {
+		setsockoptInt = func(fd int, level, opt, value int) (err error) {
+			ztesting.AssertEqual(t, "level not match", syscall.IPPROTO_IPV6, level)
+			ztesting.AssertEqual(t, "option not match", IPV6_TRANSPARENT, opt)
+			ztesting.AssertEqual(t, "value not match", 1, value)
+			return nil
+		}
+		c := ipv6Transparent(true)
+		ztesting.AssertEqualErr(t, "error not match", nil, c(0))
+	})
+	t.Run("error", func(t *testing.T) {
+		setsockoptInt = func(fd int, level, opt, value int) (err error) {
+			return fs.ErrClosed // Dummy error.
+		}
+		c := ipv6Transparent(true)
+		want := &SocketError{Opts: "IPPROTO_IPV6.IPV6_TRANSPARENT"}
+		ztesting.AssertEqualErr(t, "error not match", want, c(0))
+	})
+}
+
 func TestIPV6V6Only(t *testing.T) {
 	defer func() {
 		setsockoptInt = syscall.SetsockoptInt // Reset
@@ -1,7 +1,10 @@
 package zsyscall
 
 import (
+	"context"
 	"io"
+	"net"
+	"runtime"
 	"syscall"
 	"testing"
 
@@ -52,6 +55,40 @@ func TestSockOption_ControlFunc(t *testing.T) {
 	})
 }
 
+func TestSockOption_ListenConfig(t *testing.T) {
+	t.Parallel()
+	t.Run("nil option", func(t *testing.T) {
+		var o *SockOption
+		lc := o.ListenConfig(SockOptForTCP)
+		ztesting.AssertEqual(t, "control func is not nil", true, lc.Control == nil)
+	})
+	t.Run("non-nil option", func(t *testing.T) {
+		o := &SockOption{SO: &SockSOOption{ReuseAddr: true}}
+		lc := o.ListenConfig(SockOptForTCP)
+		ztesting.AssertEqual(t, "control func is nil", runtime.GOOS == "linux" || runtime.GOOS == "windows", lc.Control != nil)
+		ln, err := lc.Listen(context.Background(), "tcp", "127.0.0.1:0")
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+		ln.Close()
+	})
+}
+
+func TestSockOption_Dialer(t *testing.T) {
+	t.Parallel()
+	t.Run("nil option", func(t *testing.T) {
+		var o *SockOption
+		d := o.Dialer(SockOptForUDP)
+		ztesting.AssertEqual(t, "control func is not nil", true, d.Control == nil)
+	})
+	t.Run("non-nil option", func(t *testing.T) {
+		o := &SockOption{SO: &SockSOOption{ReuseAddr: true}}
+		d := o.Dialer(SockOptForUDP)
+		ztesting.AssertEqual(t, "control func is nil", runtime.GOOS == "linux" || runtime.GOOS == "windows", d.Control != nil)
+		conn, err := d.Dial("udp", "127.0.0.1:12345")
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+		conn.Close()
+	})
+}
+
 type testRawConn struct {
 	syscall.RawConn
 	fd  uintptr
@@ -63,6 +100,30 @@ func (c *testRawConn) Control(f func(fd uintptr)) error {
 	return c.err
 }
 
+func TestSockOption_ipv6(t *testing.T) {
+	t.Parallel()
+	o := &SockOption{
+		SO:   &SockSOOption{ReuseAddr: true},
+		IPV6: &SockIPV6Option{RecvOrigDstAddr: true},
+	}
+	lc := o.ListenConfig(SockOptForUDP)
+	t.Run("ipv4 socket", func(t *testing.T) {
+		pc, err := lc.ListenPacket(context.Background(), "udp4", "127.0.0.1:0")
+		ztesting.AssertEqualErr(t, "ipv6 options must not be applied", nil, err)
+		pc.Close()
+	})
+	t.Run("ipv6 socket", func(t *testing.T) {
+		if pc, err := net.ListenPacket("udp6", "[::1]:0"); err != nil {
+			t.Skip("ipv6 is not available")
+		} else {
+			pc.Close()
+		}
+		pc, err := lc.ListenPacket(context.Background(), "udp6", "[::1]:0")
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+		pc.Close()
+	})
+}
+
 func TestControllers(t *testing.T) {
 	t.Parallel()
 	t.Run("nil", func(t *testing.T) {
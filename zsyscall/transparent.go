@@ -0,0 +1,48 @@
+package zsyscall
+
+import (
+	"cmp"
+	"errors"
+	"net/netip"
+	"syscall"
+)
+
+// ErrNoOriginalDst is returned when the original
+// destination address of a packet is not found.
+var ErrNoOriginalDst = errors.New("zsyscall: original destination not found")
+
+// OriginalDst returns the original destination address of the
+// TCP connection redirected by netfilter REDIRECT or DNAT target.
+// It uses SO_ORIGINAL_DST for IPv4 and IP6T_SO_ORIGINAL_DST for IPv6.
+// Typically, the conn is a [*net.TCPConn] accepted by a listener.
+// For connections accepted with TPROXY target, use the local
+// address of the connection instead.
+// OriginalDst is supported only on Linux.
+// On other platforms, it returns [errors.ErrUnsupported].
+//   - https://www.kernel.org/doc/Documentation/networking/tproxy.txt
+func OriginalDst(conn syscall.Conn) (netip.AddrPort, error) {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	var ap netip.AddrPort
+	ctrErr := rc.Control(func(fd uintptr) {
+		ap, err = originalDst(fd)
+	})
+	if err := cmp.Or(err, ctrErr); err != nil {
+		return netip.AddrPort{}, err
+	}
+	return ap, nil
+}
+
+// ParseOrigDstAddr parses the socket control messages and returns the
+// original destination address of a UDP packet.
+// The control messages are obtained from the oob of
+// [net.UDPConn.ReadMsgUDP] of the socket with IP_RECVORIGDSTADDR or
+// IPV6_RECVORIGDSTADDR enabled. See [SockIPOption] and [SockIPV6Option].
+// It returns [ErrNoOriginalDst] if the address is not found.
+// ParseOrigDstAddr is supported only on Linux.
+// On other platforms, it returns [errors.ErrUnsupported].
+func ParseOrigDstAddr(oob []byte) (netip.AddrPort, error) {
+	return parseOrigDstAddr(oob)
+}
@@ -0,0 +1,64 @@
+//go:build linux
+
+package zsyscall
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	SO_ORIGINAL_DST      = 0x50
+	IP6T_SO_ORIGINAL_DST = 0x50
+)
+
+func originalDst(fd uintptr) (netip.AddrPort, error) {
+	var sa4 syscall.RawSockaddrInet4
+	n := uint32(unsafe.Sizeof(sa4))
+	err4 := getsockopt(int(fd), syscall.IPPROTO_IP, SO_ORIGINAL_DST, unsafe.Pointer(&sa4), &n)
+	if err4 == nil {
+		return sockaddr4ToAddrPort(&sa4), nil
+	}
+	var sa6 syscall.RawSockaddrInet6
+	n = uint32(unsafe.Sizeof(sa6))
+	if err := getsockopt(int(fd), syscall.IPPROTO_IPV6, IP6T_SO_ORIGINAL_DST, unsafe.Pointer(&sa6), &n); err == nil {
+		return sockaddr6ToAddrPort(&sa6), nil
+	}
+	return netip.AddrPort{}, &SocketError{Err: err4, Opts: "IPPROTO_IP.SO_ORIGINAL_DST"}
+}
+
+func parseOrigDstAddr(oob []byte) (netip.AddrPort, error) {
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	for _, msg := range msgs {
+		switch {
+		case msg.Header.Level == syscall.SOL_IP && msg.Header.Type == IP_ORIGDSTADDR:
+			if len(msg.Data) < syscall.SizeofSockaddrInet4 {
+				continue
+			}
+			sa := (*syscall.RawSockaddrInet4)(unsafe.Pointer(&msg.Data[0]))
+			return sockaddr4ToAddrPort(sa), nil
+		case msg.Header.Level == syscall.SOL_IPV6 && msg.Header.Type == IPV6_ORIGDSTADDR:
+			if len(msg.Data) < syscall.SizeofSockaddrInet6 {
+				continue
+			}
+			sa := (*syscall.RawSockaddrInet6)(unsafe.Pointer(&msg.Data[0]))
+			return sockaddr6ToAddrPort(sa), nil
+		}
+	}
+	return netip.AddrPort{}, ErrNoOriginalDst
+}
+
+func sockaddr4ToAddrPort(sa *syscall.RawSockaddrInet4) netip.AddrPort {
+	port := binary.BigEndian.Uint16((*[2]byte)(unsafe.Pointer(&sa.Port))[:])
+	return netip.AddrPortFrom(netip.AddrFrom4(sa.Addr), port)
+}
+
+func sockaddr6ToAddrPort(sa *syscall.RawSockaddrInet6) netip.AddrPort {
+	port := binary.BigEndian.Uint16((*[2]byte)(unsafe.Pointer(&sa.Port))[:])
+	return netip.AddrPortFrom(netip.AddrFrom16(sa.Addr).Unmap(), port)
+}
@@ -0,0 +1,81 @@
+//go:build linux
+
+package zsyscall
+
+import (
+	"net"
+	"net/netip"
+	"syscall"
+	"testing"
+	"unsafe"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestOriginalDst(t *testing.T) {
+	defer func() {
+		getsockopt = rawGetsockopt // Reset
+	}()
+	conn, _ := tcpPair(t, nil)
+
+	t.Run("ipv4", func(t *testing.T) {
+		getsockopt = func(fd, level, opt int, p unsafe.Pointer, n *uint32) error {
+			sa := (*syscall.RawSockaddrInet4)(p)
+			sa.Family = syscall.AF_INET
+			*(*[2]byte)(unsafe.Pointer(&sa.Port)) = [2]byte{0x1f, 0x90}
+			sa.Addr = [4]byte{192, 0, 2, 1}
+			return nil
+		}
+		got, err := OriginalDst(conn)
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+		ztesting.AssertEqual(t, "address not match", netip.MustParseAddrPort("192.0.2.1:8080"), got)
+	})
+	t.Run("ipv6", func(t *testing.T) {
+		getsockopt = func(fd, level, opt int, p unsafe.Pointer, n *uint32) error {
+			if level == syscall.IPPROTO_IP {
+				return syscall.ENOENT
+			}
+			sa := (*syscall.RawSockaddrInet6)(p)
+			sa.Family = syscall.AF_INET6
+			*(*[2]byte)(unsafe.Pointer(&sa.Port)) = [2]byte{0x01, 0xbb}
+			sa.Addr = netip.MustParseAddr("2001:db8::1").As16()
+			return nil
+		}
+		got, err := OriginalDst(conn)
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+		ztesting.AssertEqual(t, "address not match", netip.MustParseAddrPort("[2001:db8::1]:443"), got)
+	})
+	t.Run("not found", func(t *testing.T) {
+		getsockopt = rawGetsockopt
+		got, err := OriginalDst(conn) // Not redirected.
+		ztesting.AssertEqual(t, "address must be invalid", false, got.IsValid())
+		want := &SocketError{Opts: "IPPROTO_IP.SO_ORIGINAL_DST"}
+		ztesting.AssertEqualErr(t, "error not match", want, err)
+	})
+}
+
+func TestParseOrigDstAddr(t *testing.T) {
+	t.Parallel()
+	t.Run("udp", func(t *testing.T) {
+		opt := &SockOption{IP: &SockIPOption{RecvOrigDstAddr: true}}
+		pc, err := opt.ListenConfig(SockOptForUDP).ListenPacket(t.Context(), "udp4", "127.0.0.1:0")
+		ztesting.AssertEqualErr(t, "listen error", nil, err)
+		defer pc.Close()
+		c, err := net.Dial("udp4", pc.LocalAddr().String())
+		ztesting.AssertEqualErr(t, "dial error", nil, err)
+		defer c.Close()
+		_, _ = c.Write([]byte("test"))
+
+		buf, oob := make([]byte, 10), make([]byte, 128)
+		_, oobn, _, _, err := pc.(*net.UDPConn).ReadMsgUDPAddrPort(buf, oob)
+		ztesting.AssertEqualErr(t, "read error", nil, err)
+		got, err := ParseOrigDstAddr(oob[:oobn])
+		ztesting.AssertEqualErr(t, "error not match", nil, err)
+		ztesting.AssertEqual(t, "address not match", pc.LocalAddr().(*net.UDPAddr).AddrPort(), got)
+	})
+	t.Run("not found", func(t *testing.T) {
+		got, err := ParseOrigDstAddr(nil)
+		ztesting.AssertEqual(t, "address must be invalid", false, got.IsValid())
+		ztesting.AssertEqualErr(t, "error not match", ErrNoOriginalDst, err)
+	})
+}
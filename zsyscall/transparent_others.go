@@ -0,0 +1,16 @@
+//go:build !linux
+
+package zsyscall
+
+import (
+	"errors"
+	"net/netip"
+)
+
+func originalDst(_ uintptr) (netip.AddrPort, error) {
+	return netip.AddrPort{}, errors.ErrUnsupported
+}
+
+func parseOrigDstAddr(_ []byte) (netip.AddrPort, error) {
+	return netip.AddrPort{}, errors.ErrUnsupported
+}
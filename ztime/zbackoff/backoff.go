@@ -4,6 +4,7 @@ import (
 	"errors"
 	"math"
 	"math/rand/v2"
+	"sync"
 	"time"
 )
 
@@ -16,6 +17,8 @@ var (
 	_ Backoff = &ExponentialBackoffFullJitter{}
 	_ Backoff = &ExponentialBackoffEqualJitter{}
 	_ Backoff = &FibonacciBackoff{}
+	_ Backoff = &DecorrelatedJitterBackoff{}
+	_ Cloner  = &DecorrelatedJitterBackoff{}
 )
 
 var (
@@ -72,6 +75,25 @@ type Backoff interface {
 	Attempt(attempt int) time.Duration
 }
 
+// Cloner is implemented by stateful backoff such as
+// [DecorrelatedJitterBackoff] and backoff that wraps others.
+// [Retrier] clones the backoff that implements Cloner for
+// each retry loop so that the state is not shared between loops.
+type Cloner interface {
+	// Clone returns a new backoff with the same
+	// parameters and the initial state.
+	Clone() Backoff
+}
+
+// clone returns a clone of the b if it implements
+// the [Cloner]. Otherwise, it returns b as-is.
+func clone(b Backoff) Backoff {
+	if c, ok := b.(Cloner); ok {
+		return c.Clone()
+	}
+	return b
+}
+
 // NewFixedBackoff returns a new instance of FixedBackoff.
 // See comments on the [FixedBackoff] for details.
 // Allowed value range is value>=0, otherwise it panics.
@@ -563,6 +585,102 @@ func (b *FibonacciBackoff) Attempt(attempt int) time.Duration {
 	return b.offset + time.Duration(flc)
 }
 
+// NewDecorrelatedJitterBackoff returns a new instance of DecorrelatedJitterBackoff.
+// See comments on the [DecorrelatedJitterBackoff] for details.
+// Allowed value range is offset>0, fluctuation>0, otherwise it panics.
+func NewDecorrelatedJitterBackoff(offset, fluctuation time.Duration) *DecorrelatedJitterBackoff {
+	mustPositive(float64(offset), "offset")
+	mustPositive(float64(fluctuation), "fluctuation")
+	mustUnderMaxInt64(offset, fluctuation, "offset+fluctuation")
+	return &DecorrelatedJitterBackoff{
+		offset:      offset,
+		fluctuation: fluctuation,
+		prev:        offset,
+	}
+}
+
+// DecorrelatedJitterBackoff provides exponential backoff strategy
+// with decorrelated jitter.
+// Unlike other backoff, the duration depends on the previous duration
+// rather than the attempt count. DecorrelatedJitterBackoff is stateful.
+// The state is reset when the attempt is 1 or less, which means the
+// first retry. Sharing an instance between concurrent retry loops is safe
+// but their states interfere with each other. [Retrier] uses a clone for
+// each retry loop. Other callers should use [DecorrelatedJitterBackoff.Clone]
+// to get an instance for each retry loop.
+// It will panic if the parameter range is not satisfied.
+//
+// Algorithm:
+//
+//	Parameter:
+//	  offset: offset duration. (>0)
+//	  fluctuation: value range of fluctuation part. (>0)
+//	State:
+//	  prev: previous backoff duration. Initially offset.
+//	Input:
+//	  attempt : the count of attempts.
+//	Output:
+//	  If attempt<=1, then let prev offset.
+//	  Calculate backoff duration bod := RandomRange(offset, prev*3)
+//	  If bod > offset+fluctuation, then let bod offset+fluctuation.
+//	  Let prev bod.
+//	  Return bod.
+//	Value range:
+//	  Min: offset
+//	  Max: offset + fluctuation
+//
+// Graph:
+//
+//	      y:backoff
+//	      |
+//	offset|--------------------────────────
+//	  +   |          /\    /\  /  \/\  /
+//	fluc. |         /  \  /  \/      \/
+//	      |        /    \/   y=RandomRange(offset,prev*3)
+//	      |      ／
+//	      |    ／
+//	offset|──／----------------------------
+//	      |
+//	      |
+//	      └─────────────────────────────── x:attempts
+//	      0
+type DecorrelatedJitterBackoff struct {
+	offset      time.Duration
+	fluctuation time.Duration
+
+	mu   sync.Mutex
+	prev time.Duration
+}
+
+// Clone returns a new DecorrelatedJitterBackoff with
+// the same parameters and the initial state.
+func (b *DecorrelatedJitterBackoff) Clone() Backoff {
+	return &DecorrelatedJitterBackoff{
+		offset:      b.offset,
+		fluctuation: b.fluctuation,
+		prev:        b.offset,
+	}
+}
+
+func (b *DecorrelatedJitterBackoff) Attempt(attempt int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if attempt <= 1 {
+		b.prev = b.offset
+	}
+	limit := b.offset + b.fluctuation
+	upper := limit
+	if b.prev < limit/3 {
+		upper = 3 * b.prev
+	}
+	bod := b.offset
+	if upper > b.offset {
+		bod += time.Duration(rand.Int64N(int64(upper - b.offset)))
+	}
+	b.prev = bod
+	return bod
+}
+
 // fibonacci returns the n-th fibonacci number.
 // It does not check the overflow.
 //
@@ -568,6 +568,75 @@ func TestFibonacciBackoff(t *testing.T) {
 	}
 }
 
+func TestDecorrelatedJitterBackoff(t *testing.T) {
+	t.Parallel()
+	t.Run("panic when offset<=0", func(t *testing.T) {
+		defer func() {
+			got, _ := recover().(error)
+			want := &BackoffError{Type: errPram, Info: "offset must be positive"}
+			ztesting.AssertEqualErr(t, "error not matched", want, got)
+		}()
+		NewDecorrelatedJitterBackoff(0, 1)
+	})
+	t.Run("panic when fluctuation<=0", func(t *testing.T) {
+		defer func() {
+			got, _ := recover().(error)
+			want := &BackoffError{Type: errPram, Info: "fluctuation must be positive"}
+			ztesting.AssertEqualErr(t, "error not matched", want, got)
+		}()
+		NewDecorrelatedJitterBackoff(1, 0)
+	})
+	t.Run("panic when offset+fluctuation>MaxInt64", func(t *testing.T) {
+		defer func() {
+			got, _ := recover().(error)
+			want := &BackoffError{Type: errPram, Info: "offset+fluctuation must be under MaxInt64 (9,223,372,036,854,775,807)"}
+			ztesting.AssertEqualErr(t, "error not matched", want, got)
+		}()
+		NewDecorrelatedJitterBackoff(math.MaxInt64, 1)
+	})
+
+	testCases := map[string]struct {
+		offset, fluctuation time.Duration
+	}{
+		"offset=1":          {1, 1},
+		"offset=1ms":        {time.Millisecond, time.Second},
+		"fluctuation=small": {time.Second, time.Millisecond},
+		"fluctuation=large": {time.Nanosecond, math.MaxInt64 - 2},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			backoff := NewDecorrelatedJitterBackoff(tc.offset, tc.fluctuation)
+			prev := tc.offset
+			for attempt := range 100 {
+				got := backoff.Attempt(attempt)
+				if attempt <= 1 {
+					prev = tc.offset // State is reset.
+				}
+				upper := min(tc.offset+tc.fluctuation, max(tc.offset, 3*prev))
+				if prev > (tc.offset+tc.fluctuation)/3 {
+					upper = tc.offset + tc.fluctuation
+				}
+				ztesting.AssertEqual(t, "backoff under offset attempt="+strconv.Itoa(attempt), true, got >= tc.offset)
+				ztesting.AssertEqual(t, "backoff over upper attempt="+strconv.Itoa(attempt), true, got <= upper)
+				prev = got
+			}
+		})
+	}
+}
+
+func TestDecorrelatedJitterBackoff_Clone(t *testing.T) {
+	t.Parallel()
+	b := NewDecorrelatedJitterBackoff(time.Second, time.Hour)
+	for attempt := 1; attempt < 10; attempt++ {
+		b.Attempt(attempt)
+	}
+	c := b.Clone().(*DecorrelatedJitterBackoff)
+	ztesting.AssertEqual(t, "clone must be a new instance", true, b != c)
+	ztesting.AssertEqual(t, "offset not match", b.offset, c.offset)
+	ztesting.AssertEqual(t, "fluctuation not match", b.fluctuation, c.fluctuation)
+	ztesting.AssertEqual(t, "state must be initial", b.offset, c.prev)
+}
+
 func TestFibonacci(t *testing.T) {
 	t.Parallel()
 	testCases := map[string]struct {
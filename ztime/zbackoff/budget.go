@@ -0,0 +1,71 @@
+package zbackoff
+
+import (
+	"sync"
+)
+
+// NewBudget returns a new instance of Budget.
+// See comments on the [Budget] for details.
+// Allowed value range is ratio>0, max>=1, otherwise it panics.
+func NewBudget(ratio, max float64) *Budget {
+	mustPositive(ratio, "ratio")
+	mustZeroOrPositive(max-1, "max-1")
+	return &Budget{
+		ratio:  ratio,
+		max:    max,
+		tokens: max,
+	}
+}
+
+// Budget limits retries to prevent retry storms.
+// A Budget can be shared by multiple [Retrier] to limit
+// the total retries of them.
+// Budget is safe for concurrent use.
+//
+// Algorithm:
+//
+//	Parameter:
+//	  ratio: tokens deposited for each call. (>0)
+//	  max: maximum number of tokens. (>=1)
+//	Initial state:
+//	  tokens := max
+//	On each call of the function:
+//	  tokens := min(max, tokens+ratio)
+//	On each retry:
+//	  If tokens >= 1, then tokens := tokens-1 and the retry is allowed.
+//	  Otherwise, the retry is not allowed.
+//
+// For example, ratio=0.1 allows retries up to about 10%
+// of the calls after the initial max tokens are consumed.
+type Budget struct {
+	mu     sync.Mutex
+	ratio  float64
+	max    float64
+	tokens float64
+}
+
+// Tokens returns the number of retries currently allowed.
+func (b *Budget) Tokens() float64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.tokens
+}
+
+// deposit deposits tokens for a call.
+func (b *Budget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.max, b.tokens+b.ratio)
+}
+
+// withdraw withdraws a token for a retry.
+// It returns false if there is no token left.
+func (b *Budget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
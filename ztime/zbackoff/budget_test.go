@@ -0,0 +1,42 @@
+package zbackoff
+
+import (
+	"testing"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestBudget(t *testing.T) {
+	t.Parallel()
+	t.Run("panic when ratio<=0", func(t *testing.T) {
+		defer func() {
+			got, _ := recover().(error)
+			want := &BackoffError{Type: errPram, Info: "ratio must be positive"}
+			ztesting.AssertEqualErr(t, "error not matched", want, got)
+		}()
+		NewBudget(0, 10)
+	})
+	t.Run("panic when max<1", func(t *testing.T) {
+		defer func() {
+			got, _ := recover().(error)
+			want := &BackoffError{Type: errPram, Info: "max-1 must be zero or positive"}
+			ztesting.AssertEqualErr(t, "error not matched", want, got)
+		}()
+		NewBudget(0.1, 0.5)
+	})
+	t.Run("withdraw and deposit", func(t *testing.T) {
+		b := NewBudget(0.5, 2)
+		ztesting.AssertEqual(t, "initial tokens not match", 2.0, b.Tokens())
+		ztesting.AssertEqual(t, "withdraw failed", true, b.withdraw())
+		ztesting.AssertEqual(t, "withdraw failed", true, b.withdraw())
+		ztesting.AssertEqual(t, "withdraw succeeded", false, b.withdraw())
+		b.deposit()
+		ztesting.AssertEqual(t, "withdraw succeeded", false, b.withdraw())
+		b.deposit()
+		ztesting.AssertEqual(t, "withdraw failed", true, b.withdraw())
+		for range 10 {
+			b.deposit()
+		}
+		ztesting.AssertEqual(t, "tokens not capped", 2.0, b.Tokens())
+	})
+}
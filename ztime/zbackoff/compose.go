@@ -0,0 +1,235 @@
+package zbackoff
+
+import (
+	"math"
+	"time"
+)
+
+var (
+	_ Backoff = &CappedBackoff{}
+	_ Backoff = &FloorBackoff{}
+	_ Backoff = &ScaledBackoff{}
+	_ Backoff = &SequenceBackoff{}
+	_ Cloner  = &CappedBackoff{}
+	_ Cloner  = &FloorBackoff{}
+	_ Cloner  = &ScaledBackoff{}
+	_ Cloner  = &SequenceBackoff{}
+)
+
+func mustNonNil(b Backoff, name string) {
+	if b != nil {
+		return
+	}
+	panic(&BackoffError{Type: errPram, Info: name + " must be non-nil"})
+}
+
+// NewCappedBackoff returns a new instance of CappedBackoff.
+// See comments on the [CappedBackoff] for details.
+// Allowed value range is backoff!=nil, max>=0, otherwise it panics.
+func NewCappedBackoff(backoff Backoff, max time.Duration) *CappedBackoff {
+	mustNonNil(backoff, "backoff")
+	mustZeroOrPositive(float64(max), "max")
+	return &CappedBackoff{
+		backoff: backoff,
+		max:     max,
+	}
+}
+
+// CappedBackoff limits the maximum duration of the backoff.
+// It will panic if the parameter range is not satisfied.
+//
+// Algorithm:
+//
+//	Parameter:
+//	  backoff: underlying backoff.
+//	  max: maximum duration. (>=0)
+//	Input:
+//	  attempt: the count of attempts.
+//	Output:
+//	  Return min(backoff.Attempt(attempt), max).
+//	Value range:
+//	  Min: 0
+//	  Max: max
+type CappedBackoff struct {
+	backoff Backoff
+	max     time.Duration
+}
+
+// Clone returns a new CappedBackoff with a clone of the underlying backoff.
+func (b *CappedBackoff) Clone() Backoff {
+	return &CappedBackoff{backoff: clone(b.backoff), max: b.max}
+}
+
+func (b *CappedBackoff) Attempt(attempt int) time.Duration {
+	return min(b.backoff.Attempt(attempt), b.max)
+}
+
+// NewFloorBackoff returns a new instance of FloorBackoff.
+// See comments on the [FloorBackoff] for details.
+// Allowed value range is backoff!=nil, min>=0, otherwise it panics.
+func NewFloorBackoff(backoff Backoff, min time.Duration) *FloorBackoff {
+	mustNonNil(backoff, "backoff")
+	mustZeroOrPositive(float64(min), "min")
+	return &FloorBackoff{
+		backoff: backoff,
+		min:     min,
+	}
+}
+
+// FloorBackoff limits the minimum duration of the backoff.
+// It will panic if the parameter range is not satisfied.
+//
+// Algorithm:
+//
+//	Parameter:
+//	  backoff: underlying backoff.
+//	  min: minimum duration. (>=0)
+//	Input:
+//	  attempt: the count of attempts.
+//	Output:
+//	  Return max(backoff.Attempt(attempt), min).
+//	Value range:
+//	  Min: min
+//	  Max: MaxInt64
+type FloorBackoff struct {
+	backoff Backoff
+	min     time.Duration
+}
+
+// Clone returns a new FloorBackoff with a clone of the underlying backoff.
+func (b *FloorBackoff) Clone() Backoff {
+	return &FloorBackoff{backoff: clone(b.backoff), min: b.min}
+}
+
+func (b *FloorBackoff) Attempt(attempt int) time.Duration {
+	return max(b.backoff.Attempt(attempt), b.min)
+}
+
+// NewScaledBackoff returns a new instance of ScaledBackoff.
+// See comments on the [ScaledBackoff] for details.
+// Allowed value range is backoff!=nil, factor>=0, otherwise it panics.
+func NewScaledBackoff(backoff Backoff, factor float64) *ScaledBackoff {
+	mustNonNil(backoff, "backoff")
+	mustZeroOrPositive(factor, "factor")
+	return &ScaledBackoff{
+		backoff: backoff,
+		factor:  factor,
+	}
+}
+
+// ScaledBackoff multiplies the duration of the backoff by a factor.
+// It will panic if the parameter range is not satisfied.
+//
+// Algorithm:
+//
+//	Parameter:
+//	  backoff: underlying backoff.
+//	  factor: multiplication factor. (>=0)
+//	Input:
+//	  attempt: the count of attempts.
+//	Output:
+//	  Calculate backoff duration bod := backoff.Attempt(attempt) * factor
+//	  If bod overflows, then let bod MaxInt64.
+//	  Return bod.
+//	Value range:
+//	  Min: 0
+//	  Max: MaxInt64
+type ScaledBackoff struct {
+	backoff Backoff
+	factor  float64
+}
+
+// Clone returns a new ScaledBackoff with a clone of the underlying backoff.
+func (b *ScaledBackoff) Clone() Backoff {
+	return &ScaledBackoff{backoff: clone(b.backoff), factor: b.factor}
+}
+
+func (b *ScaledBackoff) Attempt(attempt int) time.Duration {
+	bod := float64(b.backoff.Attempt(attempt)) * b.factor
+	if bod >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(bod)
+}
+
+// SequenceStep is a step of the [SequenceBackoff].
+type SequenceStep struct {
+	// Backoff is the backoff used in this step.
+	Backoff Backoff
+	// Attempts is the number of attempts handled by this step.
+	// It is ignored for the last step, which handles
+	// all the rest of attempts.
+	Attempts int
+}
+
+// NewSequenceBackoff returns a new instance of SequenceBackoff.
+// See comments on the [SequenceBackoff] for details.
+// At least 1 step is required. All steps must have non-nil backoff
+// and steps other than the last one must have positive attempts,
+// otherwise it panics.
+func NewSequenceBackoff(steps ...SequenceStep) *SequenceBackoff {
+	if len(steps) == 0 {
+		panic(&BackoffError{Type: errPram, Info: "steps must not be empty"})
+	}
+	for i, s := range steps {
+		mustNonNil(s.Backoff, "steps.backoff")
+		if i < len(steps)-1 {
+			mustPositive(float64(s.Attempts), "steps.attempts")
+		}
+	}
+	return &SequenceBackoff{
+		steps: steps,
+	}
+}
+
+// SequenceBackoff chains multiple backoff.
+// For example, a fixed backoff for the first 3 attempts
+// followed by an exponential backoff can be made as below.
+//
+//	zbackoff.NewSequenceBackoff(
+//		zbackoff.SequenceStep{Backoff: zbackoff.NewFixedBackoff(time.Second), Attempts: 3},
+//		zbackoff.SequenceStep{Backoff: zbackoff.NewExponentialBackoff(0, time.Minute, time.Second)},
+//	)
+//
+// It will panic if the parameter range is not satisfied.
+//
+// Algorithm:
+//
+//	Parameter:
+//	  steps: list of backoff and the number of attempts.
+//	Input:
+//	  attempt: the count of attempts.
+//	Output:
+//	  If attempt<=0, return steps[0].Backoff.Attempt(attempt).
+//	  Find the step that the attempt belongs to.
+//	  The n-th step handles attempts from the sum of
+//	  attempts of the previous steps plus 1.
+//	  Return step.Backoff.Attempt(attempt - sum of attempts of previous steps).
+//	Value range:
+//	  Depends on the steps.
+type SequenceBackoff struct {
+	steps []SequenceStep
+}
+
+// Clone returns a new SequenceBackoff with clones of the backoff of steps.
+func (b *SequenceBackoff) Clone() Backoff {
+	steps := make([]SequenceStep, len(b.steps))
+	for i, s := range b.steps {
+		steps[i] = SequenceStep{Backoff: clone(s.Backoff), Attempts: s.Attempts}
+	}
+	return &SequenceBackoff{steps: steps}
+}
+
+func (b *SequenceBackoff) Attempt(attempt int) time.Duration {
+	if attempt <= 0 {
+		return b.steps[0].Backoff.Attempt(attempt)
+	}
+	last := len(b.steps) - 1
+	for _, s := range b.steps[:last] {
+		if attempt <= s.Attempts {
+			return s.Backoff.Attempt(attempt)
+		}
+		attempt -= s.Attempts
+	}
+	return b.steps[last].Backoff.Attempt(attempt)
+}
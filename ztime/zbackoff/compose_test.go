@@ -0,0 +1,209 @@
+package zbackoff
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+// attemptBackoff returns the attempt as the duration.
+type attemptBackoff struct{}
+
+func (attemptBackoff) Attempt(attempt int) time.Duration {
+	return time.Duration(attempt)
+}
+
+func TestCappedBackoff(t *testing.T) {
+	t.Parallel()
+	t.Run("panic when backoff=nil", func(t *testing.T) {
+		defer func() {
+			got, _ := recover().(error)
+			want := &BackoffError{Type: errPram, Info: "backoff must be non-nil"}
+			ztesting.AssertEqualErr(t, "error not matched", want, got)
+		}()
+		NewCappedBackoff(nil, 1)
+	})
+	t.Run("panic when max<0", func(t *testing.T) {
+		defer func() {
+			got, _ := recover().(error)
+			want := &BackoffError{Type: errPram, Info: "max must be zero or positive"}
+			ztesting.AssertEqualErr(t, "error not matched", want, got)
+		}()
+		NewCappedBackoff(attemptBackoff{}, -1)
+	})
+
+	testCases := map[string]struct {
+		max     time.Duration
+		attempt int
+		want    time.Duration
+	}{
+		"under max": {5, 3, 3},
+		"equal max": {5, 5, 5},
+		"over max":  {5, 10, 5},
+		"zero max":  {0, 10, 0},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			backoff := NewCappedBackoff(attemptBackoff{}, tc.max)
+			got := backoff.Attempt(tc.attempt)
+			ztesting.AssertEqual(t, "wrong backoff duration for attempt="+strconv.Itoa(tc.attempt), tc.want, got)
+		})
+	}
+}
+
+func TestFloorBackoff(t *testing.T) {
+	t.Parallel()
+	t.Run("panic when backoff=nil", func(t *testing.T) {
+		defer func() {
+			got, _ := recover().(error)
+			want := &BackoffError{Type: errPram, Info: "backoff must be non-nil"}
+			ztesting.AssertEqualErr(t, "error not matched", want, got)
+		}()
+		NewFloorBackoff(nil, 1)
+	})
+	t.Run("panic when min<0", func(t *testing.T) {
+		defer func() {
+			got, _ := recover().(error)
+			want := &BackoffError{Type: errPram, Info: "min must be zero or positive"}
+			ztesting.AssertEqualErr(t, "error not matched", want, got)
+		}()
+		NewFloorBackoff(attemptBackoff{}, -1)
+	})
+
+	testCases := map[string]struct {
+		min     time.Duration
+		attempt int
+		want    time.Duration
+	}{
+		"under min": {5, 3, 5},
+		"equal min": {5, 5, 5},
+		"over min":  {5, 10, 10},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			backoff := NewFloorBackoff(attemptBackoff{}, tc.min)
+			got := backoff.Attempt(tc.attempt)
+			ztesting.AssertEqual(t, "wrong backoff duration for attempt="+strconv.Itoa(tc.attempt), tc.want, got)
+		})
+	}
+}
+
+func TestScaledBackoff(t *testing.T) {
+	t.Parallel()
+	t.Run("panic when backoff=nil", func(t *testing.T) {
+		defer func() {
+			got, _ := recover().(error)
+			want := &BackoffError{Type: errPram, Info: "backoff must be non-nil"}
+			ztesting.AssertEqualErr(t, "error not matched", want, got)
+		}()
+		NewScaledBackoff(nil, 1)
+	})
+	t.Run("panic when factor<0", func(t *testing.T) {
+		defer func() {
+			got, _ := recover().(error)
+			want := &BackoffError{Type: errPram, Info: "factor must be zero or positive"}
+			ztesting.AssertEqualErr(t, "error not matched", want, got)
+		}()
+		NewScaledBackoff(attemptBackoff{}, -1)
+	})
+
+	testCases := map[string]struct {
+		factor  float64
+		attempt int
+		want    time.Duration
+	}{
+		"factor=0":   {0, 10, 0},
+		"factor=0.5": {0.5, 10, 5},
+		"factor=1":   {1, 10, 10},
+		"factor=3":   {3, 10, 30},
+		"overflow":   {2, math.MaxInt, math.MaxInt64},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			backoff := NewScaledBackoff(attemptBackoff{}, tc.factor)
+			got := backoff.Attempt(tc.attempt)
+			ztesting.AssertEqual(t, "wrong backoff duration for attempt="+strconv.Itoa(tc.attempt), tc.want, got)
+		})
+	}
+}
+
+func TestSequenceBackoff(t *testing.T) {
+	t.Parallel()
+	t.Run("panic when steps are empty", func(t *testing.T) {
+		defer func() {
+			got, _ := recover().(error)
+			want := &BackoffError{Type: errPram, Info: "steps must not be empty"}
+			ztesting.AssertEqualErr(t, "error not matched", want, got)
+		}()
+		NewSequenceBackoff()
+	})
+	t.Run("panic when backoff=nil", func(t *testing.T) {
+		defer func() {
+			got, _ := recover().(error)
+			want := &BackoffError{Type: errPram, Info: "steps.backoff must be non-nil"}
+			ztesting.AssertEqualErr(t, "error not matched", want, got)
+		}()
+		NewSequenceBackoff(SequenceStep{Attempts: 1})
+	})
+	t.Run("panic when attempts<=0", func(t *testing.T) {
+		defer func() {
+			got, _ := recover().(error)
+			want := &BackoffError{Type: errPram, Info: "steps.attempts must be positive"}
+			ztesting.AssertEqualErr(t, "error not matched", want, got)
+		}()
+		NewSequenceBackoff(SequenceStep{Backoff: attemptBackoff{}}, SequenceStep{Backoff: attemptBackoff{}})
+	})
+
+	backoff := NewSequenceBackoff(
+		SequenceStep{Backoff: NewFixedBackoff(time.Second), Attempts: 2},
+		SequenceStep{Backoff: NewScaledBackoff(attemptBackoff{}, 10), Attempts: 2},
+		SequenceStep{Backoff: attemptBackoff{}, Attempts: 0},
+	)
+	testCases := map[string]struct {
+		attempt int
+		want    time.Duration
+	}{
+		"attempt=-1": {-1, time.Second},
+		"attempt=0":  {0, time.Second},
+		"attempt=1":  {1, time.Second},
+		"attempt=2":  {2, time.Second},
+		"attempt=3":  {3, 10},
+		"attempt=4":  {4, 20},
+		"attempt=5":  {5, 1},
+		"attempt=10": {10, 6},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got := backoff.Attempt(tc.attempt)
+			ztesting.AssertEqual(t, "wrong backoff duration for attempt="+strconv.Itoa(tc.attempt), tc.want, got)
+		})
+	}
+}
+
+func TestCompose_Clone(t *testing.T) {
+	t.Parallel()
+	inner := NewDecorrelatedJitterBackoff(time.Second, time.Hour)
+	fixed := NewFixedBackoff(time.Second)
+	testCases := map[string]struct {
+		backoff Backoff
+		inner   func(Backoff) Backoff
+	}{
+		"capped":   {NewCappedBackoff(inner, time.Minute), func(b Backoff) Backoff { return b.(*CappedBackoff).backoff }},
+		"floor":    {NewFloorBackoff(inner, time.Minute), func(b Backoff) Backoff { return b.(*FloorBackoff).backoff }},
+		"scaled":   {NewScaledBackoff(inner, 2), func(b Backoff) Backoff { return b.(*ScaledBackoff).backoff }},
+		"sequence": {NewSequenceBackoff(SequenceStep{Backoff: fixed, Attempts: 1}, SequenceStep{Backoff: inner}), func(b Backoff) Backoff { return b.(*SequenceBackoff).steps[1].Backoff }},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c := tc.backoff.(Cloner).Clone()
+			ztesting.AssertEqual(t, "clone must be a new instance", true, tc.backoff != c)
+			ztesting.AssertEqual(t, "inner backoff must be cloned", true, tc.inner(c) != Backoff(inner))
+			ztesting.AssertEqual(t, "inner backoff must not be changed", Backoff(inner), tc.inner(tc.backoff))
+		})
+	}
+	c := NewSequenceBackoff(SequenceStep{Backoff: fixed}).Clone().(*SequenceBackoff)
+	ztesting.AssertEqual(t, "stateless backoff must not be cloned", Backoff(fixed), c.steps[0].Backoff)
+}
@@ -0,0 +1,211 @@
+package zbackoff
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errConfig = "invalid config."
+)
+
+// Config is the configuration of a [Backoff].
+// It is intended to be loaded from configuration files.
+// Durations are written in the format of [time.ParseDuration]
+// such as "500ms" and "1m30s". Empty durations are zero.
+//
+// Parameters used by each type are listed below.
+// See the constructors of each backoff for details.
+//
+//	Type                   | Constructor                         | Parameters
+//	---------------------- | ----------------------------------- | --------------------------------
+//	fixed                  | [NewFixedBackoff]                   | Value
+//	random                 | [NewRandomBackoff]                  | Offset, Fluctuation
+//	linear                 | [NewLinearBackoff]                  | Offset, Fluctuation, Coeff
+//	polynomial             | [NewPolynomialBackoff]              | Offset, Fluctuation, Coeff, Exponent
+//	exponential            | [NewExponentialBackoff]             | Offset, Fluctuation, Coeff
+//	exponentialFullJitter  | [NewExponentialBackoffFullJitter]   | Offset, Fluctuation, Coeff
+//	exponentialEqualJitter | [NewExponentialBackoffEqualJitter]  | Offset, Fluctuation, Coeff
+//	fibonacci              | [NewFibonacciBackoff]               | Offset, Fluctuation, Coeff
+//	decorrelatedJitter     | [NewDecorrelatedJitterBackoff]      | Offset, Fluctuation
+//	sequence               | [NewSequenceBackoff]                | Steps
+//
+// Scale, Min and Max are applied to all types in this order
+// using [NewScaledBackoff], [NewFloorBackoff] and [NewCappedBackoff].
+type Config struct {
+	// Type is the type of the backoff.
+	// Type is case insensitive.
+	Type string `json:"type" yaml:"type"`
+	// Value is the fixed duration.
+	Value string `json:"value,omitempty" yaml:"value,omitempty"`
+	// Offset is the offset duration.
+	Offset string `json:"offset,omitempty" yaml:"offset,omitempty"`
+	// Fluctuation is the value range of the fluctuation part.
+	Fluctuation string `json:"fluctuation,omitempty" yaml:"fluctuation,omitempty"`
+	// Coeff is the coefficient duration.
+	Coeff string `json:"coeff,omitempty" yaml:"coeff,omitempty"`
+	// Exponent is the exponent of the polynomial backoff.
+	Exponent float64 `json:"exponent,omitempty" yaml:"exponent,omitempty"`
+	// Steps is the steps of the sequence backoff.
+	Steps []*Config `json:"steps,omitempty" yaml:"steps,omitempty"`
+	// Attempts is the number of attempts handled by this backoff
+	// when this config is a step of the sequence backoff.
+	Attempts int `json:"attempts,omitempty" yaml:"attempts,omitempty"`
+	// Scale, if non-zero, multiplies the duration.
+	Scale float64 `json:"scale,omitempty" yaml:"scale,omitempty"`
+	// Min, if non-empty, is the minimum duration.
+	Min string `json:"min,omitempty" yaml:"min,omitempty"`
+	// Max, if non-empty, is the maximum duration.
+	Max string `json:"max,omitempty" yaml:"max,omitempty"`
+}
+
+// New returns a new [Backoff] built from the config.
+// Unlike the constructors of each backoff, New returns
+// a [*BackoffError] instead of panicking for invalid parameters.
+func New(c *Config) (b Backoff, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*BackoffError)
+			if !ok {
+				panic(r)
+			}
+			b, err = nil, e
+		}
+	}()
+	return newBackoff(c), nil
+}
+
+// newBackoff returns a new [Backoff] built from the config.
+// It panics with a [*BackoffError] for invalid config.
+func newBackoff(c *Config) Backoff {
+	if c == nil {
+		panic(&BackoffError{Type: errConfig, Info: "config must be non-nil"})
+	}
+	var b Backoff
+	switch strings.ToLower(c.Type) {
+	case "fixed":
+		b = NewFixedBackoff(mustDuration(c.Value, "value"))
+	case "random":
+		b = NewRandomBackoff(mustDuration(c.Offset, "offset"), mustDuration(c.Fluctuation, "fluctuation"))
+	case "linear":
+		b = NewLinearBackoff(mustDuration(c.Offset, "offset"), mustDuration(c.Fluctuation, "fluctuation"), mustDuration(c.Coeff, "coeff"))
+	case "polynomial":
+		b = NewPolynomialBackoff(mustDuration(c.Offset, "offset"), mustDuration(c.Fluctuation, "fluctuation"), mustDuration(c.Coeff, "coeff"), c.Exponent)
+	case "exponential":
+		b = NewExponentialBackoff(mustDuration(c.Offset, "offset"), mustDuration(c.Fluctuation, "fluctuation"), mustDuration(c.Coeff, "coeff"))
+	case "exponentialfulljitter":
+		b = NewExponentialBackoffFullJitter(mustDuration(c.Offset, "offset"), mustDuration(c.Fluctuation, "fluctuation"), mustDuration(c.Coeff, "coeff"))
+	case "exponentialequaljitter":
+		b = NewExponentialBackoffEqualJitter(mustDuration(c.Offset, "offset"), mustDuration(c.Fluctuation, "fluctuation"), mustDuration(c.Coeff, "coeff"))
+	case "fibonacci":
+		b = NewFibonacciBackoff(mustDuration(c.Offset, "offset"), mustDuration(c.Fluctuation, "fluctuation"), mustDuration(c.Coeff, "coeff"))
+	case "decorrelatedjitter":
+		b = NewDecorrelatedJitterBackoff(mustDuration(c.Offset, "offset"), mustDuration(c.Fluctuation, "fluctuation"))
+	case "sequence":
+		steps := make([]SequenceStep, 0, len(c.Steps))
+		for _, s := range c.Steps {
+			steps = append(steps, SequenceStep{Backoff: newBackoff(s), Attempts: s.Attempts})
+		}
+		b = NewSequenceBackoff(steps...)
+	default:
+		panic(&BackoffError{Type: errConfig, Info: "unknown type " + strconv.Quote(c.Type)})
+	}
+	if c.Scale != 0 {
+		b = NewScaledBackoff(b, c.Scale)
+	}
+	if c.Min != "" {
+		b = NewFloorBackoff(b, mustDuration(c.Min, "min"))
+	}
+	if c.Max != "" {
+		b = NewCappedBackoff(b, mustDuration(c.Max, "max"))
+	}
+	return b
+}
+
+func mustDuration(s string, name string) time.Duration {
+	if s == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		panic(&BackoffError{Type: errConfig, Info: name + " must be a duration. got " + strconv.Quote(s)})
+	}
+	return d
+}
+
+// Parse returns a new [Backoff] built from the string.
+// The string consists of the type and the parameters of
+// [Config] in the form of "key=value" separated by spaces.
+// Keys are case insensitive.
+// Steps of the sequence backoff are separated by ";"
+// and the type "sequence" is omitted.
+// Parse returns a [*BackoffError] for invalid strings.
+//
+// Examples:
+//
+//	"fixed value=1s"
+//	"exponentialFullJitter fluctuation=10s coeff=100ms max=5s"
+//	"fixed value=1s attempts=3; exponential fluctuation=1m coeff=1s"
+func Parse(s string) (Backoff, error) {
+	items := strings.Split(s, ";")
+	if len(items) == 1 {
+		c, err := parseConfig(items[0])
+		if err != nil {
+			return nil, err
+		}
+		return New(c)
+	}
+	c := &Config{Type: "sequence"}
+	for _, item := range items {
+		step, err := parseConfig(item)
+		if err != nil {
+			return nil, err
+		}
+		c.Steps = append(c.Steps, step)
+	}
+	return New(c)
+}
+
+// parseConfig parses a single backoff definition
+// in the form of "type key=value key=value ...".
+func parseConfig(s string) (*Config, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil, &BackoffError{Type: errConfig, Info: "type must be specified"}
+	}
+	c := &Config{Type: fields[0]}
+	for _, f := range fields[1:] {
+		key, val, found := strings.Cut(f, "=")
+		if !found {
+			return nil, &BackoffError{Type: errConfig, Info: "parameter must be key=value. got " + strconv.Quote(f)}
+		}
+		var err error
+		switch strings.ToLower(key) {
+		case "value":
+			c.Value = val
+		case "offset":
+			c.Offset = val
+		case "fluctuation":
+			c.Fluctuation = val
+		case "coeff":
+			c.Coeff = val
+		case "min":
+			c.Min = val
+		case "max":
+			c.Max = val
+		case "exponent":
+			c.Exponent, err = strconv.ParseFloat(val, 64)
+		case "scale":
+			c.Scale, err = strconv.ParseFloat(val, 64)
+		case "attempts":
+			c.Attempts, err = strconv.Atoi(val)
+		default:
+			return nil, &BackoffError{Type: errConfig, Info: "unknown parameter " + strconv.Quote(key)}
+		}
+		if err != nil {
+			return nil, &BackoffError{Type: errConfig, Info: key + " must be a number. got " + strconv.Quote(val)}
+		}
+	}
+	return c, nil
+}
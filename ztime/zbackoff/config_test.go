@@ -0,0 +1,93 @@
+package zbackoff
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		config *Config
+		want   []time.Duration // Wanted durations for attempts 1, 2, 3...
+		err    error
+	}{
+		"fixed":                  {&Config{Type: "fixed", Value: "1s"}, []time.Duration{time.Second, time.Second}, nil},
+		"case insensitive":       {&Config{Type: "FIXED", Value: "1s"}, []time.Duration{time.Second}, nil},
+		"linear":                 {&Config{Type: "linear", Offset: "1s", Fluctuation: "1m", Coeff: "1s"}, []time.Duration{2 * time.Second, 3 * time.Second}, nil},
+		"polynomial":             {&Config{Type: "polynomial", Fluctuation: "1m", Coeff: "1s", Exponent: 2}, []time.Duration{time.Second, 4 * time.Second}, nil},
+		"exponential":            {&Config{Type: "exponential", Fluctuation: "1m", Coeff: "1s"}, []time.Duration{2 * time.Second, 4 * time.Second}, nil},
+		"fibonacci":              {&Config{Type: "fibonacci", Fluctuation: "1m", Coeff: "1s"}, []time.Duration{time.Second, time.Second, 2 * time.Second}, nil},
+		"random":                 {&Config{Type: "random", Offset: "1s", Fluctuation: "1ns"}, []time.Duration{time.Second}, nil},
+		"exponentialFullJitter":  {&Config{Type: "exponentialFullJitter", Offset: "1s", Fluctuation: "1ns", Coeff: "1ns"}, []time.Duration{time.Second}, nil},
+		"exponentialEqualJitter": {&Config{Type: "exponentialEqualJitter", Fluctuation: "1ns", Coeff: "1ns"}, []time.Duration{1}, nil},
+		"decorrelatedJitter":     {&Config{Type: "decorrelatedJitter", Offset: "1s", Fluctuation: "1ns"}, []time.Duration{time.Second}, nil},
+		"scale min max":          {&Config{Type: "linear", Fluctuation: "1m", Coeff: "1s", Scale: 2, Min: "3s", Max: "6s"}, []time.Duration{3 * time.Second, 4 * time.Second, 6 * time.Second, 6 * time.Second}, nil},
+		"sequence": {&Config{Type: "sequence", Steps: []*Config{
+			{Type: "fixed", Value: "1s", Attempts: 2},
+			{Type: "exponential", Fluctuation: "1m", Coeff: "1s"},
+		}}, []time.Duration{time.Second, time.Second, 2 * time.Second, 4 * time.Second}, nil},
+		"nil config":         {nil, nil, &BackoffError{Type: errConfig, Info: "config must be non-nil"}},
+		"unknown type":       {&Config{Type: "foo"}, nil, &BackoffError{Type: errConfig, Info: `unknown type "foo"`}},
+		"invalid duration":   {&Config{Type: "fixed", Value: "foo"}, nil, &BackoffError{Type: errConfig, Info: `value must be a duration. got "foo"`}},
+		"invalid parameter":  {&Config{Type: "fixed", Value: "-1s"}, nil, &BackoffError{Type: errPram, Info: "value must be zero or positive"}},
+		"invalid step":       {&Config{Type: "sequence", Steps: []*Config{{Type: "fixed"}, {Type: "fixed"}}}, nil, &BackoffError{Type: errPram, Info: "steps.attempts must be positive"}},
+		"empty steps":        {&Config{Type: "sequence"}, nil, &BackoffError{Type: errPram, Info: "steps must not be empty"}},
+		"invalid max":        {&Config{Type: "fixed", Max: "foo"}, nil, &BackoffError{Type: errConfig, Info: `max must be a duration. got "foo"`}},
+		"invalid scale":      {&Config{Type: "fixed", Scale: -1}, nil, &BackoffError{Type: errPram, Info: "factor must be zero or positive"}},
+		"nil step in config": {&Config{Type: "sequence", Steps: []*Config{nil}}, nil, &BackoffError{Type: errConfig, Info: "config must be non-nil"}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			b, err := New(tc.config)
+			ztesting.AssertEqualErr(t, "error not matched", tc.err, err)
+			if tc.err != nil {
+				ztesting.AssertEqual(t, "non nil backoff returned", nil, b)
+				return
+			}
+			for i, want := range tc.want {
+				ztesting.AssertEqual(t, "wrong backoff duration for attempt="+strconv.Itoa(i+1), want, b.Attempt(i+1))
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		s    string
+		want []time.Duration // Wanted durations for attempts 1, 2, 3...
+		err  error
+	}{
+		"fixed":             {"fixed value=1s", []time.Duration{time.Second, time.Second}, nil},
+		"spaces":            {"  fixed   Value=1s ", []time.Duration{time.Second}, nil},
+		"all parameters":    {"polynomial offset=1s fluctuation=1m coeff=1s exponent=2 scale=2 min=1s max=30s", []time.Duration{4 * time.Second, 10 * time.Second, 20 * time.Second, 30 * time.Second}, nil},
+		"sequence":          {"fixed value=1s attempts=2; exponential fluctuation=1m coeff=1s", []time.Duration{time.Second, time.Second, 2 * time.Second, 4 * time.Second}, nil},
+		"empty":             {"", nil, &BackoffError{Type: errConfig, Info: "type must be specified"}},
+		"empty step":        {"fixed value=1s;", nil, &BackoffError{Type: errConfig, Info: "type must be specified"}},
+		"no value":          {"fixed value", nil, &BackoffError{Type: errConfig, Info: `parameter must be key=value. got "value"`}},
+		"unknown parameter": {"fixed foo=1s", nil, &BackoffError{Type: errConfig, Info: `unknown parameter "foo"`}},
+		"invalid number":    {"fixed value=1s attempts=foo", nil, &BackoffError{Type: errConfig, Info: `attempts must be a number. got "foo"`}},
+		"invalid float":     {"fixed value=1s scale=foo", nil, &BackoffError{Type: errConfig, Info: `scale must be a number. got "foo"`}},
+		"unknown type":      {"foo value=1s", nil, &BackoffError{Type: errConfig, Info: `unknown type "foo"`}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			b, err := Parse(tc.s)
+			ztesting.AssertEqualErr(t, "error not matched", tc.err, err)
+			if tc.err != nil {
+				return
+			}
+			for i, want := range tc.want {
+				ztesting.AssertEqual(t, "wrong backoff duration for attempt="+strconv.Itoa(i+1), want, b.Attempt(i+1))
+			}
+		})
+	}
+}
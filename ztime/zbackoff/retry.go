@@ -0,0 +1,204 @@
+package zbackoff
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aileron-projects/go/zerrors"
+)
+
+var (
+	// ErrMaxAttempts indicates the retry was given up
+	// because the number of attempts reached the [Retrier.MaxAttempts].
+	ErrMaxAttempts = errors.New("zbackoff: max attempts exceeded")
+	// ErrMaxElapsed indicates the retry was given up
+	// because the next attempt exceeds the [Retrier.MaxElapsed].
+	ErrMaxElapsed = errors.New("zbackoff: max elapsed time exceeded")
+	// ErrBudgetExhausted indicates the retry was given up
+	// because the [Retrier.Budget] has no retry left.
+	ErrBudgetExhausted = errors.New("zbackoff: retry budget exhausted")
+)
+
+// RetryError reports that the retry was given up.
+// Both the last error returned by the function and the
+// reason of giving up can be checked with [errors.Is].
+type RetryError struct {
+	// Err is the last error returned by the function.
+	Err error
+	// Reason is the reason of giving up.
+	// It is one of the [ErrMaxAttempts], [ErrMaxElapsed],
+	// [ErrBudgetExhausted] or the error of the context.
+	Reason error
+	// Attempts is the number of attempts made.
+	Attempts int
+}
+
+func (e *RetryError) Error() string {
+	msg := "zbackoff: retry given up after " + strconv.Itoa(e.Attempts) + " attempts"
+	if e.Reason != nil {
+		msg += ". " + e.Reason.Error()
+	}
+	if e.Err != nil {
+		msg += " [" + e.Err.Error() + "]"
+	}
+	return msg
+}
+
+func (e *RetryError) Unwrap() []error {
+	return []error{e.Err, e.Reason}
+}
+
+// Retrier runs functions repeatedly until they succeed.
+// The zero value is usable and retries any errors without wait
+// until the function succeeds or the context is done.
+// Retrier is safe for concurrent use as long as its fields
+// are not modified.
+//
+// Example:
+//
+//	r := &zbackoff.Retrier{
+//		Backoff:     zbackoff.NewExponentialBackoffFullJitter(0, 10*time.Second, 100*time.Millisecond),
+//		MaxAttempts: 5,
+//		RetryOn:     []error{io.ErrUnexpectedEOF},
+//	}
+//	err := r.Do(ctx, func(ctx context.Context) error {
+//		return call(ctx)
+//	})
+type Retrier struct {
+	// Backoff provides the wait duration before each retry.
+	// Backoff.Attempt is called with the number of failed
+	// attempts, which starts from 1.
+	// Backoff that implements [Cloner] is cloned for each call
+	// of [Retrier.Do] and [DoValue] so that stateful backoff such as
+	// [DecorrelatedJitterBackoff] is not shared between them.
+	// If nil, retries are made without wait.
+	Backoff Backoff
+	// MaxAttempts is the maximum number of attempts
+	// including the first one.
+	// If zero or negative, the number of attempts is not limited.
+	MaxAttempts int
+	// MaxElapsed is the maximum duration from the start of
+	// the first attempt to the start of the last attempt.
+	// A retry that would start after MaxElapsed is not made.
+	// If zero or negative, the elapsed time is not limited.
+	MaxElapsed time.Duration
+	// RetryOn is the list of errors to be retried.
+	// Errors are compared with [errors.Is].
+	RetryOn []error
+	// RetryOnDefs is the list of error definitions to be retried.
+	// Errors are compared with [zerrors.Definition.Is].
+	RetryOnDefs []zerrors.Definition
+	// Retryable, if non-nil, reports if the error should be retried.
+	//
+	// Errors are retried when any of RetryOn, RetryOnDefs and Retryable
+	// matches. If all of them are empty, all errors are retried.
+	// Errors of the context passed to the function are not retried
+	// when the context is done.
+	Retryable func(err error) bool
+	// Budget, if non-nil, limits the retries.
+	// Sharing a Budget across Retriers limits the total
+	// retries of the callers to prevent retry storms.
+	Budget *Budget
+	// OnAttempt, if non-nil, is called after each attempt.
+	// err is nil when the attempt succeeded.
+	OnAttempt func(attempt int, err error)
+	// OnRetry, if non-nil, is called before waiting for the next attempt.
+	// attempt is the number of the next attempt and wait is the
+	// duration to wait before it.
+	OnRetry func(attempt int, err error, wait time.Duration)
+}
+
+// Do calls f until it succeeds or the retry is given up.
+// It returns nil when f succeeded.
+// It returns the error returned by f as-is when the error is not retryable.
+// Otherwise, a [*RetryError] is returned.
+// The context is passed to f and is used to cancel waiting.
+func (r *Retrier) Do(ctx context.Context, f func(context.Context) error) error {
+	_, err := DoValue(ctx, r, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, f(ctx)
+	})
+	return err
+}
+
+// DoValue calls f with the retrier r until f succeeds or the retry is given up.
+// It returns the value returned by f when it succeeded.
+// Otherwise, it returns the value returned by the last call of f.
+// See [Retrier.Do] for the returned errors.
+func DoValue[T any](ctx context.Context, r *Retrier, f func(context.Context) (T, error)) (T, error) {
+	if r.Budget != nil {
+		r.Budget.deposit()
+	}
+	backoff := clone(r.Backoff)
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		v, err := f(ctx)
+		if r.OnAttempt != nil {
+			r.OnAttempt(attempt, err)
+		}
+		if err == nil {
+			return v, nil
+		}
+		if ctx.Err() != nil {
+			return v, &RetryError{Err: err, Reason: ctx.Err(), Attempts: attempt}
+		}
+		if !r.retryable(err) {
+			return v, err
+		}
+		if r.MaxAttempts > 0 && attempt >= r.MaxAttempts {
+			return v, &RetryError{Err: err, Reason: ErrMaxAttempts, Attempts: attempt}
+		}
+		var wait time.Duration
+		if backoff != nil {
+			wait = backoff.Attempt(attempt)
+		}
+		if r.MaxElapsed > 0 && time.Since(start)+wait > r.MaxElapsed {
+			return v, &RetryError{Err: err, Reason: ErrMaxElapsed, Attempts: attempt}
+		}
+		if r.Budget != nil && !r.Budget.withdraw() {
+			return v, &RetryError{Err: err, Reason: ErrBudgetExhausted, Attempts: attempt}
+		}
+		if r.OnRetry != nil {
+			r.OnRetry(attempt+1, err, wait)
+		}
+		if sleep(ctx, wait) != nil {
+			return v, &RetryError{Err: err, Reason: ctx.Err(), Attempts: attempt}
+		}
+	}
+}
+
+// retryable returns if the error should be retried.
+func (r *Retrier) retryable(err error) bool {
+	if len(r.RetryOn) == 0 && len(r.RetryOnDefs) == 0 && r.Retryable == nil {
+		return true
+	}
+	for _, target := range r.RetryOn {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	for _, def := range r.RetryOnDefs {
+		if def.Is(err) {
+			return true
+		}
+	}
+	return r.Retryable != nil && r.Retryable(err)
+}
+
+// sleep waits for the duration d.
+// It returns the error of the context when
+// the context is done before d elapsed.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
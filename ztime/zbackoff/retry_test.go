@@ -0,0 +1,173 @@
+package zbackoff
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/zerrors"
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestRetryError(t *testing.T) {
+	t.Parallel()
+	err := &RetryError{Err: io.EOF, Reason: ErrMaxAttempts, Attempts: 3}
+	ztesting.AssertEqual(t, "error message not match",
+		"zbackoff: retry given up after 3 attempts. zbackoff: max attempts exceeded [EOF]", err.Error())
+	ztesting.AssertEqual(t, "last error not matched", true, errors.Is(err, io.EOF))
+	ztesting.AssertEqual(t, "reason not matched", true, errors.Is(err, ErrMaxAttempts))
+	ztesting.AssertEqual(t, "wrong reason matched", false, errors.Is(err, ErrMaxElapsed))
+}
+
+func TestRetrier_Do(t *testing.T) {
+	t.Parallel()
+
+	def := zerrors.NewDefinition("E1", "zbackoff", "test error", "")
+	errTest := errors.New("test error")
+
+	testCases := map[string]struct {
+		retrier  *Retrier
+		errs     []error // Errors returned by each attempt.
+		attempts int
+		err      error // Expected error checked with errors.Is.
+		reason   error // Expected reason of giving up.
+	}{
+		"success":            {&Retrier{}, []error{nil}, 1, nil, nil},
+		"success after fail": {&Retrier{}, []error{io.EOF, io.EOF, nil}, 3, nil, nil},
+		"max attempts":       {&Retrier{MaxAttempts: 2}, []error{io.EOF, io.EOF, nil}, 2, io.EOF, ErrMaxAttempts},
+		"retry on":           {&Retrier{RetryOn: []error{io.EOF}}, []error{io.EOF, nil}, 2, nil, nil},
+		"not retry on":       {&Retrier{RetryOn: []error{io.EOF}}, []error{errTest, nil}, 1, errTest, nil},
+		"retry on defs":      {&Retrier{RetryOnDefs: []zerrors.Definition{def}}, []error{def.New(nil), nil}, 2, nil, nil},
+		"not retry on defs":  {&Retrier{RetryOnDefs: []zerrors.Definition{def}}, []error{errTest, nil}, 1, errTest, nil},
+		"retryable":          {&Retrier{Retryable: func(err error) bool { return err == errTest }}, []error{errTest, nil}, 2, nil, nil},
+		"not retryable":      {&Retrier{Retryable: func(err error) bool { return err == errTest }}, []error{io.EOF, nil}, 1, io.EOF, nil},
+		"any of them":        {&Retrier{RetryOn: []error{io.EOF}, Retryable: func(err error) bool { return err == errTest }}, []error{io.EOF, errTest, nil}, 3, nil, nil},
+		"max elapsed":        {&Retrier{Backoff: NewFixedBackoff(time.Hour), MaxElapsed: time.Minute}, []error{io.EOF, nil}, 1, io.EOF, ErrMaxElapsed},
+		"backoff":            {&Retrier{Backoff: NewFixedBackoff(time.Millisecond), MaxElapsed: time.Minute}, []error{io.EOF, nil}, 2, nil, nil},
+		"budget":             {&Retrier{Budget: NewBudget(0.1, 1)}, []error{io.EOF, io.EOF, nil}, 2, io.EOF, ErrBudgetExhausted},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			attempts := 0
+			err := tc.retrier.Do(context.Background(), func(ctx context.Context) error {
+				attempts++
+				return tc.errs[attempts-1]
+			})
+			ztesting.AssertEqual(t, "wrong number of attempts", tc.attempts, attempts)
+			ztesting.AssertEqual(t, "error not match", true, errors.Is(err, tc.err))
+			var re *RetryError
+			ztesting.AssertEqual(t, "retry error not match", tc.reason != nil, errors.As(err, &re))
+			if tc.reason != nil {
+				ztesting.AssertEqual(t, "reason not match", tc.reason, re.Reason)
+				ztesting.AssertEqual(t, "attempts not match", tc.attempts, re.Attempts)
+			}
+		})
+	}
+}
+
+// cloneBackoff is a stateful backoff that
+// returns the number of calls of Attempt.
+type cloneBackoff struct {
+	calls  int
+	clones *int
+}
+
+func (b *cloneBackoff) Clone() Backoff {
+	*b.clones++
+	return &cloneBackoff{clones: b.clones}
+}
+
+func (b *cloneBackoff) Attempt(int) time.Duration {
+	b.calls++
+	return time.Duration(b.calls)
+}
+
+func TestRetrier_clone(t *testing.T) {
+	t.Parallel()
+	clones := 0
+	b := &cloneBackoff{clones: &clones}
+	var waits []time.Duration
+	r := &Retrier{
+		Backoff:     b,
+		MaxAttempts: 3,
+		OnRetry:     func(_ int, _ error, wait time.Duration) { waits = append(waits, wait) },
+	}
+	for range 2 {
+		_ = r.Do(context.Background(), func(ctx context.Context) error { return io.EOF })
+	}
+	ztesting.AssertEqual(t, "backoff must be cloned for each call", 2, clones)
+	ztesting.AssertEqual(t, "original backoff must not be used", 0, b.calls)
+	ztesting.AssertEqual(t, "waits not match", []time.Duration{1, 2, 1, 2}, waits)
+}
+
+func TestRetrier_context(t *testing.T) {
+	t.Parallel()
+	t.Run("canceled while waiting", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		r := &Retrier{
+			Backoff: NewFixedBackoff(time.Hour),
+			OnRetry: func(int, error, time.Duration) { cancel() },
+		}
+		err := r.Do(ctx, func(ctx context.Context) error { return io.EOF })
+		ztesting.AssertEqual(t, "last error not match", true, errors.Is(err, io.EOF))
+		ztesting.AssertEqual(t, "context error not match", true, errors.Is(err, context.Canceled))
+	})
+	t.Run("canceled while running", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		attempts := 0
+		err := (&Retrier{}).Do(ctx, func(ctx context.Context) error {
+			attempts++
+			cancel()
+			return ctx.Err()
+		})
+		ztesting.AssertEqual(t, "wrong number of attempts", 1, attempts)
+		ztesting.AssertEqual(t, "context error not match", true, errors.Is(err, context.Canceled))
+	})
+}
+
+func TestRetrier_hooks(t *testing.T) {
+	t.Parallel()
+	var attempts, retries []int
+	var waits []time.Duration
+	r := &Retrier{
+		Backoff:   NewLinearBackoff(0, time.Millisecond, time.Microsecond),
+		OnAttempt: func(attempt int, err error) { attempts = append(attempts, attempt) },
+		OnRetry: func(attempt int, err error, wait time.Duration) {
+			retries = append(retries, attempt)
+			waits = append(waits, wait)
+		},
+	}
+	n := 0
+	err := r.Do(context.Background(), func(ctx context.Context) error {
+		if n++; n < 3 {
+			return io.EOF
+		}
+		return nil
+	})
+	ztesting.AssertEqual(t, "non nil error returned", nil, err)
+	ztesting.AssertEqual(t, "attempts not match", []int{1, 2, 3}, attempts)
+	ztesting.AssertEqual(t, "retries not match", []int{2, 3}, retries)
+	ztesting.AssertEqual(t, "waits not match", []time.Duration{time.Microsecond, 2 * time.Microsecond}, waits)
+}
+
+func TestDoValue(t *testing.T) {
+	t.Parallel()
+	n := 0
+	v, err := DoValue(context.Background(), &Retrier{MaxAttempts: 3}, func(ctx context.Context) (int, error) {
+		if n++; n < 3 {
+			return n, io.EOF
+		}
+		return n * 10, nil
+	})
+	ztesting.AssertEqual(t, "non nil error returned", nil, err)
+	ztesting.AssertEqual(t, "value not match", 30, v)
+
+	v, err = DoValue(context.Background(), &Retrier{MaxAttempts: 2}, func(ctx context.Context) (int, error) {
+		return 5, io.EOF
+	})
+	ztesting.AssertEqual(t, "error not match", true, errors.Is(err, ErrMaxAttempts))
+	ztesting.AssertEqual(t, "last value not match", 5, v)
+}
@@ -1,10 +1,16 @@
 package zcron
 
 import (
+	"cmp"
 	"context"
 	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/aileron-projects/go/ztime/zbackoff"
 )
 
 // Event is the event definition on cron.
@@ -17,6 +23,11 @@ import (
 //   - [OnJobExited]
 //   - [OnJobFailed]
 //   - [OnJobPanicked]
+//   - [OnJobRetried]
+//   - [OnJobQueued]
+//   - [OnJobCanceled]
+//   - [OnJobSkipped]
+//   - [OnStateError]
 type Event int
 
 const (
@@ -28,6 +39,33 @@ const (
 	OnJobExited         // OnJobExited triggered just after [Config.JobFunc] is returned.
 	OnJobFailed         // OnJobFailed triggered when [Config.JobFunc] returned an error. This trigger is before [OnJobExited].
 	OnJobPanicked       // OnJobPanicked triggered when [Config.JobFunc] panicked. This trigger is before [OnJobExited].
+	OnJobRetried        // OnJobRetried triggered just before waiting the backoff for a retry.
+	OnJobQueued         // OnJobQueued triggered when job is queued by [OverlapQueue] or [OverlapCancel].
+	OnJobCanceled       // OnJobCanceled triggered when a running job is canceled by [OverlapCancel].
+	OnJobSkipped        // OnJobSkipped triggered when job is skipped because it is locked by another owner or already run.
+	OnStateError        // OnStateError triggered when [Config.Locker] or [Config.StateStore] returned an error.
+)
+
+// OverlapPolicy is the policy that is applied when a job is
+// scheduled while [Config.MaxConcurrency] jobs are running.
+type OverlapPolicy int
+
+const (
+	// OverlapSkip declines the new run.
+	// This is the default policy.
+	OverlapSkip OverlapPolicy = iota
+	// OverlapQueue queues at most one new run.
+	// The queued run starts when one of the running jobs exited.
+	// Runs are declined while another run is queued.
+	OverlapQueue
+	// OverlapCancel cancels the context of the oldest running job
+	// and queues the new run. The queued run starts when one of the
+	// running jobs exited. Runs are declined while another run is queued.
+	// Note that jobs which ignore the context are not stopped.
+	OverlapCancel
+	// OverlapAllow always runs the new run.
+	// [Config.MaxConcurrency] is ignored.
+	OverlapAllow
 )
 
 var (
@@ -35,36 +73,106 @@ var (
 	ErrNilConfig = errors.New("ztime/zcron: nil config")
 	// ErrNilJob indicates nil job function was given.
 	ErrNilJob = errors.New("ztime/zcron: nil job function")
+	// ErrEmptyName indicates the job name is empty
+	// though it is required.
+	ErrEmptyName = errors.New("ztime/zcron: empty job name")
 )
 
 // Config is the configuration for the [Cron].
 type Config struct {
+	// Name is the name of the job.
+	// It is used as the key of the Locker and the StateStore.
+	// Name is required when the Locker or the StateStore is set.
+	// [Scheduler] uses the name given to the [Scheduler.Add] instead.
+	Name string
 	// Crontab is the cron expression.
 	// See [Parse] for the syntax.
 	Crontab string
+	// DayOr, if true, evaluates the "Day of month" and the "Day of week"
+	// of the Crontab with OR condition.
+	// See [ParseOption] for details.
+	DayOr bool
 	// MaxConcurrency is the maximum concurrency
 	// of the currently running JobFunc.
 	// Values should be 1, 2, ...
 	// 1 means exactly 1 job at a time.
 	// If less than 1, 1 is used.
 	MaxConcurrency int
+	// Overlap is the policy applied when the job is
+	// scheduled while MaxConcurrency jobs are running.
+	// Default is [OverlapSkip].
+	Overlap OverlapPolicy
 	// MaxRetry is the maximum number to retry
 	// when the JobFunc returned non-nil error.
 	// Values should be 0, 1, 2, ...
 	// 0 means no retry. If less than 0, 0 is used.
+	// Retry is not made when the JobFunc panicked or
+	// the run was canceled by [OverlapCancel].
 	MaxRetry int
+	// RetryBackoff provides the wait duration before each retry.
+	// RetryBackoff.Attempt is called with the number of failed
+	// attempts, that is, 1 for the first retry.
+	// If nil, retries are made without waiting.
+	RetryBackoff zbackoff.Backoff
+	// MisfirePolicy is the policy applied to misfired schedules.
+	// Default is [MisfireFireOnce].
+	MisfirePolicy MisfirePolicy
+	// MisfireThreshold is the duration that a schedule is
+	// treated as misfired when it was not run within it.
+	// If zero or negative, default is 1 minute.
+	MisfireThreshold time.Duration
+	// MaxMisfires is the maximum number of misfired schedules
+	// to run when the MisfirePolicy is [MisfireFireAll].
+	// If less than 1, 1 is used.
+	MaxMisfires int
+	// Jitter is the maximum random delay added to each run.
+	// Jobs are run after a random duration in [0, Jitter)
+	// from the scheduled time. It is useful to avoid thundering
+	// herds when multiple replicas run the same schedule.
+	// If zero or negative, no delay is added.
+	Jitter time.Duration
+	// Timeout is the timeout of each attempt of the JobFunc.
+	// The context passed to the JobFunc is canceled after the timeout.
+	// If zero or negative, no timeout is applied.
+	Timeout time.Duration
 	// JobFunc is the job to run.
 	JobFunc func(context.Context) error
 	// WithContext provides a context which passed to the [Job.Run].
 	// This is called once for a run and not called for retry.
 	// [context.Background] is used when WithContext is nil.
 	WithContext func() context.Context
+	// Locker, if non-nil, is used to run the job on only one of
+	// replicas that share the same Locker. The lock of the Name
+	// is acquired before each run and the run is skipped when
+	// the lock is held by another replica.
+	// Use with the StateStore to avoid running the same schedule
+	// multiple times on replicas.
+	Locker Locker
+	// LockTTL is the lease duration of the lock.
+	// It should be longer than the duration of the job
+	// including retries.
+	// If zero or negative, default is 1 hour.
+	LockTTL time.Duration
+	// StateStore, if non-nil, records the [JobState] after each run.
+	// Runs whose scheduled time is not after the recorded one are
+	// skipped because they have been run by another replica.
+	// The recorded scheduled time is also used as the last run time
+	// when the cron started, so the schedules missed while the process
+	// was stopped are handled by the MisfirePolicy.
+	StateStore StateStore
 	// EventHook is the function that hooks [Event]s.
 	// The [Event] is notified through the first argument.
 	// Additional information such as error is passed by a
 	// depending on the event type.
-	// 	- For OnJobFailed: error is given by a[0].
-	// 	- For OnJobPanicked: recovered value given by a[0].
+	// Attempt numbers are int starting from 1 and
+	// durations are [time.Duration] of the attempt.
+	// 	- For OnJobStarted: attempt number is given by a[0].
+	// 	- For OnJobExited: attempt number and duration are given by a[0] and a[1].
+	// 	- For OnJobFailed: error, attempt number and duration are given by a[0], a[1] and a[2].
+	// 	- For OnJobPanicked: recovered value, attempt number and duration are given by a[0], a[1] and a[2].
+	// 	- For OnJobRetried: next attempt number and backoff duration are given by a[0] and a[1].
+	// 	- For OnJobSkipped: reason, [ErrLocked] or [ErrAlreadyRun], is given by a[0].
+	// 	- For OnStateError: error is given by a[0].
 	EventHook func(e Event, a ...any)
 }
 
@@ -76,20 +184,18 @@ func NewCron(c *Config) (*Cron, error) {
 	if c.JobFunc == nil {
 		return nil, ErrNilJob
 	}
-	ct, err := Parse(c.Crontab)
+	if c.Name == "" && (c.Locker != nil || c.StateStore != nil) {
+		return nil, ErrEmptyName
+	}
+	ct, err := ParseWithOption(c.Crontab, &ParseOption{DayOr: c.DayOr})
 	if err != nil {
 		return nil, err
 	}
 	return &Cron{
 		cron:      ct,
 		timeAfter: time.After,
-		runner: &runner{
-			maxRetry:    max(0, c.MaxRetry),
-			queue:       make(chan struct{}, max(1, c.MaxConcurrency)),
-			jobFunc:     c.JobFunc,
-			withContext: c.WithContext,
-			eventFunc:   c.EventHook,
-		},
+		runner:    newRunner(c),
+		misfire:   newMisfire(c),
 	}, nil
 }
 
@@ -100,6 +206,10 @@ type Cron struct {
 	started atomic.Bool
 	cron    *Crontab
 	runner  *runner
+	misfire *misfire
+	// last is the time that schedules were checked last.
+	// Schedules after last are run or treated as misfired.
+	last time.Time
 	// stop stops the cron working.
 	stop      chan struct{}
 	timeAfter func(time.Duration) <-chan time.Time
@@ -122,6 +232,8 @@ func (c *Cron) WithTimeFunc(timeNow func() time.Time) {
 // the internal timer calibrates the time after 95% of the times.
 // For examples, now a job is scheduled after 10 minutes,
 // the con calibrates timer to fire the job after 9min30sec.
+// Schedules that were missed while the process was paused
+// are handled by the [Config.MisfirePolicy].
 // It blocks the process. Run in a new goroutine if blocking is
 // not necessary like below.
 //
@@ -134,27 +246,52 @@ func (c *Cron) Start() {
 	defer c.started.Store(false)
 	c.stop = make(chan struct{}, 1)
 
+	var due time.Time // The schedule that the timer waits for.
 	for {
-		now := c.cron.Now()
-		next := c.cron.NextAfter(now)
-		wait := min(next.Sub(now), time.Hour)
-		calibrate := false
+		now := maxTime(c.cron.Now(), due) // Timer fired for the due schedule.
+		if c.last.IsZero() {
+			c.last = c.lastRun(now)
+		}
+		for _, at := range c.misfire.fires(c.cron, c.last, now) {
+			c.runner.Fire(at) // Fire must not block.
+		}
+		c.last = maxTime(c.last, now)
+
+		wait := time.Hour
+		due = c.cron.NextAfter(now)
+		if !due.IsZero() {
+			wait = min(due.Sub(now), time.Hour)
+		}
 		if wait >= 10*time.Minute {
-			calibrate = true // Calibrate time after 95% of it.
-			wait = 95 * wait / 100
+			wait = 95 * wait / 100 // Calibrate time after 95% of it.
+			due = time.Time{}
 		}
 		select {
 		case <-c.timeAfter(wait):
-			if calibrate {
-				continue
-			}
 		case <-c.stop:
 			return
 		}
-		c.runner.Run() // Run must not block.
 	}
 }
 
+// lastRun returns the scheduled time of the last run
+// recorded in the state store. It returns now when
+// there is no record or the record is after now.
+func (c *Cron) lastRun(now time.Time) time.Time {
+	if c.runner.store == nil {
+		return now
+	}
+	state, err := c.runner.store.Load(context.Background(), c.runner.name)
+	if err != nil {
+		c.runner.onEvent(OnStateError, err)
+		return now
+	}
+	if state == nil || state.Scheduled.IsZero() || state.Scheduled.After(now) {
+		return now
+	}
+	return state.Scheduled
+}
+
 // Stop stops new scheduling of jobs.
 // Stopping cron does not stop the already running jobs.
 func (c *Cron) Stop() {
@@ -166,11 +303,58 @@ func (c *Cron) Stop() {
 // runner runs jobFunc and controls
 // concurrency and retries.
 type runner struct {
-	queue       chan struct{}
+	// queue is the semaphore that limits
+	// the number of running jobs.
+	queue chan struct{}
+	// pending is the semaphore that limits
+	// the number of queued runs.
+	pending     chan struct{}
+	name        string
+	locker      Locker
+	lockTTL     time.Duration
+	store       StateStore
+	jitter      time.Duration
+	overlap     OverlapPolicy
 	maxRetry    int
+	backoff     zbackoff.Backoff
+	timeout     time.Duration
 	jobFunc     func(context.Context) error
 	withContext func() context.Context
 	eventFunc   func(Event, ...any)
+
+	// mu protects cancels, id and last.
+	mu sync.Mutex
+	// cancels are the cancel functions of the
+	// running jobs in order of started.
+	cancels []*runCancel
+	id      uint64
+	// last is the latest scheduled time of the started runs.
+	last time.Time
+}
+
+// runCancel is the cancel function of a running job.
+type runCancel struct {
+	id     uint64
+	cancel context.CancelFunc
+}
+
+func newRunner(c *Config) *runner {
+	return &runner{
+		maxRetry:    max(0, c.MaxRetry),
+		queue:       make(chan struct{}, max(1, c.MaxConcurrency)),
+		pending:     make(chan struct{}, 1),
+		name:        c.Name,
+		locker:      c.Locker,
+		lockTTL:     cmp.Or(max(0, c.LockTTL), time.Hour),
+		store:       c.StateStore,
+		jitter:      c.Jitter,
+		overlap:     c.Overlap,
+		backoff:     c.RetryBackoff,
+		timeout:     c.Timeout,
+		jobFunc:     c.JobFunc,
+		withContext: c.WithContext,
+		eventFunc:   c.EventHook,
+	}
 }
 
 func (r *runner) onEvent(e Event, a ...any) {
@@ -180,38 +364,256 @@ func (r *runner) onEvent(e Event, a ...any) {
 	r.eventFunc(e, a...)
 }
 
+// Fire runs the job scheduled at the given time
+// after a random delay within the jitter.
+// It does not block.
+func (r *runner) Fire(at time.Time) {
+	if r.jitter <= 0 {
+		r.RunAt(at)
+		return
+	}
+	time.AfterFunc(rand.N(r.jitter), func() { r.RunAt(at) })
+}
+
+// Run runs the job scheduled at now.
 func (r *runner) Run() {
-	r.onEvent(OnJobStarted)
+	r.RunAt(time.Now())
+}
+
+// RunAt runs the job scheduled at the given time.
+// It does not block.
+func (r *runner) RunAt(at time.Time) {
+	r.onEvent(OnJobRun)
+
+	if r.overlap == OverlapAllow {
+		r.onEvent(OnJobAccepted)
+		go r.run(false, at)
+		return
+	}
 
 	select {
 	case r.queue <- struct{}{}:
 		r.onEvent(OnJobAccepted)
+		go r.run(true, at)
+		return
+	default:
+		if r.overlap != OverlapQueue && r.overlap != OverlapCancel {
+			r.onEvent(OnJobDeclined)
+			return // Max concurrency exceeded.
+		}
+	}
+
+	select {
+	case r.pending <- struct{}{}:
 	default:
 		r.onEvent(OnJobDeclined)
-		return // Max concurrency exceeded.
+		return // Another run is already queued.
+	}
+	if r.overlap == OverlapCancel && r.cancelOldest() {
+		r.onEvent(OnJobCanceled)
+	}
+	r.onEvent(OnJobQueued)
+	go func() {
+		r.queue <- struct{}{}
+		<-r.pending
+		r.onEvent(OnJobAccepted)
+		r.run(true, at)
+	}()
+}
+
+// cancelOldest cancels the oldest running job.
+// It returns false if there is no running job.
+func (r *runner) cancelOldest() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.cancels) == 0 {
+		return false
 	}
+	r.cancels[0].cancel()
+	r.cancels = r.cancels[1:]
+	return true
+}
+
+// register registers the cancel function of a running job.
+// Call the returned function to unregister it.
+func (r *runner) register(cancel context.CancelFunc) (unregister func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.id++
+	rc := &runCancel{id: r.id, cancel: cancel}
+	r.cancels = append(r.cancels, rc)
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for i, c := range r.cancels {
+			if c.id == rc.id {
+				r.cancels = append(r.cancels[:i], r.cancels[i+1:]...)
+				break
+			}
+		}
+	}
+}
 
-	go func() { // Run the job.
-		r.onEvent(OnJobStarted)
+// run runs the job with retries.
+// at is the scheduled time of the run.
+// If release is true, the semaphore acquired
+// from the queue is released after the run.
+func (r *runner) run(release bool, at time.Time) {
+	if release {
 		defer func() {
 			<-r.queue // Remove lock.
 		}()
-		defer func() {
-			if rec := recover(); rec != nil {
-				r.onEvent(OnJobPanicked, rec)
+	}
+
+	ctx := context.Background()
+	if r.withContext != nil {
+		ctx = r.withContext()
+	}
+	// Bookkeeping of the locker and the store uses a context that is
+	// not canceled with the job so that the state of a canceled run
+	// is still saved and the lock is still acquired and released.
+	bookCtx := context.WithoutCancel(ctx)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer r.register(cancel)()
+
+	unlock, ok := r.acquire(bookCtx, at)
+	if !ok {
+		return
+	}
+	defer unlock()
+	r.setLast(at)
+
+	start := time.Now()
+	attempts, err := r.retry(ctx)
+	if r.store == nil {
+		return
+	}
+	state := &JobState{
+		Name:      r.name,
+		Scheduled: at,
+		Started:   start,
+		Duration:  time.Since(start),
+		Attempts:  attempts,
+	}
+	if err != nil {
+		state.Error = err.Error()
+	}
+	if err := r.store.Save(bookCtx, state); err != nil {
+		r.onEvent(OnStateError, err)
+	}
+}
+
+// setLast records the scheduled time of the started run.
+// Older times than the recorded one are ignored.
+func (r *runner) setLast(at time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if at.After(r.last) {
+		r.last = at
+	}
+}
+
+// lastRun returns the latest scheduled time of the started runs.
+// It returns zero time when no run has been started.
+func (r *runner) lastRun() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last
+}
+
+// acquire acquires the lock from the locker and checks
+// that the run scheduled at the given time has not been run.
+// It returns false when the job should not be run.
+// Returned unlock function must be called after the run.
+func (r *runner) acquire(ctx context.Context, at time.Time) (unlock func(), ok bool) {
+	unlock = func() {}
+	if r.locker != nil {
+		ul, err := r.locker.TryLock(ctx, r.name, r.lockTTL)
+		if err != nil {
+			if errors.Is(err, ErrLocked) {
+				r.onEvent(OnJobSkipped, err)
+			} else {
+				r.onEvent(OnStateError, err)
 			}
-			r.onEvent(OnJobExited)
-		}()
-		ctx := context.Background()
-		if r.withContext != nil {
-			ctx = r.withContext()
+			return nil, false
 		}
-		for range r.maxRetry + 1 {
-			err := r.jobFunc(ctx)
-			if err != nil {
-				r.onEvent(OnJobFailed, err)
-				return
+		unlock = func() {
+			if err := ul(); err != nil {
+				r.onEvent(OnStateError, err)
 			}
 		}
+	}
+	if r.store != nil {
+		state, err := r.store.Load(ctx, r.name)
+		if err != nil {
+			r.onEvent(OnStateError, err) // Run the job anyway.
+		} else if state != nil && !state.Scheduled.Before(at) {
+			unlock()
+			r.onEvent(OnJobSkipped, ErrAlreadyRun)
+			return nil, false
+		}
+	}
+	return unlock, true
+}
+
+// retry calls the jobFunc with retries.
+// It returns the number of attempts and
+// the error of the last attempt.
+func (r *runner) retry(ctx context.Context) (int, error) {
+	var err error
+	for attempt := 1; attempt <= r.maxRetry+1; attempt++ {
+		if attempt > 1 {
+			var wait time.Duration
+			if r.backoff != nil {
+				wait = r.backoff.Attempt(attempt - 1)
+			}
+			r.onEvent(OnJobRetried, attempt, wait)
+			if wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-ctx.Done():
+					timer.Stop()
+					return attempt - 1, err
+				}
+			}
+		}
+		var retry bool
+		if retry, err = r.attempt(ctx, attempt); !retry {
+			return attempt, err
+		}
+		if ctx.Err() != nil {
+			return attempt, err // Canceled. Do not retry.
+		}
+	}
+	return r.maxRetry + 1, err
+}
+
+// attempt calls the jobFunc once.
+// It returns the error returned from the jobFunc.
+// retry is true when the jobFunc returned an error
+// and should be retried.
+func (r *runner) attempt(ctx context.Context, attempt int) (retry bool, err error) {
+	if r.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	r.onEvent(OnJobStarted, attempt)
+	defer func() {
+		if rec := recover(); rec != nil {
+			retry, err = false, fmt.Errorf("ztime/zcron: job panicked [%v]", rec)
+			r.onEvent(OnJobPanicked, rec, attempt, time.Since(start))
+		}
+		r.onEvent(OnJobExited, attempt, time.Since(start))
 	}()
+
+	if err := r.jobFunc(ctx); err != nil {
+		r.onEvent(OnJobFailed, err, attempt, time.Since(start))
+		return true, err
+	}
+	return false, nil
 }
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/aileron-projects/go/ztesting"
+	"github.com/aileron-projects/go/ztime/zbackoff"
 )
 
 func TestNewCron(t *testing.T) {
@@ -236,3 +237,264 @@ func TestRunner(t *testing.T) {
 		wg.Wait()
 	})
 }
+
+func TestRunner_retry(t *testing.T) {
+	t.Parallel()
+	t.Run("retry with backoff", func(t *testing.T) {
+		var mu sync.Mutex
+		var attempts []int
+		var waits []time.Duration
+		var durations []time.Duration
+		done := make(chan struct{})
+		r := newRunner(&Config{
+			MaxRetry:     2,
+			RetryBackoff: zbackoff.NewFixedBackoff(10 * time.Millisecond),
+			JobFunc: func(_ context.Context) error {
+				return io.EOF
+			},
+			EventHook: func(e Event, a ...any) {
+				mu.Lock()
+				defer mu.Unlock()
+				switch e {
+				case OnJobStarted:
+					attempts = append(attempts, a[0].(int))
+				case OnJobRetried:
+					waits = append(waits, a[1].(time.Duration))
+				case OnJobFailed:
+					durations = append(durations, a[2].(time.Duration))
+					if a[1].(int) == 3 {
+						close(done)
+					}
+				}
+			},
+		})
+		r.Run()
+		<-done
+		mu.Lock()
+		defer mu.Unlock()
+		ztesting.AssertEqual(t, "attempts not match", []int{1, 2, 3}, attempts)
+		ztesting.AssertEqual(t, "waits not match", []time.Duration{10 * time.Millisecond, 10 * time.Millisecond}, waits)
+		ztesting.AssertEqual(t, "number of durations not match", 3, len(durations))
+	})
+	t.Run("success without retry", func(t *testing.T) {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var count atomic.Int32
+		r := newRunner(&Config{
+			MaxRetry: 3,
+			JobFunc: func(_ context.Context) error {
+				count.Add(1)
+				return nil
+			},
+			EventHook: func(e Event, a ...any) {
+				if e == OnJobExited {
+					wg.Done()
+				}
+			},
+		})
+		r.Run()
+		wg.Wait()
+		time.Sleep(10 * time.Millisecond)
+		ztesting.AssertEqual(t, "call count mismatch", 1, count.Load())
+	})
+	t.Run("no retry on panic", func(t *testing.T) {
+		var wg sync.WaitGroup
+		wg.Add(1)
+		var count atomic.Int32
+		r := newRunner(&Config{
+			MaxRetry: 3,
+			JobFunc: func(_ context.Context) error {
+				count.Add(1)
+				panic(io.EOF)
+			},
+			EventHook: func(e Event, a ...any) {
+				if e == OnJobExited {
+					wg.Done()
+				}
+			},
+		})
+		r.Run()
+		wg.Wait()
+		time.Sleep(10 * time.Millisecond)
+		ztesting.AssertEqual(t, "call count mismatch", 1, count.Load())
+	})
+	t.Run("attempt timeout", func(t *testing.T) {
+		errs := make(chan error, 2)
+		r := newRunner(&Config{
+			MaxRetry: 1,
+			Timeout:  10 * time.Millisecond,
+			JobFunc: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			EventHook: func(e Event, a ...any) {
+				if e == OnJobFailed {
+					errs <- a[0].(error)
+				}
+			},
+		})
+		r.Run()
+		ztesting.AssertEqualErr(t, "error not match", context.DeadlineExceeded, <-errs)
+		ztesting.AssertEqualErr(t, "error not match", context.DeadlineExceeded, <-errs)
+	})
+}
+
+func TestRunner_overlap(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		policy   OverlapPolicy
+		accepted int32
+		declined int32
+		queued   int32
+		canceled int32
+	}{
+		"skip":   {OverlapSkip, 1, 2, 0, 0},
+		"queue":  {OverlapQueue, 2, 1, 1, 0},
+		"cancel": {OverlapCancel, 2, 1, 1, 1},
+		"allow":  {OverlapAllow, 3, 0, 0, 0},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var accepted, declined, queued, canceled atomic.Int32
+			var wg sync.WaitGroup
+			release := make(chan struct{})
+			r := newRunner(&Config{
+				Overlap: tc.policy,
+				JobFunc: func(_ context.Context) error {
+					defer wg.Done()
+					<-release // Ignore context cancellation.
+					return nil
+				},
+				EventHook: func(e Event, a ...any) {
+					switch e {
+					case OnJobAccepted:
+						accepted.Add(1)
+					case OnJobDeclined:
+						declined.Add(1)
+					case OnJobQueued:
+						queued.Add(1)
+					case OnJobCanceled:
+						canceled.Add(1)
+					}
+				},
+			})
+			wg.Add(int(tc.accepted))
+			r.Run()
+			time.Sleep(10 * time.Millisecond) // Wait the first job started.
+			r.Run()
+			r.Run()
+			close(release)
+			wg.Wait()
+			ztesting.AssertEqual(t, "accepted count mismatch", tc.accepted, accepted.Load())
+			ztesting.AssertEqual(t, "declined count mismatch", tc.declined, declined.Load())
+			ztesting.AssertEqual(t, "queued count mismatch", tc.queued, queued.Load())
+			ztesting.AssertEqual(t, "canceled count mismatch", tc.canceled, canceled.Load())
+		})
+	}
+	t.Run("canceled run is not retried", func(t *testing.T) {
+		var count atomic.Int32
+		var wg sync.WaitGroup
+		wg.Add(2)
+		r := newRunner(&Config{
+			Overlap:  OverlapCancel,
+			MaxRetry: 3,
+			JobFunc: func(ctx context.Context) error {
+				defer wg.Done()
+				if count.Add(1) == 1 {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+				return nil
+			},
+		})
+		r.Run()
+		time.Sleep(10 * time.Millisecond) // Wait the first job started.
+		r.Run()
+		wg.Wait()
+		time.Sleep(10 * time.Millisecond)
+		ztesting.AssertEqual(t, "call count mismatch", 2, count.Load())
+	})
+}
+
+func TestRunner_Fire(t *testing.T) {
+	t.Parallel()
+	t.Run("without jitter", func(t *testing.T) {
+		done := make(chan struct{})
+		r := newRunner(&Config{
+			JobFunc: func(_ context.Context) error {
+				close(done)
+				return nil
+			},
+		})
+		r.Fire(time.Now())
+		<-done
+	})
+	t.Run("with jitter", func(t *testing.T) {
+		done := make(chan struct{})
+		r := newRunner(&Config{
+			Jitter: 50 * time.Millisecond,
+			JobFunc: func(_ context.Context) error {
+				close(done)
+				return nil
+			},
+		})
+		start := time.Now()
+		r.Fire(time.Now())
+		<-done
+		ztesting.AssertEqual(t, "jitter too long", true, time.Since(start) < time.Second)
+	})
+}
+
+func TestCron_misfire(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		policy MisfirePolicy
+		want   int32
+	}{
+		"fire once": {MisfireFireOnce, 1},
+		"fire all":  {MisfireFireAll, 5},
+		"skip":      {MisfireSkip, 0},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var count atomic.Int32
+			cron, _ := NewCron(&Config{
+				Crontab:          "TZ=UTC * * * * *",
+				MisfirePolicy:    tc.policy,
+				MaxMisfires:      10,
+				MisfireThreshold: 10 * time.Second,
+				Overlap:          OverlapAllow,
+				JobFunc: func(ctx context.Context) error {
+					count.Add(1)
+					return nil
+				},
+			})
+			var mu sync.Mutex
+			now := time.Date(2000, 1, 1, 0, 0, 30, 0, time.UTC)
+			cron.WithTimeFunc(func() time.Time {
+				mu.Lock()
+				defer mu.Unlock()
+				return now
+			})
+			waited := make(chan struct{})
+			calls := 0
+			cron.WithTimeAfterFunc(func(d time.Duration) <-chan time.Time {
+				mu.Lock()
+				defer mu.Unlock()
+				if calls++; calls == 1 {
+					now = now.Add(5 * time.Minute) // Process paused for 5 minutes.
+					ch := make(chan time.Time, 1)
+					ch <- now
+					return ch
+				}
+				close(waited)
+				return nil
+			})
+			go cron.Start()
+			<-waited
+			cron.Stop()
+			time.Sleep(50 * time.Millisecond) // Wait all jobs exited.
+			ztesting.AssertEqual(t, "call count mismatch", tc.want, count.Load())
+		})
+	}
+}
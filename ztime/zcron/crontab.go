@@ -3,39 +3,162 @@ package zcron
 import (
 	"errors"
 	"fmt"
+	"iter"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 )
 
+const (
+	minYear = 1970 // Minimum value of the year field.
+	maxYear = 2099 // Maximum value of the year field.
+)
+
 // Crontab is a cron scheduler.
 type Crontab struct {
-	second  uint64
-	minute  uint64
-	hour    uint64
-	day     uint64
-	month   uint64
-	week    uint64
+	second uint64
+	minute uint64
+	hour   uint64
+	day    uint64
+	month  uint64
+	week   uint64
+	// dayLast is the "L" and "L-n" expression of the day of month.
+	// n-th bit represents the n days before the last day.
+	dayLast uint64
+	// dayNearest is the "nW" expression of the day of month.
+	// n-th bit represents the nearest weekday to the n-th day.
+	dayNearest uint64
+	// dayLastWeekday is the "LW" expression of the day of month.
+	dayLastWeekday bool
+	// weekLast is the "nL" expression of the day of week.
+	// n-th bit represents the last n-th day of week in the month.
+	weekLast uint64
+	// weekNth is the "n#k" expression of the day of week.
+	// k-th bit of the weekNth[n] represents the k-th n-th day
+	// of week in the month.
+	weekNth [7]uint64
+	// year is the bit array of years.
+	// n-th bit represents the year minYear+n.
+	// nil means every year.
+	year []uint64
+	// dayOr, if true, evaluates the day of month and
+	// the day of week with OR condition.
+	dayOr bool
+	// exp is the normalized expression of each field.
+	// Fields are second, minute, hour, day of month,
+	// month, day of week and year.
+	exp     [7]string
+	loc     *time.Location
 	timeNow func() time.Time
 }
 
+// valid returns if the crontab has at least one scheduled day
+// between minYear and maxYear. It does not depend on the current
+// time, so crontabs whose years are all in the past are valid.
 func (c *Crontab) valid() bool {
-	now := c.timeNow()
-	var day, week int
-	var month time.Month
-	for i := 0; i < 10*366; i++ { // Check 10 years.
-		now = now.Add(24 * time.Hour)
-		month = now.Month()
-		day = now.Day()
-		week = int(now.Weekday())
-		if c.day&(1<<day) > 0 && c.week&(1<<week) > 0 && c.month&(1<<month) > 0 {
+	// Civil dates are calculated in UTC to avoid DST transitions.
+	date := time.Date(minYear, 1, 1, 0, 0, 0, 0, time.UTC)
+	for date.Year() <= maxYear {
+		year, month, day := date.Date()
+		if !c.matchYear(year) {
+			next, ok := c.nextYear(year)
+			if !ok {
+				return false
+			}
+			date = time.Date(next, 1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+		if c.month&(1<<month) > 0 && c.matchDay(year, month, day, int(date.Weekday())) {
+			return true
+		}
+		date = date.AddDate(0, 0, 1)
+	}
+	return false
+}
+
+// matchYear returns if the year is scheduled.
+func (c *Crontab) matchYear(year int) bool {
+	if c.year == nil {
+		return true
+	}
+	if year < minYear || year > maxYear {
+		return false
+	}
+	n := year - minYear
+	return c.year[n/64]&(1<<(n%64)) > 0
+}
+
+// nextYear returns the next scheduled year after the given year.
+// It returns false when there is no more scheduled year.
+func (c *Crontab) nextYear(year int) (int, bool) {
+	if c.year == nil {
+		return year + 1, true
+	}
+	for y := max(year+1, minYear); y <= maxYear; y++ {
+		if c.matchYear(y) {
+			return y, true
+		}
+	}
+	return 0, false
+}
+
+// matchDay returns if the day is scheduled.
+// week is the day of week of the day.
+// The day of month and the day of week are evaluated with
+// AND condition, or OR condition when dayOr is true.
+func (c *Crontab) matchDay(year int, month time.Month, day, week int) bool {
+	last := daysIn(year, month)
+	dom := c.day&(1<<day) > 0 ||
+		c.dayLast&(1<<(last-day)) > 0 ||
+		(c.dayLastWeekday && day == nearestWeekday(year, month, last, last)) ||
+		(c.dayNearest > 0 && c.matchNearest(year, month, day, last))
+	dow := c.week&(1<<week) > 0 ||
+		(c.weekLast&(1<<week) > 0 && day+7 > last) ||
+		c.weekNth[week]&(1<<((day-1)/7+1)) > 0
+	if c.dayOr {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// matchNearest returns if the day is the nearest
+// weekday of any days specified by the "nW" expression.
+func (c *Crontab) matchNearest(year int, month time.Month, day, last int) bool {
+	// The nearest weekday is at most 2 days away from the target.
+	for n := max(1, day-2); n <= min(last, day+2); n++ {
+		if c.dayNearest&(1<<n) > 0 && nearestWeekday(year, month, n, last) == day {
 			return true
 		}
 	}
 	return false
 }
 
+// daysIn returns the number of days in the month.
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// nearestWeekday returns the nearest weekday (Monday to Friday)
+// to the given day within the month.
+// last is the last day of the month.
+func nearestWeekday(year int, month time.Month, day, last int) int {
+	switch time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Weekday() {
+	case time.Saturday:
+		if day == 1 {
+			return day + 2 // Monday.
+		}
+		return day - 1 // Friday.
+	case time.Sunday:
+		if day == last {
+			return day - 2 // Friday.
+		}
+		return day + 1 // Monday.
+	default:
+		return day
+	}
+}
+
 func (c *Crontab) WithTimeFunc(timeNow func() time.Time) {
 	if timeNow == nil {
 		return
@@ -43,6 +166,12 @@ func (c *Crontab) WithTimeFunc(timeNow func() time.Time) {
 	c.timeNow = timeNow
 }
 
+// Location returns the time zone of the crontab.
+// It is the time zone specified by "TZ=" or [time.Local].
+func (c *Crontab) Location() *time.Location {
+	return c.loc
+}
+
 // Now returns the current time returned from the internal clock.
 // Use [Crontab.WithTimeFunc] to replace the internal clock.
 func (c *Crontab) Now() time.Time {
@@ -57,8 +186,272 @@ func (c *Crontab) Next() time.Time {
 	return c.NextAfter(c.timeNow())
 }
 
+// Expression returns the normalized cron expression without timezone.
+// Aliases are expanded, names of months and days of week are replaced
+// with numbers and the second field is always included.
+// The year field is included only when it is not "*".
+// For example, "@weekly" is normalized to "0 0 0 * * 0" and
+// "0 9 * JAN MON-FRI" is normalized to "0 0 9 * 1 1-5".
+func (c *Crontab) Expression() string {
+	if c.exp[6] == "*" {
+		return strings.Join(c.exp[:6], " ")
+	}
+	return strings.Join(c.exp[:], " ")
+}
+
+// String returns the normalized cron expression with timezone.
+// For example, "TZ=UTC 0 0 9 * 1 1-5".
+// See also [Crontab.Expression].
+func (c *Crontab) String() string {
+	return "TZ=" + c.loc.String() + " " + c.Expression()
+}
+
+// Schedules returns an iterator over the scheduled times after from.
+// If until is non-zero, only the times not after until are yielded.
+// If n is positive, at most n times are yielded.
+// If both until is zero and n is zero or negative, the iterator
+// continues until there is no more schedule or the caller stops it.
+// Scheduled times are evaluated in the location of from.
+//
+// Example:
+//
+//	for t := range ct.Schedules(time.Now(), time.Time{}, 5) {
+//		fmt.Println(t) // Next 5 scheduled times.
+//	}
+func (c *Crontab) Schedules(from, until time.Time, n int) iter.Seq[time.Time] {
+	return func(yield func(time.Time) bool) {
+		for i := 0; n <= 0 || i < n; i++ {
+			from = c.NextAfter(from)
+			if from.IsZero() || (!until.IsZero() && from.After(until)) {
+				return
+			}
+			if !yield(from) {
+				return
+			}
+		}
+	}
+}
+
+// PrevBefore returns the previous cron scheduled time before t.
+// It is the latest time that [Crontab.NextAfter] returns
+// before t, so daylight saving time transitions are handled
+// with the same rules.
+// It returns zero time when there is no schedule before t.
+func (c *Crontab) PrevBefore(t time.Time) time.Time {
+	loc := t.Location()
+	ceil := wallClock(t) // ceil is the wall clock to search schedules before.
+	if t.Nanosecond() > 0 {
+		ceil = ceil.Add(time.Second) // t's own second is before t.
+	}
+	for {
+		w := c.prevWall(ceil)
+		if w.IsZero() {
+			return w
+		}
+		year, month, day := w.Date()
+		hour, min, sec := w.Clock()
+		guess := time.Date(year, month, day, hour, min, sec, 0, loc)
+		prev := c.NextAfter(guess.Add(-time.Second))
+		if !prev.IsZero() && prev.Before(t) {
+			// The guess can be earlier than the actual
+			// schedule around the zone transitions.
+			for next := c.NextAfter(prev); !next.IsZero() && next.Before(t); next = c.NextAfter(next) {
+				prev = next
+			}
+			return prev
+		}
+		ceil = w
+	}
+}
+
+// prevWall returns the previous scheduled wall clock before t.
+// t must be a wall clock returned by [wallClock].
+// Returned time is also a wall clock in UTC.
+// It returns zero time when there is no schedule.
+func (c *Crontab) prevWall(t time.Time) time.Time {
+	year, month, day := t.Date()
+	hour, min, sec := t.Clock()
+	ceil := true // Whether the time of the day is limited.
+	for {
+		if year < 1 {
+			return time.Time{}
+		}
+		if !c.matchYear(year) { // No schedule this year.
+			prev, ok := c.prevYear(year)
+			if !ok {
+				return time.Time{}
+			}
+			year, month, day, ceil = prev, 12, 31, false
+			continue
+		}
+		if c.month&(1<<month) == 0 { // No schedule this month.
+			year, month, day = time.Date(year, month, 0, 0, 0, 0, 0, time.UTC).Date() // Last day of the previous month.
+			ceil = false
+			continue
+		}
+		week := int(time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Weekday())
+		if c.matchDay(year, month, day, week) {
+			if h, m, s, ok := c.prevClock(hour, min, sec, ceil); ok {
+				return time.Date(year, month, day, h, m, s, 0, time.UTC)
+			}
+		}
+		year, month, day = time.Date(year, month, day-1, 0, 0, 0, 0, time.UTC).Date() // Previous day.
+		ceil = false
+	}
+}
+
+// prevClock returns the latest scheduled time of the day
+// before the given hour, min and sec.
+// If ceil is false, the latest time of the day is returned.
+func (c *Crontab) prevClock(hour, min, sec int, ceil bool) (int, int, int, bool) {
+	if !ceil {
+		return prevTime(c.hour, 24), prevTime(c.minute, 60), prevTime(c.second, 60), true
+	}
+	if c.hour&(1<<hour) > 0 {
+		if c.minute&(1<<min) > 0 {
+			if s := prevTime(c.second, sec); s >= 0 {
+				return hour, min, s, true
+			}
+		}
+		if m := prevTime(c.minute, min); m >= 0 {
+			return hour, m, prevTime(c.second, 60), true
+		}
+	}
+	if h := prevTime(c.hour, hour); h >= 0 {
+		return h, prevTime(c.minute, 60), prevTime(c.second, 60), true
+	}
+	return 0, 0, 0, false
+}
+
+// prevTime returns the largest value in targets less than now.
+// It returns -1 when not found.
+func prevTime(targets uint64, now int) int {
+	for i := now - 1; i >= 0; i-- {
+		if targets&(1<<i) > 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// prevYear returns the previous scheduled year before the given year.
+// It returns false when there is no more scheduled year.
+func (c *Crontab) prevYear(year int) (int, bool) {
+	if c.year == nil {
+		return year - 1, year > 1
+	}
+	for y := min(year-1, maxYear); y >= minYear; y-- {
+		if c.matchYear(y) {
+			return y, true
+		}
+	}
+	return 0, false
+}
+
 // NexAfter returns the next cron scheduled time after t.
+// It returns zero time when there is no more schedule,
+// which happens only when the year field is specified.
+// Schedules are evaluated with the wall clock of the t's location.
+// Daylight saving time transitions are handled with following rules.
+//
+//   - Skipped wall clock times, when the clock jumps forward,
+//     are scheduled at the transition time.
+//     For example, "0 30 2 * * *" runs at 03:00 on the day
+//     that the clock jumps from 02:00 to 03:00.
+//     Multiple skipped times are merged into a single schedule.
+//   - Repeated wall clock times, when the clock jumps backward,
+//     are scheduled only once at the first occurrence.
+//     For example, "0 30 1 * * *" runs only once on the day
+//     that the clock jumps back from 02:00 to 01:00.
+//     Exceptionally, crontabs that match every hour such as
+//     "0 */15 * * * *" are scheduled at both occurrences
+//     to keep their intervals.
 func (c *Crontab) NextAfter(t time.Time) time.Time {
+	loc := t.Location()
+	at := t              // at is an instant in the current zone segment.
+	from := wallClock(t) // from is the wall clock to search schedules after.
+	if start, _ := t.ZoneBounds(); !start.IsZero() {
+		// t may be in the repeated times.
+		_, prevOffset := start.Add(-time.Second).Zone()
+		_, offset := t.Zone()
+		if after := c.repeatedAfter(start, prevOffset, offset); after.After(from) {
+			from = after
+		}
+	}
+	for {
+		w := c.nextWall(from)
+		if w.IsZero() {
+			return w
+		}
+		_, offset := at.Zone()
+		next := w.Add(-time.Duration(offset) * time.Second).In(loc)
+		end := zoneEnd(at, next)
+		if end.IsZero() {
+			return next // No zone transition before the next schedule.
+		}
+		_, newOffset := end.Zone()
+		if newOffset > offset && w.Before(wallClock(end)) {
+			return end // Skipped time is scheduled at the transition.
+		}
+		from = c.repeatedAfter(end, offset, newOffset)
+		at = end
+	}
+}
+
+// zoneEnd returns the first zone transition after t
+// that changes the offset. It returns zero time when
+// there is no transition until the given time.
+// Boundaries returned by [time.Time.ZoneBounds] that do not
+// change the offset, such as the end of years, are skipped.
+func zoneEnd(t, until time.Time) time.Time {
+	_, offset := t.Zone()
+	for probe := t; !probe.After(until); {
+		_, end := probe.ZoneBounds()
+		if end.IsZero() || end.After(until) {
+			return time.Time{}
+		}
+		if !end.After(probe) {
+			// ZoneBounds can return a wrong boundary around
+			// the end of years that are out of the tz database.
+			// Zone rules do not change within the rest of the year,
+			// so continue from the beginning of the next year.
+			probe = time.Date(probe.UTC().Year()+1, 1, 1, 0, 0, 0, 0, time.UTC).In(probe.Location())
+			continue
+		}
+		if _, o := end.Zone(); o != offset {
+			return end
+		}
+		probe = end
+	}
+	return time.Time{}
+}
+
+// repeatedAfter returns the wall clock to search
+// schedules after the zone transition at the given time.
+// Repeated wall clock times are skipped when
+// the clock jumps backward except for crontabs
+// that match every hour.
+func (c *Crontab) repeatedAfter(transition time.Time, oldOffset, newOffset int) time.Time {
+	after := wallClock(transition).Add(-time.Second)
+	if newOffset < oldOffset && c.hour != toBitArray(0, 23, 1) {
+		after = after.Add(time.Duration(oldOffset-newOffset) * time.Second)
+	}
+	return after
+}
+
+// wallClock returns the wall clock of t as UTC time.
+// Sub-second values are truncated.
+func wallClock(t time.Time) time.Time {
+	year, month, day := t.Date()
+	hour, min, sec := t.Clock()
+	return time.Date(year, month, day, hour, min, sec, 0, time.UTC)
+}
+
+// nextWall returns the next scheduled wall clock after t.
+// t must be a wall clock returned by [wallClock].
+// Returned time is also a wall clock in UTC.
+// It returns zero time when there is no more schedule.
+func (c *Crontab) nextWall(t time.Time) time.Time {
 	now := t
 	loc := now.Location()
 	hour, min, sec := now.Clock()
@@ -70,6 +463,15 @@ func (c *Crontab) NextAfter(t time.Time) time.Time {
 		year, month, day = now.Date()
 		week = int(now.Weekday())
 
+		if !c.matchYear(year) { // No schedule this year.
+			next, ok := c.nextYear(year)
+			if !ok {
+				return time.Time{}
+			}
+			now = time.Date(next, 1, 1, 0, 0, 0, 0, loc)
+			hour, min, sec = -1, -1, -1
+			continue
+		}
 		if c.month&(1<<month) == 0 { // No schedule this month.
 			month += 1 // Move to the next month.
 			if month > 12 {
@@ -80,7 +482,7 @@ func (c *Crontab) NextAfter(t time.Time) time.Time {
 			hour, min, sec = -1, -1, -1
 			continue
 		}
-		if c.day&(1<<day) == 0 || c.week&(1<<week) == 0 { // No schedule this day and day of week.
+		if !c.matchDay(year, month, day, week) { // No schedule this day and day of week.
 			now = now.Add(24 * time.Hour) // Move to the next day.
 			hour, min, sec = -1, -1, -1
 			continue
@@ -160,17 +562,19 @@ func (e *ParseError) Is(target error) bool {
 }
 
 // Parse parses the given cron expression and returns Crontab.
+// It is the same as [ParseWithOption] with nil option.
 // The syntax follows
 //
-//	TZ=UTC * * * * * *
-//	|      | | | | | |
-//	|      | | | | | |- Day of week
-//	|      | | | | |--- Month
-//	|      | | | |----- Day of month
-//	|      | | |------- Hour
-//	|      | |--------- Minute
-//	|      |----------- Second (Optional)
-//	|------------------ Timezone (Optional)
+//	TZ=UTC * * * * * * *
+//	|      | | | | | | |
+//	|      | | | | | | |- Year (Optional)
+//	|      | | | | | |--- Day of week
+//	|      | | | | |----- Month
+//	|      | | | |------- Day of month
+//	|      | | |--------- Hour
+//	|      | |----------- Minute
+//	|      |------------- Second (Optional)
+//	|-------------------- Timezone (Optional)
 //
 //	Field name   | Mandatory  | Values          | Special characters
 //	----------   | ---------- | --------------  | -------------------
@@ -178,12 +582,45 @@ func (e *ParseError) Is(target error) bool {
 //	Second       | No         | 0-59            | * / , -
 //	Minute       | Yes        | 0-59            | * / , -
 //	Hours        | Yes        | 0-23            | * / , -
-//	Day of month | Yes        | 1-31            | * / , -
+//	Day of month | Yes        | 1-31            | * / , - ? L W
 //	Month        | Yes        | 1-12 or JAN-DEC | * / , -
-//	Day of week  | Yes        | 0-6 or SUN-SAT  | * / , -
+//	Day of week  | Yes        | 0-6 or SUN-SAT  | * / , - ? L #
+//	Year         | No         | 1970-2099       | * / , -
+//
+// The second field is required when the year field is specified.
+// That is, 5 fields expression has no second and no year field,
+// 6 fields expression has no year field and
+// 7 fields expression has all fields.
+//
+// Expressions that never match any day are rejected,
+// for example "0 0 0 30 2 *" or "0 0 0 29 2 * 2025".
+// The validation does not depend on the current time.
+// Expressions whose years are all in the past are accepted and
+// [Crontab.NextAfter] returns zero time for them.
+//
+// Following special characters are available for
+// the "Day of month" and the "Day of week".
+// They can be combined with other values by ",".
+//
+//	Field        | Expression | Meaning
+//	------------ | ---------- | ---------------------------------------------------
+//	Day of month | ?          | Same as "*"
+//	Day of month | L          | The last day of the month
+//	Day of month | L-n        | n days before the last day of the month (n=0-30)
+//	Day of month | nW         | The nearest weekday (MON-FRI) to the n-th day (n=1-31)
+//	Day of month | LW         | The last weekday (MON-FRI) of the month
+//	Day of week  | ?          | Same as "*"
+//	Day of week  | L          | Same as "6" or "SAT"
+//	Day of week  | nL         | The last n-th day of week of the month (e.g. 5L, FRIL)
+//	Day of week  | n#k        | The k-th n-th day of week of the month (e.g. 2#1, TUE#1)
+//
+// The nearest weekday never crosses the month boundary.
+// For example, "1W" is Monday the 3rd when the 1st is a Saturday
+// and "nW" never matches when the month has less than n days.
 //
 // Note that the "Day of month" and the "Day of week" are
-// evaluated with AND condition.
+// evaluated with AND condition by default.
+// Use [ParseWithOption] to evaluate them with OR condition.
 //
 // Following aliases are defined for convenience.
 //
@@ -225,9 +662,34 @@ func (e *ParseError) Is(target error) bool {
 //   - https://en.wikipedia.org/wiki/Cron
 //   - https://crontab.guru/
 //   - https://crontab.cronhub.io/
+//   - https://www.quartz-scheduler.org/documentation/quartz-2.3.0/tutorials/crontrigger.html
 func Parse(crontab string) (*Crontab, error) {
+	return ParseWithOption(crontab, nil)
+}
+
+// ParseOption is the option for [ParseWithOption].
+type ParseOption struct {
+	// DayOr, if true, evaluates the "Day of month" and the "Day of week"
+	// with OR condition like the traditional Vixie cron.
+	// The OR condition is applied only when both fields are restricted,
+	// that is, neither of them starts with "*" nor is "?".
+	// For example, "0 0 1,15 * MON" runs at the 1st, the 15th and every Monday.
+	// If false, they are evaluated with AND condition.
+	DayOr bool
+}
+
+// ParseWithOption parses the given cron expression with the
+// option and returns Crontab. Nil option is the same as zero option.
+// See [Parse] for the syntax.
+func ParseWithOption(crontab string, opt *ParseOption) (*Crontab, error) {
+	if opt == nil {
+		opt = &ParseOption{}
+	}
 	crontab = strings.Trim(crontab, " \n\r\t\f,")
 	fields := strings.Fields(replaceAlias(crontab))
+	if len(fields) == 0 {
+		return nil, &ParseError{What: "number of fields"}
+	}
 
 	loc := time.Local // Default location.
 	if strings.HasPrefix(fields[0], "TZ=") {
@@ -240,14 +702,19 @@ func Parse(crontab string) (*Crontab, error) {
 	}
 
 	c := &Crontab{
+		loc:     loc,
 		timeNow: func() time.Time { return time.Now().In(loc) },
 	}
 	switch len(fields) {
 	case 5:
 		// Valid number of fields.
 		fields = append([]string{"0"}, fields...) // Add the "second" field.
+		fields = append(fields, "*")              // Add the "year" field.
 	case 6:
 		// Valid number of fields.
+		fields = append(fields, "*") // Add the "year" field.
+	case 7:
+		// Valid number of fields.
 	default:
 		return nil, &ParseError{What: "number of fields"}
 	}
@@ -261,21 +728,162 @@ func Parse(crontab string) (*Crontab, error) {
 	if c.hour, ok = parseValue(fields[2], 0, 23); !ok {
 		return nil, &ParseError{What: "hour", Value: fields[2]}
 	}
-	if c.day, ok = parseValue(fields[3], 1, 31); !ok {
+	if ok = c.parseDay(fields[3]); !ok {
 		return nil, &ParseError{What: "day of month", Value: fields[3]}
 	}
 	if c.month, ok = parseValue(normalizeMonth(fields[4]), 1, 12); !ok {
 		return nil, &ParseError{What: "month", Value: fields[4]}
 	}
-	if c.week, ok = parseValue(normalizeWeek(fields[5]), 0, 6); !ok {
+	if ok = c.parseWeek(fields[5]); !ok {
 		return nil, &ParseError{What: "day of week", Value: fields[5]}
 	}
+	if c.year, ok = parseYear(fields[6]); !ok {
+		return nil, &ParseError{What: "year", Value: fields[6]}
+	}
+	c.dayOr = opt.DayOr && restricted(fields[3]) && restricted(fields[5])
+	for i, f := range fields {
+		c.exp[i] = strings.ToUpper(f)
+	}
+	c.exp[4] = normalizeMonth(c.exp[4])
+	c.exp[5] = normalizeWeekItems(c.exp[5])
 	if !c.valid() {
 		return nil, &ParseError{What: "scheduling (unschedulable)"}
 	}
 	return c, nil
 }
 
+// restricted returns if the day of month or
+// the day of week expression is restricted.
+// Expressions that start with "*" or "?" are not restricted.
+func restricted(exp string) bool {
+	return !strings.HasPrefix(exp, "*") && !strings.HasPrefix(exp, "?")
+}
+
+// parseDay parses the day of month expression.
+// In addition to the expressions that [parseValue] accepts,
+// "?", "L", "L-n", "LW" and "nW" are allowed.
+func (c *Crontab) parseDay(exp string) bool {
+	for _, e := range strings.Split(strings.ToUpper(exp), ",") {
+		switch {
+		case e == "?":
+			c.day |= toBitArray(1, 31, 1)
+		case e == "L":
+			c.dayLast |= 1
+		case e == "LW":
+			c.dayLastWeekday = true
+		case strings.HasPrefix(e, "L-"):
+			n, err := strconv.Atoi(e[2:])
+			if err != nil || n < 0 || n > 30 {
+				return false
+			}
+			c.dayLast |= 1 << n
+		case strings.HasSuffix(e, "W"):
+			n, err := strconv.Atoi(e[:len(e)-1])
+			if err != nil || n < 1 || n > 31 {
+				return false
+			}
+			c.dayNearest |= 1 << n
+		default:
+			v, ok := parseValue(e, 1, 31)
+			if !ok {
+				return false
+			}
+			c.day |= v
+		}
+	}
+	return true
+}
+
+// parseWeek parses the day of week expression.
+// In addition to the expressions that [parseValue] accepts,
+// "?", "L", "nL" and "n#k" are allowed.
+// Day of week names, SUN-SAT, are also accepted.
+func (c *Crontab) parseWeek(exp string) bool {
+	for _, e := range strings.Split(strings.ToUpper(exp), ",") {
+		switch {
+		case e == "?":
+			c.week |= toBitArray(0, 6, 1)
+		case e == "L":
+			c.week |= 1 << time.Saturday
+		case strings.HasSuffix(e, "L"):
+			n, ok := parseWeekday(e[:len(e)-1])
+			if !ok {
+				return false
+			}
+			c.weekLast |= 1 << n
+		case strings.Contains(e, "#"):
+			w, k, _ := strings.Cut(e, "#")
+			n, ok := parseWeekday(w)
+			if !ok {
+				return false
+			}
+			nth, err := strconv.Atoi(k)
+			if err != nil || nth < 1 || nth > 5 {
+				return false
+			}
+			c.weekNth[n] |= 1 << nth
+		default:
+			v, ok := parseValue(normalizeWeek(e), 0, 6)
+			if !ok {
+				return false
+			}
+			c.week |= v
+		}
+	}
+	return true
+}
+
+// normalizeWeekItems returns normalized day of week expression.
+// Unlike [normalizeWeek], names in "nL" and "n#k" are also
+// replaced with numbers. exp must be a valid upper case expression.
+func normalizeWeekItems(exp string) string {
+	items := strings.Split(exp, ",")
+	for i, e := range items {
+		switch {
+		case e != "L" && strings.HasSuffix(e, "L"):
+			items[i] = normalizeWeek(e[:len(e)-1]) + "L"
+		case strings.Contains(e, "#"):
+			w, k, _ := strings.Cut(e, "#")
+			items[i] = normalizeWeek(w) + "#" + k
+		default:
+			items[i] = normalizeWeek(e)
+		}
+	}
+	return strings.Join(items, ",")
+}
+
+// parseWeekday parses a single day of week.
+// Both a number 0-6 and a name SUN-SAT are accepted.
+func parseWeekday(exp string) (int, bool) {
+	n, err := strconv.Atoi(normalizeWeek(exp))
+	if err != nil || n < 0 || n > 6 {
+		return 0, false
+	}
+	return n, true
+}
+
+// parseYear parses the year expression.
+// Allowed expressions are the same as [parseValue]
+// with the range of 1970-2099.
+// It returns nil bit array for the wildcard "*",
+// which means every year including out of range years.
+func parseYear(exp string) ([]uint64, bool) {
+	if exp == "*" {
+		return nil, true
+	}
+	years := make([]uint64, (maxYear-minYear)/64+1)
+	ok := parseSteps(exp, minYear, maxYear, func(ini, end, step int) {
+		for i := ini; i <= end; i += step {
+			n := i - minYear
+			years[n/64] |= 1 << (n % 64)
+		}
+	})
+	if !ok {
+		return nil, false
+	}
+	return years, true
+}
+
 // replaceAlias replaces aliases.
 func replaceAlias(exp string) string {
 	if strings.Count(exp, "@") > 1 {
@@ -397,43 +1005,56 @@ func normalizeWeek(exp string) string {
 //   - Number with step   : "5/3"
 //   - Range with step    : "10-20/3"
 func parseValue(exp string, min, max int) (cron uint64, ok bool) {
-	if min > max || min < 0 || max < 0 { // This is not allowed.
+	result := uint64(0)
+	ok = parseSteps(exp, min, max, func(ini, end, step int) {
+		result |= toBitArray(ini, end, step)
+	})
+	if !ok {
 		return 0, false
 	}
+	return result, true
+}
+
+// parseSteps parses comma separated expressions and
+// calls set for each value range with its step.
+// See [parseValue] for the allowed expressions.
+func parseSteps(exp string, min, max int, set func(ini, end, step int)) bool {
+	if min > max || min < 0 || max < 0 { // This is not allowed.
+		return false
+	}
 
-	result := uint64(0)
 	for _, e := range strings.Split(exp, ",") {
 		fields := strings.Split(e, "/")
 		switch len(fields) {
 		case 1: // Format 'wildcard', 'number' or 'range'.
 			ini, end, ok := parseRange(fields[0], min, max)
 			if !ok {
-				return 0, false
+				return false
 			}
-			result |= toBitArray(ini, end, 1)
+			set(ini, end, 1)
 
 		case 2: // Format 'wildcard with step', 'number with step' or 'range with step'.
 			step, err := strconv.Atoi(fields[1]) // Parse step.
 			if err != nil {
-				return 0, false
+				return false
 			}
 			if step <= 0 {
-				return 0, false // Zero or negative step is not supported.
+				return false // Zero or negative step is not supported.
 			}
 			ini, end, ok := parseRange(fields[0], min, max)
 			if !ok {
-				return 0, false
+				return false
 			}
 			if ini == end {
 				end = max
 			}
-			result |= toBitArray(ini, end, step)
+			set(ini, end, step)
 
 		default: // Unsupported format.
-			return 0, false
+			return false
 		}
 	}
-	return result, true
+	return true
 }
 
 // toBitArray returns bit array that represents
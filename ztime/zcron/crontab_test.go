@@ -132,6 +132,342 @@ func TestCrontab_NextAfter(t *testing.T) {
 	}
 }
 
+func TestCrontab_NextAfterExtended(t *testing.T) {
+	t.Parallel()
+
+	// 2000/01/01=SAT, 2000/01/31=MON, 2000/02/29=TUE
+	// 2000/04/01=SAT, 2000/04/30=SUN, 2000/05/31=WED
+	testCases := map[string]struct {
+		cron  string
+		dayOr bool
+		t     time.Time
+		want  time.Time
+	}{
+		"last day 01":            {"0 0 0 L * *", false, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 1, 31, 0, 0, 0, 0, time.UTC)},
+		"last day 02":            {"0 0 0 L * *", false, time.Date(2000, 1, 31, 0, 0, 0, 0, time.UTC), time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC)},
+		"last day offset":        {"0 0 0 L-1 * *", false, time.Date(2000, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 2, 28, 0, 0, 0, 0, time.UTC)},
+		"last day and number":    {"0 0 0 1,L * *", false, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 1, 31, 0, 0, 0, 0, time.UTC)},
+		"last weekday":           {"0 0 0 LW * *", false, time.Date(2000, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 4, 28, 0, 0, 0, 0, time.UTC)},
+		"nearest weekday first":  {"0 0 0 1W * *", false, time.Date(2000, 3, 31, 0, 0, 0, 0, time.UTC), time.Date(2000, 4, 3, 0, 0, 0, 0, time.UTC)},
+		"nearest weekday sat":    {"0 0 0 15W * *", false, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 1, 14, 0, 0, 0, 0, time.UTC)},
+		"nearest weekday last":   {"0 0 0 30W * *", false, time.Date(2000, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 4, 28, 0, 0, 0, 0, time.UTC)},
+		"nearest weekday short":  {"0 0 0 31W * *", false, time.Date(2000, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 5, 31, 0, 0, 0, 0, time.UTC)},
+		"last day of week":       {"0 0 0 ? * 5L", false, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 1, 28, 0, 0, 0, 0, time.UTC)},
+		"last day of week name":  {"0 0 0 ? * FRIL", false, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 1, 28, 0, 0, 0, 0, time.UTC)},
+		"saturday":               {"0 0 0 ? * L", false, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 1, 8, 0, 0, 0, 0, time.UTC)},
+		"nth day of week":        {"0 0 0 ? * 2#2", false, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 1, 11, 0, 0, 0, 0, time.UTC)},
+		"nth day of week name":   {"0 0 0 ? * TUE#5", false, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC)},
+		"year":                   {"0 0 0 * * * 2090", false, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2090, 1, 1, 0, 0, 0, 0, time.UTC)},
+		"year step":              {"0 0 0 1 1 * */10", false, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)},
+		"year no more schedule":  {"0 0 0 1 1 * 2090", false, time.Date(2090, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+		"past year":              {"0 0 0 * * * 2000", false, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Time{}},
+		"day and week with AND":  {"0 0 0 15 * MON", false, time.Date(2000, 1, 10, 0, 0, 0, 0, time.UTC), time.Date(2000, 5, 15, 0, 0, 0, 0, time.UTC)},
+		"day and week with OR 1": {"0 0 0 15 * MON", true, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 1, 3, 0, 0, 0, 0, time.UTC)},
+		"day and week with OR 2": {"0 0 0 15 * MON", true, time.Date(2000, 1, 10, 0, 0, 0, 0, time.UTC), time.Date(2000, 1, 15, 0, 0, 0, 0, time.UTC)},
+		"day only with OR":       {"0 0 0 15 * *", true, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 1, 15, 0, 0, 0, 0, time.UTC)},
+		"week only with OR":      {"0 0 0 ? * MON", true, time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2000, 1, 3, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ct, err := ParseWithOption("TZ=UTC "+tc.cron, &ParseOption{DayOr: tc.dayOr})
+			ztesting.AssertEqual(t, "non nil error returned", nil, err)
+			got := ct.NextAfter(tc.t)
+			ztesting.AssertEqual(t, "time not match", 0, tc.want.Compare(got))
+		})
+	}
+}
+
+func TestCrontab_NextAfterDST(t *testing.T) {
+	t.Parallel()
+
+	// Clock jumps forward 02:00 EST -> 03:00 EDT on 2024/03/10 and
+	// jumps backward 02:00 EDT -> 01:00 EST on 2024/11/03.
+	loc, err := time.LoadLocation("America/New_York")
+	ztesting.AssertEqual(t, "failed to load location", nil, err)
+	edt := time.FixedZone("EDT", -4*3600)
+	est := time.FixedZone("EST", -5*3600)
+
+	testCases := map[string]struct {
+		cron string
+		t    time.Time
+		want time.Time
+	}{
+		"skipped 01":       {"0 30 2 * * *", time.Date(2024, 3, 10, 0, 0, 0, 0, est), time.Date(2024, 3, 10, 3, 0, 0, 0, edt)},
+		"skipped 02":       {"0 30 2 * * *", time.Date(2024, 3, 10, 3, 0, 0, 0, edt), time.Date(2024, 3, 11, 2, 30, 0, 0, edt)},
+		"skipped merged 1": {"0 * * * * *", time.Date(2024, 3, 10, 1, 59, 0, 0, est), time.Date(2024, 3, 10, 3, 0, 0, 0, edt)},
+		"skipped merged 2": {"0 * * * * *", time.Date(2024, 3, 10, 3, 0, 0, 0, edt), time.Date(2024, 3, 10, 3, 1, 0, 0, edt)},
+		"not skipped":      {"0 0 3 * * *", time.Date(2024, 3, 10, 0, 0, 0, 0, est), time.Date(2024, 3, 10, 3, 0, 0, 0, edt)},
+		"midnight":         {"0 0 0 * * *", time.Date(2024, 3, 10, 0, 0, 0, 0, est), time.Date(2024, 3, 11, 0, 0, 0, 0, edt)},
+		"repeated 01":      {"0 30 1 * * *", time.Date(2024, 11, 3, 0, 0, 0, 0, edt), time.Date(2024, 11, 3, 1, 30, 0, 0, edt)},
+		"repeated 02":      {"0 30 1 * * *", time.Date(2024, 11, 3, 1, 30, 0, 0, edt), time.Date(2024, 11, 4, 1, 30, 0, 0, est)},
+		"repeated 03":      {"0 45 1 * * *", time.Date(2024, 11, 3, 1, 10, 0, 0, est), time.Date(2024, 11, 4, 1, 45, 0, 0, est)},
+		"repeated 04":      {"0 0 2 * * *", time.Date(2024, 11, 3, 1, 10, 0, 0, est), time.Date(2024, 11, 3, 2, 0, 0, 0, est)},
+		"every hour 01":    {"0 */15 * * * *", time.Date(2024, 11, 3, 1, 45, 0, 0, edt), time.Date(2024, 11, 3, 1, 0, 0, 0, est)},
+		"every hour 02":    {"0 */15 * * * *", time.Date(2024, 11, 3, 1, 0, 0, 0, est), time.Date(2024, 11, 3, 1, 15, 0, 0, est)},
+		"far future 01":    {"0 0 0 * * *", time.Date(2040, 12, 30, 20, 0, 0, 0, est), time.Date(2040, 12, 31, 0, 0, 0, 0, est)},
+		"far future 02":    {"0 30 2 * * *", time.Date(2041, 3, 9, 20, 0, 0, 0, est), time.Date(2041, 3, 10, 3, 0, 0, 0, edt)},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ct, err := Parse("TZ=America/New_York " + tc.cron)
+			ztesting.AssertEqual(t, "non nil error returned", nil, err)
+			got := ct.NextAfter(tc.t.In(loc))
+			ztesting.AssertEqual(t, "time not match", tc.want.String(), got.In(tc.want.Location()).String())
+		})
+	}
+}
+
+func TestZoneEnd(t *testing.T) {
+	t.Parallel()
+	ny, err := time.LoadLocation("America/New_York")
+	ztesting.AssertEqual(t, "failed to load location", nil, err)
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	ztesting.AssertEqual(t, "failed to load location", nil, err)
+
+	testCases := map[string]struct {
+		t, until time.Time
+		want     time.Time
+	}{
+		"transition":       {time.Date(2024, 1, 1, 0, 0, 0, 0, ny), time.Date(2025, 1, 1, 0, 0, 0, 0, ny), time.Date(2024, 3, 10, 7, 0, 0, 0, time.UTC)},
+		"until before":     {time.Date(2024, 1, 1, 0, 0, 0, 0, ny), time.Date(2024, 3, 1, 0, 0, 0, 0, ny), time.Time{}},
+		"no transition":    {time.Date(2024, 1, 1, 0, 0, 0, 0, tokyo), time.Date(2034, 1, 1, 0, 0, 0, 0, tokyo), time.Time{}},
+		"far future":       {time.Date(2041, 1, 1, 0, 0, 0, 0, ny), time.Date(2042, 1, 1, 0, 0, 0, 0, ny), time.Date(2041, 3, 10, 7, 0, 0, 0, time.UTC)},
+		"far future years": {time.Date(2040, 12, 30, 20, 0, 0, 0, ny), time.Date(2042, 1, 1, 0, 0, 0, 0, ny), time.Date(2041, 3, 10, 7, 0, 0, 0, time.UTC)},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got := zoneEnd(tc.t, tc.until)
+			ztesting.AssertEqual(t, "time not match", tc.want.String(), got.UTC().String())
+		})
+	}
+}
+
+func TestCrontab_PrevBefore(t *testing.T) {
+	t.Parallel()
+
+	loc, err := time.LoadLocation("America/New_York")
+	ztesting.AssertEqual(t, "failed to load location", nil, err)
+	edt := time.FixedZone("EDT", -4*3600)
+	est := time.FixedZone("EST", -5*3600)
+
+	testCases := map[string]struct {
+		cron string
+		t    time.Time
+		want time.Time
+	}{
+		"every second":     {"* * * * * *", time.Date(2024, 1, 1, 0, 0, 0, 0, est), time.Date(2023, 12, 31, 23, 59, 59, 0, est)},
+		"nanosecond":       {"* * * * * *", time.Date(2024, 1, 1, 0, 0, 0, 1, est), time.Date(2024, 1, 1, 0, 0, 0, 0, est)},
+		"previous day":     {"0 30 2 * * *", time.Date(2024, 1, 2, 2, 30, 0, 0, est), time.Date(2024, 1, 1, 2, 30, 0, 0, est)},
+		"same day":         {"0 30 2 * * *", time.Date(2024, 1, 2, 2, 31, 0, 0, est), time.Date(2024, 1, 2, 2, 30, 0, 0, est)},
+		"previous month":   {"0 0 0 L * *", time.Date(2024, 3, 15, 0, 0, 0, 0, est), time.Date(2024, 2, 29, 0, 0, 0, 0, est)},
+		"previous year":    {"0 0 0 1 1 *", time.Date(2024, 1, 1, 0, 0, 0, 0, est), time.Date(2023, 1, 1, 0, 0, 0, 0, est)},
+		"last weekday":     {"0 0 0 * * 5L", time.Date(2024, 3, 1, 0, 0, 0, 0, est), time.Date(2024, 2, 23, 0, 0, 0, 0, est)},
+		"year field":       {"0 0 0 1 1 * 2090", time.Date(2095, 1, 1, 0, 0, 0, 0, est), time.Date(2090, 1, 1, 0, 0, 0, 0, est)},
+		"skipped":          {"0 30 2 * * *", time.Date(2024, 3, 10, 4, 0, 0, 0, edt), time.Date(2024, 3, 10, 3, 0, 0, 0, edt)},
+		"repeated 01":      {"0 30 1 * * *", time.Date(2024, 11, 3, 1, 40, 0, 0, est), time.Date(2024, 11, 3, 1, 30, 0, 0, edt)},
+		"repeated 02":      {"0 */15 * * * *", time.Date(2024, 11, 3, 1, 10, 0, 0, est), time.Date(2024, 11, 3, 1, 0, 0, 0, est)},
+		"repeated 03":      {"0 */15 * * * *", time.Date(2024, 11, 3, 1, 0, 0, 0, est), time.Date(2024, 11, 3, 1, 45, 0, 0, edt)},
+		"no more schedule": {"0 0 0 1 1 * 2090", time.Date(2090, 1, 1, 0, 0, 0, 0, est), time.Time{}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ct, err := Parse("TZ=America/New_York " + tc.cron)
+			ztesting.AssertEqual(t, "non nil error returned", nil, err)
+			got := ct.PrevBefore(tc.t.In(loc))
+			if tc.want.IsZero() {
+				ztesting.AssertEqual(t, "time not zero", true, got.IsZero())
+				return
+			}
+			ztesting.AssertEqual(t, "time not match", tc.want.String(), got.In(tc.want.Location()).String())
+			next := ct.NextAfter(got)
+			ztesting.AssertEqual(t, "next schedule is before t", false, !next.IsZero() && next.Before(tc.t))
+		})
+	}
+}
+
+func TestCrontab_Schedules(t *testing.T) {
+	t.Parallel()
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		cron  string
+		until time.Time
+		n     int
+		want  []time.Time
+	}{
+		"limit n": {"0 0 * * *", time.Time{}, 2, []time.Time{
+			time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+		}},
+		"limit until": {"0 0 * * *", time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), 0, []time.Time{
+			time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+		}},
+		"limit both": {"0 0 * * *", time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), 1, []time.Time{
+			time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		}},
+		"no more schedule": {"0 0 0 1 1 * 2090,2091", time.Time{}, 0, []time.Time{
+			time.Date(2090, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2091, 1, 1, 0, 0, 0, 0, time.UTC),
+		}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ct, err := Parse("TZ=UTC " + tc.cron)
+			ztesting.AssertEqual(t, "non nil error returned", nil, err)
+			var got []time.Time
+			for at := range ct.Schedules(from, tc.until, tc.n) {
+				got = append(got, at)
+			}
+			ztesting.AssertEqual(t, "schedules not match", fmt.Sprint(tc.want), fmt.Sprint(got))
+		})
+	}
+
+	t.Run("break", func(t *testing.T) {
+		ct, _ := Parse("TZ=UTC * * * * *")
+		count := 0
+		for range ct.Schedules(from, time.Time{}, 0) {
+			if count++; count == 3 {
+				break
+			}
+		}
+		ztesting.AssertEqual(t, "iteration not stopped", 3, count)
+	})
+}
+
+func TestCrontab_Expression(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		cron string
+		exp  string
+		str  string
+	}{
+		"5 fields":   {"TZ=UTC 30 2 * * *", "0 30 2 * * *", "TZ=UTC 0 30 2 * * *"},
+		"6 fields":   {"TZ=UTC 5 30 2 * * *", "5 30 2 * * *", "TZ=UTC 5 30 2 * * *"},
+		"7 fields":   {"TZ=UTC 5 30 2 * * * 2090", "5 30 2 * * * 2090", "TZ=UTC 5 30 2 * * * 2090"},
+		"year *":     {"TZ=UTC 5 30 2 * * * *", "5 30 2 * * *", "TZ=UTC 5 30 2 * * *"},
+		"alias":      {"TZ=UTC @weekly", "0 0 0 * * 0", "TZ=UTC 0 0 0 * * 0"},
+		"names":      {"TZ=Asia/Tokyo 0 9 * jan-Mar mon-FRI", "0 0 9 * 1-3 1-5", "TZ=Asia/Tokyo 0 0 9 * 1-3 1-5"},
+		"extended":   {"TZ=UTC 0 0 lw,l-2 * fril,tue#2", "0 0 0 LW,L-2 * 5L,2#2", "TZ=UTC 0 0 0 LW,L-2 * 5L,2#2"},
+		"spaces":     {"  TZ=UTC   0  0 * *   * ", "0 0 0 * * *", "TZ=UTC 0 0 0 * * *"},
+		"cron tz":    {"CRON_TZ=UTC 0 0 * * *", "0 0 0 * * *", "TZ=UTC 0 0 0 * * *"},
+		"week alias": {"TZ=UTC 0 0 * * L", "0 0 0 * * L", "TZ=UTC 0 0 0 * * L"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ct, err := Parse(tc.cron)
+			ztesting.AssertEqual(t, "non nil error returned", nil, err)
+			ztesting.AssertEqual(t, "expression not match", tc.exp, ct.Expression())
+			ztesting.AssertEqual(t, "string not match", tc.str, ct.String())
+			reparsed, err := Parse(ct.String())
+			ztesting.AssertEqual(t, "normalized expression not parsed", nil, err)
+			ztesting.AssertEqual(t, "reparsed expression not match", tc.exp, reparsed.Expression())
+		})
+	}
+}
+
+func TestNearestWeekday(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		year  int
+		month time.Month
+		day   int
+		want  int
+	}{
+		"weekday":        {2000, 1, 3, 3},   // MON
+		"saturday":       {2000, 1, 15, 14}, // SAT > FRI
+		"sunday":         {2000, 1, 16, 17}, // SUN > MON
+		"first saturday": {2000, 1, 1, 3},   // SAT > MON
+		"last sunday":    {2000, 4, 30, 28}, // SUN > FRI
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			got := nearestWeekday(tc.year, tc.month, tc.day, daysIn(tc.year, tc.month))
+			ztesting.AssertEqual(t, "invalid result", tc.want, got)
+		})
+	}
+}
+
+func TestParseDayWeekYear(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		exp  string
+		week bool
+		year bool
+		ok   bool
+	}{
+		"day ?":        {exp: "?", ok: true},
+		"day L":        {exp: "L", ok: true},
+		"day l":        {exp: "l", ok: true},
+		"day L-30":     {exp: "L-30", ok: true},
+		"day L-31":     {exp: "L-31", ok: false},
+		"day L-x":      {exp: "L-x", ok: false},
+		"day LW":       {exp: "LW", ok: true},
+		"day 1W":       {exp: "1W", ok: true},
+		"day 0W":       {exp: "0W", ok: false},
+		"day 32W":      {exp: "32W", ok: false},
+		"day xW":       {exp: "xW", ok: false},
+		"day 1,L,15W":  {exp: "1,L,15W", ok: true},
+		"day 0":        {exp: "0", ok: false},
+		"week ?":       {exp: "?", week: true, ok: true},
+		"week L":       {exp: "L", week: true, ok: true},
+		"week 6L":      {exp: "6L", week: true, ok: true},
+		"week satL":    {exp: "satL", week: true, ok: true},
+		"week 7L":      {exp: "7L", week: true, ok: false},
+		"week 1#1":     {exp: "1#1", week: true, ok: true},
+		"week MON#5":   {exp: "MON#5", week: true, ok: true},
+		"week 1#0":     {exp: "1#0", week: true, ok: false},
+		"week 1#6":     {exp: "1#6", week: true, ok: false},
+		"week 1#x":     {exp: "1#x", week: true, ok: false},
+		"week x#1":     {exp: "x#1", week: true, ok: false},
+		"week MON,2#1": {exp: "MON,2#1,5L", week: true, ok: true},
+		"week 7":       {exp: "7", week: true, ok: false},
+		"year *":       {exp: "*", year: true, ok: true},
+		"year 1970":    {exp: "1970", year: true, ok: true},
+		"year 2099":    {exp: "2099", year: true, ok: true},
+		"year 1969":    {exp: "1969", year: true, ok: false},
+		"year 2100":    {exp: "2100", year: true, ok: false},
+		"year range":   {exp: "2030-2040/2,2050", year: true, ok: true},
+		"year ?":       {exp: "?", year: true, ok: false},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c := &Crontab{}
+			var ok bool
+			switch {
+			case tc.week:
+				ok = c.parseWeek(tc.exp)
+			case tc.year:
+				_, ok = parseYear(tc.exp)
+			default:
+				ok = c.parseDay(tc.exp)
+			}
+			ztesting.AssertEqual(t, "wrong bool value returned", tc.ok, ok)
+		})
+	}
+	t.Run("year bits", func(t *testing.T) {
+		years, _ := parseYear("1970,2033-2035/2,2099")
+		c := &Crontab{year: years}
+		for y := 1960; y <= 2110; y++ {
+			want := y == 1970 || y == 2033 || y == 2035 || y == 2099
+			ztesting.AssertEqual(t, "year not match "+fmt.Sprint(y), want, c.matchYear(y))
+		}
+		next, ok := c.nextYear(1950)
+		ztesting.AssertEqual(t, "next year not match", 1970, next)
+		ztesting.AssertEqual(t, "next year not found", true, ok)
+		_, ok = c.nextYear(2099)
+		ztesting.AssertEqual(t, "next year found", false, ok)
+	})
+}
+
 func TestNextTime(t *testing.T) {
 	t.Parallel()
 
@@ -311,10 +647,45 @@ func TestParse(t *testing.T) {
 			nil,
 		},
 		"invalid number of fields": {
-			"* * * * * * *",
+			"* * * * * * * *",
+			&ParseError{What: "number of fields"},
+			nil,
+		},
+		"empty": {
+			"",
 			&ParseError{What: "number of fields"},
 			nil,
 		},
+		"invalid year": {
+			"* * * * * * 1969",
+			&ParseError{What: "year"},
+			nil,
+		},
+		"invalid special day of month": {
+			"* * * L-31 * *",
+			&ParseError{What: "day of month"},
+			nil,
+		},
+		"invalid special day of week": {
+			"* * * ? * 1#6",
+			&ParseError{What: "day of week"},
+			nil,
+		},
+		"unschedulable nearest weekday": {
+			"0 0 0 31W 2 *", // Feb 31st
+			&ParseError{What: "scheduling (unschedulable)"},
+			nil,
+		},
+		"unschedulable year": {
+			"0 0 0 29 2 * 2025", // Not a leap year.
+			&ParseError{What: "scheduling (unschedulable)"},
+			nil,
+		},
+		"unschedulable nth day of week": {
+			"0 0 0 ? 2 MON#5 2090", // No 5th Monday in Feb 2090.
+			&ParseError{What: "scheduling (unschedulable)"},
+			nil,
+		},
 		"invalid location": {
 			"TZ=NotExists * * * * *",
 			&ParseError{What: "timezone"},
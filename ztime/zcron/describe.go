@@ -0,0 +1,206 @@
+package zcron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Description returns the human readable description of the crontab.
+// The description is generated from the normalized expression
+// returned by [Crontab.Expression]. The format of the description
+// is not stable and should not be parsed by programs.
+// For example, "TZ=UTC 30 2 * * MON" is described as
+// "At 02:30 on Monday in UTC".
+func (c *Crontab) Description() string {
+	var sb strings.Builder
+	sb.WriteString(c.describeTime())
+	dom := describeDate(c.exp[3], &field{prefix: "on day", unit: "day", special: describeDaySpecial})
+	dow := describeDate(c.exp[5], &field{prefix: "on", unit: "day of week", name: weekdayName, special: describeWeekSpecial})
+	switch {
+	case dom != "" && dow != "" && c.dayOr:
+		sb.WriteString(" " + dom + " or " + dow)
+	case dom != "" && dow != "":
+		sb.WriteString(" " + dom + " and " + dow)
+	case dom != "":
+		sb.WriteString(" " + dom)
+	case dow != "":
+		sb.WriteString(" " + dow)
+	}
+	if month := describeDate(c.exp[4], &field{prefix: "in", unit: "month", name: monthName}); month != "" {
+		sb.WriteString(" " + month)
+	}
+	if year := describeDate(c.exp[6], &field{prefix: "in", unit: "year"}); year != "" {
+		sb.WriteString(" " + year)
+	}
+	sb.WriteString(" in " + c.loc.String())
+	desc := sb.String()
+	return strings.ToUpper(desc[:1]) + desc[1:]
+}
+
+// describeTime returns the description of the
+// second, minute and hour fields.
+func (c *Crontab) describeTime() string {
+	sec, min, hour := c.exp[0], c.exp[1], c.exp[2]
+	s, errS := strconv.Atoi(sec)
+	m, errM := strconv.Atoi(min)
+	h, errH := strconv.Atoi(hour)
+	if errS == nil && errM == nil && errH == nil {
+		if s == 0 {
+			return fmt.Sprintf("at %02d:%02d", h, m)
+		}
+		return fmt.Sprintf("at %02d:%02d:%02d", h, m, s)
+	}
+
+	// A wildcard field is described only when all the
+	// lower fields are "0". For example, "0 * * * * *" is
+	// described as "every minute" but "* * * * * *" is
+	// described as "every second", not "every second, every minute".
+	var parts []string
+	lowerZero := true
+	if sec != "0" {
+		parts = append(parts, describeField(sec, &field{prefix: "at second", unit: "second"}))
+		lowerZero = false
+	}
+	if min != "0" && (min != "*" || lowerZero) {
+		parts = append(parts, describeField(min, &field{prefix: "at minute", unit: "minute"}))
+	}
+	if min != "0" {
+		lowerZero = false
+	}
+	if hour != "*" || lowerZero {
+		parts = append(parts, describeField(hour, &field{unit: "hour", special: describeHourSpecial}))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// field is the description rules of a field.
+type field struct {
+	// prefix is prepended to the list of values.
+	// For example, "at minute" of "at minute 5, 10".
+	prefix string
+	// unit is the singular unit name of the field.
+	// "s" is appended for plural form.
+	unit string
+	// name, if non-nil, returns the name of the value.
+	// For example, "Monday" for the 1 of day of week.
+	name func(int) string
+	// special, if non-nil, describes the item that
+	// is not a standard value, range or step expression.
+	// It returns false if the item is not special.
+	special func(item string) (string, bool)
+}
+
+func (f *field) valueName(v string) string {
+	n, err := strconv.Atoi(v)
+	if err != nil || f.name == nil {
+		return v
+	}
+	return f.name(n)
+}
+
+// describeDate returns the description of a date field.
+// Unlike [describeField], it returns an empty string
+// for the wildcards "*" and "?" which do not restrict the date.
+func describeDate(exp string, f *field) string {
+	if exp == "*" || exp == "?" {
+		return ""
+	}
+	return describeField(exp, f)
+}
+
+// describeField returns the description of a field.
+// exp must be a normalized expression.
+func describeField(exp string, f *field) string {
+	var values, parts []string
+	for _, item := range strings.Split(exp, ",") {
+		if f.special != nil {
+			if desc, ok := f.special(item); ok {
+				parts = append(parts, desc)
+				continue
+			}
+		}
+		rng, step, hasStep := strings.Cut(item, "/")
+		from, to, hasRange := strings.Cut(rng, "-")
+		switch {
+		case rng == "*" && !hasStep:
+			parts = append(parts, "every "+f.unit)
+		case rng == "*":
+			parts = append(parts, "every "+step+" "+f.unit+"s")
+		case hasStep && hasRange:
+			parts = append(parts, "every "+step+" "+f.unit+"s from "+f.valueName(from)+" through "+f.valueName(to))
+		case hasStep:
+			parts = append(parts, "every "+step+" "+f.unit+"s starting at "+f.valueName(from))
+		case hasRange:
+			values = append(values, f.valueName(from)+" through "+f.valueName(to))
+		default:
+			values = append(values, f.valueName(from))
+		}
+	}
+	if len(values) > 0 {
+		parts = append([]string{strings.TrimPrefix(f.prefix+" "+strings.Join(values, ", "), " ")}, parts...)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// describeHourSpecial describes values and ranges of hours
+// as a time range such as "between 09:00 and 17:59".
+func describeHourSpecial(item string) (string, bool) {
+	if strings.ContainsAny(item, "*/") {
+		return "", false
+	}
+	from, to, found := strings.Cut(item, "-")
+	if !found {
+		to = from
+	}
+	f, _ := strconv.Atoi(from)
+	t, _ := strconv.Atoi(to)
+	return fmt.Sprintf("between %02d:00 and %02d:59", f, t), true
+}
+
+// describeDaySpecial describes the "L", "L-n", "LW" and "nW"
+// expressions of the day of month.
+func describeDaySpecial(item string) (string, bool) {
+	switch {
+	case item == "L":
+		return "on the last day of the month", true
+	case item == "LW":
+		return "on the last weekday of the month", true
+	case strings.HasPrefix(item, "L-"):
+		if item[2:] == "1" {
+			return "on 1 day before the last day of the month", true
+		}
+		return "on " + item[2:] + " days before the last day of the month", true
+	case strings.HasSuffix(item, "W"):
+		return "on the nearest weekday to day " + item[:len(item)-1] + " of the month", true
+	}
+	return "", false
+}
+
+// describeWeekSpecial describes the "L", "nL" and "n#k"
+// expressions of the day of week.
+func describeWeekSpecial(item string) (string, bool) {
+	switch {
+	case item == "L":
+		return "on " + weekdayName(int(time.Saturday)), true
+	case strings.HasSuffix(item, "L"):
+		n, _ := strconv.Atoi(item[:len(item)-1])
+		return "on the last " + weekdayName(n) + " of the month", true
+	case strings.Contains(item, "#"):
+		w, k, _ := strings.Cut(item, "#")
+		n, _ := strconv.Atoi(w)
+		nth, _ := strconv.Atoi(k)
+		ordinals := []string{"", "first", "second", "third", "fourth", "fifth"}
+		return "on the " + ordinals[nth] + " " + weekdayName(n) + " of the month", true
+	}
+	return "", false
+}
+
+func weekdayName(n int) string {
+	return time.Weekday(n).String()
+}
+
+func monthName(n int) string {
+	return time.Month(n).String()
+}
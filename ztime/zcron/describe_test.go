@@ -0,0 +1,56 @@
+package zcron
+
+import (
+	"testing"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestCrontab_Description(t *testing.T) {
+	t.Parallel()
+
+	testCases := map[string]struct {
+		cron  string
+		dayOr bool
+		want  string
+	}{
+		"at time":          {"TZ=UTC 30 2 * * *", false, "At 02:30 in UTC"},
+		"at time seconds":  {"TZ=UTC 5 30 2 * * *", false, "At 02:30:05 in UTC"},
+		"weekday":          {"TZ=UTC 30 2 * * MON", false, "At 02:30 on Monday in UTC"},
+		"weekday range":    {"TZ=UTC 0 9 * * 1-5", false, "At 09:00 on Monday through Friday in UTC"},
+		"every second":     {"TZ=UTC * * * * * *", false, "Every second in UTC"},
+		"every minute":     {"TZ=UTC * * * * *", false, "Every minute in UTC"},
+		"every hour":       {"TZ=UTC 0 * * * *", false, "Every hour in UTC"},
+		"at minute":        {"TZ=UTC 15,45 * * * *", false, "At minute 15, 45 in UTC"},
+		"every n seconds":  {"TZ=UTC */10 * 9 * * *", false, "Every 10 seconds, between 09:00 and 09:59 in UTC"},
+		"every n minutes":  {"TZ=UTC */15 9-17 * * *", false, "Every 15 minutes, between 09:00 and 17:59 in UTC"},
+		"every n hours":    {"TZ=UTC 0 */6 * * *", false, "Every 6 hours in UTC"},
+		"step range":       {"TZ=UTC 0 0 9-17/2 * * *", false, "Every 2 hours from 9 through 17 in UTC"},
+		"step start":       {"TZ=UTC 5/20 * * * *", false, "Every 20 minutes starting at 5 in UTC"},
+		"days":             {"TZ=UTC 0 0 1,15 * *", false, "At 00:00 on day 1, 15 in UTC"},
+		"last day":         {"TZ=UTC 0 0 L * *", false, "At 00:00 on the last day of the month in UTC"},
+		"before last day":  {"TZ=UTC 0 0 L-3 * *", false, "At 00:00 on 3 days before the last day of the month in UTC"},
+		"last weekday":     {"TZ=UTC 0 0 LW * *", false, "At 00:00 on the last weekday of the month in UTC"},
+		"nearest weekday":  {"TZ=UTC 0 0 15W * *", false, "At 00:00 on the nearest weekday to day 15 of the month in UTC"},
+		"last friday":      {"TZ=UTC 0 0 * * FRIL", false, "At 00:00 on the last Friday of the month in UTC"},
+		"second tuesday":   {"TZ=UTC 0 0 * * TUE#2", false, "At 00:00 on the second Tuesday of the month in UTC"},
+		"day and week":     {"TZ=UTC 0 0 1 * MON", false, "At 00:00 on day 1 and on Monday in UTC"},
+		"day or week":      {"TZ=UTC 0 0 1 * MON", true, "At 00:00 on day 1 or on Monday in UTC"},
+		"months":           {"TZ=UTC 0 0 1 JAN,JUL *", false, "At 00:00 on day 1 in January, July in UTC"},
+		"every n months":   {"TZ=UTC 0 0 1 */3 *", false, "At 00:00 on day 1 every 3 months in UTC"},
+		"years":            {"TZ=UTC 0 0 0 1 1 * 2090-2095", false, "At 00:00 on day 1 in January in 2090 through 2095 in UTC"},
+		"alias":            {"TZ=UTC @daily", false, "At 00:00 in UTC"},
+		"location":         {"TZ=Asia/Tokyo 0 0 * * *", false, "At 00:00 in Asia/Tokyo"},
+		"question mark":    {"TZ=UTC 0 0 ? * ?", false, "At 00:00 in UTC"},
+		"saturday":         {"TZ=UTC 0 0 * * L", false, "At 00:00 on Saturday in UTC"},
+		"minute and hours": {"TZ=UTC 30 9,17 * * *", false, "At minute 30, between 09:00 and 09:59, between 17:00 and 17:59 in UTC"},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ct, err := ParseWithOption(tc.cron, &ParseOption{DayOr: tc.dayOr})
+			ztesting.AssertEqual(t, "non nil error returned", nil, err)
+			ztesting.AssertEqual(t, "description not match", tc.want, ct.Description())
+		})
+	}
+}
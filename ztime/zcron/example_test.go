@@ -86,3 +86,45 @@ func ExampleCrontab() {
 	// 2000-04-30 00:00:00 | 2000-05-31 23:59:59
 	// 2000-05-15 00:00:00 | 2000-05-31 23:59:59
 }
+
+func ExampleCrontab_lastFriday() {
+	ct, err := zcron.Parse("TZ=UTC 0 0 18 ? * FRIL") // Last Friday of the month at 18:00.
+	if err != nil {
+		panic(err)
+	}
+
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	for range 4 {
+		now = ct.NextAfter(now)
+		fmt.Println(now.Format(time.DateTime), now.Weekday())
+	}
+
+	// Output:
+	// 2000-01-28 18:00:00 Friday
+	// 2000-02-25 18:00:00 Friday
+	// 2000-03-31 18:00:00 Friday
+	// 2000-04-28 18:00:00 Friday
+}
+
+func ExampleCrontab_Schedules() {
+	ct, err := zcron.Parse("TZ=UTC 30 2 * * mon")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(ct.String())
+	fmt.Println(ct.Description())
+
+	from := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	fmt.Println(ct.PrevBefore(from).Format(time.DateTime))
+	for t := range ct.Schedules(from, time.Time{}, 3) {
+		fmt.Println(t.Format(time.DateTime))
+	}
+
+	// Output:
+	// TZ=UTC 0 30 2 * * 1
+	// At 02:30 on Monday in UTC
+	// 1999-12-27 02:30:00
+	// 2000-01-03 02:30:00
+	// 2000-01-10 02:30:00
+	// 2000-01-17 02:30:00
+}
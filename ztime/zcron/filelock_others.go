@@ -0,0 +1,16 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+
+package zcron
+
+import (
+	"errors"
+	"os"
+)
+
+func lockFile(_ *os.File) error {
+	return errors.ErrUnsupported
+}
+
+func unlockFile(_ *os.File) error {
+	return errors.ErrUnsupported
+}
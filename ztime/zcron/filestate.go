@@ -0,0 +1,96 @@
+package zcron
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	_ Locker     = &FileLocker{}
+	_ StateStore = &FileStore{}
+)
+
+// FileLocker is the [Locker] that uses advisory file locks.
+// Lock files named "<key>.lock" are created in the Dir.
+// Replicas that share the Dir, for example through a shared
+// volume, run the jobs exclusively.
+// The lock is released by the OS when the owner process exited,
+// so the ttl given to the TryLock is not used.
+// FileLocker is supported only on unix-like platforms such as
+// linux, darwin and BSDs. TryLock returns [errors.ErrUnsupported]
+// on other platforms.
+// Note that advisory locks may not work on some network file systems.
+type FileLocker struct {
+	// Dir is the directory to create lock files.
+	// The directory must exist.
+	Dir string
+}
+
+func (l *FileLocker) TryLock(_ context.Context, key string, _ time.Duration) (func() error, error) {
+	f, err := os.OpenFile(filepath.Join(l.Dir, url.PathEscape(key)+".lock"), os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() error {
+		err := unlockFile(f)
+		return errors.Join(err, f.Close())
+	}, nil
+}
+
+// FileStore is the [StateStore] that stores
+// states as JSON files named "<name>.json" in the Dir.
+// Files are replaced atomically by renaming temporary files.
+type FileStore struct {
+	// Dir is the directory to store state files.
+	// The directory must exist.
+	Dir string
+}
+
+func (s *FileStore) Load(_ context.Context, name string) (*JobState, error) {
+	b, err := os.ReadFile(s.path(name))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	state := &JobState{}
+	if err := json.Unmarshal(b, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (s *FileStore) Save(_ context.Context, state *JobState) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(s.Dir, url.PathEscape(state.Name)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // Removed only when failed to rename.
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path(state.Name))
+}
+
+func (s *FileStore) path(name string) string {
+	return filepath.Join(s.Dir, url.PathEscape(name)+".json")
+}
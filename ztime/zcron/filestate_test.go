@@ -0,0 +1,79 @@
+package zcron
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestFileLocker(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("file lock is not supported on windows")
+	}
+	t.Run("lock and unlock", func(t *testing.T) {
+		l := &FileLocker{Dir: t.TempDir()}
+		unlock, err := l.TryLock(context.Background(), "foo/bar", time.Minute)
+		ztesting.AssertEqualErr(t, "lock error", nil, err)
+		_, err = os.Stat(filepath.Join(l.Dir, "foo%2Fbar.lock"))
+		ztesting.AssertEqualErr(t, "lock file not found", nil, err)
+		_, err = l.TryLock(context.Background(), "foo/bar", time.Minute)
+		ztesting.AssertEqualErr(t, "lock error", ErrLocked, err)
+		ztesting.AssertEqualErr(t, "unlock error", nil, unlock())
+		unlock, err = l.TryLock(context.Background(), "foo/bar", time.Minute)
+		ztesting.AssertEqualErr(t, "lock error", nil, err)
+		ztesting.AssertEqualErr(t, "unlock error", nil, unlock())
+	})
+	t.Run("dir not exist", func(t *testing.T) {
+		l := &FileLocker{Dir: filepath.Join(t.TempDir(), "not-exist")}
+		_, err := l.TryLock(context.Background(), "foo", time.Minute)
+		ztesting.AssertEqualErr(t, "lock error", os.ErrNotExist, err)
+	})
+}
+
+func TestFileStore(t *testing.T) {
+	t.Parallel()
+	t.Run("save and load", func(t *testing.T) {
+		s := &FileStore{Dir: t.TempDir()}
+		state, err := s.Load(context.Background(), "foo/bar")
+		ztesting.AssertEqualErr(t, "load error", nil, err)
+		ztesting.AssertEqual(t, "state not nil", nil, state)
+
+		want := &JobState{
+			Name:      "foo/bar",
+			Scheduled: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
+			Started:   time.Date(2000, 1, 1, 0, 0, 1, 0, time.UTC),
+			Duration:  time.Second,
+			Attempts:  1,
+		}
+		ztesting.AssertEqualErr(t, "save error", nil, s.Save(context.Background(), want))
+		ztesting.AssertEqualErr(t, "save error", nil, s.Save(context.Background(), want)) // Overwrite.
+		state, err = s.Load(context.Background(), "foo/bar")
+		ztesting.AssertEqualErr(t, "load error", nil, err)
+		ztesting.AssertEqual(t, "state not match", want, state)
+		entries, _ := os.ReadDir(s.Dir)
+		ztesting.AssertEqual(t, "temporary files remain", 1, len(entries))
+	})
+	t.Run("invalid json", func(t *testing.T) {
+		s := &FileStore{Dir: t.TempDir()}
+		_ = os.WriteFile(filepath.Join(s.Dir, "foo.json"), []byte("invalid"), 0o644)
+		_, err := s.Load(context.Background(), "foo")
+		ztesting.AssertEqual(t, "error is nil", true, err != nil)
+	})
+	t.Run("dir not exist", func(t *testing.T) {
+		s := &FileStore{Dir: filepath.Join(t.TempDir(), "not-exist")}
+		err := s.Save(context.Background(), &JobState{Name: "foo"})
+		ztesting.AssertEqualErr(t, "save error", os.ErrNotExist, err)
+	})
+	t.Run("read error", func(t *testing.T) {
+		s := &FileStore{Dir: t.TempDir()}
+		_ = os.Mkdir(filepath.Join(s.Dir, "foo.json"), 0o755) // Directory cannot be read as a file.
+		_, err := s.Load(context.Background(), "foo")
+		ztesting.AssertEqual(t, "error is nil", true, err != nil)
+	})
+}
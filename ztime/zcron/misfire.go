@@ -0,0 +1,92 @@
+package zcron
+
+import (
+	"time"
+)
+
+// MisfirePolicy is the policy applied to the schedules
+// that were missed, or misfired, for example because the
+// process was paused or the host was sleeping.
+// A schedule is treated as misfired when it was not run
+// within the [Config.MisfireThreshold] from the scheduled time.
+type MisfirePolicy int
+
+const (
+	// MisfireFireOnce runs the job once for all misfired schedules.
+	// This is the default policy.
+	MisfireFireOnce MisfirePolicy = iota
+	// MisfireFireAll runs the job for each misfired schedules
+	// up to the [Config.MaxMisfires].
+	// Note that the runs are limited by the [Config.MaxConcurrency]
+	// and the [Config.Overlap]. Use [OverlapAllow] or enough
+	// MaxConcurrency to run all of them.
+	MisfireFireAll
+	// MisfireSkip does not run the job for misfired schedules.
+	MisfireSkip
+)
+
+// misfire calculates the number of runs
+// based on the misfire policy.
+type misfire struct {
+	policy    MisfirePolicy
+	max       int
+	threshold time.Duration
+}
+
+func newMisfire(c *Config) *misfire {
+	threshold := c.MisfireThreshold
+	if threshold <= 0 {
+		threshold = time.Minute
+	}
+	return &misfire{
+		policy:    c.MisfirePolicy,
+		max:       max(1, c.MaxMisfires),
+		threshold: threshold,
+	}
+}
+
+// fires returns the scheduled times to run for the schedules in (last, now].
+// Returned times are sorted in ascending order.
+// For [MisfireFireOnce], misfired schedules are merged into one.
+func (m *misfire) fires(ct *Crontab, last, now time.Time) []time.Time {
+	deadline := now.Add(-m.threshold)
+
+	var onTime time.Time // Schedule in (max(last,deadline), now].
+	if next := ct.NextAfter(maxTime(last, deadline)); !next.IsZero() && !next.After(now) {
+		onTime = next
+	}
+
+	limit := 1
+	if m.policy == MisfireFireAll {
+		limit = m.max
+	}
+	var missed []time.Time
+	for next := ct.NextAfter(last); len(missed) < limit; next = ct.NextAfter(next) {
+		if next.IsZero() || next.After(deadline) {
+			break
+		}
+		missed = append(missed, next)
+	}
+
+	switch {
+	case m.policy == MisfireSkip || len(missed) == 0:
+		if onTime.IsZero() {
+			return nil
+		}
+		return []time.Time{onTime}
+	case m.policy == MisfireFireOnce:
+		return []time.Time{maxTime(onTime, missed[0])}
+	default: // MisfireFireAll
+		if onTime.IsZero() {
+			return missed
+		}
+		return append(missed, onTime)
+	}
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
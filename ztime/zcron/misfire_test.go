@@ -0,0 +1,56 @@
+package zcron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestNewMisfire(t *testing.T) {
+	t.Parallel()
+	m := newMisfire(&Config{})
+	ztesting.AssertEqual(t, "policy not match", MisfireFireOnce, m.policy)
+	ztesting.AssertEqual(t, "max not match", 1, m.max)
+	ztesting.AssertEqual(t, "threshold not match", time.Minute, m.threshold)
+	m = newMisfire(&Config{MisfirePolicy: MisfireFireAll, MaxMisfires: 5, MisfireThreshold: time.Second})
+	ztesting.AssertEqual(t, "policy not match", MisfireFireAll, m.policy)
+	ztesting.AssertEqual(t, "max not match", 5, m.max)
+	ztesting.AssertEqual(t, "threshold not match", time.Second, m.threshold)
+}
+
+func TestMisfire_fires(t *testing.T) {
+	t.Parallel()
+
+	base := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		m      *misfire
+		last   time.Time
+		now    time.Time
+		runs   int
+		latest time.Time
+	}{
+		"no schedule":          {&misfire{MisfireFireOnce, 1, time.Minute}, base, base.Add(30 * time.Second), 0, time.Time{}},
+		"on time":              {&misfire{MisfireFireOnce, 1, time.Minute}, base, base.Add(time.Minute), 1, base.Add(time.Minute)},
+		"on time skip":         {&misfire{MisfireSkip, 1, time.Minute}, base, base.Add(time.Minute), 1, base.Add(time.Minute)},
+		"fire once":            {&misfire{MisfireFireOnce, 1, time.Minute}, base, base.Add(10*time.Minute + 30*time.Second), 1, base.Add(10 * time.Minute)},
+		"fire all":             {&misfire{MisfireFireAll, 3, time.Minute}, base, base.Add(10*time.Minute + 30*time.Second), 4, base.Add(10 * time.Minute)},
+		"fire all under max":   {&misfire{MisfireFireAll, 20, time.Minute}, base, base.Add(10*time.Minute + 30*time.Second), 10, base.Add(10 * time.Minute)},
+		"skip":                 {&misfire{MisfireSkip, 1, time.Minute}, base, base.Add(10*time.Minute + 30*time.Second), 1, base.Add(10 * time.Minute)},
+		"missed only once":     {&misfire{MisfireFireOnce, 1, 10 * time.Second}, base, base.Add(5*time.Minute + 30*time.Second), 1, base.Add(time.Minute)},
+		"missed only all":      {&misfire{MisfireFireAll, 10, 10 * time.Second}, base, base.Add(5*time.Minute + 30*time.Second), 5, base.Add(5 * time.Minute)},
+		"missed only skip":     {&misfire{MisfireSkip, 1, 10 * time.Second}, base, base.Add(5*time.Minute + 30*time.Second), 0, time.Time{}},
+		"last after now":       {&misfire{MisfireFireAll, 10, time.Minute}, base.Add(time.Hour), base, 0, time.Time{}},
+		"last equals schedule": {&misfire{MisfireFireAll, 10, time.Minute}, base.Add(time.Minute), base.Add(time.Minute), 0, time.Time{}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			ct, _ := Parse("TZ=UTC * * * * *")
+			fires := tc.m.fires(ct, tc.last, tc.now)
+			ztesting.AssertEqual(t, "runs not match", tc.runs, len(fires))
+			if tc.runs > 0 {
+				ztesting.AssertEqual(t, "latest not match", tc.latest, fires[len(fires)-1])
+			}
+		})
+	}
+}
@@ -0,0 +1,357 @@
+package zcron
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// ErrJobExists indicates a job with the same name already exists.
+	ErrJobExists = errors.New("ztime/zcron: job already exists")
+	// ErrJobNotFound indicates the job was not found.
+	ErrJobNotFound = errors.New("ztime/zcron: job not found")
+)
+
+// JobInfo is the information of a job registered to the [Scheduler].
+type JobInfo struct {
+	// Name is the name of the job.
+	Name string
+	// Crontab is the cron expression of the job.
+	Crontab string
+	// Paused is true when the job is paused
+	// or the job has no more schedule.
+	Paused bool
+	// Next is the next scheduled time of the job.
+	// It is zero when the job is paused.
+	Next time.Time
+	// Last is the latest scheduled time of the runs that were started.
+	// Runs declined by the [OverlapPolicy] or skipped by the
+	// [Config.Locker] or the [Config.StateStore] are not included.
+	// When the StateStore is set, it is initially the recorded last run.
+	// It is zero when the job has never run.
+	Last time.Time
+}
+
+// NewScheduler returns a new instance of [Scheduler].
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		jobs:      map[string]*schedJob{},
+		stop:      make(chan struct{}, 1),
+		wake:      make(chan struct{}, 1),
+		timeNow:   time.Now,
+		timeAfter: time.After,
+	}
+}
+
+// Scheduler schedules and runs multiple named jobs.
+// Unlike [Cron], which runs exactly one job, Scheduler
+// manages all jobs in a single timer loop.
+// Jobs can be added, removed, paused and resumed at runtime.
+// Each job is configured with [Config] and all the options
+// such as MaxConcurrency, MaxRetry and MisfirePolicy are applied per job.
+// Scheduler must be created with [NewScheduler].
+//
+// Example:
+//
+//	s := zcron.NewScheduler()
+//	_ = s.Add("cleanup", &zcron.Config{Crontab: "0 * * * *", JobFunc: cleanup})
+//	_ = s.Add("report", &zcron.Config{Crontab: "TZ=UTC 0 9 * * MON", JobFunc: report})
+//	go s.Start()
+//	defer s.Stop()
+type Scheduler struct {
+	// started represents if the scheduler is started or not.
+	started atomic.Bool
+	// stop stops the scheduler working.
+	stop chan struct{}
+	// wake wakes up the timer loop when jobs are changed.
+	wake chan struct{}
+
+	mu    sync.Mutex
+	jobs  map[string]*schedJob
+	queue jobQueue // queue is the queue of non-paused jobs ordered by next time.
+
+	timeNow   func() time.Time
+	timeAfter func(time.Duration) <-chan time.Time
+}
+
+// WithTimeAfterFunc replaces internal wait functions.
+func (s *Scheduler) WithTimeAfterFunc(timeAfter func(time.Duration) <-chan time.Time) {
+	s.timeAfter = timeAfter
+}
+
+// WithTimeFunc replaces internal clock.
+// It should be called before jobs are added.
+func (s *Scheduler) WithTimeFunc(timeNow func() time.Time) {
+	s.timeNow = timeNow
+}
+
+// Add adds a new job with the name.
+// The name is used as the key of the [Config.Locker]
+// and the [Config.StateStore] instead of the [Config.Name].
+// When the StateStore is set, the recorded last run is loaded
+// and the schedules missed since then are handled by the
+// [Config.MisfirePolicy].
+// It returns [ErrJobExists] if a job with the same name already exists.
+// Errors same as [NewCron] are returned for invalid configs.
+func (s *Scheduler) Add(name string, c *Config) error {
+	if c == nil {
+		return ErrNilConfig
+	}
+	if c.JobFunc == nil {
+		return ErrNilJob
+	}
+	if name == "" && (c.Locker != nil || c.StateStore != nil) {
+		return ErrEmptyName
+	}
+	ct, err := ParseWithOption(c.Crontab, &ParseOption{DayOr: c.DayOr})
+	if err != nil {
+		return err
+	}
+	var state *JobState
+	if c.StateStore != nil {
+		if state, err = c.StateStore.Load(context.Background(), name); err != nil {
+			return err
+		}
+	}
+	r := newRunner(c)
+	r.name = name
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[name]; ok {
+		return ErrJobExists
+	}
+	j := &schedJob{
+		name:    name,
+		crontab: strings.TrimSpace(c.Crontab),
+		cron:    ct,
+		runner:  r,
+		misfire: newMisfire(c),
+		index:   -1,
+	}
+	s.jobs[name] = j
+	from := s.timeNow()
+	if state != nil && !state.Scheduled.IsZero() && state.Scheduled.Before(from) {
+		r.setLast(state.Scheduled)
+		from = state.Scheduled // Catch up the missed schedules.
+	}
+	s.schedule(j, from)
+	return nil
+}
+
+// Remove removes the job with the name.
+// Running jobs are not stopped.
+// It returns [ErrJobNotFound] if the job was not found.
+func (s *Scheduler) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return ErrJobNotFound
+	}
+	delete(s.jobs, name)
+	s.unschedule(j)
+	return nil
+}
+
+// Pause pauses the job with the name.
+// Paused jobs are not run until resumed.
+// Running jobs are not stopped.
+// It returns [ErrJobNotFound] if the job was not found.
+func (s *Scheduler) Pause(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return ErrJobNotFound
+	}
+	s.unschedule(j)
+	return nil
+}
+
+// Resume resumes the paused job with the name.
+// The job is scheduled at the next time after now.
+// Resuming non-paused jobs does nothing.
+// It returns [ErrJobNotFound] if the job was not found.
+func (s *Scheduler) Resume(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[name]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if j.index < 0 {
+		s.schedule(j, s.timeNow())
+	}
+	return nil
+}
+
+// Jobs returns the information of all jobs sorted by name.
+func (s *Scheduler) Jobs() []JobInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	infos := make([]JobInfo, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		info := JobInfo{
+			Name:    j.name,
+			Crontab: j.crontab,
+			Paused:  j.index < 0,
+			Last:    j.runner.lastRun(),
+		}
+		if !info.Paused {
+			info.Next = j.next
+		}
+		infos = append(infos, info)
+	}
+	slices.SortFunc(infos, func(a, b JobInfo) int { return strings.Compare(a.Name, b.Name) })
+	return infos
+}
+
+// Start starts the scheduler.
+// The scheduler can only run 1 process at a time.
+// Calling Start multiple times does not run the multiple schedulers.
+// Like [Cron.Start], the internal timer calibrates the time
+// when the next schedule is after 10 minutes or more.
+// It blocks the process. Run in a new goroutine if blocking is
+// not necessary like below.
+//
+//	s := zcron.NewScheduler()
+//	go s.Start()
+func (s *Scheduler) Start() {
+	if s.started.Swap(true) {
+		return // already running.
+	}
+	defer s.started.Store(false)
+
+	for {
+		wait := s.runDue()
+		if wait >= 10*time.Minute {
+			wait = 95 * wait / 100 // Calibrate time after 95% of it.
+		}
+		select {
+		case <-s.timeAfter(wait):
+		case <-s.wake:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Stop stops new scheduling of jobs.
+// Stopping scheduler does not stop the already running jobs.
+func (s *Scheduler) Stop() {
+	if !s.started.Load() {
+		return
+	}
+	select {
+	case s.stop <- struct{}{}:
+	default: // Already requested.
+	}
+}
+
+// runDue runs the jobs that reached their scheduled time
+// and returns the duration until the next schedule.
+func (s *Scheduler) runDue() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.timeNow()
+	for len(s.queue) > 0 && !s.queue[0].next.After(now) {
+		j := s.queue[0]
+		for _, at := range j.misfire.fires(j.cron, j.checked, now) {
+			j.runner.Fire(at) // Fire must not block.
+		}
+		j.checked = now
+		j.next = j.cron.NextAfter(now.In(j.cron.Location()))
+		if j.next.IsZero() {
+			heap.Remove(&s.queue, 0) // No more schedule.
+			continue
+		}
+		heap.Fix(&s.queue, 0)
+	}
+	if len(s.queue) == 0 {
+		return time.Hour
+	}
+	return min(s.queue[0].next.Sub(now), time.Hour)
+}
+
+// schedule pushes the job to the queue with the next time after now.
+// It must be called with s.mu held.
+func (s *Scheduler) schedule(j *schedJob, now time.Time) {
+	j.checked = now
+	j.next = j.cron.NextAfter(now.In(j.cron.Location()))
+	if j.next.IsZero() {
+		return // No more schedule.
+	}
+	heap.Push(&s.queue, j)
+	s.notify()
+}
+
+// unschedule removes the job from the queue if exists.
+// It must be called with s.mu held.
+func (s *Scheduler) unschedule(j *schedJob) {
+	if j.index < 0 {
+		return
+	}
+	heap.Remove(&s.queue, j.index)
+	s.notify()
+}
+
+// notify wakes up the timer loop.
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// schedJob is a job registered to the [Scheduler].
+type schedJob struct {
+	name    string
+	crontab string
+	cron    *Crontab
+	runner  *runner
+	misfire *misfire
+	next    time.Time
+	checked time.Time // The time that schedules were checked last.
+	index   int       // Index in the queue. -1 when not in the queue.
+}
+
+// jobQueue is the priority queue of jobs ordered by the next time.
+// It implements [heap.Interface].
+type jobQueue []*schedJob
+
+func (q jobQueue) Len() int {
+	return len(q)
+}
+
+func (q jobQueue) Less(i, j int) bool {
+	return q[i].next.Before(q[j].next)
+}
+
+func (q jobQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *jobQueue) Push(x any) {
+	j := x.(*schedJob)
+	j.index = len(*q)
+	*q = append(*q, j)
+}
+
+func (q *jobQueue) Pop() any {
+	old := *q
+	n := len(old)
+	j := old[n-1]
+	old[n-1] = nil
+	j.index = -1
+	*q = old[:n-1]
+	return j
+}
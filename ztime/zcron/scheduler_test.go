@@ -0,0 +1,154 @@
+package zcron
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestScheduler_Add(t *testing.T) {
+	t.Parallel()
+	job := func(context.Context) error { return nil }
+	testCases := map[string]struct {
+		name string
+		c    *Config
+		err  error
+	}{
+		"nil config":  {name: "test", c: nil, err: ErrNilConfig},
+		"nil job":     {name: "test", c: &Config{Crontab: "* * * * *"}, err: ErrNilJob},
+		"parse error": {name: "test", c: &Config{Crontab: "INVALID", JobFunc: job}, err: &ParseError{What: "number of fields"}},
+		"exists":      {name: "exists", c: &Config{Crontab: "* * * * *", JobFunc: job}, err: ErrJobExists},
+		"success":     {name: "test", c: &Config{Crontab: "* * * * *", JobFunc: job}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			s := NewScheduler()
+			_ = s.Add("exists", &Config{Crontab: "* * * * *", JobFunc: job})
+			err := s.Add(tc.name, tc.c)
+			ztesting.AssertEqualErr(t, "error not match", tc.err, err)
+		})
+	}
+}
+
+func TestScheduler_Jobs(t *testing.T) {
+	t.Parallel()
+	job := func(context.Context) error { return nil }
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewScheduler()
+	s.WithTimeFunc(func() time.Time { return now })
+	_ = s.Add("b", &Config{Crontab: "TZ=UTC 0 * * * *", JobFunc: job})
+	_ = s.Add("a", &Config{Crontab: " TZ=UTC */10 * * * * ", JobFunc: job})
+	_ = s.Add("c", &Config{Crontab: "TZ=Asia/Tokyo 0 9 * * *", JobFunc: job})
+
+	jobs := s.Jobs()
+	ztesting.AssertEqual(t, "number of jobs not match", 3, len(jobs))
+	ztesting.AssertEqual(t, "job a not match", JobInfo{
+		Name: "a", Crontab: "TZ=UTC */10 * * * *", Next: now.Add(10 * time.Minute),
+	}, jobs[0])
+	ztesting.AssertEqual(t, "job b next not match", now.Add(time.Hour), jobs[1].Next)
+	ztesting.AssertEqual(t, "job c next not match", true, now.Add(24*time.Hour).Equal(jobs[2].Next))
+
+	ztesting.AssertEqualErr(t, "pause error", nil, s.Pause("a"))
+	ztesting.AssertEqualErr(t, "pause error", nil, s.Pause("a"))
+	ztesting.AssertEqualErr(t, "pause error", ErrJobNotFound, s.Pause("x"))
+	jobs = s.Jobs()
+	ztesting.AssertEqual(t, "job a must be paused", true, jobs[0].Paused)
+	ztesting.AssertEqual(t, "next must be zero", true, jobs[0].Next.IsZero())
+
+	now = now.Add(15 * time.Minute)
+	ztesting.AssertEqualErr(t, "resume error", nil, s.Resume("a"))
+	ztesting.AssertEqualErr(t, "resume error", nil, s.Resume("a"))
+	ztesting.AssertEqualErr(t, "resume error", ErrJobNotFound, s.Resume("x"))
+	jobs = s.Jobs()
+	ztesting.AssertEqual(t, "job a must be resumed", false, jobs[0].Paused)
+	ztesting.AssertEqual(t, "next not match", now.Add(5*time.Minute), jobs[0].Next)
+
+	ztesting.AssertEqualErr(t, "remove error", nil, s.Remove("b"))
+	ztesting.AssertEqualErr(t, "remove error", ErrJobNotFound, s.Remove("b"))
+	ztesting.AssertEqual(t, "number of jobs not match", 2, len(s.Jobs()))
+	ztesting.AssertEqual(t, "queue length not match", 2, len(s.queue))
+}
+
+func TestScheduler_Start(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	count := map[string]int{}
+	done := make(chan struct{})
+	job := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			count[name]++
+			if count["a"] == 3 {
+				close(done)
+			}
+			return nil
+		}
+	}
+
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	var nowMu sync.Mutex
+	s := NewScheduler()
+	s.WithTimeFunc(func() time.Time {
+		nowMu.Lock()
+		defer nowMu.Unlock()
+		return now
+	})
+	var waits []time.Duration
+	s.WithTimeAfterFunc(func(d time.Duration) <-chan time.Time {
+		nowMu.Lock()
+		defer nowMu.Unlock()
+		waits = append(waits, d)
+		now = now.Add(d) // Advance the clock.
+		return time.After(time.Millisecond)
+	})
+	_ = s.Add("a", &Config{Crontab: "TZ=UTC * * * * * *", JobFunc: job("a")})
+	_ = s.Add("b", &Config{Crontab: "TZ=UTC */2 * * * * *", JobFunc: job("b")})
+	_ = s.Add("c", &Config{Crontab: "TZ=UTC * * * * * *", JobFunc: job("c")})
+	_ = s.Pause("c")
+
+	go s.Start()
+	go s.Start() // Already running.
+	<-done
+	s.Stop()
+	s.Stop()
+
+	mu.Lock()
+	defer mu.Unlock()
+	ztesting.AssertEqual(t, "job b count is too small", true, count["b"] >= 1)
+	ztesting.AssertEqual(t, "paused job must not run", 0, count["c"])
+	ztesting.AssertEqual(t, "last must be set", false, s.Jobs()[0].Last.IsZero())
+}
+
+func TestScheduler_calibrate(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewScheduler()
+	s.WithTimeFunc(func() time.Time { return now })
+	waits := make(chan time.Duration, 10)
+	s.WithTimeAfterFunc(func(d time.Duration) <-chan time.Time {
+		waits <- d
+		return nil
+	})
+	go s.Start()
+	ztesting.AssertEqual(t, "wait without jobs not match", 95*time.Hour/100, <-waits)
+	_ = s.Add("a", &Config{Crontab: "TZ=UTC */5 * * * *", JobFunc: func(context.Context) error { return nil }})
+	ztesting.AssertEqual(t, "wait not match", 5*time.Minute, <-waits)
+	s.Stop()
+}
+
+func TestScheduler_noMoreSchedule(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2091, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := NewScheduler()
+	s.WithTimeFunc(func() time.Time { return now })
+	err := s.Add("a", &Config{Crontab: "TZ=UTC 0 0 0 1 1 * 2090", JobFunc: func(context.Context) error { return nil }})
+	ztesting.AssertEqualErr(t, "add error", nil, err)
+	jobs := s.Jobs()
+	ztesting.AssertEqual(t, "job must be paused", true, jobs[0].Paused)
+	ztesting.AssertEqual(t, "queue length not match", 0, len(s.queue))
+	ztesting.AssertEqual(t, "wait not match", time.Hour, s.runDue())
+}
@@ -0,0 +1,131 @@
+package zcron
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrLocked indicates the lock is held by another owner.
+	ErrLocked = errors.New("ztime/zcron: locked by another owner")
+	// ErrAlreadyRun indicates the scheduled run has already
+	// been run, for example by another replica.
+	ErrAlreadyRun = errors.New("ztime/zcron: already run")
+)
+
+var (
+	_ Locker     = &MemoryLocker{}
+	_ StateStore = &MemoryStore{}
+)
+
+// Locker is the lock, or lease, that allows only one owner
+// to run a job at a time.
+// Locker is used to run a scheduled job on only one of
+// replicas which share the same schedule.
+// Implementations must be safe for concurrent use.
+type Locker interface {
+	// TryLock tries to acquire the lock of the key without blocking.
+	// It returns [ErrLocked] when the lock is held by another owner.
+	// ttl is the lease duration of the lock. Implementations should
+	// release the lock after the ttl when the owner did not call unlock,
+	// for example because the process crashed.
+	// unlock must be non-nil when the returned err is nil.
+	TryLock(ctx context.Context, key string, ttl time.Duration) (unlock func() error, err error)
+}
+
+// JobState is the state of a job recorded in a [StateStore].
+type JobState struct {
+	// Name is the name of the job.
+	Name string `json:"name"`
+	// Scheduled is the scheduled time of the last run.
+	Scheduled time.Time `json:"scheduled"`
+	// Started is the time that the last run was started.
+	Started time.Time `json:"started"`
+	// Duration is the duration of the last run
+	// including all retries.
+	Duration time.Duration `json:"duration"`
+	// Attempts is the number of attempts of the last run.
+	Attempts int `json:"attempts"`
+	// Error is the error message of the last attempt.
+	// It is empty when the last run succeeded.
+	Error string `json:"error,omitempty"`
+}
+
+// StateStore stores [JobState]s.
+// Implementations must be safe for concurrent use.
+type StateStore interface {
+	// Load loads the state of the job with the name.
+	// It returns nil state and nil error when not found.
+	Load(ctx context.Context, name string) (*JobState, error)
+	// Save saves the state of the job.
+	// State with the same name is overwritten.
+	Save(ctx context.Context, state *JobState) error
+}
+
+// MemoryLocker is the in-memory [Locker].
+// It can be used for tests or for sharing locks
+// between schedulers in the same process.
+// The zero value is ready to use.
+type MemoryLocker struct {
+	mu    sync.Mutex
+	locks map[string]*memoryLock
+	id    uint64
+}
+
+type memoryLock struct {
+	id     uint64
+	expire time.Time
+}
+
+func (l *MemoryLocker) TryLock(_ context.Context, key string, ttl time.Duration) (func() error, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if lock, ok := l.locks[key]; ok && now.Before(lock.expire) {
+		return nil, ErrLocked
+	}
+	if l.locks == nil {
+		l.locks = map[string]*memoryLock{}
+	}
+	l.id++
+	lock := &memoryLock{id: l.id, expire: now.Add(ttl)}
+	l.locks[key] = lock
+	return func() error {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.locks[key] == lock { // Lock may be expired and acquired by another owner.
+			delete(l.locks, key)
+		}
+		return nil
+	}, nil
+}
+
+// MemoryStore is the in-memory [StateStore].
+// It can be used for tests.
+// The zero value is ready to use.
+type MemoryStore struct {
+	mu     sync.Mutex
+	states map[string]JobState
+}
+
+func (s *MemoryStore) Load(_ context.Context, name string) (*JobState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.states[name]
+	if !ok {
+		return nil, nil
+	}
+	return &state, nil
+}
+
+func (s *MemoryStore) Save(_ context.Context, state *JobState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.states == nil {
+		s.states = map[string]JobState{}
+	}
+	s.states[state.Name] = *state
+	return nil
+}
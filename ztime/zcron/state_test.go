@@ -0,0 +1,214 @@
+package zcron
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestMemoryLocker(t *testing.T) {
+	t.Parallel()
+	t.Run("lock and unlock", func(t *testing.T) {
+		l := &MemoryLocker{}
+		unlock, err := l.TryLock(context.Background(), "foo", time.Minute)
+		ztesting.AssertEqualErr(t, "lock error", nil, err)
+		_, err = l.TryLock(context.Background(), "foo", time.Minute)
+		ztesting.AssertEqualErr(t, "lock error", ErrLocked, err)
+		unlockBar, err := l.TryLock(context.Background(), "bar", time.Minute)
+		ztesting.AssertEqualErr(t, "lock error", nil, err)
+		ztesting.AssertEqualErr(t, "unlock error", nil, unlock())
+		ztesting.AssertEqualErr(t, "unlock error", nil, unlockBar())
+		unlock, err = l.TryLock(context.Background(), "foo", time.Minute)
+		ztesting.AssertEqualErr(t, "lock error", nil, err)
+		ztesting.AssertEqualErr(t, "unlock error", nil, unlock())
+	})
+	t.Run("expired", func(t *testing.T) {
+		l := &MemoryLocker{}
+		unlock1, err := l.TryLock(context.Background(), "foo", time.Millisecond)
+		ztesting.AssertEqualErr(t, "lock error", nil, err)
+		time.Sleep(5 * time.Millisecond)
+		unlock2, err := l.TryLock(context.Background(), "foo", time.Minute)
+		ztesting.AssertEqualErr(t, "lock error", nil, err)
+		ztesting.AssertEqualErr(t, "unlock error", nil, unlock1()) // Must not unlock the new owner's lock.
+		_, err = l.TryLock(context.Background(), "foo", time.Minute)
+		ztesting.AssertEqualErr(t, "lock error", ErrLocked, err)
+		ztesting.AssertEqualErr(t, "unlock error", nil, unlock2())
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+	s := &MemoryStore{}
+	state, err := s.Load(context.Background(), "foo")
+	ztesting.AssertEqualErr(t, "load error", nil, err)
+	ztesting.AssertEqual(t, "state not nil", nil, state)
+
+	want := &JobState{Name: "foo", Scheduled: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), Attempts: 2, Error: "EOF"}
+	ztesting.AssertEqualErr(t, "save error", nil, s.Save(context.Background(), want))
+	state, err = s.Load(context.Background(), "foo")
+	ztesting.AssertEqualErr(t, "load error", nil, err)
+	ztesting.AssertEqual(t, "state not match", want, state)
+	state.Attempts = 99 // Modifying loaded state does not affect the store.
+	state, _ = s.Load(context.Background(), "foo")
+	ztesting.AssertEqual(t, "state not match", 2, state.Attempts)
+}
+
+type errLocker struct{ err error }
+
+func (l *errLocker) TryLock(context.Context, string, time.Duration) (func() error, error) {
+	if l.err != nil {
+		return nil, l.err
+	}
+	return func() error { return io.ErrClosedPipe }, nil
+}
+
+type errStore struct{ err error }
+
+func (s *errStore) Load(context.Context, string) (*JobState, error) { return nil, s.err }
+func (s *errStore) Save(context.Context, *JobState) error           { return s.err }
+
+// ctxStore is a [MemoryStore] that returns
+// the context error if the context is done.
+type ctxStore struct{ MemoryStore }
+
+func (s *ctxStore) Load(ctx context.Context, name string) (*JobState, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.MemoryStore.Load(ctx, name)
+}
+
+func (s *ctxStore) Save(ctx context.Context, state *JobState) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.MemoryStore.Save(ctx, state)
+}
+
+func TestRunner_state(t *testing.T) {
+	t.Parallel()
+	at := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	run := func(c *Config) (count int, events []Event, errs []error) {
+		c.Name = "test"
+		c.JobFunc = func(context.Context) error {
+			count++
+			return io.EOF
+		}
+		c.EventHook = func(e Event, a ...any) {
+			switch e {
+			case OnJobSkipped, OnStateError:
+				events = append(events, e)
+				errs = append(errs, a[0].(error))
+			}
+		}
+		r := newRunner(c)
+		r.run(false, at)
+		return count, events, errs
+	}
+
+	t.Run("save state", func(t *testing.T) {
+		store := &MemoryStore{}
+		count, events, _ := run(&Config{StateStore: store, Locker: &MemoryLocker{}, MaxRetry: 1})
+		ztesting.AssertEqual(t, "call count mismatch", 2, count)
+		ztesting.AssertEqual(t, "events mismatch", 0, len(events))
+		state, _ := store.Load(context.Background(), "test")
+		ztesting.AssertEqual(t, "scheduled not match", at, state.Scheduled)
+		ztesting.AssertEqual(t, "attempts not match", 2, state.Attempts)
+		ztesting.AssertEqual(t, "error not match", "EOF", state.Error)
+	})
+	t.Run("already run", func(t *testing.T) {
+		store := &MemoryStore{}
+		_ = store.Save(context.Background(), &JobState{Name: "test", Scheduled: at})
+		count, events, errs := run(&Config{StateStore: store})
+		ztesting.AssertEqual(t, "call count mismatch", 0, count)
+		ztesting.AssertEqual(t, "events mismatch", []Event{OnJobSkipped}, events)
+		ztesting.AssertEqualErr(t, "error not match", ErrAlreadyRun, errs[0])
+	})
+	t.Run("locked", func(t *testing.T) {
+		locker := &MemoryLocker{}
+		_, _ = locker.TryLock(context.Background(), "test", time.Minute)
+		count, events, errs := run(&Config{Locker: locker})
+		ztesting.AssertEqual(t, "call count mismatch", 0, count)
+		ztesting.AssertEqual(t, "events mismatch", []Event{OnJobSkipped}, events)
+		ztesting.AssertEqualErr(t, "error not match", ErrLocked, errs[0])
+	})
+	t.Run("lock error", func(t *testing.T) {
+		count, events, errs := run(&Config{Locker: &errLocker{err: io.ErrUnexpectedEOF}})
+		ztesting.AssertEqual(t, "call count mismatch", 0, count)
+		ztesting.AssertEqual(t, "events mismatch", []Event{OnStateError}, events)
+		ztesting.AssertEqualErr(t, "error not match", io.ErrUnexpectedEOF, errs[0])
+	})
+	t.Run("unlock error", func(t *testing.T) {
+		count, events, errs := run(&Config{Locker: &errLocker{}})
+		ztesting.AssertEqual(t, "call count mismatch", 1, count)
+		ztesting.AssertEqual(t, "events mismatch", []Event{OnStateError}, events)
+		ztesting.AssertEqualErr(t, "error not match", io.ErrClosedPipe, errs[0])
+	})
+	t.Run("canceled context", func(t *testing.T) {
+		store := &ctxStore{}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		count, events, _ := run(&Config{StateStore: store, WithContext: func() context.Context { return ctx }})
+		ztesting.AssertEqual(t, "call count mismatch", 1, count)
+		ztesting.AssertEqual(t, "events mismatch", 0, len(events))
+		state, _ := store.Load(context.Background(), "test")
+		ztesting.AssertEqual(t, "scheduled not match", at, state.Scheduled)
+	})
+	t.Run("store error", func(t *testing.T) {
+		count, events, errs := run(&Config{StateStore: &errStore{err: io.ErrUnexpectedEOF}})
+		ztesting.AssertEqual(t, "call count mismatch", 1, count)
+		ztesting.AssertEqual(t, "events mismatch", []Event{OnStateError, OnStateError}, events)
+		ztesting.AssertEqualErr(t, "error not match", io.ErrUnexpectedEOF, errs[0])
+	})
+}
+
+func TestRunner_lastRun(t *testing.T) {
+	t.Parallel()
+	at := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := &MemoryStore{}
+	r := newRunner(&Config{Name: "test", StateStore: store, JobFunc: func(context.Context) error { return nil }})
+	ztesting.AssertEqual(t, "last not match", time.Time{}, r.lastRun())
+	r.run(false, at)
+	ztesting.AssertEqual(t, "last not match", at, r.lastRun())
+	r.run(false, at.Add(-time.Hour)) // Skipped by the store.
+	ztesting.AssertEqual(t, "last not match", at, r.lastRun())
+	r.setLast(at.Add(-time.Minute)) // Older time is ignored.
+	ztesting.AssertEqual(t, "last not match", at, r.lastRun())
+}
+
+func TestCron_stateStore(t *testing.T) {
+	t.Parallel()
+	t.Run("empty name", func(t *testing.T) {
+		_, err := NewCron(&Config{Crontab: "* * * * *", StateStore: &MemoryStore{}, JobFunc: func(context.Context) error { return nil }})
+		ztesting.AssertEqualErr(t, "error not match", ErrEmptyName, err)
+		err = NewScheduler().Add("", &Config{Crontab: "* * * * *", Locker: &MemoryLocker{}, JobFunc: func(context.Context) error { return nil }})
+		ztesting.AssertEqualErr(t, "error not match", ErrEmptyName, err)
+	})
+	t.Run("last run", func(t *testing.T) {
+		now := time.Date(2000, 1, 1, 1, 0, 0, 0, time.UTC)
+		store := &MemoryStore{}
+		cron, _ := NewCron(&Config{Name: "test", Crontab: "* * * * *", StateStore: store, JobFunc: func(context.Context) error { return nil }})
+		ztesting.AssertEqual(t, "last not match", now, cron.lastRun(now))
+		_ = store.Save(context.Background(), &JobState{Name: "test", Scheduled: now.Add(-time.Hour)})
+		ztesting.AssertEqual(t, "last not match", now.Add(-time.Hour), cron.lastRun(now))
+		_ = store.Save(context.Background(), &JobState{Name: "test", Scheduled: now.Add(time.Hour)})
+		ztesting.AssertEqual(t, "last not match", now, cron.lastRun(now))
+		cron.runner.store = &errStore{err: io.EOF}
+		ztesting.AssertEqual(t, "last not match", now, cron.lastRun(now))
+	})
+	t.Run("scheduler catch up", func(t *testing.T) {
+		now := time.Date(2000, 1, 1, 1, 0, 30, 0, time.UTC)
+		store := &MemoryStore{}
+		_ = store.Save(context.Background(), &JobState{Name: "test", Scheduled: now.Add(-time.Hour)})
+		s := NewScheduler()
+		s.WithTimeFunc(func() time.Time { return now })
+		err := s.Add("test", &Config{Crontab: "TZ=UTC * * * * *", StateStore: store, JobFunc: func(context.Context) error { return nil }})
+		ztesting.AssertEqualErr(t, "add error", nil, err)
+		ztesting.AssertEqual(t, "next not match", now.Add(-time.Hour+30*time.Second), s.Jobs()[0].Next)
+		err = s.Add("error", &Config{Crontab: "* * * * *", StateStore: &errStore{err: io.EOF}, JobFunc: func(context.Context) error { return nil }})
+		ztesting.AssertEqualErr(t, "add error", io.EOF, err)
+	})
+}
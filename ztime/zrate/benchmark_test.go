@@ -48,3 +48,11 @@ func BenchmarkLeakyBucketLimiter(b *testing.B) {
 		lim.WaitNow(context.Background())
 	}
 }
+
+func BenchmarkGCRALimiter(b *testing.B) {
+	lim := zrate.NewGCRALimiterWidth(math.MaxInt, math.MaxInt, time.Nanosecond)
+	b.ResetTimer()
+	for b.Loop() {
+		lim.AllowNow()
+	}
+}
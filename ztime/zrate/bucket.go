@@ -26,9 +26,7 @@ func NewFixedWindowLimiterWidth(limit int, width time.Duration) Limiter {
 	if width <= 0 {
 		return NoopLimiter(true)
 	}
-	return &BucketLimiter{
-		getToken: getTokenFunc(limit, limit, width, time.Now),
-	}
+	return newBucket(limit, limit, width, time.Now).limiter()
 }
 
 // NewTokenBucketLimiter returns a new limiter instance
@@ -49,9 +47,7 @@ func NewTokenBucketInterval(bucketSize, fillRate int, fillInterval time.Duration
 	if fillInterval <= 0 {
 		return NoopLimiter(true)
 	}
-	return &BucketLimiter{
-		getToken: getTokenFunc(bucketSize, max(0, fillRate), fillInterval, time.Now),
-	}
+	return newBucket(bucketSize, fillRate, fillInterval, time.Now).limiter()
 }
 
 // BucketLimiter limits the rate of something to be proceeded using bucket algorithm.
@@ -79,47 +75,76 @@ func NewTokenBucketInterval(bucketSize, fillRate int, fillInterval time.Duration
 //		w.WriteHeader(http.StatusOK)
 //	}
 type BucketLimiter struct {
-	getToken func() (ok bool, retryAfter time.Duration)
+	getToken func(n int) (ok bool, q Quota)
+	reserve  func(n int) *Reservation
 }
 
 func (lim *BucketLimiter) AllowNow() Token {
-	ok, _ := lim.getToken()
-	if ok {
+	return lim.AllowN(1)
+}
+
+func (lim *BucketLimiter) WaitNow(ctx context.Context) Token {
+	return lim.WaitN(ctx, 1)
+}
+
+func (lim *BucketLimiter) AllowN(n int) Token {
+	if n <= 0 {
 		return TokenOK
 	}
-	return TokenNG
+	ok, q := lim.getToken(n)
+	return &quotaToken{token: token{ok: ok}, quota: q}
 }
 
-func (lim *BucketLimiter) WaitNow(ctx context.Context) Token {
+func (lim *BucketLimiter) WaitN(ctx context.Context, n int) Token {
+	if n <= 0 {
+		return TokenOK
+	}
 	for {
-		ok, retryAfter := lim.getToken()
-		if ok {
-			return TokenOK
+		ok, q := lim.getToken(n)
+		if ok || q.RetryAfter == math.MaxInt64 {
+			return &quotaToken{token: token{ok: ok}, quota: q}
 		}
 		select {
-		case <-time.After(retryAfter):
+		case <-time.After(q.RetryAfter):
 		case <-ctx.Done():
 			return &token{err: ctx.Err()}
 		}
 	}
 }
 
+// Reserve reserves n tokens.
+// See the comments on [Reserver] for details.
+func (lim *BucketLimiter) Reserve(n int) *Reservation {
+	if n <= 0 {
+		return &Reservation{ok: true, readyAt: time.Now(), timeNow: time.Now}
+	}
+	return lim.reserve(n)
+}
+
 // getTokenFunc returns function to get tokens.
-func getTokenFunc(bucketSize, fillRate int, interval time.Duration, timeNow func() time.Time) func() (ok bool, retryAfter time.Duration) {
+func getTokenFunc(bucketSize, fillRate int, interval time.Duration, timeNow func() time.Time) func(n int) (ok bool, q Quota) {
 	if bucketSize <= 0 {
-		return func() (bool, time.Duration) { return false, math.MaxInt64 }
+		return func(int) (bool, Quota) { return false, Quota{RetryAfter: math.MaxInt64} }
 	}
 	if interval <= 0 {
-		return func() (bool, time.Duration) { return true, 0 }
+		return func(int) (bool, Quota) {
+			return true, Quota{Limit: bucketSize, Remaining: bucketSize, Reset: timeNow()}
+		}
 	}
-	return (&bucket{
+	return newBucket(bucketSize, fillRate, interval, timeNow).getToken
+}
+
+// newBucket returns a new bucket.
+// bucketSize and interval must be positive.
+func newBucket(bucketSize, fillRate int, interval time.Duration, timeNow func() time.Time) *bucket {
+	return &bucket{
 		tokens:       int64(bucketSize),
 		bucketSize:   float64(bucketSize),
 		fillRate:     float64(max(fillRate, 0)),
 		fillInterval: interval,
 		lastFilled:   timeNow(),
 		timeNow:      timeNow,
-	}).getToken
+	}
 }
 
 // bucket is the token bucket for limiters.
@@ -129,6 +154,7 @@ type bucket struct {
 	mu sync.Mutex
 	// tokens is the number of tokens
 	// available for consume.
+	// It can be negative when tokens are reserved in advance.
 	tokens int64
 	// bucketSize is the size of bucket.
 	// It is held as float64 for calculation.
@@ -139,14 +165,14 @@ type bucket struct {
 	// It is held as float64 for calculation.
 	fillRate float64
 	// fillInterval is the token re-fill interval.
-	// If bucket is empty, tokens are re-filled by following equation.
-	// tokens = max(bucketSize, fillRate*(now-lastFilled)/fillInterval )
+	// If tokens are not enough, they are re-filled by following equation.
+	// tokens = min(bucketSize, tokens + fillRate*(now-lastFilled)/fillInterval )
 	// fillInterval must not be zero.
 	fillInterval time.Duration
 	// lastFilled is the last time that tokens are
 	// re-filled to the bucket.
-	// If bucket is empty, tokens are re-filled by following equation.
-	// tokens = max(bucketSize, fillRate*(now-lastFilled)/fillInterval )
+	// If tokens are not enough, they are re-filled by following equation.
+	// tokens = min(bucketSize, tokens + fillRate*(now-lastFilled)/fillInterval )
 	lastFilled time.Time
 
 	// timeNow returns the current time.
@@ -154,24 +180,108 @@ type bucket struct {
 	timeNow func() time.Time
 }
 
-func (b *bucket) getToken() (ok bool, retryAfter time.Duration) {
+// limiter returns a new BucketLimiter that uses the bucket.
+func (b *bucket) limiter() *BucketLimiter {
+	return &BucketLimiter{
+		getToken: b.getToken,
+		reserve:  b.reserve,
+	}
+}
+
+func (b *bucket) getToken(n int) (ok bool, q Quota) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	if b.tokens > 0 {
-		b.tokens -= 1
-		return true, 0
+	if float64(n) > b.bucketSize {
+		return false, b.quota(math.MaxInt64)
 	}
+	now := b.timeNow()
+	b.refill(now, int64(n))
+	if b.tokens < int64(n) {
+		return false, b.quota(b.readyAt(int64(n)).Sub(now))
+	}
+	b.tokens -= int64(n)
+	return true, b.quota(0)
+}
 
+// reserve reserves n tokens.
+// The tokens can be borrowed from the future
+// which makes the tokens negative.
+func (b *bucket) reserve(n int) *Reservation {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if float64(n) > b.bucketSize {
+		return &Reservation{timeNow: b.timeNow}
+	}
 	now := b.timeNow()
+	b.refill(now, int64(n))
+	readyAt := now
+	if b.tokens < int64(n) {
+		if b.fillRate <= 0 {
+			return &Reservation{timeNow: b.timeNow} // Never re-filled.
+		}
+		readyAt = b.readyAt(int64(n))
+	}
+	b.tokens -= int64(n)
+	return &Reservation{
+		ok:         true,
+		readyAt:    readyAt,
+		cancelFunc: func() { b.cancel(int64(n), readyAt) },
+		timeNow:    b.timeNow,
+	}
+}
+
+// cancel returns n reserved tokens to the bucket
+// if the reservation is not available yet.
+func (b *bucket) cancel(n int64, readyAt time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.timeNow().Before(readyAt) {
+		return
+	}
+	b.tokens = min(b.tokens+n, int64(b.bucketSize))
+}
+
+// refill re-fills tokens to the bucket when
+// the tokens are not enough for consuming n tokens.
+// refill must be called with b.mu held.
+func (b *bucket) refill(now time.Time, n int64) {
+	if b.tokens >= n {
+		return
+	}
 	passed := now.Sub(b.lastFilled)
 	if passed < b.fillInterval {
-		return false, b.fillInterval - passed
+		return
 	}
+	x := float64(b.tokens) + b.fillRate*float64(passed)/float64(b.fillInterval)
+	b.tokens = int64(min(x, b.bucketSize)) // Limit up to the bucket size.
+	b.lastFilled = now
+}
 
-	x := b.fillRate * float64(passed) / float64(b.fillInterval)
-	x = min(x, b.bucketSize) // Limit up to the bucket size.
+// readyAt returns the time when n tokens will be available.
+// If the tokens are never re-filled, it returns the
+// next re-fill time.
+// readyAt must be called with b.mu held.
+func (b *bucket) readyAt(n int64) time.Time {
+	if b.fillRate <= 0 {
+		return b.lastFilled.Add(b.fillInterval)
+	}
+	k := max(1, math.Ceil(float64(n-b.tokens)/b.fillRate))
+	return b.lastFilled.Add(time.Duration(k) * b.fillInterval)
+}
 
-	b.tokens = int64(x) - 1 // Consume 1 for this request.
-	b.lastFilled = now
-	return true, 0
+// quota returns the current quota of the bucket.
+// The quota is fully restored when the bucket is filled
+// with the tokens accumulated since the lastFilled.
+// quota must be called with b.mu held.
+func (b *bucket) quota(retryAfter time.Duration) Quota {
+	q := Quota{
+		Limit:      int(b.bucketSize),
+		Remaining:  int(max(0, b.tokens)),
+		RetryAfter: retryAfter,
+	}
+	if b.fillRate > 0 {
+		full := math.Ceil((b.bucketSize - float64(b.tokens)) / b.fillRate)
+		q.Reset = b.lastFilled.Add(time.Duration(full) * b.fillInterval)
+	}
+	return q
 }
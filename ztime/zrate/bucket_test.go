@@ -146,95 +146,195 @@ func TestBucketLimiter(t *testing.T) {
 	})
 }
 
+func TestBucketLimiter_AllowN(t *testing.T) {
+	t.Parallel()
+	t.Run("n=0", func(t *testing.T) {
+		lim := newBucket(1, 1, time.Second, time.Now).limiter()
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.AllowN(0).OK())
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.WaitN(context.Background(), 0).OK())
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.AllowN(1).OK())
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.AllowN(-1).OK())
+	})
+	t.Run("consume cost", func(t *testing.T) {
+		tm := time.Now()
+		now := &tm
+		lim := newBucket(10, 5, time.Second, func() time.Time { return *now }).limiter()
+		q1, _ := QuotaOf(lim.AllowN(4))
+		ztesting.AssertEqual(t, "incorrect remaining", 6, q1.Remaining)
+		token := lim.AllowN(7)
+		ztesting.AssertEqual(t, "incorrect token status", false, token.OK())
+		q2, _ := QuotaOf(token)
+		ztesting.AssertEqual(t, "incorrect retry after", time.Second, q2.RetryAfter)
+		q3, _ := QuotaOf(lim.AllowN(6))
+		ztesting.AssertEqual(t, "incorrect remaining", 0, q3.Remaining)
+		*now = now.Add(time.Second)
+		q4, _ := QuotaOf(lim.AllowN(3)) // 5 tokens are re-filled.
+		ztesting.AssertEqual(t, "incorrect remaining", 2, q4.Remaining)
+	})
+	t.Run("cost exceeds bucket size", func(t *testing.T) {
+		lim := newBucket(10, 5, time.Second, time.Now).limiter()
+		token := lim.AllowN(11)
+		ztesting.AssertEqual(t, "incorrect token status", false, token.OK())
+		q, _ := QuotaOf(token)
+		ztesting.AssertEqual(t, "incorrect retry after", math.MaxInt64, q.RetryAfter)
+		token = lim.WaitN(context.Background(), 11) // Returns without waiting.
+		ztesting.AssertEqual(t, "incorrect token status", false, token.OK())
+	})
+	t.Run("WaitN", func(t *testing.T) {
+		lim := newBucket(10, 10, 100*time.Millisecond, time.Now).limiter()
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.WaitN(context.Background(), 8).OK())
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.WaitN(context.Background(), 8).OK())
+	})
+}
+
+func TestBucketLimiter_Reserve(t *testing.T) {
+	t.Parallel()
+	t.Run("n=0", func(t *testing.T) {
+		lim := newBucket(1, 1, time.Second, time.Now).limiter()
+		r := lim.Reserve(0)
+		ztesting.AssertEqual(t, "incorrect reservation status", true, r.OK())
+		ztesting.AssertEqual(t, "incorrect delay", 0, r.Delay())
+	})
+	t.Run("borrow and cancel", func(t *testing.T) {
+		now := time.Now()
+		lim := newBucket(10, 5, time.Second, func() time.Time { return now }).limiter()
+		r1 := lim.Reserve(10)
+		ztesting.AssertEqual(t, "incorrect reservation status", true, r1.OK())
+		ztesting.AssertEqual(t, "incorrect delay", 0, r1.Delay())
+		r2 := lim.Reserve(5)
+		ztesting.AssertEqual(t, "incorrect reservation status", true, r2.OK())
+		ztesting.AssertEqual(t, "incorrect delay", time.Second, r2.Delay())
+		q1, _ := QuotaOf(lim.AllowN(1))
+		ztesting.AssertEqual(t, "incorrect retry after", 2*time.Second, q1.RetryAfter)
+		r2.Cancel() // Borrowed tokens are returned.
+		q2, _ := QuotaOf(lim.AllowN(1))
+		ztesting.AssertEqual(t, "incorrect retry after", time.Second, q2.RetryAfter)
+		r1.Cancel() // Already available. Nothing happens.
+		q3, _ := QuotaOf(lim.AllowN(1))
+		ztesting.AssertEqual(t, "incorrect retry after", time.Second, q3.RetryAfter)
+	})
+	t.Run("cancel after ready", func(t *testing.T) {
+		tm := time.Now()
+		now := &tm
+		lim := newBucket(5, 5, time.Second, func() time.Time { return *now }).limiter()
+		_ = lim.Reserve(5)
+		r := lim.Reserve(5)
+		ztesting.AssertEqual(t, "incorrect delay", time.Second, r.Delay())
+		*now = now.Add(time.Second)
+		ztesting.AssertEqual(t, "incorrect delay", 0, r.Delay())
+		r.Cancel() // Tokens are not returned.
+		ztesting.AssertEqual(t, "incorrect token status", false, lim.AllowN(1).OK())
+	})
+	t.Run("never available", func(t *testing.T) {
+		lim := newBucket(5, 0, time.Second, time.Now).limiter()
+		ztesting.AssertEqual(t, "incorrect reservation status", false, lim.Reserve(6).OK())
+		ztesting.AssertEqual(t, "incorrect reservation status", true, lim.Reserve(5).OK())
+		ztesting.AssertEqual(t, "incorrect reservation status", false, lim.Reserve(1).OK())
+	})
+}
+
 func TestBucket(t *testing.T) {
 	t.Parallel()
+	t.Run("quota", func(t *testing.T) {
+		now := time.Now()
+		getToken := getTokenFunc(3, 2, time.Second, func() time.Time { return now })
+		_, q1 := getToken(1)
+		ztesting.AssertEqual(t, "incorrect quota", Quota{Limit: 3, Remaining: 2, Reset: now.Add(time.Second)}, q1)
+		_, q2 := getToken(1)
+		ztesting.AssertEqual(t, "incorrect quota", Quota{Limit: 3, Remaining: 1, Reset: now.Add(time.Second)}, q2)
+		_, q3 := getToken(1)
+		ztesting.AssertEqual(t, "incorrect quota", Quota{Limit: 3, Remaining: 0, Reset: now.Add(2 * time.Second)}, q3)
+		ok, q4 := getToken(1)
+		ztesting.AssertEqual(t, "token unexpectedly true", false, ok)
+		ztesting.AssertEqual(t, "incorrect quota", Quota{Limit: 3, Remaining: 0, Reset: now.Add(2 * time.Second), RetryAfter: time.Second}, q4)
+	})
 	t.Run("bucketSize=-1", func(t *testing.T) {
 		getToken := getTokenFunc(-1, 10, time.Second, time.Now)
-		ok, after := getToken()
+		ok, q := getToken(1)
 		ztesting.AssertEqual(t, "token unexpectedly true", false, ok)
-		ztesting.AssertEqual(t, "retry after time incorrect", math.MaxInt64, after)
+		ztesting.AssertEqual(t, "retry after time incorrect", math.MaxInt64, q.RetryAfter)
 	})
 	t.Run("bucketSize=0", func(t *testing.T) {
 		getToken := getTokenFunc(0, 10, time.Second, time.Now)
-		ok, after := getToken()
+		ok, q := getToken(1)
 		ztesting.AssertEqual(t, "token unexpectedly true", false, ok)
-		ztesting.AssertEqual(t, "retry after time incorrect", math.MaxInt64, after)
+		ztesting.AssertEqual(t, "retry after time incorrect", math.MaxInt64, q.RetryAfter)
 	})
 	t.Run("interval=-1", func(t *testing.T) {
 		getToken := getTokenFunc(1, 10, -1, time.Now)
-		ok, after := getToken()
+		ok, q := getToken(1)
 		ztesting.AssertEqual(t, "token unexpectedly false", true, ok)
-		ztesting.AssertEqual(t, "retry after time incorrect", 0, after)
+		ztesting.AssertEqual(t, "retry after time incorrect", 0, q.RetryAfter)
 	})
 	t.Run("interval=0", func(t *testing.T) {
 		getToken := getTokenFunc(1, 10, 0, time.Now)
-		ok, after := getToken()
+		ok, q := getToken(1)
 		ztesting.AssertEqual(t, "token unexpectedly false", true, ok)
-		ztesting.AssertEqual(t, "retry after time incorrect", 0, after)
+		ztesting.AssertEqual(t, "retry after time incorrect", 0, q.RetryAfter)
 	})
 	t.Run("fillRate=-1", func(t *testing.T) {
 		now := time.Now()
 		getToken := getTokenFunc(1, -1, time.Hour, func() time.Time { return now })
-		ok1, after1 := getToken()
+		ok1, q1 := getToken(1)
 		ztesting.AssertEqual(t, "token unexpectedly false", true, ok1)
-		ztesting.AssertEqual(t, "retry after time incorrect", 0, after1)
-		ok2, after2 := getToken()
+		ztesting.AssertEqual(t, "retry after time incorrect", 0, q1.RetryAfter)
+		ok2, q2 := getToken(1)
 		ztesting.AssertEqual(t, "token unexpectedly true", false, ok2)
-		ztesting.AssertEqual(t, "retry after time incorrect", time.Hour, after2)
+		ztesting.AssertEqual(t, "retry after time incorrect", time.Hour, q2.RetryAfter)
 	})
 	t.Run("fillRate=0", func(t *testing.T) {
 		now := time.Now()
 		getToken := getTokenFunc(1, 0, time.Hour, func() time.Time { return now })
-		ok1, after1 := getToken()
+		ok1, q1 := getToken(1)
 		ztesting.AssertEqual(t, "token unexpectedly false", true, ok1)
-		ztesting.AssertEqual(t, "retry after time incorrect", 0, after1)
-		ok2, after2 := getToken()
+		ztesting.AssertEqual(t, "retry after time incorrect", 0, q1.RetryAfter)
+		ok2, q2 := getToken(1)
 		ztesting.AssertEqual(t, "token unexpectedly true", false, ok2)
-		ztesting.AssertEqual(t, "retry after time incorrect", time.Hour, after2)
+		ztesting.AssertEqual(t, "retry after time incorrect", time.Hour, q2.RetryAfter)
 	})
 	t.Run("fillRate=1", func(t *testing.T) {
 		nowTime := time.Now()
 		now := &nowTime
 		getToken := getTokenFunc(1, 1, time.Second, func() time.Time { return *now })
-		ok1, after1 := getToken()
+		ok1, q1 := getToken(1)
 		ztesting.AssertEqual(t, "token unexpectedly false", true, ok1)
-		ztesting.AssertEqual(t, "retry after time incorrect", 0, after1)
+		ztesting.AssertEqual(t, "retry after time incorrect", 0, q1.RetryAfter)
 		*now = (*now).Add(time.Second) // Forward 1 sec.
-		ok2, after2 := getToken()
+		ok2, q2 := getToken(1)
 		ztesting.AssertEqual(t, "token unexpectedly false", true, ok2)
-		ztesting.AssertEqual(t, "retry after time incorrect", 0, after2)
+		ztesting.AssertEqual(t, "retry after time incorrect", 0, q2.RetryAfter)
 	})
 	t.Run("fillRate=1,try3times", func(t *testing.T) {
 		nowTime := time.Now()
 		now := &nowTime
 		getToken := getTokenFunc(1, 1, time.Second, func() time.Time { return *now })
-		ok1, after1 := getToken()
+		ok1, q1 := getToken(1)
 		ztesting.AssertEqual(t, "token unexpectedly false", true, ok1)
-		ztesting.AssertEqual(t, "retry after time incorrect", 0, after1)
+		ztesting.AssertEqual(t, "retry after time incorrect", 0, q1.RetryAfter)
 		*now = (*now).Add(time.Second) // Forward 1 sec.
-		ok2, after2 := getToken()
+		ok2, q2 := getToken(1)
 		ztesting.AssertEqual(t, "token unexpectedly false", true, ok2)
-		ztesting.AssertEqual(t, "retry after time incorrect", 0, after2)
+		ztesting.AssertEqual(t, "retry after time incorrect", 0, q2.RetryAfter)
 		*now = (*now).Add(100 * time.Millisecond) // Forward 0.1 sec.
-		ok3, after3 := getToken()
+		ok3, q3 := getToken(1)
 		ztesting.AssertEqual(t, "token unexpectedly true", false, ok3)
-		ztesting.AssertEqual(t, "retry after time incorrect", 900*time.Millisecond, after3)
+		ztesting.AssertEqual(t, "retry after time incorrect", 900*time.Millisecond, q3.RetryAfter)
 	})
 	t.Run("fillRate=2", func(t *testing.T) {
 		nowTime := time.Now()
 		now := &nowTime
 		getToken := getTokenFunc(2, 2, time.Second, func() time.Time { return *now })
-		_, _ = getToken() // Remove first token.
-		_, _ = getToken() // Remove second token. Now the bucket is empty.
-		ok1, after1 := getToken()
+		_, _ = getToken(1) // Remove first token.
+		_, _ = getToken(1) // Remove second token. Now the bucket is empty.
+		ok1, q1 := getToken(1)
 		ztesting.AssertEqual(t, "token unexpectedly true", false, ok1)
-		ztesting.AssertEqual(t, "retry after time incorrect", time.Second, after1)
+		ztesting.AssertEqual(t, "retry after time incorrect", time.Second, q1.RetryAfter)
 		*now = (*now).Add(time.Second) // Forward 1 sec.
-		ok2, after2 := getToken()
+		ok2, q2 := getToken(1)
 		ztesting.AssertEqual(t, "token unexpectedly false", true, ok2)
-		ztesting.AssertEqual(t, "retry after time incorrect", 0, after2)
-		ok3, after3 := getToken()
+		ztesting.AssertEqual(t, "retry after time incorrect", 0, q2.RetryAfter)
+		ok3, q3 := getToken(1)
 		ztesting.AssertEqual(t, "token unexpectedly false", true, ok3)
-		ztesting.AssertEqual(t, "retry after time incorrect", 0, after3)
+		ztesting.AssertEqual(t, "retry after time incorrect", 0, q3.RetryAfter)
 	})
 }
@@ -1,7 +1,9 @@
 package zrate
 
 import (
+	"container/list"
 	"context"
+	"sync"
 )
 
 // NewConcurrentLimiter returns a new instance of ConcurrentLimiter
@@ -13,11 +15,15 @@ func NewConcurrentLimiter(limit int) Limiter {
 		return NoopLimiter(false)
 	}
 	return &ConcurrentLimiter{
-		bucket: make(chan struct{}, limit),
+		limit: limit,
 	}
 }
 
 // ConcurrentLimiter limits the max concurrency.
+// ConcurrentLimiter works as a weighted semaphore
+// with [ConcurrentLimiter.AllowN] and [ConcurrentLimiter.WaitN].
+// Waiters are served in FIFO order so that waiters with
+// large weight are not starved by the ones with small weight.
 // When using limiter as API rate limiting, implementation would be like below.
 //
 // Example usage:
@@ -37,26 +43,103 @@ func NewConcurrentLimiter(limit int) Limiter {
 //		w.WriteHeader(http.StatusOK)
 //	}
 type ConcurrentLimiter struct {
-	// bucket holds tokens currently occupied.
-	// cap(bucket) == limit.
-	// len(bucket) == current number of concurrency.
-	bucket chan struct{}
+	mu sync.Mutex
+	// limit is the maximum number of concurrency.
+	limit int
+	// current is the current number of concurrency.
+	// current<=limit.
+	current int
+	// waiters is the FIFO list of *concurrentWaiter.
+	waiters list.List
+}
+
+// concurrentWaiter is a waiter of [ConcurrentLimiter].
+type concurrentWaiter struct {
+	// n is the weight of the waiter.
+	n int
+	// ready is closed when the waiter acquired the weight.
+	ready chan struct{}
 }
 
 func (lim *ConcurrentLimiter) AllowNow() Token {
-	select {
-	case lim.bucket <- struct{}{}:
-		return &token{ok: true, releaseFunc: func() { <-lim.bucket }}
-	default:
+	return lim.AllowN(1)
+}
+
+func (lim *ConcurrentLimiter) WaitNow(ctx context.Context) Token {
+	return lim.WaitN(ctx, 1)
+}
+
+func (lim *ConcurrentLimiter) AllowN(n int) Token {
+	if n <= 0 {
+		return TokenOK
+	}
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	if lim.limit-lim.current < n || lim.waiters.Len() > 0 {
 		return TokenNG
 	}
+	lim.current += n
+	return &token{ok: true, releaseFunc: func() { lim.release(n) }}
 }
 
-func (lim *ConcurrentLimiter) WaitNow(ctx context.Context) Token {
+func (lim *ConcurrentLimiter) WaitN(ctx context.Context, n int) Token {
+	if n <= 0 {
+		return TokenOK
+	}
+	lim.mu.Lock()
+	if n > lim.limit {
+		lim.mu.Unlock()
+		return TokenNG
+	}
+	if lim.limit-lim.current >= n && lim.waiters.Len() == 0 {
+		lim.current += n
+		lim.mu.Unlock()
+		return &token{ok: true, releaseFunc: func() { lim.release(n) }}
+	}
+	w := &concurrentWaiter{n: n, ready: make(chan struct{})}
+	elem := lim.waiters.PushBack(w)
+	lim.mu.Unlock()
+
 	select {
-	case lim.bucket <- struct{}{}:
-		return &token{ok: true, releaseFunc: func() { <-lim.bucket }}
+	case <-w.ready:
+		return &token{ok: true, releaseFunc: func() { lim.release(n) }}
 	case <-ctx.Done():
+		lim.mu.Lock()
+		select {
+		case <-w.ready: // Acquired after canceled. Return the weight.
+			lim.current -= n
+		default:
+			lim.waiters.Remove(elem)
+		}
+		lim.notifyWaiters() // Following waiters might be able to acquire.
+		lim.mu.Unlock()
 		return &token{err: ctx.Err()}
 	}
 }
+
+// release releases the weight n.
+func (lim *ConcurrentLimiter) release(n int) {
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	lim.current -= n
+	lim.notifyWaiters()
+}
+
+// notifyWaiters wakes up the waiters in FIFO order
+// as long as their weight can be acquired.
+// notifyWaiters must be called with lim.mu held.
+func (lim *ConcurrentLimiter) notifyWaiters() {
+	for {
+		front := lim.waiters.Front()
+		if front == nil {
+			return
+		}
+		w := front.Value.(*concurrentWaiter)
+		if lim.limit-lim.current < w.n {
+			return
+		}
+		lim.current += w.n
+		lim.waiters.Remove(front)
+		close(w.ready)
+	}
+}
@@ -103,3 +103,89 @@ func TestConcurrentLimiter_WaitNow(t *testing.T) {
 		ztesting.AssertEqual(t, "process not allowed", true, t3.OK())
 	})
 }
+
+func TestConcurrentLimiter_AllowN(t *testing.T) {
+	t.Parallel()
+	t.Run("n=0", func(t *testing.T) {
+		lim := NewConcurrentLimiter(1).(*ConcurrentLimiter)
+		ztesting.AssertEqual(t, "process not allowed", true, lim.AllowN(0).OK())
+		ztesting.AssertEqual(t, "process not allowed", true, lim.WaitN(context.Background(), 0).OK())
+		ztesting.AssertEqual(t, "process not allowed", true, lim.AllowN(1).OK())
+		ztesting.AssertEqual(t, "process not allowed", true, lim.AllowN(-1).OK())
+	})
+	t.Run("weighted", func(t *testing.T) {
+		lim := NewConcurrentLimiter(5).(*ConcurrentLimiter)
+		t1 := lim.AllowN(3)
+		ztesting.AssertEqual(t, "process not allowed", true, t1.OK())
+		t2 := lim.AllowN(3)
+		ztesting.AssertEqual(t, "process allowed", false, t2.OK())
+		t3 := lim.AllowN(2)
+		ztesting.AssertEqual(t, "process not allowed", true, t3.OK())
+		t1.Release()
+		t1.Release() // Released only once.
+		t4 := lim.AllowN(3)
+		ztesting.AssertEqual(t, "process not allowed", true, t4.OK())
+		t5 := lim.AllowN(1)
+		ztesting.AssertEqual(t, "process allowed", false, t5.OK())
+	})
+	t.Run("weight exceeds limit", func(t *testing.T) {
+		lim := NewConcurrentLimiter(5).(*ConcurrentLimiter)
+		t1 := lim.AllowN(6)
+		ztesting.AssertEqual(t, "process allowed", false, t1.OK())
+		t2 := lim.WaitN(context.Background(), 6) // Returns without waiting.
+		ztesting.AssertEqual(t, "process allowed", false, t2.OK())
+	})
+}
+
+func TestConcurrentLimiter_WaitN(t *testing.T) {
+	t.Parallel()
+	t.Run("FIFO", func(t *testing.T) {
+		lim := NewConcurrentLimiter(2).(*ConcurrentLimiter)
+		t1 := lim.AllowN(2)
+		ztesting.AssertEqual(t, "process not allowed", true, t1.OK())
+		acquired := make(chan Token)
+		go func() { acquired <- lim.WaitN(context.Background(), 2) }()
+		for { // Wait until the goroutine starts waiting.
+			lim.mu.Lock()
+			n := lim.waiters.Len()
+			lim.mu.Unlock()
+			if n > 0 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t1.Release()
+		t2 := lim.AllowN(1) // The waiter precedes.
+		ztesting.AssertEqual(t, "process allowed", false, t2.OK())
+		t3 := <-acquired
+		ztesting.AssertEqual(t, "process not allowed", true, t3.OK())
+		t3.Release()
+		t4 := lim.AllowN(2)
+		ztesting.AssertEqual(t, "process not allowed", true, t4.OK())
+	})
+	t.Run("canceled waiter", func(t *testing.T) {
+		lim := NewConcurrentLimiter(2).(*ConcurrentLimiter)
+		t1 := lim.AllowN(1)
+		ztesting.AssertEqual(t, "process not allowed", true, t1.OK())
+		acquired := make(chan Token)
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() { acquired <- lim.WaitN(ctx, 2) }()
+		for { // Wait until the goroutine starts waiting.
+			lim.mu.Lock()
+			n := lim.waiters.Len()
+			lim.mu.Unlock()
+			if n > 0 {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t2 := lim.AllowN(1) // The waiter precedes.
+		ztesting.AssertEqual(t, "process allowed", false, t2.OK())
+		cancel()
+		t3 := <-acquired
+		ztesting.AssertEqual(t, "process allowed", false, t3.OK())
+		ztesting.AssertEqualErr(t, "wrong error reason", context.Canceled, t3.Err())
+		t4 := lim.WaitN(context.Background(), 1)
+		ztesting.AssertEqual(t, "process not allowed", true, t4.OK())
+	})
+}
@@ -2,6 +2,7 @@ package zrate_test
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/aileron-projects/go/ztime/zrate"
 )
@@ -62,3 +63,46 @@ func ExampleNewTokenBucketLimiter() {
 	// 3 false
 	// 4 false
 }
+
+func ExampleNewGCRALimiter() {
+	limiter := zrate.NewGCRALimiterWidth(3, 3, time.Hour)
+	for i := range 5 {
+		token := limiter.AllowNow()
+		quota, _ := zrate.QuotaOf(token)
+		fmt.Println(i, token.OK(), quota.Remaining)
+	}
+	// Output:
+	// 0 true 2
+	// 1 true 1
+	// 2 true 0
+	// 3 false 0
+	// 4 false 0
+}
+
+func ExampleWeightedLimiter() {
+	limiter := zrate.NewTokenBucketLimiter(10, 5).(zrate.WeightedLimiter)
+	for i, cost := range []int{4, 4, 4, 2} {
+		token := limiter.AllowN(cost)
+		fmt.Println(i, cost, token.OK())
+	}
+	// Output:
+	// 0 4 true
+	// 1 4 true
+	// 2 4 false
+	// 3 2 true
+}
+
+func ExampleReserver() {
+	limiter := zrate.NewTokenBucketLimiter(3, 1).(zrate.Reserver)
+	r1 := limiter.Reserve(3)
+	fmt.Println(r1.OK(), r1.Delay() == 0)
+	r2 := limiter.Reserve(1) // Borrow a token from the future.
+	fmt.Println(r2.OK(), r2.Delay() > 0)
+	r2.Cancel()              // Return the token if it will not be used.
+	r3 := limiter.Reserve(5) // Exceeds the bucket size.
+	fmt.Println(r3.OK())
+	// Output:
+	// true true
+	// true true
+	// false
+}
@@ -0,0 +1,191 @@
+package zrate
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// NewGCRALimiter returns a new instance of
+// a limiter that works with generic cell rate algorithm (GCRA).
+// NewGCRALimiter is short for NewGCRALimiterWidth(limit, limit, time.Second).
+func NewGCRALimiter(limit int) Limiter {
+	return NewGCRALimiterWidth(limit, limit, time.Second)
+}
+
+// NewGCRALimiterWidth returns a new instance of
+// a limiter that works with generic cell rate algorithm (GCRA).
+// The limit is the maximum count allowed within the width
+// and the burst is the maximum count allowed at once.
+// For limit<=0 or burst<=0, the limiter always returns token that indicates dis-allow.
+// For width<=0, the limiter always returns token that indicates allow.
+func NewGCRALimiterWidth(limit, burst int, width time.Duration) Limiter {
+	if limit <= 0 || burst <= 0 {
+		return NoopLimiter(false)
+	}
+	if width <= 0 {
+		return NoopLimiter(true)
+	}
+	interval := max(1, width/time.Duration(limit))
+	return &GCRALimiter{
+		interval:  interval,
+		tolerance: interval * time.Duration(burst),
+		burst:     burst,
+		timeNow:   time.Now,
+	}
+}
+
+// GCRALimiter limits the rate of something to process
+// using generic cell rate algorithm (GCRA).
+// GCRA works like the token bucket algorithm that fills a token
+// at every emission interval, width/limit, up to the burst.
+// GCRALimiter holds only a single timestamp, the theoretical
+// arrival time (TAT), and does not use locks.
+// So it is memory efficient to keep a limiter for each key
+// such as client IP addresses.
+// When using limiter as API rate limiting, implementation would be like below.
+//
+// Example usage:
+//
+//	type handler struct {
+//		limiter zrate.Limiter
+//	}
+//
+//	func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+//		token := h.limiter.AllowNow() // Basically, use AllowNow for GCRALimiter.
+//		defer token.Release()         // Release is not required for GCRA.
+//		if q, ok := zrate.QuotaOf(token); ok {
+//			w.Header().Set("RateLimit-Remaining", strconv.Itoa(q.Remaining))
+//		}
+//		if !token.OK() {
+//			w.WriteHeader(http.StatusTooManyRequests)
+//			return
+//		}
+//		// Some process.
+//		w.WriteHeader(http.StatusOK)
+//	}
+type GCRALimiter struct {
+	// tat is the theoretical arrival time in unix nano.
+	// tat is the time when all tokens are re-filled.
+	tat atomic.Int64
+	// interval is the emission interval.
+	// A token is filled at every interval.
+	// interval is equal to width/limit and must be positive.
+	interval time.Duration
+	// tolerance is the duration that the tat can
+	// precede the current time.
+	// tolerance is equal to interval*burst.
+	tolerance time.Duration
+	// burst is the maximum count allowed at once.
+	burst int
+
+	// timeNow returns the current time.
+	// This can be replaced for testing.
+	timeNow func() time.Time
+}
+
+func (lim *GCRALimiter) AllowNow() Token {
+	return lim.AllowN(1)
+}
+
+func (lim *GCRALimiter) WaitNow(ctx context.Context) Token {
+	return lim.WaitN(ctx, 1)
+}
+
+func (lim *GCRALimiter) AllowN(n int) Token {
+	if n <= 0 {
+		return TokenOK
+	}
+	ok, q := lim.getToken(n)
+	return &quotaToken{token: token{ok: ok}, quota: q}
+}
+
+func (lim *GCRALimiter) WaitN(ctx context.Context, n int) Token {
+	if n <= 0 {
+		return TokenOK
+	}
+	for {
+		ok, q := lim.getToken(n)
+		if ok || n > lim.burst {
+			return &quotaToken{token: token{ok: ok}, quota: q}
+		}
+		select {
+		case <-time.After(q.RetryAfter):
+		case <-ctx.Done():
+			return &token{err: ctx.Err()}
+		}
+	}
+}
+
+// Reserve reserves n tokens.
+// See the comments on [Reserver] for details.
+func (lim *GCRALimiter) Reserve(n int) *Reservation {
+	if n <= 0 {
+		return &Reservation{ok: true, readyAt: lim.timeNow(), timeNow: lim.timeNow}
+	}
+	if n > lim.burst {
+		return &Reservation{timeNow: lim.timeNow}
+	}
+	cost := int64(n) * int64(lim.interval)
+	for {
+		now := lim.timeNow().UnixNano()
+		tat := lim.tat.Load()
+		newTat := max(tat, now) + cost
+		if lim.tat.CompareAndSwap(tat, newTat) {
+			readyAt := time.Unix(0, max(now, newTat-int64(lim.tolerance)))
+			return &Reservation{
+				ok:         true,
+				readyAt:    readyAt,
+				cancelFunc: func() { lim.cancel(cost, readyAt) },
+				timeNow:    lim.timeNow,
+			}
+		}
+	}
+}
+
+// cancel returns the reserved cost
+// if the reservation is not available yet.
+func (lim *GCRALimiter) cancel(cost int64, readyAt time.Time) {
+	for {
+		now := lim.timeNow()
+		if !now.Before(readyAt) {
+			return
+		}
+		tat := lim.tat.Load()
+		if lim.tat.CompareAndSwap(tat, max(tat-cost, now.UnixNano())) {
+			return
+		}
+	}
+}
+
+// getToken tries to obtain n tokens.
+func (lim *GCRALimiter) getToken(n int) (ok bool, q Quota) {
+	cost := int64(n) * int64(lim.interval)
+	for {
+		now := lim.timeNow().UnixNano()
+		tat := lim.tat.Load()
+		if n > lim.burst {
+			return false, lim.quota(now, max(tat, now), math.MaxInt64)
+		}
+		newTat := max(tat, now) + cost
+		allowAt := newTat - int64(lim.tolerance)
+		if now < allowAt {
+			return false, lim.quota(now, max(tat, now), time.Duration(allowAt-now))
+		}
+		if lim.tat.CompareAndSwap(tat, newTat) {
+			return true, lim.quota(now, newTat, 0)
+		}
+	}
+}
+
+// quota returns the quota for the given tat.
+func (lim *GCRALimiter) quota(now, tat int64, retryAfter time.Duration) Quota {
+	remaining := (now + int64(lim.tolerance) - tat) / int64(lim.interval)
+	return Quota{
+		Limit:      lim.burst,
+		Remaining:  int(max(0, remaining)),
+		Reset:      time.Unix(0, tat),
+		RetryAfter: retryAfter,
+	}
+}
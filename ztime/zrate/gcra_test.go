@@ -0,0 +1,161 @@
+package zrate
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestNewGCRALimiterWidth(t *testing.T) {
+	t.Parallel()
+	t.Run("limit=-1", func(t *testing.T) {
+		lim := NewGCRALimiter(-1)
+		for range 5 { // Token always should be false.
+			token := lim.AllowNow()
+			ztesting.AssertEqual(t, "incorrect token status", false, token.OK())
+		}
+	})
+	t.Run("limit=0", func(t *testing.T) {
+		lim := NewGCRALimiter(0)
+		for range 5 { // Token always should be false.
+			token := lim.AllowNow()
+			ztesting.AssertEqual(t, "incorrect token status", false, token.OK())
+		}
+	})
+	t.Run("limit=1", func(t *testing.T) {
+		lim := NewGCRALimiter(1)
+		token1, token2 := lim.AllowNow(), lim.AllowNow()
+		ztesting.AssertEqual(t, "incorrect token status", true, token1.OK())
+		ztesting.AssertEqual(t, "incorrect token status", false, token2.OK())
+	})
+	t.Run("burst=0", func(t *testing.T) {
+		lim := NewGCRALimiterWidth(1, 0, time.Second)
+		for range 5 { // Token always should be false.
+			token := lim.AllowNow()
+			ztesting.AssertEqual(t, "incorrect token status", false, token.OK())
+		}
+	})
+	t.Run("width=0", func(t *testing.T) {
+		lim := NewGCRALimiterWidth(1, 1, 0)
+		for range 5 { // Token always should be true.
+			token := lim.AllowNow()
+			ztesting.AssertEqual(t, "incorrect token status", true, token.OK())
+		}
+	})
+}
+
+func TestGCRALimiter(t *testing.T) {
+	t.Parallel()
+	t.Run("burst", func(t *testing.T) {
+		now := time.Now().Round(0) // Strip monotonic clock reading to compare with the quota.
+		lim := NewGCRALimiterWidth(2, 2, time.Second).(*GCRALimiter)
+		lim.timeNow = func() time.Time { return now }
+		q1, _ := QuotaOf(lim.AllowNow())
+		ztesting.AssertEqual(t, "incorrect quota", Quota{Limit: 2, Remaining: 1, Reset: now.Add(500 * time.Millisecond)}, q1)
+		q2, _ := QuotaOf(lim.AllowNow())
+		ztesting.AssertEqual(t, "incorrect quota", Quota{Limit: 2, Remaining: 0, Reset: now.Add(time.Second)}, q2)
+		token := lim.AllowNow()
+		ztesting.AssertEqual(t, "incorrect token status", false, token.OK())
+		q3, _ := QuotaOf(token)
+		ztesting.AssertEqual(t, "incorrect quota", Quota{Limit: 2, Remaining: 0, Reset: now.Add(time.Second), RetryAfter: 500 * time.Millisecond}, q3)
+	})
+	t.Run("refilled", func(t *testing.T) {
+		tm := time.Now()
+		now := &tm
+		lim := NewGCRALimiterWidth(2, 1, time.Second).(*GCRALimiter)
+		lim.timeNow = func() time.Time { return *now }
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.AllowNow().OK())
+		ztesting.AssertEqual(t, "incorrect token status", false, lim.AllowNow().OK())
+		*now = now.Add(499 * time.Millisecond)
+		ztesting.AssertEqual(t, "incorrect token status", false, lim.AllowNow().OK())
+		*now = now.Add(time.Millisecond)
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.AllowNow().OK())
+		ztesting.AssertEqual(t, "incorrect token status", false, lim.AllowNow().OK())
+	})
+	t.Run("WaitNow returns OK token", func(t *testing.T) {
+		lim := NewGCRALimiterWidth(10, 1, time.Second)
+		for range 3 { // Token always should be true.
+			token := lim.WaitNow(context.Background())
+			ztesting.AssertEqual(t, "incorrect token status", true, token.OK())
+		}
+	})
+	t.Run("context canceled", func(t *testing.T) {
+		lim := NewGCRALimiterWidth(1, 1, time.Hour)
+		token := lim.AllowNow() // Remove first token.
+		ztesting.AssertEqual(t, "incorrect token status", true, token.OK())
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		token = lim.WaitNow(ctx)
+		ztesting.AssertEqual(t, "incorrect token status", false, token.OK())
+		ztesting.AssertEqual(t, "incorrect error", context.DeadlineExceeded, token.Err())
+	})
+}
+
+func TestGCRALimiter_AllowN(t *testing.T) {
+	t.Parallel()
+	t.Run("n=0", func(t *testing.T) {
+		lim := NewGCRALimiterWidth(1, 1, time.Second).(*GCRALimiter)
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.AllowN(0).OK())
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.WaitN(context.Background(), 0).OK())
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.AllowN(1).OK())
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.AllowN(-1).OK())
+	})
+	t.Run("consume cost", func(t *testing.T) {
+		now := time.Now()
+		lim := NewGCRALimiterWidth(10, 10, time.Second).(*GCRALimiter)
+		lim.timeNow = func() time.Time { return now }
+		q1, _ := QuotaOf(lim.AllowN(4))
+		ztesting.AssertEqual(t, "incorrect remaining", 6, q1.Remaining)
+		token := lim.AllowN(7)
+		ztesting.AssertEqual(t, "incorrect token status", false, token.OK())
+		q2, _ := QuotaOf(token)
+		ztesting.AssertEqual(t, "incorrect retry after", 100*time.Millisecond, q2.RetryAfter)
+		q3, _ := QuotaOf(lim.AllowN(6))
+		ztesting.AssertEqual(t, "incorrect remaining", 0, q3.Remaining)
+	})
+	t.Run("cost exceeds burst", func(t *testing.T) {
+		lim := NewGCRALimiterWidth(10, 10, time.Second).(*GCRALimiter)
+		token := lim.AllowN(11)
+		ztesting.AssertEqual(t, "incorrect token status", false, token.OK())
+		q, _ := QuotaOf(token)
+		ztesting.AssertEqual(t, "incorrect retry after", math.MaxInt64, q.RetryAfter)
+		token = lim.WaitN(context.Background(), 11) // Returns without waiting.
+		ztesting.AssertEqual(t, "incorrect token status", false, token.OK())
+	})
+}
+
+func TestGCRALimiter_Reserve(t *testing.T) {
+	t.Parallel()
+	t.Run("n=0", func(t *testing.T) {
+		lim := NewGCRALimiterWidth(1, 1, time.Second).(*GCRALimiter)
+		r := lim.Reserve(0)
+		ztesting.AssertEqual(t, "incorrect reservation status", true, r.OK())
+		ztesting.AssertEqual(t, "incorrect delay", 0, r.Delay())
+	})
+	t.Run("borrow and cancel", func(t *testing.T) {
+		now := time.Now()
+		lim := NewGCRALimiterWidth(10, 10, time.Second).(*GCRALimiter)
+		lim.timeNow = func() time.Time { return now }
+		r1 := lim.Reserve(10)
+		ztesting.AssertEqual(t, "incorrect reservation status", true, r1.OK())
+		ztesting.AssertEqual(t, "incorrect delay", 0, r1.Delay())
+		r2 := lim.Reserve(5)
+		ztesting.AssertEqual(t, "incorrect reservation status", true, r2.OK())
+		ztesting.AssertEqual(t, "incorrect delay", 500*time.Millisecond, r2.Delay())
+		q1, _ := QuotaOf(lim.AllowN(1))
+		ztesting.AssertEqual(t, "incorrect retry after", 600*time.Millisecond, q1.RetryAfter)
+		r2.Cancel() // Borrowed tokens are returned.
+		q2, _ := QuotaOf(lim.AllowN(1))
+		ztesting.AssertEqual(t, "incorrect retry after", 100*time.Millisecond, q2.RetryAfter)
+		r1.Cancel() // Already available. Nothing happens.
+		q3, _ := QuotaOf(lim.AllowN(1))
+		ztesting.AssertEqual(t, "incorrect retry after", 100*time.Millisecond, q3.RetryAfter)
+	})
+	t.Run("cost exceeds burst", func(t *testing.T) {
+		lim := NewGCRALimiterWidth(10, 10, time.Second).(*GCRALimiter)
+		ztesting.AssertEqual(t, "incorrect reservation status", false, lim.Reserve(11).OK())
+	})
+}
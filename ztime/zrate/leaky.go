@@ -23,8 +23,7 @@ func NewLeakyBucketLimiter(queueSize int, interval time.Duration) Limiter {
 	return &LeakyBucketLimiter{
 		interval: interval,
 		timeNow:  time.Now,
-		queue:    make(chan struct{}, queueSize),
-		notifier: make(chan struct{}, 1),
+		queue:    make(chan *leakyWaiter, queueSize),
 	}
 }
 
@@ -52,21 +51,19 @@ type LeakyBucketLimiter struct {
 	// mu protects lastLeak.
 	mu sync.Mutex
 	// lastLeak is the last dequeue time.
+	// For the cost n>1, lastLeak is moved forward
+	// by (n-1)*interval so that the cost occupies n intervals.
 	lastLeak time.Time
 	// interval is the leak, or dequeue interval.
 	// interval can be zero or positive.
 	interval time.Duration
 
 	// queue is the wait queue.
-	// The input struct{}{} is notified through notifier.
-	// Waiters wait until they accept a struct{}{}
-	// through notifier.
+	// Waiters wait until they are notified
+	// by the dequeue worker.
 	// cap(queue) == actual queue size.
 	// len(queue) == number of waiters.
-	queue chan struct{}
-	// queue is the channel for notifying the waiters.
-	// Its capacity is 1.
-	notifier chan struct{}
+	queue chan *leakyWaiter
 	// dequeueWorking is the flag if the dequeue worker
 	// is working in a goroutine or not.
 	dequeueWorking atomic.Bool
@@ -76,36 +73,93 @@ type LeakyBucketLimiter struct {
 	timeNow func() time.Time
 }
 
+const (
+	waiterWaiting int32 = iota
+	waiterGranted
+	waiterCanceled
+)
+
+// leakyWaiter is a waiter in the queue of [LeakyBucketLimiter].
+type leakyWaiter struct {
+	// cost is the cost of the waiter.
+	cost int
+	// state is the state of the waiter.
+	// The state is changed from waiterWaiting to
+	// waiterGranted by the worker or to waiterCanceled
+	// by the waiter only once.
+	state atomic.Int32
+	// ready is closed when the waiter is granted.
+	ready chan struct{}
+}
+
 func (lim *LeakyBucketLimiter) AllowNow() Token {
-	if len(lim.queue) > 0 {
-		return TokenNG // Someone already waiting.
+	return lim.AllowN(1)
+}
+
+func (lim *LeakyBucketLimiter) WaitNow(ctx context.Context) Token {
+	return lim.WaitN(ctx, 1)
+}
+
+func (lim *LeakyBucketLimiter) AllowN(n int) Token {
+	if n <= 0 {
+		return TokenOK
 	}
 	lim.mu.Lock()
 	defer lim.mu.Unlock()
 	now := lim.timeNow()
-	if now.Sub(lim.lastLeak) >= lim.interval {
-		lim.lastLeak = now
-		return TokenOK
+	waiting := len(lim.queue)
+	if waiting > 0 { // Someone already waiting.
+		retryAfter := lim.lastLeak.Add(time.Duration(waiting+1) * lim.interval).Sub(now)
+		return &quotaToken{quota: lim.quota(now, max(retryAfter, lim.interval))}
+	}
+	if wait := lim.interval - now.Sub(lim.lastLeak); wait > 0 {
+		return &quotaToken{quota: lim.quota(now, wait)}
 	}
-	return TokenNG
+	lim.lastLeak = now.Add(time.Duration(n-1) * lim.interval)
+	return &quotaToken{token: token{ok: true}, quota: lim.quota(now, 0)}
 }
 
-func (lim *LeakyBucketLimiter) WaitNow(ctx context.Context) Token {
+func (lim *LeakyBucketLimiter) WaitN(ctx context.Context, n int) Token {
+	if n <= 0 {
+		return TokenOK
+	}
+	w := &leakyWaiter{cost: n, ready: make(chan struct{})}
 	select {
-	case lim.queue <- struct{}{}:
+	case lim.queue <- w:
 	default:
-		return TokenNG
+		lim.mu.Lock()
+		defer lim.mu.Unlock()
+		now := lim.timeNow()
+		return &quotaToken{quota: lim.quota(now, max(0, lim.interval-now.Sub(lim.lastLeak)))}
 	}
 	lim.notifyWorker()
 	select {
-	case <-lim.notifier:
-		return TokenOK
+	case <-w.ready:
 	case <-ctx.Done():
-		select {
-		case <-lim.queue: // Discard, or cancel this request.
-		default: // It might already been dequeued by the worker.
+		if w.state.CompareAndSwap(waiterWaiting, waiterCanceled) {
+			return &token{err: ctx.Err()} // The worker skips this waiter.
 		}
-		return &token{err: ctx.Err()}
+		<-w.ready // Already granted by the worker.
+	}
+	lim.mu.Lock()
+	defer lim.mu.Unlock()
+	return &quotaToken{token: token{ok: true}, quota: lim.quota(lim.timeNow(), 0)}
+}
+
+// quota returns the current quota of the limiter.
+// The quota is the free space of the waiting queue.
+// quota must be called with lim.mu held.
+func (lim *LeakyBucketLimiter) quota(now time.Time, retryAfter time.Duration) Quota {
+	waiting := len(lim.queue)
+	reset := lim.lastLeak.Add(time.Duration(waiting) * lim.interval)
+	if reset.Before(now) {
+		reset = now
+	}
+	return Quota{
+		Limit:      cap(lim.queue),
+		Remaining:  cap(lim.queue) - waiting,
+		Reset:      reset,
+		RetryAfter: retryAfter,
 	}
 }
 
@@ -118,25 +172,29 @@ func (lim *LeakyBucketLimiter) notifyWorker() {
 		timer := time.NewTimer(time.Second)
 		defer timer.Stop()
 		for {
-			var s struct{}
+			var w *leakyWaiter
 			select {
-			case s = <-lim.queue:
+			case w = <-lim.queue:
 			case <-time.After(10 * time.Second): // Keep at least 10 seconds. No reason to the value.
 				return
 			}
+			if w.state.Load() == waiterCanceled {
+				continue
+			}
+			lim.mu.Lock()
 			wait := lim.interval - lim.timeNow().Sub(lim.lastLeak)
+			lim.mu.Unlock()
 			if wait > 0 {
 				timer.Reset(wait)
 				<-timer.C
 			}
-			select {
-			case lim.notifier <- s: // Notify to the next waiter.
-				lim.mu.Lock()
-				lim.lastLeak = lim.timeNow()
-				lim.mu.Unlock()
-			default: // Waiter might canceled.
-				// This case, do not update lastLeak time.
+			if !w.state.CompareAndSwap(waiterWaiting, waiterGranted) {
+				continue // Waiter canceled. Do not update lastLeak time.
 			}
+			lim.mu.Lock()
+			lim.lastLeak = lim.timeNow().Add(time.Duration(w.cost-1) * lim.interval)
+			lim.mu.Unlock()
+			close(w.ready) // Notify to the waiter.
 		}
 	}()
 }
@@ -60,6 +60,17 @@ func TestLeakyBucketLimiter_AllowNow(t *testing.T) {
 		t2 := lim.AllowNow()
 		ztesting.AssertEqual(t, "process allowed", false, t2.OK())
 	})
+	t.Run("quota", func(t *testing.T) {
+		lim := NewLeakyBucketLimiter(2, time.Second).(*LeakyBucketLimiter)
+		now := time.Now()
+		lim.timeNow = func() time.Time { return now }
+		q1, _ := QuotaOf(lim.AllowNow())
+		ztesting.AssertEqual(t, "incorrect quota", Quota{Limit: 2, Remaining: 2, Reset: now}, q1)
+		t2 := lim.AllowNow()
+		ztesting.AssertEqual(t, "process allowed", false, t2.OK())
+		q2, _ := QuotaOf(t2)
+		ztesting.AssertEqual(t, "incorrect quota", Quota{Limit: 2, Remaining: 2, Reset: now, RetryAfter: time.Second}, q2)
+	})
 	t.Run("interval passed", func(t *testing.T) {
 		lim := NewLeakyBucketLimiter(2, time.Second).(*LeakyBucketLimiter)
 		tm := time.Now()
@@ -171,3 +182,54 @@ func TestLeakyBucketLimiter_WaitNow(t *testing.T) {
 		ztesting.AssertEqualErr(t, "wrong error reason", context.DeadlineExceeded, t1.Err())
 	})
 }
+
+func TestLeakyBucketLimiter_AllowN(t *testing.T) {
+	t.Parallel()
+	t.Run("n=0", func(t *testing.T) {
+		lim := NewLeakyBucketLimiter(1, time.Second).(*LeakyBucketLimiter)
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.AllowN(0).OK())
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.WaitN(context.Background(), 0).OK())
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.AllowN(1).OK())
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.AllowN(-1).OK())
+	})
+	t.Run("consume cost", func(t *testing.T) {
+		lim := NewLeakyBucketLimiter(2, time.Second).(*LeakyBucketLimiter)
+		tm := time.Now()
+		now := &tm
+		lim.timeNow = func() time.Time { return *now }
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.AllowN(3).OK())
+		*now = now.Add(time.Second)
+		token := lim.AllowN(1)
+		ztesting.AssertEqual(t, "incorrect token status", false, token.OK())
+		q, _ := QuotaOf(token)
+		ztesting.AssertEqual(t, "incorrect retry after", 2*time.Second, q.RetryAfter)
+		*now = now.Add(2 * time.Second)
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.AllowN(1).OK())
+	})
+}
+
+func TestLeakyBucketLimiter_WaitN(t *testing.T) {
+	t.Parallel()
+	t.Run("consume cost", func(t *testing.T) {
+		lim := NewLeakyBucketLimiter(2, 50*time.Millisecond)
+		start := time.Now()
+		t1 := lim.(*LeakyBucketLimiter).WaitN(context.Background(), 3)
+		ztesting.AssertEqual(t, "process not allowed", true, t1.OK())
+		t2 := lim.WaitNow(context.Background())
+		ztesting.AssertEqual(t, "process not allowed", true, t2.OK())
+		ztesting.AssertEqual(t, "cost not consumed", true, time.Since(start) >= 150*time.Millisecond)
+	})
+	t.Run("canceled waiter skipped", func(t *testing.T) {
+		lim := NewLeakyBucketLimiter(2, 100*time.Millisecond).(*LeakyBucketLimiter)
+		t1 := lim.WaitN(context.Background(), 1)
+		ztesting.AssertEqual(t, "process not allowed", true, t1.OK())
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		t2 := lim.WaitN(ctx, 10)
+		ztesting.AssertEqual(t, "incorrect error", context.Canceled, t2.Err())
+		start := time.Now()
+		t3 := lim.WaitN(context.Background(), 1)
+		ztesting.AssertEqual(t, "process not allowed", true, t3.OK())
+		ztesting.AssertEqual(t, "canceled cost consumed", true, time.Since(start) < time.Second)
+	})
+}
@@ -2,15 +2,30 @@ package zrate
 
 import (
 	"context"
+	"math"
 	"sync"
+	"time"
 )
 
 var (
-	_ Token   = NoopToken(true)
-	_ Token   = &token{}
-	_ Limiter = &ConcurrentLimiter{}
-	_ Limiter = &BucketLimiter{}
-	_ Limiter = &LeakyBucketLimiter{}
+	_ Token      = NoopToken(true)
+	_ Token      = &token{}
+	_ QuotaToken = &quotaToken{}
+	_ Limiter    = &ConcurrentLimiter{}
+	_ Limiter    = &BucketLimiter{}
+	_ Limiter    = &LeakyBucketLimiter{}
+	_ Limiter    = &SlidingWindowLimiter{}
+	_ Limiter    = &GCRALimiter{}
+
+	_ WeightedLimiter = NoopLimiter(true)
+	_ WeightedLimiter = &ConcurrentLimiter{}
+	_ WeightedLimiter = &BucketLimiter{}
+	_ WeightedLimiter = &LeakyBucketLimiter{}
+	_ WeightedLimiter = &SlidingWindowLimiter{}
+	_ WeightedLimiter = &GCRALimiter{}
+	_ Reserver        = NoopLimiter(true)
+	_ Reserver        = &BucketLimiter{}
+	_ Reserver        = &GCRALimiter{}
 )
 
 const (
@@ -27,6 +42,80 @@ type Limiter interface {
 	WaitNow(context.Context) Token
 }
 
+// WeightedLimiter is the [Limiter] that can consume
+// multiple units, or the cost, at once.
+// AllowNow and WaitNow are equivalent to AllowN and WaitN with n=1.
+// All limiters in this package implement WeightedLimiter.
+// For n<=0, the limiters always return token that indicates allow
+// without consuming anything.
+type WeightedLimiter interface {
+	Limiter
+	// AllowN reports if the cost n can be consumed now.
+	AllowN(n int) Token
+	// WaitN waits until the cost n can be consumed
+	// or the context is done.
+	// It returns token that indicates dis-allow without waiting
+	// if the cost n exceeds the limit of the limiter.
+	WaitN(ctx context.Context, n int) Token
+}
+
+// Reserver is the limiter that can reserve tokens in advance.
+// [BucketLimiter] and [GCRALimiter] implement Reserver.
+type Reserver interface {
+	// Reserve reserves the cost n without blocking
+	// and returns when the reserved tokens will be available.
+	// Reserved tokens are consumed even if they are not available yet.
+	// Callers must wait until [Reservation.ReadyAt] before proceeding
+	// or call [Reservation.Cancel] if they will not use the reservation.
+	Reserve(n int) *Reservation
+}
+
+// Reservation is the tokens reserved by [Reserver].
+type Reservation struct {
+	once sync.Once
+	// ok is true when the tokens were reserved.
+	ok bool
+	// readyAt is the time when the reserved tokens are available.
+	readyAt time.Time
+	// cancelFunc returns the reserved tokens to the limiter.
+	cancelFunc func()
+	// timeNow returns the current time.
+	timeNow func() time.Time
+}
+
+// OK reports if the tokens were reserved.
+// It is false when the cost exceeds the limit of the
+// limiter and the tokens will never be available.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// ReadyAt returns the time when the reserved tokens are available.
+// It returns zero time if the tokens were not reserved.
+func (r *Reservation) ReadyAt() time.Time {
+	return r.readyAt
+}
+
+// Delay returns the duration to wait before the reserved tokens are available.
+// It returns zero if the tokens are already available and
+// [math.MaxInt64] if the tokens were not reserved.
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return math.MaxInt64
+	}
+	return max(0, r.readyAt.Sub(r.timeNow()))
+}
+
+// Cancel cancels the reservation and returns the reserved
+// tokens to the limiter as far as possible.
+// Tokens are not returned once the reservation became available.
+// It's safe to call Cancel multiple times.
+func (r *Reservation) Cancel() {
+	if r.cancelFunc != nil {
+		r.once.Do(r.cancelFunc)
+	}
+}
+
 // Token represents limiter tokens.
 type Token interface {
 	// OK returns if the token is valid or not.
@@ -42,6 +131,45 @@ type Token interface {
 	Err() error
 }
 
+// Quota is the quota of a limiter at the time when a token was requested.
+// It can be used for the RateLimit and Retry-After headers of HTTP.
+type Quota struct {
+	// Limit is the maximum number of tokens
+	// that can be obtained at once.
+	Limit int
+	// Remaining is the number of tokens that can be
+	// obtained immediately after the request.
+	Remaining int
+	// Reset is the time when the quota is fully restored.
+	// It is zero time when the quota will never be restored.
+	Reset time.Time
+	// RetryAfter is the duration to wait before
+	// the next token can be obtained.
+	// It is zero when the token was obtained.
+	RetryAfter time.Duration
+}
+
+// QuotaToken is the [Token] that reports the quota of the limiter.
+// Tokens returned by [BucketLimiter], [LeakyBucketLimiter],
+// [SlidingWindowLimiter] and [GCRALimiter] implement QuotaToken
+// except for the tokens with errors.
+type QuotaToken interface {
+	Token
+	// Quota returns the quota at the time
+	// when the token was requested.
+	Quota() Quota
+}
+
+// QuotaOf returns the quota reported by the token.
+// It returns false if the token does not implement [QuotaToken].
+func QuotaOf(t Token) (Quota, bool) {
+	qt, ok := t.(QuotaToken)
+	if !ok {
+		return Quota{}, false
+	}
+	return qt.Quota(), true
+}
+
 // NoopLimiter always reports the fixed value of [TokenOK] or [TokenNG].
 // NoopLimiter implements [Limiter] interface
 type NoopLimiter bool
@@ -60,6 +188,27 @@ func (lim NoopLimiter) WaitNow(_ context.Context) Token {
 	return TokenNG
 }
 
+func (lim NoopLimiter) AllowN(n int) Token {
+	if lim || n <= 0 {
+		return TokenOK
+	}
+	return TokenNG
+}
+
+func (lim NoopLimiter) WaitN(_ context.Context, n int) Token {
+	if lim || n <= 0 {
+		return TokenOK
+	}
+	return TokenNG
+}
+
+func (lim NoopLimiter) Reserve(n int) *Reservation {
+	if lim || n <= 0 {
+		return &Reservation{ok: true, readyAt: time.Now(), timeNow: time.Now}
+	}
+	return &Reservation{timeNow: time.Now}
+}
+
 // NoopToken always reports the fixed value of true or false.
 // Release() does nothing and Err() always returns nil.
 // NoopToken implements [Token] interface
@@ -98,3 +247,13 @@ func (t *token) Release() {
 func (t *token) Err() error {
 	return t.err
 }
+
+// quotaToken is the token that implements [QuotaToken].
+type quotaToken struct {
+	token
+	quota Quota
+}
+
+func (t *quotaToken) Quota() Quota {
+	return t.quota
+}
@@ -1,8 +1,11 @@
 package zrate
 
 import (
+	"context"
 	"io"
+	"math"
 	"testing"
+	"time"
 
 	"github.com/aileron-projects/go/ztesting"
 )
@@ -50,3 +53,76 @@ func TestToken(t *testing.T) {
 		ztesting.AssertEqual(t, "release func called multiple times", 1, callCount)
 	})
 }
+
+func TestQuotaOf(t *testing.T) {
+	t.Parallel()
+	t.Run("quota token", func(t *testing.T) {
+		want := Quota{Limit: 10, Remaining: 5, Reset: time.Unix(100, 0), RetryAfter: time.Second}
+		q, ok := QuotaOf(&quotaToken{token: token{ok: true}, quota: want})
+		ztesting.AssertEqual(t, "quota not found", true, ok)
+		ztesting.AssertEqual(t, "incorrect quota", want, q)
+	})
+	t.Run("non quota token", func(t *testing.T) {
+		q, ok := QuotaOf(TokenOK)
+		ztesting.AssertEqual(t, "quota unexpectedly found", false, ok)
+		ztesting.AssertEqual(t, "incorrect quota", Quota{}, q)
+	})
+}
+
+func TestNoopLimiter(t *testing.T) {
+	t.Parallel()
+	t.Run("true", func(t *testing.T) {
+		lim := NoopLimiter(true)
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.AllowN(10).OK())
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.WaitN(context.Background(), 10).OK())
+		r := lim.Reserve(10)
+		ztesting.AssertEqual(t, "incorrect reservation status", true, r.OK())
+		ztesting.AssertEqual(t, "incorrect delay", 0, r.Delay())
+	})
+	t.Run("false", func(t *testing.T) {
+		lim := NoopLimiter(false)
+		ztesting.AssertEqual(t, "incorrect token status", false, lim.AllowN(10).OK())
+		ztesting.AssertEqual(t, "incorrect token status", false, lim.WaitN(context.Background(), 10).OK())
+		r := lim.Reserve(10)
+		ztesting.AssertEqual(t, "incorrect reservation status", false, r.OK())
+		ztesting.AssertEqual(t, "incorrect delay", math.MaxInt64, r.Delay())
+	})
+	t.Run("n=0", func(t *testing.T) {
+		lim := NoopLimiter(false)
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.AllowN(0).OK())
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.WaitN(context.Background(), 0).OK())
+		ztesting.AssertEqual(t, "incorrect reservation status", true, lim.Reserve(0).OK())
+	})
+}
+
+func TestReservation(t *testing.T) {
+	t.Parallel()
+	t.Run("not reserved", func(t *testing.T) {
+		r := &Reservation{timeNow: time.Now}
+		ztesting.AssertEqual(t, "incorrect reservation status", false, r.OK())
+		ztesting.AssertEqual(t, "incorrect ready time", time.Time{}, r.ReadyAt())
+		ztesting.AssertEqual(t, "incorrect delay", math.MaxInt64, r.Delay())
+		r.Cancel() // Nothing happens.
+	})
+	t.Run("reserved", func(t *testing.T) {
+		now := time.Now()
+		var callCount int
+		r := &Reservation{
+			ok:         true,
+			readyAt:    now.Add(time.Second),
+			cancelFunc: func() { callCount += 1 },
+			timeNow:    func() time.Time { return now },
+		}
+		ztesting.AssertEqual(t, "incorrect reservation status", true, r.OK())
+		ztesting.AssertEqual(t, "incorrect ready time", now.Add(time.Second), r.ReadyAt())
+		ztesting.AssertEqual(t, "incorrect delay", time.Second, r.Delay())
+		r.Cancel()
+		r.Cancel()
+		ztesting.AssertEqual(t, "cancel func called multiple times", 1, callCount)
+	})
+	t.Run("ready", func(t *testing.T) {
+		now := time.Now()
+		r := &Reservation{ok: true, readyAt: now.Add(-time.Second), timeNow: func() time.Time { return now }}
+		ztesting.AssertEqual(t, "incorrect delay", 0, r.Delay())
+	})
+}
@@ -2,6 +2,7 @@ package zrate
 
 import (
 	"context"
+	"math"
 	"sync"
 	"time"
 )
@@ -102,22 +103,33 @@ func (lim *SlidingWindowLimiter) updateSubWindow() {
 }
 
 func (lim *SlidingWindowLimiter) AllowNow() Token {
+	return lim.AllowN(1)
+}
+
+func (lim *SlidingWindowLimiter) WaitNow(ctx context.Context) Token {
+	return lim.WaitN(ctx, 1)
+}
+
+func (lim *SlidingWindowLimiter) AllowN(n int) Token {
+	if n <= 0 {
+		return TokenOK
+	}
 	lim.mu.Lock()
 	defer lim.mu.Unlock()
 	lim.updateSubWindow()
-	if lim.sum >= lim.limit {
-		return TokenNG
+	if lim.sum+int64(n) > lim.limit {
+		return &quotaToken{quota: lim.quota(lim.timeNow(), int64(n), false)}
 	}
-	lim.sum += 1
-	lim.subWindow[lim.index] += 1
-	return TokenOK
+	lim.sum += int64(n)
+	lim.subWindow[lim.index] += int64(n)
+	return &quotaToken{token: token{ok: true}, quota: lim.quota(lim.timeNow(), int64(n), true)}
 }
 
-func (lim *SlidingWindowLimiter) WaitNow(ctx context.Context) Token {
+func (lim *SlidingWindowLimiter) WaitN(ctx context.Context, n int) Token {
 	for {
-		t := lim.AllowNow()
-		if t.OK() {
-			return TokenOK
+		t := lim.AllowN(n)
+		if t.OK() || int64(n) > lim.limit {
+			return t
 		}
 		select {
 		case <-time.After(lim.subWidth):
@@ -126,3 +138,35 @@ func (lim *SlidingWindowLimiter) WaitNow(ctx context.Context) Token {
 		}
 	}
 }
+
+// quota returns the current quota of the limiter.
+// The retry after duration for the cost n is calculated when allowed is false.
+// quota must be called with lim.mu held.
+func (lim *SlidingWindowLimiter) quota(now time.Time, n int64, allowed bool) Quota {
+	q := Quota{
+		Limit:     int(lim.limit),
+		Remaining: int(max(0, lim.limit-lim.sum)),
+		Reset:     now,
+	}
+	// The k-th oldest sub window, which starts from 0,
+	// expires at lastUpdate+(k+1)*subWidth.
+	var expired int64
+	found := allowed // RetryAfter is not necessary when allowed.
+	for k := range subWindowSize {
+		count := lim.subWindow[(lim.index+1+k)%subWindowSize]
+		if count == 0 {
+			continue
+		}
+		expireAt := lim.lastUpdate.Add(time.Duration(k+1) * lim.subWidth)
+		expired += count
+		if !found && lim.sum-expired+n <= lim.limit {
+			q.RetryAfter = max(0, expireAt.Sub(now))
+			found = true
+		}
+		q.Reset = expireAt
+	}
+	if !found {
+		q.RetryAfter = math.MaxInt64 // The cost n exceeds the limit.
+	}
+	return q
+}
@@ -2,6 +2,7 @@ package zrate
 
 import (
 	"context"
+	"math"
 	"testing"
 	"time"
 
@@ -41,6 +42,24 @@ func TestNewSlidingWindowLimiterWidth(t *testing.T) {
 
 func TestSlidingWindowLimiter(t *testing.T) {
 	t.Parallel()
+	t.Run("quota", func(t *testing.T) {
+		now := time.Now()
+		lim := &SlidingWindowLimiter{
+			limit:      2,
+			lastUpdate: now,
+			subWidth:   10 * time.Millisecond,
+			timeNow:    func() time.Time { return now },
+		}
+		q1, _ := QuotaOf(lim.AllowNow())
+		ztesting.AssertEqual(t, "incorrect quota", Quota{Limit: 2, Remaining: 1, Reset: now.Add(time.Second)}, q1)
+		now = now.Add(500 * time.Millisecond)
+		q2, _ := QuotaOf(lim.AllowNow())
+		ztesting.AssertEqual(t, "incorrect quota", Quota{Limit: 2, Remaining: 0, Reset: now.Add(time.Second)}, q2)
+		token := lim.AllowNow()
+		ztesting.AssertEqual(t, "incorrect token status", false, token.OK())
+		q3, _ := QuotaOf(token)
+		ztesting.AssertEqual(t, "incorrect quota", Quota{Limit: 2, Remaining: 0, Reset: now.Add(time.Second), RetryAfter: 500 * time.Millisecond}, q3)
+	})
 	t.Run("reached limit", func(t *testing.T) {
 		lim := &SlidingWindowLimiter{
 			limit:      100,
@@ -119,3 +138,45 @@ func TestSlidingWindowLimiter(t *testing.T) {
 		ztesting.AssertEqual(t, "incorrect error", context.DeadlineExceeded, token.Err())
 	})
 }
+
+func TestSlidingWindowLimiter_AllowN(t *testing.T) {
+	t.Parallel()
+	t.Run("n=0", func(t *testing.T) {
+		lim := NewSlidingWindowLimiterWidth(1, time.Second).(*SlidingWindowLimiter)
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.AllowN(0).OK())
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.WaitN(context.Background(), 0).OK())
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.AllowN(1).OK())
+		ztesting.AssertEqual(t, "incorrect token status", true, lim.AllowN(-1).OK())
+	})
+	t.Run("consume cost", func(t *testing.T) {
+		now := time.Now()
+		lim := &SlidingWindowLimiter{
+			limit:      10,
+			lastUpdate: now,
+			subWidth:   10 * time.Millisecond,
+			timeNow:    func() time.Time { return now },
+		}
+		q1, _ := QuotaOf(lim.AllowN(4))
+		ztesting.AssertEqual(t, "incorrect remaining", 6, q1.Remaining)
+		token := lim.AllowN(7)
+		ztesting.AssertEqual(t, "incorrect token status", false, token.OK())
+		q2, _ := QuotaOf(token)
+		ztesting.AssertEqual(t, "incorrect retry after", time.Second, q2.RetryAfter)
+		now = now.Add(500 * time.Millisecond)
+		q3, _ := QuotaOf(lim.AllowN(6))
+		ztesting.AssertEqual(t, "incorrect remaining", 0, q3.Remaining)
+		token = lim.AllowN(4)
+		ztesting.AssertEqual(t, "incorrect token status", false, token.OK())
+		q4, _ := QuotaOf(token)
+		ztesting.AssertEqual(t, "incorrect retry after", 500*time.Millisecond, q4.RetryAfter)
+	})
+	t.Run("cost exceeds limit", func(t *testing.T) {
+		lim := NewSlidingWindowLimiterWidth(10, time.Second).(*SlidingWindowLimiter)
+		token := lim.AllowN(11)
+		ztesting.AssertEqual(t, "incorrect token status", false, token.OK())
+		q, _ := QuotaOf(token)
+		ztesting.AssertEqual(t, "incorrect retry after", math.MaxInt64, q.RetryAfter)
+		token = lim.WaitN(context.Background(), 11) // Returns without waiting.
+		ztesting.AssertEqual(t, "incorrect token status", false, token.OK())
+	})
+}
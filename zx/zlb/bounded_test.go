@@ -0,0 +1,110 @@
+package zlb_test
+
+import (
+	"testing"
+
+	"github.com/aileron-projects/go/ztesting"
+	"github.com/aileron-projects/go/zx/zlb"
+)
+
+// boundedLB is the consistent hash load balancer with bounded loads.
+type boundedLB interface {
+	Get(uint64) (*Target, bool)
+	zlb.LoadReporter
+}
+
+func testBoundedLoad(t *testing.T, newLB func(factor float64, ts ...*Target) boundedLB) {
+	t.Helper()
+	targets := func() []*Target {
+		return []*Target{
+			{name: "t0", id: 100, weight: 10, active: true},
+			{name: "t1", id: 200, weight: 10, active: true},
+			{name: "t2", id: 300, weight: 10, active: true},
+			{name: "t3", id: 400, weight: 10, active: false},
+			{name: "t4", id: 500, weight: 10, active: true},
+		}
+	}
+	t.Run("same as unbounded", func(t *testing.T) {
+		lb := newLB(1.25, targets()...)
+		ub := newLB(0, targets()...)
+		for hint := range uint64(100) {
+			got, found := lb.Get(hint)
+			ztesting.AssertEqual(t, "found not match", true, found)
+			want, _ := ub.Get(hint)
+			ztesting.AssertEqual(t, "target not match", want.name, got.name)
+			lb.Done(got.ID(), 0)
+		}
+	})
+	t.Run("bounded", func(t *testing.T) {
+		lb := newLB(1.25, targets()...)
+		count := map[string]int{}
+		for range 100 {
+			target, _ := lb.Get(12345) // Hot key.
+			count[target.name]++
+		}
+		ztesting.AssertEqual(t, "inactive target returned", 0, count["t3"])
+		for name, c := range count {
+			if c > 32 { // ceil(1.25*100/4)
+				t.Errorf("target %s overloaded: %d", name, c)
+			}
+		}
+	})
+	t.Run("unbounded", func(t *testing.T) {
+		lb := newLB(0, targets()...)
+		count := map[string]int{}
+		for range 100 {
+			target, _ := lb.Get(12345)
+			count[target.name]++
+		}
+		ztesting.AssertEqual(t, "all requests must go to a target", 1, len(count))
+	})
+	t.Run("release", func(t *testing.T) {
+		lb := newLB(1.0001, targets()...)
+		first, _ := lb.Get(12345)
+		second, _ := lb.Get(12345)
+		ztesting.AssertEqual(t, "overloaded target must be avoided", true, first.name != second.name)
+		lb.Done(first.ID(), 0)
+		lb.Done(second.ID(), 0)
+		lb.Done(second.ID(), 0) // Ignored.
+		third, _ := lb.Get(12345)
+		ztesting.AssertEqual(t, "target not match", first.name, third.name)
+	})
+}
+
+func TestRingHash_boundedLoad(t *testing.T) {
+	t.Parallel()
+	testBoundedLoad(t, func(factor float64, ts ...*Target) boundedLB {
+		lb := zlb.NewRingHash(ts...)
+		lb.LoadFactor = factor
+		return lb
+	})
+	t.Run("remove", func(t *testing.T) {
+		t0 := &Target{name: "t0", id: 100, weight: 10, active: true}
+		lb := zlb.NewRingHash(t0)
+		lb.LoadFactor = 2
+		lb.Get(0)
+		lb.Remove(t0.ID())
+		lb.Done(t0.ID(), 0) // Ignored.
+		_, found := lb.Get(0)
+		ztesting.AssertEqual(t, "found not match", false, found)
+	})
+}
+
+func TestMaglev_boundedLoad(t *testing.T) {
+	t.Parallel()
+	testBoundedLoad(t, func(factor float64, ts ...*Target) boundedLB {
+		lb := zlb.NewMaglev(ts...)
+		lb.LoadFactor = factor
+		return lb
+	})
+	t.Run("remove", func(t *testing.T) {
+		t0 := &Target{name: "t0", id: 100, weight: 10, active: true}
+		lb := zlb.NewMaglev(t0)
+		lb.LoadFactor = 2
+		lb.Get(0)
+		lb.Remove(t0.ID())
+		lb.Done(t0.ID(), 0) // Ignored.
+		_, found := lb.Get(0)
+		ztesting.AssertEqual(t, "found not match", false, found)
+	})
+}
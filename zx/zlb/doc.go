@@ -16,6 +16,9 @@
 //	| WeightedDirectHash |  Yes   |  Yes   |    Yes    |    No      |    O(n)     |
 //	| RingHash           |  Yes   |  Yes   |    Yes    |    Yes     |  O(log(n))  |
 //	| Maglev             |  Yes   |  Yes   |    Yes    |    Yes     |    O(1)     |
+//	| LeastConn          |  Yes   |  Yes   |    No     |     --     |    O(n)     |
+//	| LeastRequest       |  Yes   |  Yes   |    No     |     --     |    O(1)     |
+//	| PeakEWMA           |  Yes   |  Yes   |    No     |     --     |    O(1)     |
 //
 // See the comments:
 //   - [Priority]: priority based, or weight based load balancer.
@@ -29,4 +32,17 @@
 //   - [DirectHashW]: weighted direct hash load balancer.
 //   - [RingHash]: ring hash load balancer.
 //   - [Maglev]: maglev hash load balancer.
+//   - [LeastConn]: least connection load balancer.
+//   - [LeastRequest]: power of two choices least request load balancer.
+//   - [PeakEWMA]: peak EWMA latency load balancer.
+//   - [Locality]: locality-aware priority failover load balancer.
+//
+// Load-aware load balancers implement [LoadReporter]. Callers must
+// report the completion of requests to the targets returned from Get.
+// [RingHash] and [Maglev] also implement it to support consistent hashing
+// with bounded loads. See their LoadFactor.
+//
+// Targets with dynamic status and weight:
+//   - [ManagedTarget]: target whose status and weight can be changed.
+//   - [HealthCheck]: periodic health checks that change the target status.
 package zlb
@@ -0,0 +1,128 @@
+package zlb
+
+import (
+	"math"
+	"time"
+)
+
+// NewPeakEWMA returns a new instance of peak EWMA load balancer.
+// Targets can be added or removed after instantiation.
+// See the comments on [PeakEWMA].
+func NewPeakEWMA[T Target](targets ...T) *PeakEWMA[T] {
+	lb := &PeakEWMA[T]{
+		loadLB: newLoadLB[T](),
+	}
+	lb.Add(targets...)
+	return lb
+}
+
+// PeakEWMA is the load balancer that uses peak exponentially weighted
+// moving average (EWMA) of latencies. The load balancer considers
+// both active status and weight of targets.
+// Like [LeastRequest], it selects two random targets with power of
+// two choices (P2C) algorithm and returns the one with the lower cost.
+// The cost is the EWMA latency multiplied by the number of in-flight
+// requests plus one, divided by the weight.
+// The EWMA is immediately set to the observed latency when it is
+// higher than the current average so that the load balancer reacts
+// to latency spikes quickly. Otherwise, the average decays with
+// the time constant Decay.
+// Targets without observed latencies have zero average latency
+// and are preferred.
+// Callers must report the completion and the latency of requests
+// with [PeakEWMA.Done].
+// For n targets, computational complexity is O(1).
+// But it tries MaxRetry+1 times at most until it finds an active
+// and non-zero weight target for each choice.
+//
+// Algorithm:
+//
+//	Ti = T[i] = Target[i]
+//	Wi = W[i] = Weight[i]
+//	Ci = C[i] = In-flight requests of T[i]
+//	Li = L[i] = EWMA latency of T[i]
+//
+//	function Get(_):
+//	  a <-- random active target
+//	  b <-- random active target other than a
+//	  target <-- a if cost(a) <= cost(b) else b
+//	  C[target] <-- C[target]+1
+//	  return target
+//
+//	function cost(i):
+//	  return (Li+1) * (Ci+1) / Wi
+//
+//	function Done(target, latency):
+//	  C[target] <-- C[target]-1
+//	  if latency > L[target]:
+//	    L[target] <-- latency
+//	  else:
+//	    w <-- exp(-elapsed/Decay)
+//	    L[target] <-- L[target]*w + latency*(1-w)
+//
+// References:
+//   - https://linkerd.io/2016/03/16/beyond-round-robin-load-balancing-for-latency/
+//   - https://github.com/twitter/finagle/blob/develop/finagle-core/src/main/scala/com/twitter/finagle/loadbalancer/PeakEwma.scala
+type PeakEWMA[T Target] struct {
+	*loadLB[T]
+	// MaxRetry is the maximum retry count to find
+	// an active and non-zero weight target for each choice.
+	// If zero, no retries are applied.
+	MaxRetry int
+	// Decay is the time constant of the EWMA.
+	// Larger value makes the average smoother.
+	// If zero or negative, 10 seconds is used.
+	Decay time.Duration
+}
+
+// Get returns a target.
+// Returned value found is true when an active target was found.
+// [PeakEWMA.Done] must be called when the request to the
+// returned target completed.
+func (lb *PeakEWMA[T]) Get(_ uint64) (t T, found bool) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	i := lb.p2c(lb.MaxRetry, func(t T) float64 {
+		l := lb.load(t.ID())
+		return (l.ewma + 1) * float64(l.inflight+1) / float64(t.Weight())
+	})
+	if i < 0 {
+		return t, false
+	}
+	return lb.acquire(i), true
+}
+
+// Done reports the completion of a request to the target
+// and updates the EWMA latency of the target.
+func (lb *PeakEWMA[T]) Done(id uint64, latency time.Duration) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	l := lb.done(id)
+	if l == nil {
+		return
+	}
+	now := time.Now()
+	rtt := float64(max(latency, 0))
+	if rtt > l.ewma {
+		l.ewma = rtt // Peak sensitive.
+	} else {
+		decay := lb.Decay
+		if decay <= 0 {
+			decay = 10 * time.Second
+		}
+		w := math.Exp(-float64(now.Sub(l.last)) / float64(decay))
+		l.ewma = l.ewma*w + rtt*(1-w)
+	}
+	l.last = now
+}
+
+// Latency returns the EWMA latency of the target with the id.
+// It returns zero if no latency was observed.
+func (lb *PeakEWMA[T]) Latency(id uint64) time.Duration {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	if l, ok := lb.loads[id]; ok {
+		return time.Duration(l.ewma)
+	}
+	return 0
+}
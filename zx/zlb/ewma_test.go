@@ -0,0 +1,50 @@
+package zlb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/ztesting"
+	"github.com/aileron-projects/go/zx/zlb"
+)
+
+func TestPeakEWMA(t *testing.T) {
+	t.Parallel()
+	testLoadTargets(t, func(ts ...*Target) loadBalancer {
+		lb := zlb.NewPeakEWMA(ts...)
+		lb.MaxRetry = 10
+		return lb
+	})
+
+	t.Run("prefer low latency", func(t *testing.T) {
+		t0 := &Target{name: "t0", id: 0, weight: 1, active: true}
+		t1 := &Target{name: "t1", id: 1, weight: 1, active: true}
+		lb := zlb.NewPeakEWMA(t0, t1)
+		lb.MaxRetry = 100
+		lb.Get(0)
+		lb.Get(0)
+		lb.Done(t0.ID(), 100*time.Millisecond)
+		lb.Done(t1.ID(), time.Millisecond)
+		count := map[string]int{}
+		for range 50 {
+			target, _ := lb.Get(0)
+			count[target.name]++
+			lb.Done(target.ID(), map[string]time.Duration{"t0": 100 * time.Millisecond, "t1": time.Millisecond}[target.name])
+		}
+		ztesting.AssertEqual(t, "t1 count not match", 50, count["t1"])
+	})
+	t.Run("peak and decay", func(t *testing.T) {
+		t0 := &Target{name: "t0", id: 0, weight: 1, active: true}
+		lb := zlb.NewPeakEWMA(t0)
+		lb.Decay = 10 * time.Millisecond
+		ztesting.AssertEqual(t, "latency not match", time.Duration(0), lb.Latency(t0.ID()))
+		lb.Get(0)
+		lb.Done(t0.ID(), 100*time.Millisecond)
+		ztesting.AssertEqual(t, "latency must be peak", 100*time.Millisecond, lb.Latency(t0.ID()))
+		time.Sleep(50 * time.Millisecond)
+		lb.Get(0)
+		lb.Done(t0.ID(), time.Millisecond)
+		ztesting.AssertEqual(t, "latency must be decayed", true, lb.Latency(t0.ID()) < 10*time.Millisecond)
+		lb.Done(999, time.Second) // Ignored.
+	})
+}
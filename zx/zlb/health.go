@@ -0,0 +1,241 @@
+package zlb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrUnhealthy is the error returned from [HTTPHealthChecker]
+// when the response status code is not healthy.
+var ErrUnhealthy = errors.New("zx/zlb: unhealthy target")
+
+// HealthChecker checks the health of targets.
+type HealthChecker interface {
+	// Check checks the health of the target with the addr.
+	// It returns nil if the target is healthy.
+	// Check must be safe for concurrent call.
+	Check(ctx context.Context, addr string) error
+}
+
+// HealthCheckFunc is the function that implements [HealthChecker].
+type HealthCheckFunc func(ctx context.Context, addr string) error
+
+func (f HealthCheckFunc) Check(ctx context.Context, addr string) error {
+	return f(ctx, addr)
+}
+
+// TCPHealthChecker checks the health of targets by connecting to them.
+// Targets are healthy when the connection was established.
+// Connections are closed immediately after established.
+type TCPHealthChecker struct {
+	// Dialer is the dialer used to connect to targets.
+	// If nil, a zero [net.Dialer] is used.
+	Dialer *net.Dialer
+}
+
+func (c *TCPHealthChecker) Check(ctx context.Context, addr string) error {
+	d := c.Dialer
+	if d == nil {
+		d = &net.Dialer{}
+	}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// HTTPHealthChecker checks the health of targets by sending
+// HTTP GET requests to them. Targets are healthy when the
+// response status code is 2xx or 3xx by default.
+type HTTPHealthChecker struct {
+	// Client is the HTTP client used to send requests.
+	// If nil, [http.DefaultClient] is used.
+	Client *http.Client
+	// Scheme is the URL scheme of requests.
+	// If empty, "http" is used.
+	Scheme string
+	// Path is the URL path of requests.
+	// If empty, "/" is used.
+	Path string
+	// Header is the additional header of requests.
+	Header http.Header
+	// Healthy optionally reports if the status code is healthy.
+	// If nil, status codes from 200 to 399 are healthy.
+	Healthy func(code int) bool
+}
+
+func (c *HTTPHealthChecker) Check(ctx context.Context, addr string) error {
+	scheme, path := c.Scheme, c.Path
+	if scheme == "" {
+		scheme = "http"
+	}
+	if path == "" {
+		path = "/"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+addr+path, nil)
+	if err != nil {
+		return err
+	}
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	_ = resp.Body.Close()
+	healthy := c.Healthy
+	if healthy == nil {
+		healthy = func(code int) bool { return code >= 200 && code < 400 }
+	}
+	if !healthy(resp.StatusCode) {
+		return fmt.Errorf("%w: status code %d", ErrUnhealthy, resp.StatusCode)
+	}
+	return nil
+}
+
+// HealthCheck periodically checks the health of [ManagedTarget]s
+// and changes their active status. Targets become inactive after
+// UnhealthyThreshold consecutive failures and become active again
+// after HealthyThreshold consecutive successes.
+//
+// Example:
+//
+//	lb := zlb.NewMaglev(
+//		zlb.NewManagedTarget("10.0.0.1:8080", 1),
+//		zlb.NewManagedTarget("10.0.0.2:8080", 1),
+//	)
+//	hc := &zlb.HealthCheck{
+//		Checker:  &zlb.HTTPHealthChecker{Path: "/healthz"},
+//		Interval: 5 * time.Second,
+//	}
+//	go hc.Run(ctx, lb.Targets)
+type HealthCheck struct {
+	// Checker checks the health of targets.
+	// Checker must not be nil.
+	Checker HealthChecker
+	// Interval is the interval of health checks.
+	// If zero or negative, 10 seconds is used.
+	Interval time.Duration
+	// Timeout is the timeout of a health check.
+	// If zero or negative, the Interval is used.
+	Timeout time.Duration
+	// HealthyThreshold is the number of consecutive successes
+	// required to make a target active.
+	// If zero or negative, 1 is used.
+	HealthyThreshold int
+	// UnhealthyThreshold is the number of consecutive failures
+	// required to make a target inactive.
+	// If zero or negative, 1 is used.
+	UnhealthyThreshold int
+	// OnChange, if non-nil, is called when the active status of
+	// a target was changed by the health check.
+	// The err is the error of the last health check,
+	// which is nil when the target became active.
+	OnChange func(t *ManagedTarget, active bool, err error)
+
+	mu     sync.Mutex
+	counts map[*ManagedTarget]int // Positive for successes, negative for failures.
+}
+
+// Run runs health checks until the ctx is done.
+// The targets returns the targets to check. It is called every
+// interval so that dynamically added targets are checked.
+// Typically, the Targets method of load balancers is given.
+// Health checks of targets run concurrently and the first check
+// runs immediately.
+func (h *HealthCheck) Run(ctx context.Context, targets func() []*ManagedTarget) {
+	interval := h.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		h.CheckAll(ctx, targets())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll checks the health of the targets once and
+// updates their active status.
+// It returns after all checks completed.
+// Consecutive results of targets not in the targets are discarded.
+func (h *HealthCheck) CheckAll(ctx context.Context, targets []*ManagedTarget) {
+	timeout := cmpPositive(h.Timeout, cmpPositive(h.Interval, 10*time.Second))
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			h.record(t, h.Checker.Check(cctx, t.Addr()))
+		}()
+	}
+	wg.Wait()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for t := range h.counts { // Forget removed targets.
+		if !slices.Contains(targets, t) {
+			delete(h.counts, t)
+		}
+	}
+}
+
+// record records the result of a health check
+// and updates the status of the target.
+func (h *HealthCheck) record(t *ManagedTarget, err error) {
+	h.mu.Lock()
+	if h.counts == nil {
+		h.counts = map[*ManagedTarget]int{}
+	}
+	n := h.counts[t]
+	if err == nil {
+		n = max(n, 0) + 1
+	} else {
+		n = min(n, 0) - 1
+	}
+	h.counts[t] = n
+	h.mu.Unlock()
+
+	active := t.Active()
+	switch {
+	case !active && n >= max(h.HealthyThreshold, 1):
+		active = true
+	case active && -n >= max(h.UnhealthyThreshold, 1):
+		active = false
+	default:
+		return
+	}
+	t.SetActive(active)
+	if h.OnChange != nil {
+		h.OnChange(t, active, err)
+	}
+}
+
+// cmpPositive returns a if a is positive, otherwise b.
+func cmpPositive(a, b time.Duration) time.Duration {
+	if a > 0 {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,132 @@
+package zlb_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/ztesting"
+	"github.com/aileron-projects/go/zx/zlb"
+)
+
+func TestTCPHealthChecker(t *testing.T) {
+	t.Parallel()
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := ln.Addr().String()
+	c := &zlb.TCPHealthChecker{}
+	err := c.Check(context.Background(), addr)
+	ztesting.AssertEqualErr(t, "error not match", nil, err)
+	ln.Close()
+	err = c.Check(context.Background(), addr)
+	ztesting.AssertEqual(t, "error should not be nil", true, err != nil)
+}
+
+func TestHTTPHealthChecker(t *testing.T) {
+	t.Parallel()
+	svr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/healthz":
+			w.WriteHeader(http.StatusNoContent)
+		case "/header":
+			w.WriteHeader(map[bool]int{true: 200, false: 400}[r.Header.Get("X-Test") == "test"])
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer svr.Close()
+	addr := strings.TrimPrefix(svr.URL, "http://")
+
+	testCases := map[string]struct {
+		c    *zlb.HTTPHealthChecker
+		addr string
+		err  error
+	}{
+		"healthy":          {c: &zlb.HTTPHealthChecker{Path: "/healthz"}, addr: addr},
+		"unhealthy":        {c: &zlb.HTTPHealthChecker{}, addr: addr, err: zlb.ErrUnhealthy},
+		"with header":      {c: &zlb.HTTPHealthChecker{Path: "/header", Header: http.Header{"X-Test": {"test"}}}, addr: addr},
+		"custom healthy":   {c: &zlb.HTTPHealthChecker{Healthy: func(code int) bool { return code == 503 }}, addr: addr},
+		"custom unhealthy": {c: &zlb.HTTPHealthChecker{Path: "/healthz", Healthy: func(code int) bool { return code == 200 }}, addr: addr, err: zlb.ErrUnhealthy},
+		"with client":      {c: &zlb.HTTPHealthChecker{Client: svr.Client(), Scheme: "http", Path: "/healthz"}, addr: addr},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := tc.c.Check(context.Background(), tc.addr)
+			ztesting.AssertEqualErr(t, "error not match", tc.err, err)
+		})
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	t.Parallel()
+	t.Run("thresholds", func(t *testing.T) {
+		var mu sync.Mutex
+		healthy := map[string]bool{"t1": true, "t2": true}
+		var changes []string
+		hc := &zlb.HealthCheck{
+			Checker: zlb.HealthCheckFunc(func(_ context.Context, addr string) error {
+				mu.Lock()
+				defer mu.Unlock()
+				if healthy[addr] {
+					return nil
+				}
+				return errors.New("unhealthy")
+			}),
+			HealthyThreshold:   2,
+			UnhealthyThreshold: 2,
+			OnChange: func(t *zlb.ManagedTarget, active bool, _ error) {
+				mu.Lock()
+				defer mu.Unlock()
+				changes = append(changes, t.Addr()+map[bool]string{true: ":up", false: ":down"}[active])
+			},
+		}
+		t1, t2 := zlb.NewManagedTarget("t1", 1), zlb.NewManagedTarget("t2", 1)
+		targets := []*zlb.ManagedTarget{t1, t2}
+		check := func(active1, active2 bool) {
+			t.Helper()
+			hc.CheckAll(context.Background(), targets)
+			ztesting.AssertEqual(t, "t1 status not match", active1, t1.Active())
+			ztesting.AssertEqual(t, "t2 status not match", active2, t2.Active())
+		}
+
+		check(true, true)
+		mu.Lock()
+		healthy["t1"] = false
+		mu.Unlock()
+		check(true, true) // 1 failure.
+		check(false, true)
+		check(false, true)
+		mu.Lock()
+		healthy["t1"] = true
+		mu.Unlock()
+		check(false, true) // 1 success.
+		check(true, true)
+
+		mu.Lock()
+		defer mu.Unlock()
+		ztesting.AssertEqual(t, "changes not match", "t1:down,t1:up", strings.Join(changes, ","))
+	})
+	t.Run("run", func(t *testing.T) {
+		mt := zlb.NewManagedTarget("test", 1)
+		ctx, cancel := context.WithCancel(context.Background())
+		count := 0
+		hc := &zlb.HealthCheck{
+			Checker: zlb.HealthCheckFunc(func(_ context.Context, _ string) error {
+				count++
+				if count == 3 {
+					cancel()
+				}
+				return errors.New("unhealthy")
+			}),
+			Interval: time.Millisecond,
+		}
+		hc.Run(ctx, func() []*zlb.ManagedTarget { return []*zlb.ManagedTarget{mt} })
+		ztesting.AssertEqual(t, "count not match", 3, count)
+		ztesting.AssertEqual(t, "status not match", false, mt.Active())
+	})
+}
@@ -0,0 +1,151 @@
+package zlb
+
+import (
+	"math/rand/v2"
+)
+
+// NewLeastConn returns a new instance of least connection load balancer.
+// Targets can be added or removed after instantiation.
+// See the comments on [LeastConn].
+func NewLeastConn[T Target](targets ...T) *LeastConn[T] {
+	lb := &LeastConn[T]{
+		loadLB: newLoadLB[T](),
+	}
+	lb.Add(targets...)
+	return lb
+}
+
+// NewLeastRequest returns a new instance of least request load balancer.
+// Targets can be added or removed after instantiation.
+// See the comments on [LeastRequest].
+func NewLeastRequest[T Target](targets ...T) *LeastRequest[T] {
+	lb := &LeastRequest[T]{
+		loadLB: newLoadLB[T](),
+	}
+	lb.Add(targets...)
+	return lb
+}
+
+// LeastConn is the load balancer that uses weighted least connection algorithm.
+// The load balancer considers both active status and weight of targets.
+// It selects the target with the least number of in-flight requests,
+// or connections, divided by its weight.
+// Ties are broken by the position from a random start index.
+// Callers must report the completion of requests with [LeastConn.Done].
+// For n targets, computational complexity is O(n).
+//
+// Algorithm:
+//
+//	Ti = T[i] = Target[i]
+//	Wi = W[i] = Weight[i]
+//	Ci = C[i] = In-flight requests of T[i]
+//
+//	function Get(_):
+//	  start <-- random(0, n)
+//	  minScore <-- +Inf
+//	  target <-- nil
+//	  for j range {0 ... n-1}:
+//	    i <-- (start+j)%n
+//	    score <-- Ci / Wi
+//	    if score < minScore:
+//	      minScore <-- score
+//	      target <-- Ti
+//	  C[target] <-- C[target]+1
+//	  return target
+//
+//	function Done(target):
+//	  C[target] <-- C[target]-1
+//
+// References:
+//   - https://en.wikipedia.org/wiki/Load_balancing_(computing)
+type LeastConn[T Target] struct {
+	*loadLB[T]
+}
+
+// Get returns a target.
+// Returned value found is true when an active target was found.
+// [LeastConn.Done] must be called when the request to the
+// returned target completed.
+func (lb *LeastConn[T]) Get(_ uint64) (t T, found bool) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	n := len(lb.targets)
+	if n == 0 {
+		return t, false
+	}
+
+	start := rand.IntN(n)
+	index := -1
+	minScore := 0.0
+	for j := range n {
+		i := (start + j) % n
+		target := lb.targets[i]
+		w := float64(target.Weight())
+		if !target.Active() || w == 0 {
+			continue
+		}
+		score := float64(lb.load(target.ID()).inflight) / w
+		if index < 0 || score < minScore {
+			minScore = score
+			index = i
+		}
+	}
+	if index < 0 {
+		return t, false
+	}
+	return lb.acquire(index), true
+}
+
+// LeastRequest is the load balancer that uses power of two choices (P2C)
+// least request algorithm. The load balancer considers both active status
+// and weight of targets. It selects two random targets and returns
+// the one with the less number of in-flight requests divided by its weight.
+// Callers must report the completion of requests with [LeastRequest.Done].
+// For n targets, computational complexity is O(1).
+// But it tries MaxRetry+1 times at most until it finds an active
+// and non-zero weight target for each choice.
+// Note that the load balancer may return false even active and
+// non-zero weight targets are exist.
+//
+// Algorithm:
+//
+//	Ti = T[i] = Target[i]
+//	Wi = W[i] = Weight[i]
+//	Ci = C[i] = In-flight requests of T[i]
+//
+//	function Get(_):
+//	  a <-- random active target
+//	  b <-- random active target other than a
+//	  target <-- a if Ca/Wa <= Cb/Wb else b
+//	  C[target] <-- C[target]+1
+//	  return target
+//
+//	function Done(target):
+//	  C[target] <-- C[target]-1
+//
+// References:
+//   - https://www.eecs.harvard.edu/~michaelm/postscripts/handbook2001.pdf
+//   - https://www.envoyproxy.io/docs/envoy/latest/intro/arch_overview/upstream/load_balancing/load_balancers
+type LeastRequest[T Target] struct {
+	*loadLB[T]
+	// MaxRetry is the maximum retry count to find
+	// an active and non-zero weight target for each choice.
+	// If zero, no retries are applied.
+	MaxRetry int
+}
+
+// Get returns a target.
+// Returned value found is true when an active target was found.
+// [LeastRequest.Done] must be called when the request to the
+// returned target completed.
+func (lb *LeastRequest[T]) Get(_ uint64) (t T, found bool) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	i := lb.p2c(lb.MaxRetry, func(t T) float64 {
+		return float64(lb.load(t.ID()).inflight) / float64(t.Weight())
+	})
+	if i < 0 {
+		return t, false
+	}
+	return lb.acquire(i), true
+}
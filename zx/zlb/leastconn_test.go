@@ -0,0 +1,132 @@
+package zlb_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/ztesting"
+	"github.com/aileron-projects/go/zx/zlb"
+)
+
+// loadBalancer is the load-aware load balancer for testing.
+type loadBalancer interface {
+	Get(uint64) (*Target, bool)
+	Add(...*Target)
+	Remove(uint64)
+	zlb.LoadReporter
+}
+
+var loadTargets = map[string]struct {
+	found   bool
+	targets []*Target
+}{
+	"0 target": {
+		found: false,
+	},
+	"1 target, inactive": {
+		found: false,
+		targets: []*Target{
+			{name: "t0", id: 0, weight: 1, active: false},
+		},
+	},
+	"1 target, 0 weight": {
+		found: false,
+		targets: []*Target{
+			{name: "t0", id: 0, weight: 0, active: true},
+		},
+	},
+	"1 target, non-0 weight": {
+		found: true,
+		targets: []*Target{
+			{name: "t0", id: 0, weight: 1, active: true},
+		},
+	},
+	"2 targets, 1 active": {
+		found: true,
+		targets: []*Target{
+			{name: "t0", id: 0, weight: 1, active: false},
+			{name: "t1", id: 1, weight: 1, active: true},
+		},
+	},
+}
+
+func testLoadTargets(t *testing.T, newLB func(...*Target) loadBalancer) {
+	t.Helper()
+	for name, tc := range loadTargets {
+		t.Run(name, func(t *testing.T) {
+			lb := newLB(tc.targets...)
+			for range 10 {
+				target, found := lb.Get(0)
+				ztesting.AssertEqual(t, "found not match", tc.found, found)
+				if found {
+					ztesting.AssertEqual(t, "inactive target returned", true, target.Active())
+					lb.Done(target.ID(), time.Millisecond)
+				}
+			}
+		})
+	}
+}
+
+func TestLeastConn(t *testing.T) {
+	t.Parallel()
+	testLoadTargets(t, func(ts ...*Target) loadBalancer { return zlb.NewLeastConn(ts...) })
+
+	t.Run("least connection", func(t *testing.T) {
+		t0 := &Target{name: "t0", id: 0, weight: 1, active: true}
+		t1 := &Target{name: "t1", id: 1, weight: 2, active: true}
+		lb := zlb.NewLeastConn(t0, t1)
+		count := map[string]int{}
+		for range 6 {
+			target, _ := lb.Get(0) // Never done.
+			count[target.name]++
+		}
+		ztesting.AssertEqual(t, "t0 count not match", 2, count["t0"])
+		ztesting.AssertEqual(t, "t1 count not match", 4, count["t1"])
+
+		for range 2 {
+			lb.Done(t0.ID(), 0)
+		}
+		for range 2 {
+			target, _ := lb.Get(0)
+			ztesting.AssertEqual(t, "target not match", "t0", target.name)
+		}
+	})
+	t.Run("remove", func(t *testing.T) {
+		t0 := &Target{name: "t0", id: 0, weight: 1, active: true}
+		t1 := &Target{name: "t1", id: 1, weight: 1, active: true}
+		lb := zlb.NewLeastConn(t0, t1)
+		lb.Get(0)
+		lb.Get(0)
+		lb.Remove(0)
+		lb.Done(0, 0) // Ignored.
+		lb.Add(t0)
+		target, _ := lb.Get(0)
+		ztesting.AssertEqual(t, "load of removed target must be reset", "t0", target.name)
+	})
+}
+
+func TestLeastRequest(t *testing.T) {
+	t.Parallel()
+	testLoadTargets(t, func(ts ...*Target) loadBalancer {
+		lb := zlb.NewLeastRequest(ts...)
+		lb.MaxRetry = 10
+		return lb
+	})
+
+	t.Run("least request", func(t *testing.T) {
+		t0 := &Target{name: "t0", id: 0, weight: 1, active: true}
+		t1 := &Target{name: "t1", id: 1, weight: 1, active: true}
+		lb := zlb.NewLeastRequest(t0, t1)
+		lb.MaxRetry = 100
+		for range 10 {
+			lb.Get(0)
+		}
+		for range 5 {
+			lb.Done(t1.ID(), 0)
+		}
+		for range 5 {
+			target, _ := lb.Get(0) // With 2 targets, both are always compared.
+			ztesting.AssertEqual(t, "target not match", "t1", target.name)
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package zlb
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// LoadReporter is implemented by load-aware load balancers.
+// Callers must report the completion of requests to the targets
+// returned from Get so that the load balancers can track the load.
+//
+// Example:
+//
+//	t, found := lb.Get(0)
+//	if !found {
+//		return
+//	}
+//	start := time.Now()
+//	defer func() { lb.Done(t.ID(), time.Since(start)) }()
+type LoadReporter interface {
+	// Done reports the completion of a request to the target
+	// with the id that was returned from Get.
+	// The latency is the time taken by the request.
+	// Some load balancing algorithms ignores the latency.
+	// Done must be called exactly once for each successful Get.
+	// Done is safe for concurrent call.
+	Done(id uint64, latency time.Duration)
+}
+
+// targetLoad is the load of a target.
+type targetLoad struct {
+	inflight int64     // inflight is the number of in-flight requests.
+	ewma     float64   // ewma is the EWMA of latencies in nanoseconds.
+	last     time.Time // last is the time when the ewma was updated.
+}
+
+// loadLB is the base struct for load-aware load balancers.
+type loadLB[T Target] struct {
+	*baseLB[T]
+	// loads is the load of targets keyed by target ID.
+	loads map[uint64]*targetLoad
+}
+
+func newLoadLB[T Target]() *loadLB[T] {
+	return &loadLB[T]{
+		baseLB: &baseLB[T]{},
+		loads:  map[uint64]*targetLoad{},
+	}
+}
+
+// Remove removes targets and their load.
+func (lb *loadLB[T]) Remove(id uint64) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.remove(id)
+	delete(lb.loads, id)
+}
+
+// Done reports the completion of a request to the target.
+func (lb *loadLB[T]) Done(id uint64, _ time.Duration) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.done(id)
+}
+
+// done decrements the in-flight requests of the target.
+// It returns nil if the target is not found.
+// It must be called with lb.mu held.
+func (lb *loadLB[T]) done(id uint64) *targetLoad {
+	l, ok := lb.loads[id]
+	if !ok {
+		return nil
+	}
+	l.inflight = max(l.inflight-1, 0)
+	return l
+}
+
+// load returns the load of the target with the id.
+// It must be called with lb.mu held.
+func (lb *loadLB[T]) load(id uint64) *targetLoad {
+	l, ok := lb.loads[id]
+	if !ok {
+		l = &targetLoad{}
+		lb.loads[id] = l
+	}
+	return l
+}
+
+// acquire increments the in-flight requests of the
+// target at the index i and returns the target.
+// It must be called with lb.mu held.
+func (lb *loadLB[T]) acquire(i int) T {
+	t := lb.targets[i]
+	lb.load(t.ID()).inflight++
+	return t
+}
+
+// pick returns the index of a random active and non-zero weight
+// target other than the except. It tries maxRetry+1 times at most.
+// It returns -1 if not found.
+// It must be called with lb.mu held.
+func (lb *loadLB[T]) pick(maxRetry, except int) int {
+	n := len(lb.targets)
+	for range max(1, maxRetry+1) {
+		i := rand.IntN(n)
+		t := lb.targets[i]
+		if i != except && t.Active() && t.Weight() > 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// p2c selects a target with the power of two choices algorithm.
+// The target with lower cost is selected.
+// It returns -1 if not found.
+// It must be called with lb.mu held.
+func (lb *loadLB[T]) p2c(maxRetry int, cost func(T) float64) int {
+	if len(lb.targets) == 0 {
+		return -1
+	}
+	i := lb.pick(maxRetry, -1)
+	if i < 0 || len(lb.targets) == 1 {
+		return i
+	}
+	j := lb.pick(maxRetry, i)
+	if j < 0 || cost(lb.targets[i]) <= cost(lb.targets[j]) {
+		return i
+	}
+	return j
+}
+
+// boundedLoad tracks in-flight requests for consistent hashing
+// with bounded loads. Each target is capped at c times the
+// average load weighted by the target weight.
+//
+// References:
+//   - https://arxiv.org/abs/1608.01350
+//   - https://research.google/blog/consistent-hashing-with-bounded-loads/
+type boundedLoad struct {
+	loads map[uint64]int64 // In-flight requests keyed by target ID.
+	total int64            // Total in-flight requests.
+}
+
+// hasCapacity returns true if the load of the target t is
+// less than the capacity. The factor is the c and the sumWeight
+// is the sum of the weights of all active targets.
+func (b *boundedLoad) hasCapacity(t Target, factor float64, sumWeight uint64) bool {
+	if sumWeight == 0 {
+		return true
+	}
+	capacity := math.Ceil(factor * float64(b.total+1) * float64(t.Weight()) / float64(sumWeight))
+	return float64(b.loads[t.ID()]) < capacity
+}
+
+func (b *boundedLoad) acquire(id uint64) {
+	if b.loads == nil {
+		b.loads = map[uint64]int64{}
+	}
+	b.loads[id]++
+	b.total++
+}
+
+func (b *boundedLoad) release(id uint64) {
+	if b.loads[id] <= 0 {
+		return
+	}
+	b.loads[id]--
+	b.total--
+}
+
+func (b *boundedLoad) remove(id uint64) {
+	b.total -= b.loads[id]
+	delete(b.loads, id)
+}
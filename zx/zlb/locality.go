@@ -0,0 +1,196 @@
+package zlb
+
+import (
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// NewLocality returns a new instance of locality-aware load balancer.
+// The levels are the load balancers of each locality level.
+// The first level is the local zone and the following levels
+// are the failover destinations in the order of preference.
+// Any load balancer can be used for each level.
+// See the comments on [Locality].
+func NewLocality[T Target](levels ...LoadBalancer[T]) *Locality[T] {
+	return &Locality[T]{levels: levels}
+}
+
+// Locality is a hierarchical load balancer that groups targets by
+// locality, or priority, levels such as zones and data centers.
+// Traffic is kept in the first level while its healthy fraction is
+// above the Threshold. When the level is degraded, traffic is spilled
+// to the next levels in proportion to the degradation.
+// A level is selected at random according to the load of the levels,
+// and the target is selected by the load balancer of the level with
+// the given hint. The next levels are tried when the load balancer of
+// the selected level did not return any target.
+// The healthy fraction of a level is the number of active and
+// non-zero weight targets divided by the number of all targets.
+// For n targets, computational complexity is O(n) in addition to
+// the complexity of the load balancers of levels.
+//
+// Algorithm:
+//
+//	Li = L[i] = Level[i]
+//	Hi = H[i] = Healthy fraction of L[i]
+//
+//	function Get(hint):
+//	  remaining <-- 1
+//	  for i range {L0 ... Lm-1}:
+//	    health <-- min(1, Hi/Threshold)
+//	    load[i] <-- min(health, remaining)
+//	    remaining <-- remaining - load[i]
+//	  if remaining > 0:
+//	    normalize load so that the sum is 1
+//	  level <-- weighted random(load)
+//	  return L[level].Get(hint)
+//
+//	Threshold = 0.7
+//	┌─────────────────┬────────┬────────┬────────┐
+//	│ Level L[i]      │   L0   │   L1   │   L2   │
+//	│ Healthy H[i]    │  100%  │  100%  │  100%  │ --> Load: 100%,  0%,  0%
+//	│ Healthy H[i]    │   70%  │  100%  │  100%  │ --> Load: 100%,  0%,  0%
+//	│ Healthy H[i]    │   35%  │  100%  │  100%  │ --> Load:  50%, 50%,  0%
+//	│ Healthy H[i]    │   35%  │   21%  │  100%  │ --> Load:  50%, 30%, 20%
+//	│ Healthy H[i]    │   35%  │    0%  │   35%  │ --> Load:  50%,  0%, 50%
+//	│ Healthy H[i]    │   14%  │    0%  │   14%  │ --> Load:  50%,  0%, 50%
+//	└─────────────────┴────────┴────────┴────────┘
+//
+// References:
+//   - https://www.envoyproxy.io/docs/envoy/latest/intro/arch_overview/upstream/load_balancing/priority
+//   - https://www.envoyproxy.io/docs/envoy/latest/intro/arch_overview/upstream/load_balancing/locality_weight
+type Locality[T Target] struct {
+	levels []LoadBalancer[T]
+	// Threshold is the healthy fraction of a level above which
+	// all traffic is kept in the level. A level with the healthy
+	// fraction h below the Threshold receives h/Threshold of traffic
+	// and the rest is spilled to the next levels.
+	// If zero, negative or greater than 1, 0.7 is used.
+	// It corresponds to the inverse of the overprovisioning factor of Envoy.
+	Threshold float64
+	// LevelOf returns the index of the level, or zone, that
+	// the target belongs to. It is used by [Locality.Add].
+	// Targets are added to the first level when LevelOf is nil
+	// or it returned an index out of range.
+	LevelOf func(t T) int
+}
+
+var _ LoadBalancer[Target] = &Locality[Target]{}
+
+// Levels returns the load balancers of levels.
+func (lb *Locality[T]) Levels() []LoadBalancer[T] {
+	return lb.levels
+}
+
+// Targets returns the targets of all levels.
+func (lb *Locality[T]) Targets() []T {
+	var targets []T
+	for _, l := range lb.levels {
+		targets = append(targets, l.Targets()...)
+	}
+	return targets
+}
+
+// Add adds targets to the load balancer of their levels.
+// The level of each target is determined by the LevelOf.
+// Add does nothing when there are no levels.
+func (lb *Locality[T]) Add(targets ...T) {
+	if len(lb.levels) == 0 {
+		return
+	}
+	for _, t := range targets {
+		i := 0
+		if lb.LevelOf != nil {
+			i = lb.LevelOf(t)
+		}
+		if i < 0 || i >= len(lb.levels) {
+			i = 0
+		}
+		lb.levels[i].Add(t)
+	}
+}
+
+// Remove removes targets with the id from all levels.
+func (lb *Locality[T]) Remove(id uint64) {
+	for _, l := range lb.levels {
+		l.Remove(id)
+	}
+}
+
+// Done reports the completion of a request to the target.
+// It is forwarded to the load balancers of levels
+// that implement [LoadReporter].
+func (lb *Locality[T]) Done(id uint64, latency time.Duration) {
+	for _, l := range lb.levels {
+		if r, ok := l.(LoadReporter); ok {
+			r.Done(id, latency)
+		}
+	}
+}
+
+// Get returns a target.
+// Returned value found is true when an active target was found.
+func (lb *Locality[T]) Get(hint uint64) (t T, found bool) {
+	loads := lb.Loads()
+	r := rand.Float64()
+	start := -1
+	for i, load := range loads {
+		if r < load {
+			start = i
+			break
+		}
+		r -= load
+	}
+	if start < 0 {
+		start = slices.IndexFunc(loads, func(l float64) bool { return l > 0 })
+		if start < 0 {
+			return t, false // No healthy levels.
+		}
+	}
+	for i := range len(lb.levels) {
+		if t, found = lb.levels[(start+i)%len(lb.levels)].Get(hint); found {
+			return t, true
+		}
+	}
+	return t, false
+}
+
+// Loads returns the fraction of traffic for each level.
+// The sum of loads is 1 unless there are no healthy levels.
+func (lb *Locality[T]) Loads() []float64 {
+	threshold := lb.Threshold
+	if threshold <= 0 || threshold > 1 {
+		threshold = 0.7
+	}
+	healths := make([]float64, len(lb.levels))
+	loads := make([]float64, len(lb.levels))
+	remaining, sum := 1.0, 0.0
+	for i, l := range lb.levels {
+		healths[i] = min(1, healthyFraction(l.Targets())/threshold)
+		loads[i] = min(healths[i], remaining)
+		remaining -= loads[i]
+		sum += healths[i]
+	}
+	if remaining > 0 && sum > 0 { // All levels are degraded.
+		for i := range loads {
+			loads[i] = healths[i] / sum
+		}
+	}
+	return loads
+}
+
+// healthyFraction returns the fraction of active
+// and non-zero weight targets.
+func healthyFraction[T Target](targets []T) float64 {
+	if len(targets) == 0 {
+		return 0
+	}
+	healthy := 0
+	for _, t := range targets {
+		if t.Active() && t.Weight() > 0 {
+			healthy++
+		}
+	}
+	return float64(healthy) / float64(len(targets))
+}
@@ -0,0 +1,123 @@
+package zlb_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/ztesting"
+	"github.com/aileron-projects/go/zx/zlb"
+)
+
+// levelTargets returns n targets with the given number of active targets.
+func levelTargets(name string, id uint64, n, active int) []*Target {
+	ts := make([]*Target, n)
+	for i := range n {
+		ts[i] = &Target{name: name, id: id + uint64(i), weight: 1, active: i < active}
+	}
+	return ts
+}
+
+func TestLocality_Loads(t *testing.T) {
+	t.Parallel()
+	testCases := map[string]struct {
+		threshold float64
+		active    []int // Active targets of 100 targets for each level.
+		loads     []float64
+	}{
+		"all healthy":         {active: []int{100, 100, 100}, loads: []float64{1, 0, 0}},
+		"above threshold":     {active: []int{70, 100, 100}, loads: []float64{1, 0, 0}},
+		"spill to next":       {active: []int{35, 100, 100}, loads: []float64{0.5, 0.5, 0}},
+		"spill to next two":   {active: []int{35, 21, 100}, loads: []float64{0.5, 0.3, 0.2}},
+		"skip unhealthy":      {active: []int{35, 0, 35}, loads: []float64{0.5, 0, 0.5}},
+		"all degraded":        {active: []int{14, 0, 14}, loads: []float64{0.5, 0, 0.5}},
+		"all unhealthy":       {active: []int{0, 0, 0}, loads: []float64{0, 0, 0}},
+		"custom threshold":    {threshold: 0.5, active: []int{25, 100}, loads: []float64{0.5, 0.5}},
+		"threshold too large": {threshold: 2, active: []int{35, 100}, loads: []float64{0.5, 0.5}},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var levels []zlb.LoadBalancer[*Target]
+			for i, a := range tc.active {
+				levels = append(levels, zlb.NewRandomW(levelTargets("l", uint64(i*1000), 100, a)...))
+			}
+			lb := zlb.NewLocality(levels...)
+			lb.Threshold = tc.threshold
+			loads := lb.Loads()
+			for i := range loads {
+				loads[i] = math.Round(loads[i]*100) / 100
+			}
+			ztesting.AssertEqual(t, "loads not match", tc.loads, loads)
+		})
+	}
+}
+
+func TestLocality_Get(t *testing.T) {
+	t.Parallel()
+	t.Run("failover", func(t *testing.T) {
+		l0 := levelTargets("l0", 0, 10, 0)
+		l1 := levelTargets("l1", 100, 10, 10)
+		lb := zlb.NewLocality[*Target](zlb.NewRoundRobin(l0...), zlb.NewRoundRobin(l1...))
+		for range 100 {
+			target, found := lb.Get(0)
+			ztesting.AssertEqual(t, "found not match", true, found)
+			ztesting.AssertEqual(t, "level not match", "l1", target.name)
+		}
+	})
+	t.Run("spill", func(t *testing.T) {
+		l0 := levelTargets("l0", 0, 10, 5)
+		l1 := levelTargets("l1", 100, 10, 10)
+		lb := zlb.NewLocality[*Target](zlb.NewRandomW(l0...), zlb.NewRandomW(l1...))
+		lb.Threshold = 1
+		count := map[string]int{}
+		for range 10000 {
+			target, _ := lb.Get(0)
+			count[target.name]++
+		}
+		if count["l0"] < 4000 || count["l0"] > 6000 {
+			t.Errorf("traffic not spilled: %v", count)
+		}
+	})
+	t.Run("no targets", func(t *testing.T) {
+		lb := zlb.NewLocality[*Target](zlb.NewRandom[*Target](), zlb.NewRandom(levelTargets("l1", 0, 2, 0)...))
+		_, found := lb.Get(0)
+		ztesting.AssertEqual(t, "found not match", false, found)
+	})
+	t.Run("inner not found", func(t *testing.T) {
+		l0 := &Target{name: "l0", id: 0, weight: 1, active: true}
+		l1 := &Target{name: "l1", id: 1, weight: 1, active: true}
+		inner := zlb.NewMaglev[*Target]()
+		inner.Add(l0) // Lookup table is not updated.
+		lb := zlb.NewLocality[*Target](inner, zlb.NewRandom(l1))
+		target, found := lb.Get(0)
+		ztesting.AssertEqual(t, "found not match", true, found)
+		ztesting.AssertEqual(t, "target not match", "l1", target.name)
+	})
+	t.Run("targets and remove", func(t *testing.T) {
+		l0 := levelTargets("l0", 0, 2, 2)
+		l1 := levelTargets("l1", 100, 2, 2)
+		lc := zlb.NewLeastConn(l0...)
+		lb := zlb.NewLocality[*Target](lc, zlb.NewRandom(l1...))
+		ztesting.AssertEqual(t, "number of levels not match", 2, len(lb.Levels()))
+		ztesting.AssertEqual(t, "number of targets not match", 4, len(lb.Targets()))
+		lb.Remove(0)
+		lb.Remove(100)
+		ztesting.AssertEqual(t, "number of targets not match", 2, len(lb.Targets()))
+		target, _ := lb.Get(0)
+		lb.Done(target.ID(), time.Millisecond)
+		target, _ = lb.Get(0)
+		ztesting.AssertEqual(t, "target not match", uint64(1), target.ID())
+	})
+	t.Run("add", func(t *testing.T) {
+		lb := zlb.NewLocality[*Target](zlb.NewRandom[*Target](), zlb.NewRandom[*Target]())
+		lb.Add(levelTargets("l0", 0, 1, 1)...) // LevelOf is nil.
+		lb.LevelOf = func(t *Target) int {
+			return map[string]int{"l0": 0, "l1": 1, "l9": 9}[t.name]
+		}
+		lb.Add(levelTargets("l1", 100, 2, 2)...)
+		lb.Add(levelTargets("l9", 900, 1, 1)...) // Out of range.
+		ztesting.AssertEqual(t, "number of l0 targets not match", 2, len(lb.Levels()[0].Targets()))
+		ztesting.AssertEqual(t, "number of l1 targets not match", 2, len(lb.Levels()[1].Targets()))
+		zlb.NewLocality[*Target]().Add(levelTargets("l0", 0, 1, 1)...) // No levels.
+	})
+}
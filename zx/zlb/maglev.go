@@ -2,6 +2,7 @@ package zlb
 
 import (
 	"math"
+	"time"
 )
 
 // NewMaglev returns a new instance of maglev hash load balancer.
@@ -24,9 +25,13 @@ func NewMaglev[T Target](targets ...T) *Maglev[T] {
 // Note: The load balancer may return false even when active and
 // non-zero-weight targets exist.
 //
-// Target weights are evaluated only when the lookup table is rebuilt,
-// and cannot be updated dynamically.
-// Inactive targets are excluded from the table during reconstruction.
+// Target weights and status are evaluated only when the lookup table
+// is rebuilt. Inactive targets are excluded from the table during
+// reconstruction. The table is automatically rebuilt when the status
+// or weight of [ManagedTarget]s changed. Changes of other targets are
+// not reflected until [Maglev.Update] is called.
+// Call [Maglev.Close] to stop watching ManagedTargets when the
+// load balancer is no longer used.
 //
 // Algorithm:
 //   - See references for the original Maglev hashing design.
@@ -47,17 +52,74 @@ type Maglev[T Target] struct {
 	// The actual lookup table size wil be adjusted
 	// based on the target weights.
 	Size int
+	// UpdateDelay is the delay of automatic updates of the lookup
+	// table triggered by changes of [ManagedTarget]s.
+	// Changes within the delay are coalesced into a single update.
+	// If zero or negative, 100 milliseconds is used.
+	UpdateDelay time.Duration
+	// LoadFactor is the factor c of consistent hashing with bounded loads.
+	// If greater than 1, each target is capped at c times the average
+	// in-flight requests weighted by the target weight and requests
+	// to overloaded targets are walked to the next position of the
+	// lookup table. Targets are selected in the same way as unbounded
+	// one while they are not overloaded. Callers must report the
+	// completion of requests with [Maglev.Done].
+	// Typical value is 1.25. Otherwise, bounded load is disabled.
+	// LoadFactor must not be changed after Get is called.
+	LoadFactor float64
+
+	updater   autoUpdater
+	bound     boundedLoad
+	sumWeight uint64 // Sum of weights of targets in the table.
+}
+
+// Add adds targets.
+// lb.Update should be called after adding targets.
+// [ManagedTarget]s are watched and the lookup table is automatically
+// updated when their status or weight changed.
+func (lb *Maglev[T]) Add(targets ...T) {
+	lb.baseLB.Add(targets...)
+	for _, t := range targets {
+		lb.updater.watch(t, lb.updateDelay, lb.Update)
+	}
 }
 
 // Remove removes target.
 // lb.Update is internally called after removing the target.
 func (lb *Maglev[T]) Remove(id uint64) {
+	lb.updater.unwatch(id)
 	lb.mu.Lock()
 	defer lb.mu.Unlock()
 	lb.remove(id)
+	lb.bound.remove(id)
 	lb.update()
 }
 
+// Done reports the completion of a request to the target.
+// It must be called for each successful Get when
+// LoadFactor is greater than 1. Otherwise, it does nothing.
+func (lb *Maglev[T]) Done(id uint64, _ time.Duration) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.bound.release(id)
+}
+
+// Close stops watching [ManagedTarget]s.
+// The lookup table is no longer updated automatically after Close
+// but the load balancer can still be used with [Maglev.Update].
+// Close always returns nil.
+func (lb *Maglev[T]) Close() error {
+	lb.updater.close()
+	return nil
+}
+
+func (lb *Maglev[T]) updateDelay() time.Duration {
+	if lb.UpdateDelay <= 0 {
+		return 100 * time.Millisecond
+	}
+	return lb.UpdateDelay
+}
+
 // Update update lookup table.
 func (lb *Maglev[T]) Update() {
 	lb.mu.Lock()
@@ -75,6 +137,7 @@ func (lb *Maglev[T]) update() {
 		targets = append(targets, i)
 		sumWeight += int(t.Weight())
 	}
+	lb.sumWeight = uint64(sumWeight)
 	if len(targets) == 0 {
 		clear(lb.table)
 		lb.table = nil
@@ -142,12 +205,36 @@ func (lb *Maglev[T]) Get(key uint64) (t T, found bool) {
 		i := lb.table[index]
 		t = lb.targets[i]
 		if t.Active() && t.Weight() > 0 {
+			if lb.LoadFactor > 1 {
+				return lb.getBounded(index), true
+			}
 			return t, true
 		}
 	}
 	return t, false
 }
 
+// getBounded returns the first target that is not overloaded
+// walking the lookup table from the index.
+// It returns the target at the index if all targets are overloaded.
+// It must be called with lb.mu held.
+func (lb *Maglev[T]) getBounded(index int) T {
+	n := len(lb.table)
+	for j := range n {
+		t := lb.targets[lb.table[(index+j)%n]]
+		if !t.Active() || t.Weight() == 0 {
+			continue
+		}
+		if lb.bound.hasCapacity(t, lb.LoadFactor, lb.sumWeight) {
+			lb.bound.acquire(t.ID())
+			return t
+		}
+	}
+	t := lb.targets[lb.table[index]]
+	lb.bound.acquire(t.ID())
+	return t
+}
+
 // genPrimeEuler returns a prime number grater than
 // the given min value using euler's formula.
 // genPrimeEuler returns a prime value grater than 3.
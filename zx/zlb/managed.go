@@ -0,0 +1,176 @@
+package zlb
+
+import (
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// NewManagedTarget returns a new instance of [ManagedTarget].
+// The ID of the target is the FNV-1a hash of the addr.
+// The returned target is active.
+func NewManagedTarget(addr string, weight uint16) *ManagedTarget {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(addr))
+	t := &ManagedTarget{id: h.Sum64(), addr: addr}
+	t.active.Store(true)
+	t.weight.Store(uint32(weight))
+	return t
+}
+
+// ManagedTarget is a [Target] whose active status and weight
+// can be changed dynamically.
+// The active status is typically changed by [HealthCheck].
+// Consistent hash load balancers, [Maglev] and [RingHash],
+// rebuild their lookup tables when the status or weight of
+// registered ManagedTargets changed.
+// ManagedTarget is safe for concurrent use.
+type ManagedTarget struct {
+	id     uint64
+	addr   string
+	active atomic.Bool
+	weight atomic.Uint32
+
+	mu       sync.Mutex
+	watchers map[*func()]struct{}
+}
+
+// ID returns the identifier of this target.
+func (t *ManagedTarget) ID() uint64 {
+	return t.id
+}
+
+// Addr returns the address of this target.
+func (t *ManagedTarget) Addr() string {
+	return t.addr
+}
+
+// Active returns if this target is active or not.
+func (t *ManagedTarget) Active() bool {
+	return t.active.Load()
+}
+
+// Weight returns the weight of this target.
+func (t *ManagedTarget) Weight() uint16 {
+	return uint16(t.weight.Load())
+}
+
+// SetActive sets the active status of this target.
+func (t *ManagedTarget) SetActive(active bool) {
+	if t.active.Swap(active) != active {
+		t.notify()
+	}
+}
+
+// SetWeight sets the weight of this target.
+func (t *ManagedTarget) SetWeight(weight uint16) {
+	if t.weight.Swap(uint32(weight)) != uint32(weight) {
+		t.notify()
+	}
+}
+
+// watch registers the f that is called when the status or weight changed.
+// It returns a function that unregisters the f.
+func (t *ManagedTarget) watch(f func()) (cancel func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.watchers == nil {
+		t.watchers = map[*func()]struct{}{}
+	}
+	t.watchers[&f] = struct{}{}
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.watchers, &f)
+	}
+}
+
+func (t *ManagedTarget) notify() {
+	t.mu.Lock()
+	fs := make([]func(), 0, len(t.watchers))
+	for f := range t.watchers {
+		fs = append(fs, *f)
+	}
+	t.mu.Unlock()
+	for _, f := range fs {
+		f()
+	}
+}
+
+// watcher is implemented by [ManagedTarget].
+type watcher interface {
+	watch(f func()) (cancel func())
+}
+
+// autoUpdater calls the update function of load balancers
+// when the status or weight of [ManagedTarget]s changed.
+// Changes within the delay are coalesced into a single update.
+type autoUpdater struct {
+	mu      sync.Mutex
+	cancels map[uint64]func() // Keyed by target ID.
+	pending bool
+	closed  bool
+}
+
+// watch watches the target t if it implements the [watcher].
+// The update is called after the delay from the first change.
+func (u *autoUpdater) watch(t Target, delay func() time.Duration, update func()) {
+	w, ok := t.(watcher)
+	if !ok {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.closed {
+		return
+	}
+	if u.cancels == nil {
+		u.cancels = map[uint64]func(){}
+	}
+	if cancel, ok := u.cancels[t.ID()]; ok {
+		cancel() // Target with the same ID is replaced.
+	}
+	u.cancels[t.ID()] = w.watch(func() { u.schedule(delay(), update) })
+}
+
+// unwatch stops watching the target with the id.
+func (u *autoUpdater) unwatch(id uint64) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if cancel, ok := u.cancels[id]; ok {
+		cancel()
+		delete(u.cancels, id)
+	}
+}
+
+// close stops watching all targets.
+// Targets are no longer watched after close
+// and the scheduled update, if any, is not called.
+func (u *autoUpdater) close() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.closed = true
+	for _, cancel := range u.cancels {
+		cancel()
+	}
+	u.cancels = nil
+}
+
+func (u *autoUpdater) schedule(delay time.Duration, update func()) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.pending || u.closed {
+		return
+	}
+	u.pending = true
+	time.AfterFunc(delay, func() {
+		u.mu.Lock()
+		u.pending = false
+		closed := u.closed
+		u.mu.Unlock()
+		if !closed {
+			update()
+		}
+	})
+}
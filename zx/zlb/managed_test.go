@@ -0,0 +1,143 @@
+package zlb
+
+import (
+	"hash/fnv"
+	"testing"
+	"time"
+
+	"github.com/aileron-projects/go/ztesting"
+)
+
+func TestManagedTarget(t *testing.T) {
+	t.Parallel()
+	t.Run("new", func(t *testing.T) {
+		h := fnv.New64a()
+		h.Write([]byte("127.0.0.1:8080"))
+		mt := NewManagedTarget("127.0.0.1:8080", 3)
+		ztesting.AssertEqual(t, "id not match", h.Sum64(), mt.ID())
+		ztesting.AssertEqual(t, "addr not match", "127.0.0.1:8080", mt.Addr())
+		ztesting.AssertEqual(t, "active not match", true, mt.Active())
+		ztesting.AssertEqual(t, "weight not match", uint16(3), mt.Weight())
+	})
+	t.Run("notify changes", func(t *testing.T) {
+		mt := NewManagedTarget("test", 1)
+		count := 0
+		cancel := mt.watch(func() { count++ })
+		mt.SetActive(true) // Not changed.
+		mt.SetWeight(1)    // Not changed.
+		ztesting.AssertEqual(t, "count not match", 0, count)
+		mt.SetActive(false)
+		mt.SetWeight(2)
+		ztesting.AssertEqual(t, "count not match", 2, count)
+		ztesting.AssertEqual(t, "active not match", false, mt.Active())
+		ztesting.AssertEqual(t, "weight not match", uint16(2), mt.Weight())
+		cancel()
+		mt.SetActive(true)
+		ztesting.AssertEqual(t, "count not match", 2, count)
+	})
+}
+
+func TestAutoUpdater(t *testing.T) {
+	t.Parallel()
+	t.Run("coalesce", func(t *testing.T) {
+		mt := NewManagedTarget("test", 1)
+		updated := make(chan struct{}, 10)
+		u := &autoUpdater{}
+		u.watch(mt, func() time.Duration { return 20 * time.Millisecond }, func() { updated <- struct{}{} })
+		u.watch(&testTarget{id: 1}, nil, nil) // Not watched.
+		mt.SetWeight(2)
+		mt.SetWeight(3)
+		mt.SetActive(false)
+		<-updated
+		time.Sleep(50 * time.Millisecond)
+		ztesting.AssertEqual(t, "updates must be coalesced", 0, len(updated))
+		mt.SetActive(true)
+		<-updated
+	})
+	t.Run("unwatch", func(t *testing.T) {
+		mt := NewManagedTarget("test", 1)
+		u := &autoUpdater{}
+		u.watch(mt, func() time.Duration { return 0 }, func() { t.Error("update must not be called") })
+		u.watch(mt, func() time.Duration { return 0 }, func() { t.Error("update must not be called") })
+		ztesting.AssertEqual(t, "number of watchers not match", 1, len(mt.watchers))
+		u.unwatch(mt.ID())
+		u.unwatch(mt.ID())
+		ztesting.AssertEqual(t, "number of watchers not match", 0, len(mt.watchers))
+		mt.SetActive(false)
+		time.Sleep(10 * time.Millisecond)
+	})
+	t.Run("close", func(t *testing.T) {
+		mt1 := NewManagedTarget("test1", 1)
+		mt2 := NewManagedTarget("test2", 1)
+		u := &autoUpdater{}
+		u.watch(mt1, func() time.Duration { return 20 * time.Millisecond }, func() { t.Error("update must not be called") })
+		u.watch(mt2, func() time.Duration { return 20 * time.Millisecond }, func() { t.Error("update must not be called") })
+		mt1.SetActive(false) // Scheduled update is canceled by close.
+		u.close()
+		ztesting.AssertEqual(t, "number of watchers not match", 0, len(mt1.watchers))
+		ztesting.AssertEqual(t, "number of watchers not match", 0, len(mt2.watchers))
+		u.watch(mt1, func() time.Duration { return 0 }, func() { t.Error("update must not be called") })
+		ztesting.AssertEqual(t, "closed updater must not watch", 0, len(mt1.watchers))
+		mt2.SetActive(false)
+		time.Sleep(50 * time.Millisecond)
+	})
+}
+
+func TestMaglev_autoUpdate(t *testing.T) {
+	t.Parallel()
+	t1 := NewManagedTarget("t1", 1)
+	t2 := NewManagedTarget("t2", 1)
+	lb := NewMaglev(t1, t2)
+	lb.UpdateDelay = time.Millisecond
+	tableTargets := func() map[uint64]bool {
+		lb.mu.Lock()
+		defer lb.mu.Unlock()
+		m := map[uint64]bool{}
+		for _, i := range lb.table {
+			m[lb.targets[i].ID()] = true
+		}
+		return m
+	}
+	ztesting.AssertEqual(t, "number of targets not match", 2, len(tableTargets()))
+
+	t1.SetActive(false)
+	for tableTargets()[t1.ID()] {
+		time.Sleep(time.Millisecond)
+	}
+	ztesting.AssertEqual(t, "t2 must be in the table", true, tableTargets()[t2.ID()])
+
+	t1.SetActive(true)
+	for !tableTargets()[t1.ID()] {
+		time.Sleep(time.Millisecond)
+	}
+
+	lb.Remove(t1.ID())
+	ztesting.AssertEqual(t, "number of watchers not match", 0, len(t1.watchers))
+	ztesting.AssertEqualErr(t, "close error", nil, lb.Close())
+	ztesting.AssertEqual(t, "number of watchers not match", 0, len(t2.watchers))
+}
+
+func TestRingHash_autoUpdate(t *testing.T) {
+	t.Parallel()
+	t1 := NewManagedTarget("t1", 1)
+	t2 := NewManagedTarget("t2", 1)
+	lb := NewRingHash(t1, t2)
+	lb.UpdateDelay = time.Millisecond
+	ringSize := func() int {
+		lb.mu.Lock()
+		defer lb.mu.Unlock()
+		return len(lb.ring)
+	}
+	ztesting.AssertEqual(t, "ring size not match", 2, ringSize())
+
+	t1.SetWeight(10)
+	for ringSize() != 11 {
+		time.Sleep(time.Millisecond)
+	}
+
+	lb.Remove(t1.ID())
+	ztesting.AssertEqual(t, "ring size not match", 1, ringSize())
+	ztesting.AssertEqual(t, "number of watchers not match", 0, len(t1.watchers))
+	ztesting.AssertEqualErr(t, "close error", nil, lb.Close())
+	ztesting.AssertEqual(t, "number of watchers not match", 0, len(t2.watchers))
+}
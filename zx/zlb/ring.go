@@ -3,6 +3,7 @@ package zlb
 import (
 	"slices"
 	"sort"
+	"time"
 )
 
 // NewRingHash returns a new instance of ring hash load balancer.
@@ -23,8 +24,12 @@ func NewRingHash[T Target](targets ...T) *RingHash[T] {
 // Note that the load balancer may return false even active and
 // non-zero weight targets are exist.
 // The internal virtual ring is updated when [RingHash.Update] is called.
-// Target weights are evaluated at the time of updating the virtual ring
-// and cannot be updated dynamically.
+// Target weights and status are evaluated only when the virtual ring
+// is updated. The virtual ring is automatically updated when the status
+// or weight of [ManagedTarget]s changed. Changes of other targets are
+// not reflected until [RingHash.Update] is called.
+// Call [RingHash.Close] to stop watching ManagedTargets when the
+// load balancer is no longer used.
 //
 // Algorithm:
 //
@@ -75,17 +80,74 @@ func NewRingHash[T Target](targets ...T) *RingHash[T] {
 type RingHash[T Target] struct {
 	*baseLB[T]
 	ring [][2]uint64
+	// UpdateDelay is the delay of automatic updates of the virtual
+	// ring triggered by changes of [ManagedTarget]s.
+	// Changes within the delay are coalesced into a single update.
+	// If zero or negative, 100 milliseconds is used.
+	UpdateDelay time.Duration
+	// LoadFactor is the factor c of consistent hashing with bounded loads.
+	// If greater than 1, each target is capped at c times the average
+	// in-flight requests weighted by the target weight and requests
+	// to overloaded targets are walked to the next position of the
+	// virtual ring. Targets are selected in the same way as unbounded
+	// one while they are not overloaded. Callers must report the
+	// completion of requests with [RingHash.Done].
+	// Typical value is 1.25. Otherwise, bounded load is disabled.
+	// LoadFactor must not be changed after Get is called.
+	LoadFactor float64
+
+	updater   autoUpdater
+	bound     boundedLoad
+	sumWeight uint64 // Sum of weights of active targets.
+}
+
+// Add adds targets.
+// lb.Update should be called after adding targets.
+// [ManagedTarget]s are watched and the virtual ring is automatically
+// updated when their status or weight changed.
+func (lb *RingHash[T]) Add(targets ...T) {
+	lb.baseLB.Add(targets...)
+	for _, t := range targets {
+		lb.updater.watch(t, lb.updateDelay, lb.Update)
+	}
 }
 
 // Remove removes target.
 // lb.Update is internally called after removing the target.
 func (lb *RingHash[T]) Remove(id uint64) {
+	lb.updater.unwatch(id)
 	lb.mu.Lock()
 	defer lb.mu.Unlock()
 	lb.remove(id)
+	lb.bound.remove(id)
 	lb.update()
 }
 
+// Done reports the completion of a request to the target.
+// It must be called for each successful Get when
+// LoadFactor is greater than 1. Otherwise, it does nothing.
+func (lb *RingHash[T]) Done(id uint64, _ time.Duration) {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+	lb.bound.release(id)
+}
+
+// Close stops watching [ManagedTarget]s.
+// The virtual ring is no longer updated automatically after Close
+// but the load balancer can still be used with [RingHash.Update].
+// Close always returns nil.
+func (lb *RingHash[T]) Close() error {
+	lb.updater.close()
+	return nil
+}
+
+func (lb *RingHash[T]) updateDelay() time.Duration {
+	if lb.UpdateDelay <= 0 {
+		return 100 * time.Millisecond
+	}
+	return lb.UpdateDelay
+}
+
 // Update update lookup table.
 func (lb *RingHash[T]) Update() {
 	lb.mu.Lock()
@@ -95,8 +157,12 @@ func (lb *RingHash[T]) Update() {
 
 func (lb *RingHash[T]) update() {
 	sumWeight := uint64(0)
+	lb.sumWeight = 0
 	for i := range lb.targets {
 		sumWeight += uint64(lb.targets[i].Weight())
+		if lb.targets[i].Active() {
+			lb.sumWeight += uint64(lb.targets[i].Weight())
+		}
 	}
 	clear(lb.ring)
 	if uint64(cap(lb.ring)) < sumWeight {
@@ -137,7 +203,13 @@ func (lb *RingHash[T]) Get(key uint64) (t T, found bool) {
 		return lb.ring[i][0] >= key
 	})
 
-	for range min(10*len(lb.targets), n) { // No reason to the min value.
+	bounded := lb.LoadFactor > 1
+	steps := min(10*len(lb.targets), n) // No reason to the min value.
+	if bounded {
+		steps = n // Walk the entire ring to find non-overloaded targets.
+	}
+	first := -1 // Index of the first active target.
+	for range steps {
 		if index >= n {
 			index = 0
 		}
@@ -147,6 +219,21 @@ func (lb *RingHash[T]) Get(key uint64) (t T, found bool) {
 			index++
 			continue
 		}
+		if !bounded {
+			return t, true
+		}
+		if lb.bound.hasCapacity(t, lb.LoadFactor, lb.sumWeight) {
+			lb.bound.acquire(t.ID())
+			return t, true
+		}
+		if first < 0 {
+			first = int(i)
+		}
+		index++
+	}
+	if first >= 0 { // All targets are overloaded.
+		t = lb.targets[first]
+		lb.bound.acquire(t.ID())
 		return t, true
 	}
 	return t, false